@@ -0,0 +1,627 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/JunxiHe459/gateway/controller"
+	"github.com/JunxiHe459/gateway/dto"
+	"github.com/JunxiHe459/gateway/migration"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/e421083458/gorm"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// 命令行子命令，不启动 http 服务
+// 默认直接读写数据库；指定 -api 时调用运行中的管理接口，用 token 或用户名与密码认证
+// 密码与 token 从环境变量或文件读取，不在命令行参数中出现，避免被进程列表看到
+// 开启两步验证的管理员用 -user 登录时还需要 -totp
+const commandUsage = `usage: gateway [-api URL [-token-file FILE | -user NAME [-password-file FILE] [-totp CODE]]] <command> [args]
+
+commands:
+  service list
+  service show NAME [-format yaml|json]
+  service add -f FILE [-dry-run]
+  service update -f FILE [-dry-run]
+  service delete NAME
+  renter list
+  renter show RENTER_ID [-show-secret]
+  renter add -id RENTER_ID [-name NAME] [-secret SECRET] [-white-ips IPS] [-qpd N] [-qps N] [-dry-run]
+  renter update -id RENTER_ID [-name NAME] [-secret SECRET] [-white-ips IPS] [-qpd N] [-qps N] [-dry-run]
+  renter delete RENTER_ID
+  admin reset-password -user NAME [-password-file FILE] [-enable] [-reset-totp]
+  config export [-format yaml|json] [-o FILE] [-show-secret]
+  config import -f FILE|- [-dry-run] [-prune]
+  config validate -f FILE|-
+  migrate up [-to VERSION] [-admin-user NAME]
+  migrate down [-steps N]
+  migrate status
+
+environment:
+  GATEWAY_API, GATEWAY_USER
+  GATEWAY_PASSWORD    admin password, or -password-file
+  GATEWAY_TOKEN       admin api token or jwt, or -token-file
+`
+
+type commandHandler func(backend commandBackend, args []string) error
+
+var commands = map[string]map[string]commandHandler{
+	"service": {
+		"list":   serviceList,
+		"show":   serviceShow,
+		"add":    serviceAdd,
+		"update": serviceUpdate,
+		"delete": serviceDelete,
+	},
+	"renter": {
+		"list":   renterList,
+		"show":   renterShow,
+		"add":    renterAdd,
+		"update": renterUpdate,
+		"delete": renterDelete,
+	},
+	"admin": {
+		"reset-password": adminResetPassword,
+	},
+	"config": {
+		"export":   configExport,
+		"import":   configImport,
+		"validate": configValidate,
+	},
+	"migrate": {
+		"up":     migrateUp,
+		"down":   migrateDown,
+		"status": migrateStatus,
+	},
+}
+
+// runCommand 返回进程退出码，2 表示参数错误
+func runCommand(args []string) int {
+	flags := flag.NewFlagSet("gateway", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, commandUsage) }
+	api := flags.String("api", os.Getenv("GATEWAY_API"), "admin api address, empty to use the database directly")
+	user := flags.String("user", os.Getenv("GATEWAY_USER"), "admin user name for -api")
+	passwordFile := flags.String("password-file", "", "file holding the admin password for -user, default $GATEWAY_PASSWORD")
+	tokenFile := flags.String("token-file", "", "file holding the admin api token, default $GATEWAY_TOKEN")
+	totpCode := flags.String("totp", "", "two-factor code or recovery code for -user")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	password, err := readSecret(*passwordFile, "GATEWAY_PASSWORD")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	token, err := readSecret(*tokenFile, "GATEWAY_TOKEN")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	args = flags.Args()
+	if len(args) < 2 || commands[args[0]][args[1]] == nil {
+		flags.Usage()
+		return 2
+	}
+
+	// validate 只检查文件，不需要连接
+	var backend commandBackend
+	if args[0] != "config" || args[1] != "validate" {
+		if *api == "" {
+			backend = newDBBackend()
+		} else {
+			apiBackend, err := newAPIBackend(*api, token, *user, password, *totpCode)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			backend = apiBackend
+		}
+	}
+	if err := commands[args[0]][args[1]](backend, args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if err == flag.ErrHelp || errors.Cause(err) == errCommandUsage {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+var errCommandUsage = errors.New("invalid arguments")
+
+func serviceList(backend commandBackend, args []string) error {
+	doc, err := backend.Export(false)
+	if err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tTYPE\tRULE\tIP_LIST\tDESC")
+	for _, item := range doc.Services {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", item.Name, item.LoadType, serviceRule(item),
+			item.LoadBalance.IpList, item.Desc)
+	}
+	return writer.Flush()
+}
+
+func serviceShow(backend commandBackend, args []string) error {
+	flags := flag.NewFlagSet("service show", flag.ContinueOnError)
+	format := flags.String("format", public.GatewayConfigFormatYAML, "yaml or json")
+	name, err := parseWithName(flags, args, "NAME")
+	if err != nil {
+		return err
+	}
+	doc, err := backend.Export(false)
+	if err != nil {
+		return err
+	}
+	for _, item := range doc.Services {
+		if item.Name == name {
+			return printValue(item, *format)
+		}
+	}
+	return errors.Errorf("service %s not found", name)
+}
+
+func serviceAdd(backend commandBackend, args []string) error {
+	return serviceApply(backend, args, false)
+}
+
+func serviceUpdate(backend commandBackend, args []string) error {
+	return serviceApply(backend, args, true)
+}
+
+// serviceApply 文件内容为配置文档中的一个服务，通过导入写入，与 config import 使用同一套检查
+func serviceApply(backend commandBackend, args []string, exist bool) error {
+	flags := flag.NewFlagSet("service", flag.ContinueOnError)
+	file := flags.String("f", "", "service file in yaml or json, - for stdin")
+	dryRun := flags.Bool("dry-run", false, "print changes without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	data, err := readInput(*file)
+	if err != nil {
+		return err
+	}
+	item := dto.GatewayServiceConfig{}
+	if err := decodeStrict(data, &item); err != nil {
+		return err
+	}
+
+	doc, err := backend.Export(false)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, current := range doc.Services {
+		found = found || current.Name == item.Name
+	}
+	if found != exist {
+		if exist {
+			return errors.Errorf("service %s not found", item.Name)
+		}
+		return errors.Errorf("service %s already exists", item.Name)
+	}
+	return applyAndPrint(backend, &dto.GatewayConfig{
+		Version:  public.GatewayConfigVersion,
+		Services: []dto.GatewayServiceConfig{item},
+	}, *dryRun, false)
+}
+
+func serviceDelete(backend commandBackend, args []string) error {
+	name, err := parseWithName(flag.NewFlagSet("service delete", flag.ContinueOnError), args, "NAME")
+	if err != nil {
+		return err
+	}
+	if err := backend.DeleteService(name); err != nil {
+		return err
+	}
+	fmt.Printf("delete service %s\n", name)
+	return nil
+}
+
+func renterList(backend commandBackend, args []string) error {
+	doc, err := backend.Export(false)
+	if err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "RENTER_ID\tNAME\tQPD\tQPS\tWHITE_IPS")
+	for _, item := range doc.Renters {
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%d\t%s\n", item.RenterID, item.Name, item.Qpd, item.Qps, item.WhiteIPS)
+	}
+	return writer.Flush()
+}
+
+func renterShow(backend commandBackend, args []string) error {
+	flags := flag.NewFlagSet("renter show", flag.ContinueOnError)
+	format := flags.String("format", public.GatewayConfigFormatYAML, "yaml or json")
+	showSecret := flags.Bool("show-secret", false, "print the secret instead of "+public.RedactedValue)
+	renterID, err := parseWithName(flags, args, "RENTER_ID")
+	if err != nil {
+		return err
+	}
+	doc, err := backend.Export(*showSecret)
+	if err != nil {
+		return err
+	}
+	for _, item := range doc.Renters {
+		if item.RenterID == renterID {
+			return printValue(item, *format)
+		}
+	}
+	return errors.Errorf("renter %s not found", renterID)
+}
+
+func renterAdd(backend commandBackend, args []string) error {
+	return renterApply(backend, args, false)
+}
+
+func renterUpdate(backend commandBackend, args []string) error {
+	return renterApply(backend, args, true)
+}
+
+// renterApply 修改时只覆盖命令行中出现的参数，secret 为空时添加使用默认密钥
+func renterApply(backend commandBackend, args []string, exist bool) error {
+	flags := flag.NewFlagSet("renter", flag.ContinueOnError)
+	renterID := flags.String("id", "", "renter id")
+	name := flags.String("name", "", "renter name")
+	secret := flags.String("secret", "", "secret, empty for md5(renter id)")
+	whiteIPS := flags.String("white-ips", "", "ip white list")
+	qpd := flags.Int64("qpd", 0, "requests per day, 0 for unlimited")
+	qps := flags.Int64("qps", 0, "requests per second, 0 for unlimited")
+	dryRun := flags.Bool("dry-run", false, "print changes without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *renterID == "" {
+		return errors.Wrap(errCommandUsage, "-id is required")
+	}
+
+	doc, err := backend.Export(false)
+	if err != nil {
+		return err
+	}
+	var item *dto.GatewayRenterConfig
+	for index := range doc.Renters {
+		if doc.Renters[index].RenterID == *renterID {
+			item = &doc.Renters[index]
+		}
+	}
+	if (item != nil) != exist {
+		if exist {
+			return errors.Errorf("renter %s not found", *renterID)
+		}
+		return errors.Errorf("renter %s already exists", *renterID)
+	}
+	if item == nil {
+		item = &dto.GatewayRenterConfig{RenterID: *renterID}
+	}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "name":
+			item.Name = *name
+		case "secret":
+			item.Secret = *secret
+		case "white-ips":
+			item.WhiteIPS = *whiteIPS
+		case "qpd":
+			item.Qpd = *qpd
+		case "qps":
+			item.Qps = *qps
+		}
+	})
+	return applyAndPrint(backend, &dto.GatewayConfig{
+		Version: public.GatewayConfigVersion,
+		Renters: []dto.GatewayRenterConfig{*item},
+	}, *dryRun, false)
+}
+
+func renterDelete(backend commandBackend, args []string) error {
+	renterID, err := parseWithName(flag.NewFlagSet("renter delete", flag.ContinueOnError), args, "RENTER_ID")
+	if err != nil {
+		return err
+	}
+	if err := backend.DeleteRenter(renterID); err != nil {
+		return err
+	}
+	fmt.Printf("delete renter %s\n", renterID)
+	return nil
+}
+
+// adminResetPassword 不指定密码时随机生成并打印
+func adminResetPassword(backend commandBackend, args []string) error {
+	flags := flag.NewFlagSet("admin reset-password", flag.ContinueOnError)
+	user := flags.String("user", "", "admin user name")
+	passwordFile := flags.String("password-file", "", "file holding the new password, empty to generate one")
+	enable := flags.Bool("enable", false, "also enable a disabled admin")
+	resetTOTP := flags.Bool("reset-totp", false, "also reset two-factor authentication, e.g. after losing the authenticator")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *user == "" {
+		return errors.Wrap(errCommandUsage, "-user is required")
+	}
+	password := ""
+	if *passwordFile != "" {
+		var err error
+		if password, err = readSecret(*passwordFile, ""); err != nil {
+			return err
+		}
+	}
+	generated := password == ""
+	if generated {
+		password = public.NewSalt()
+	}
+	if err := backend.ResetPassword(*user, password, *enable, *resetTOTP); err != nil {
+		return err
+	}
+	if generated {
+		fmt.Printf("password of %s reset to %s\n", *user, password)
+	} else {
+		fmt.Printf("password of %s reset\n", *user)
+	}
+	return nil
+}
+
+func configExport(backend commandBackend, args []string) error {
+	flags := flag.NewFlagSet("config export", flag.ContinueOnError)
+	format := flags.String("format", public.GatewayConfigFormatYAML, "yaml or json")
+	output := flags.String("o", "", "output file, default stdout")
+	showSecret := flags.Bool("show-secret", false, "export renter secrets instead of "+public.RedactedValue)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	doc, err := backend.Export(*showSecret)
+	if err != nil {
+		return err
+	}
+	data, err := controller.EncodeGatewayConfig(doc, *format)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(*output, data, 0600)
+}
+
+func configImport(backend commandBackend, args []string) error {
+	flags := flag.NewFlagSet("config import", flag.ContinueOnError)
+	file := flags.String("f", "", "config file, - for stdin")
+	dryRun := flags.Bool("dry-run", false, "print changes without writing")
+	prune := flags.Bool("prune", false, "soft delete services and renters missing from the file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	data, err := readInput(*file)
+	if err != nil {
+		return err
+	}
+	doc, err := controller.DecodeGatewayConfig(data)
+	if err != nil {
+		return err
+	}
+	return applyAndPrint(backend, doc, *dryRun, *prune)
+}
+
+func configValidate(backend commandBackend, args []string) error {
+	flags := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	file := flags.String("f", "", "config file, - for stdin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	data, err := readInput(*file)
+	if err != nil {
+		return err
+	}
+	doc, err := controller.DecodeGatewayConfig(data)
+	if err != nil {
+		return err
+	}
+	if err := controller.ValidateGatewayConfig(doc); err != nil {
+		return err
+	}
+	fmt.Printf("ok, %d service(s), %d renter(s)\n", len(doc.Services), len(doc.Renters))
+	return nil
+}
+
+// migrateUp 执行迁移后没有管理员时创建超级管理员，随机密码只打印这一次
+func migrateUp(backend commandBackend, args []string) error {
+	flags := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+	target := flags.Int("to", 0, "target version, 0 for latest")
+	adminUser := flags.String("admin-user", "admin", "user name of the bootstrap admin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	db, err := migrateDB(backend)
+	if err != nil {
+		return err
+	}
+	done, err := migration.Up(backend.(*dbBackend).c, db, *target)
+	for _, m := range done {
+		fmt.Printf("up %d %s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(done) == 0 {
+		fmt.Println("already up to date")
+	}
+	password, err := migration.Bootstrap(backend.(*dbBackend).c, db, *adminUser)
+	if err != nil {
+		return errors.Wrap(err, "bootstrap admin")
+	}
+	if password != "" {
+		fmt.Printf("created super admin %s with password %s, change it after first login\n", *adminUser, password)
+	}
+	return nil
+}
+
+func migrateDown(backend commandBackend, args []string) error {
+	flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+	steps := flags.Int("steps", 1, "number of migrations to roll back")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *steps <= 0 {
+		return errors.Wrap(errCommandUsage, "-steps should be positive")
+	}
+	db, err := migrateDB(backend)
+	if err != nil {
+		return err
+	}
+	done, err := migration.Down(backend.(*dbBackend).c, db, *steps)
+	for _, m := range done {
+		fmt.Printf("down %d %s\n", m.Version, m.Name)
+	}
+	return err
+}
+
+func migrateStatus(backend commandBackend, args []string) error {
+	db, err := migrateDB(backend)
+	if err != nil {
+		return err
+	}
+	list, err := migration.List(backend.(*dbBackend).c, db)
+	if err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tNAME\tSTATUS\tAPPLIED_AT")
+	for _, item := range list {
+		status, appliedAt := "pending", ""
+		if item.Applied {
+			status, appliedAt = "applied", item.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", item.Version, item.Name, status, appliedAt)
+	}
+	return writer.Flush()
+}
+
+// migrateDB 迁移只能直接连接数据库
+func migrateDB(backend commandBackend) (*gorm.DB, error) {
+	if _, ok := backend.(*dbBackend); !ok {
+		return nil, errors.Wrap(errCommandUsage, "migrate cannot be used with -api")
+	}
+	return lib.GetGormPool("default")
+}
+
+func applyAndPrint(backend commandBackend, doc *dto.GatewayConfig, dryRun, prune bool) error {
+	changes, err := backend.Apply(doc, dryRun, prune)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		fmt.Println(formatChange(change))
+	}
+	if dryRun {
+		fmt.Printf("%d change(s), dry run, nothing written\n", len(changes))
+	} else {
+		fmt.Printf("%d change(s) applied\n", len(changes))
+	}
+	return nil
+}
+
+// parseWithName 解析 flag 与一个位置参数，位置参数可以写在 flag 前面
+func parseWithName(flags *flag.FlagSet, args []string, name string) (string, error) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		if err := flags.Parse(args[1:]); err != nil {
+			return "", err
+		}
+		return args[0], nil
+	}
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+	if flags.NArg() != 1 {
+		return "", errors.Wrapf(errCommandUsage, "%s is required", name)
+	}
+	return flags.Arg(0), nil
+}
+
+func readInput(file string) ([]byte, error) {
+	switch file {
+	case "":
+		return nil, errors.Wrap(errCommandUsage, "-f is required")
+	case "-":
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(file)
+}
+
+// readSecret file 为空时读取环境变量 env，否则读取文件第一行
+func readSecret(file, env string) (string, error) {
+	if file == "" {
+		return os.Getenv(env), nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(strings.SplitN(string(data), "\n", 2)[0], "\r"), nil
+}
+
+// decodeStrict yaml 或 json，未知字段报错
+func decodeStrict(data []byte, out interface{}) error {
+	raw, err := public.YAMLToJSON(data)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(out)
+}
+
+func printValue(value interface{}, format string) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	switch format {
+	case public.GatewayConfigFormatJSON:
+		data = append(data, '\n')
+	case public.GatewayConfigFormatYAML:
+		if data, err = public.JSONToYAML(data); err != nil {
+			return err
+		}
+	default:
+		return errors.Wrapf(errCommandUsage, "unknown format %q", format)
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+func serviceRule(item dto.GatewayServiceConfig) string {
+	switch {
+	case item.HTTPRule != nil:
+		return item.HTTPRule.Rule
+	case item.TCPRule != nil:
+		return ":" + strconv.Itoa(item.TCPRule.Port)
+	case item.GRPCRule != nil:
+		return ":" + strconv.Itoa(item.GRPCRule.Port)
+	}
+	return ""
+}
+
+// formatChange 输出一行变更与逐字段 diff
+func formatChange(change dto.GatewayConfigChange) string {
+	lines := []string{fmt.Sprintf("%s %s %s", change.Action, change.ResourceType, change.Name)}
+	keys := make([]string, 0, len(change.Diff))
+	for key := range change.Diff {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("    %s: %v -> %v", key, change.Diff[key].Before, change.Diff[key].After))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/JunxiHe459/gateway/controller"
+	"github.com/JunxiHe459/gateway/dto"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// commandBackend 命令行的两种执行方式
+// dbBackend 直接通过 dao 读写数据库，用于初次部署与找回管理员
+// apiBackend 调用运行中的管理接口，权限与审计日志和页面操作一致
+// Export showSecret 为 false 时租户密钥为 public.RedactedValue，原样导入时保留原值
+type commandBackend interface {
+	Export(showSecret bool) (*dto.GatewayConfig, error)
+	Apply(doc *dto.GatewayConfig, dryRun, prune bool) ([]dto.GatewayConfigChange, error)
+	DeleteService(name string) error
+	DeleteRenter(renterID string) error
+	ResetPassword(userName, password string, enable, resetTOTP bool) error
+}
+
+type dbBackend struct {
+	c *gin.Context
+}
+
+func newDBBackend() *dbBackend {
+	return &dbBackend{c: public.NewBackgroundContext()}
+}
+
+func (b *dbBackend) Export(showSecret bool) (*dto.GatewayConfig, error) {
+	db, err := lib.GetGormPool("default")
+	if err != nil {
+		return nil, err
+	}
+	return controller.ExportGatewayConfig(b.c, db, showSecret)
+}
+
+func (b *dbBackend) Apply(doc *dto.GatewayConfig, dryRun, prune bool) ([]dto.GatewayConfigChange, error) {
+	var changes []dto.GatewayConfigChange
+	err := b.transaction(dryRun, func(tx *gorm.DB) (err error) {
+		changes, err = controller.ApplyGatewayConfig(b.c, tx, doc, prune)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		controller.NotifyGatewayConfigChanges(changes)
+	}
+	return changes, nil
+}
+
+func (b *dbBackend) DeleteService(name string) error {
+	var change *dto.GatewayConfigChange
+	err := b.transaction(false, func(tx *gorm.DB) (err error) {
+		change, err = controller.DeleteGatewayService(b.c, tx, name)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	controller.NotifyGatewayConfigChanges([]dto.GatewayConfigChange{*change})
+	return nil
+}
+
+func (b *dbBackend) DeleteRenter(renterID string) error {
+	return b.transaction(false, func(tx *gorm.DB) error {
+		_, err := controller.DeleteGatewayRenter(b.c, tx, renterID)
+		return err
+	})
+}
+
+func (b *dbBackend) ResetPassword(userName, password string, enable, resetTOTP bool) error {
+	return b.transaction(false, func(tx *gorm.DB) error {
+		return controller.ResetAdminPassword(b.c, tx, userName, password, enable, resetTOTP)
+	})
+}
+
+// transaction rollback 为 true 时执行后回滚，用于 dry-run
+func (b *dbBackend) transaction(rollback bool, handler func(tx *gorm.DB) error) error {
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		return err
+	}
+	tx = tx.Begin()
+	if err := handler(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if rollback {
+		return tx.Rollback().Error
+	}
+	return tx.Commit().Error
+}
+
+// apiPageSize 按名称查找 id 时每页的条数，列表接口的关键字是模糊匹配，需要翻页找到完全相同的一项
+const apiPageSize = 100
+
+type apiBackend struct {
+	addr   string
+	token  string
+	client *http.Client
+}
+
+// newAPIBackend 有 token 时每个请求带上 Authorization，否则登录后保存 session cookie，之后的请求都带上
+func newAPIBackend(addr, token, userName, password, totpCode string) (*apiBackend, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	b := &apiBackend{
+		addr:   strings.TrimRight(addr, "/"),
+		token:  token,
+		client: &http.Client{Jar: jar, Timeout: 30 * time.Second},
+	}
+	if token != "" {
+		return b, nil
+	}
+	login := &dto.AdminLoginInput{Username: userName, Password: password}
+	out := &dto.AdminLoginOutput{}
+	if err := b.call(http.MethodPost, "/admin/login", nil, login, out); err != nil {
+		return nil, errors.Wrap(err, "login")
+	}
+	switch out.TwoFactor {
+	case public.TOTPStateVerify:
+		if totpCode == "" {
+			return nil, errors.New("login: two-factor code required, use -totp or -token")
+		}
+		if err := b.call(http.MethodPost, "/admin/login/totp", nil, &dto.AdminTOTPCodeInput{Code: totpCode}, nil); err != nil {
+			return nil, errors.Wrap(err, "login")
+		}
+	case public.TOTPStateSetup:
+		return nil, errors.New("login: two-factor authentication must be set up in the admin console first")
+	}
+	return b, nil
+}
+
+func (b *apiBackend) Export(showSecret bool) (*dto.GatewayConfig, error) {
+	query := url.Values{"format": {public.GatewayConfigFormatJSON}, "show_secret": {boolParam(showSecret)}}
+	body, err := b.do(http.MethodGet, "/config/export", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return controller.DecodeGatewayConfig(body)
+}
+
+func (b *apiBackend) Apply(doc *dto.GatewayConfig, dryRun, prune bool) ([]dto.GatewayConfigChange, error) {
+	query := url.Values{"dry_run": {boolParam(dryRun)}, "prune": {boolParam(prune)}}
+	out := &dto.GatewayConfigImportOutput{}
+	if err := b.call(http.MethodPost, "/config/import", query, doc, out); err != nil {
+		return nil, err
+	}
+	return out.Changes, nil
+}
+
+// DeleteService 删除接口按 id，先按名称翻页查出 id
+func (b *apiBackend) DeleteService(name string) error {
+	for page := 1; ; page++ {
+		query := url.Values{"keyword": {name}, "page_number": {strconv.Itoa(page)}, "page_size": {strconv.Itoa(apiPageSize)}}
+		out := &dto.ServiceListOutput{}
+		if err := b.call(http.MethodGet, "/service/service_list", query, nil, out); err != nil {
+			return err
+		}
+		for _, item := range out.ServiceList {
+			if item.ServiceName == name {
+				query := url.Values{"id": {strconv.FormatInt(item.ID, 10)}}
+				return b.call(http.MethodGet, "/service/delete", query, nil, nil)
+			}
+		}
+		if len(out.ServiceList) < apiPageSize || int64(page*apiPageSize) >= out.TotalServices {
+			return errors.Errorf("service %s not found", name)
+		}
+	}
+}
+
+func (b *apiBackend) DeleteRenter(renterID string) error {
+	for page := 1; ; page++ {
+		query := url.Values{"info": {renterID}, "page_number": {strconv.Itoa(page)}, "page_size": {strconv.Itoa(apiPageSize)}}
+		out := &dto.RenterListOutput{}
+		if err := b.call(http.MethodGet, "/renter/renter_list", query, nil, out); err != nil {
+			return err
+		}
+		for _, item := range out.List {
+			if item.AppID == renterID {
+				query := url.Values{"id": {strconv.FormatInt(item.ID, 10)}}
+				return b.call(http.MethodGet, "/renter/delete_renter", query, nil, nil)
+			}
+		}
+		if len(out.List) < apiPageSize || int64(page*apiPageSize) >= out.Total {
+			return errors.Errorf("renter %s not found", renterID)
+		}
+	}
+}
+
+// ResetPassword 通过接口重置需要超级管理员登录，修改接口需要同时提交角色与禁用状态
+func (b *apiBackend) ResetPassword(userName, password string, enable, resetTOTP bool) error {
+	out := &dto.AdminUserListOutput{}
+	if err := b.call(http.MethodGet, "/admin_user/list", nil, nil, out); err != nil {
+		return err
+	}
+	for _, item := range out.List {
+		if item.UserName != userName {
+			continue
+		}
+		update := &dto.UpdateAdminUserInput{ID: item.ID, Password: password, Role: item.Role, IsDisable: item.IsDisable}
+		if enable {
+			update.IsDisable = 0
+		}
+		if resetTOTP {
+			update.ResetTOTP = 1
+		}
+		return b.call(http.MethodPost, "/admin_user/update", nil, update, nil)
+	}
+	return errors.New("管理员不存在")
+}
+
+// call 请求接口并把 middleware.Response 的 data 解析到 out
+func (b *apiBackend) call(method, path string, query url.Values, in, out interface{}) error {
+	var payload []byte
+	if in != nil {
+		var err error
+		if payload, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	body, err := b.do(method, path, query, payload)
+	if err != nil {
+		return err
+	}
+	resp := &middleware.Response{Data: out}
+	if err := json.Unmarshal(body, resp); err != nil {
+		return errors.Wrapf(err, "%s %s", method, path)
+	}
+	if resp.ErrorCode != middleware.SuccessCode {
+		return errors.Errorf("%s %s: %d %s", method, path, resp.ErrorCode, resp.ErrorMsg)
+	}
+	return nil
+}
+
+// do 返回原始 body，接口报错时解析 errmsg
+func (b *apiBackend) do(method, path string, query url.Values, payload []byte) ([]byte, error) {
+	target := b.addr + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, target, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+	res, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		resp := &middleware.Response{}
+		if json.Unmarshal(body, resp) == nil && resp.ErrorMsg != "" {
+			return nil, errors.Errorf("%s %s: %d %s", method, path, resp.ErrorCode, resp.ErrorMsg)
+		}
+		return nil, errors.Errorf("%s %s: %s", method, path, res.Status)
+	}
+	return body, nil
+}
+
+func boolParam(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
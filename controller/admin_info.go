@@ -41,13 +41,24 @@ func (adminInfo *AdminInfoController) AdminInfo(c *gin.Context) {
 		return
 	}
 
+	admin := c.MustGet("admin").(*dao.Admin)
+	totp, err := (&dao.AdminTOTP{}).FindByAdmin(c, global.DB, admin.Id)
+	if err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
 	out := &dto.AdminInfoOutput{
 		ID:           adminSessionInfo.ID,
 		UsernName:    adminSessionInfo.UsernName,
 		LoginTime:    adminSessionInfo.LoginTime,
 		Avatar:       "https://wpimg.wallstcn.com/f778738c-e4f8-4870-b634-56703b4acafe.gif",
-		Introduction: "A super administrator",
-		Roles:        []string{"admin"},
+		Introduction: admin.GetRole(),
+		Roles:        []string{admin.GetRole()},
+		Permissions:  public.RolePermissionMap[admin.GetRole()],
+
+		TOTPEnabled:       totp.Enabled(),
+		TOTPRequired:      public.TOTPRequired(admin.GetRole()),
+		RecoveryCodeCount: totp.RecoveryCodeCount(),
 	}
 	middleware.ResponseSuccess(c, out)
 }
@@ -85,9 +96,7 @@ func (adminInfo *AdminInfoController) ChangePassword(c *gin.Context) {
 
 	// 根据用户名找到这个用户
 	admin := &dao.Admin{}
-	admin, err = admin.Find(c, global.DB, &dao.Admin{
-		UserName: adminSessionInfo.UsernName,
-	})
+	admin, err = admin.FindByUserName(c, global.DB, adminSessionInfo.UsernName)
 	if err != nil {
 		print("Admin.Find Error: ", err.Error())
 		middleware.ResponseError(c, 2001, err)
@@ -95,14 +104,25 @@ func (adminInfo *AdminInfoController) ChangePassword(c *gin.Context) {
 	}
 
 	// 得到加盐后的密码
+	before := public.AuditSnapshot(admin)
 	pwd := public.SaltPassword(admin.Salt, params.Password)
 	admin.Password = pwd
-	err = admin.Save(c, global.DB)
+	tx := global.DB.Begin()
+	err = admin.Save(c, tx)
 	if err != nil {
+		tx.Rollback()
 		print("Admin.Save Error: ", err.Error())
 		middleware.ResponseError(c, 2002, err)
 		return
 	}
+	err = saveAuditLog(c, tx, public.AuditActionChangePassword, public.AuditResourceAdmin, int64(admin.Id), admin.UserName,
+		before, public.AuditSnapshot(admin))
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+	tx.Commit()
 
 	middleware.ResponseSuccess(c, "Changed password successfully")
 }
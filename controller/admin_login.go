@@ -9,6 +9,7 @@ import (
 	"github.com/JunxiHe459/gateway/public"
 	"github.com/gin-gonic/contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 	"net/http"
 	"time"
 )
@@ -19,12 +20,14 @@ type AdminLoginController struct {
 func RegiterAdmin(group *gin.RouterGroup) {
 	admin := &AdminLoginController{}
 	group.POST("/login", admin.AdminLogin)
+	group.POST("/login/totp", admin.AdminLoginTOTP)
+	group.POST("/login/totp_setup", admin.AdminLoginTOTPSetup)
 	group.GET("/logout", admin.AdminLogout)
 }
 
 // AdminLogin godoc
 // @Summary Admin Login
-// @Description Admin Login 接口
+// @Description Admin Login 接口，开启两步验证或角色要求两步验证时 two_factor 不为空，需要继续调用 /admin/login/totp
 // @Tags Admin
 // @ID /admin/login
 // @Accept json
@@ -54,27 +57,119 @@ func (adminLogin *AdminLoginController) AdminLogin(c *gin.Context) {
 		return
 	}
 
-	// 设置 session
-	adminSession := &dto.AdminSessionInfo{
-		ID:        admin.Id,
-		UsernName: params.Username,
-		LoginTime: time.Now(),
-	}
-	adminSessionBinary, err := json.Marshal(adminSession)
+	// 两步验证，密码校验通过后先保存等待验证的登录
+	totp, err := (&dao.AdminTOTP{}).FindByAdmin(c, global.DB, admin.Id)
 	if err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	twoFactor := ""
+	if totp.Enabled() {
+		twoFactor = public.TOTPStateVerify
+	} else if public.TOTPRequired(admin.GetRole()) {
+		twoFactor = public.TOTPStateSetup
+	}
+	if twoFactor != "" {
+		pending := &dto.AdminPendingSessionInfo{
+			ID:        admin.Id,
+			UsernName: admin.UserName,
+			ExpireAt:  time.Now().Add(public.AdminLoginPendingTimeout()),
+		}
+		if err := savePendingLogin(c, pending); err != nil {
+			middleware.ResponseError(c, 2001, err)
+			return
+		}
+		middleware.ResponseSuccess(c, &dto.AdminLoginOutput{Token: params.Username, TwoFactor: twoFactor})
+		return
+	}
+
+	// 设置 session
+	if err := saveAdminSession(c, admin); err != nil {
 		print("LoginAndCheck json marshal failed: ", err.Error())
 		middleware.ResponseError(c, 2001, err)
 		return
 	}
-	s := sessions.Default(c)
-	s.Set(public.AdminSessionInfoKey, string(adminSessionBinary))
-	// 存到 redis 里面
-	_ = s.Save()
 
 	middleware.ResponseSuccess(c, &dto.AdminLoginOutput{Token: params.Username})
 	return
 }
 
+// AdminLoginTOTP godoc
+// @Summary Admin Login TOTP
+// @Description 登录第二步，输入验证码或恢复码；角色要求两步验证但未绑定时，先调用 /admin/login/totp_setup，验证通过后返回恢复码
+// @Tags Admin
+// @ID /admin/login/totp
+// @Accept json
+// @Produce json
+// @Param body body dto.AdminTOTPCodeInput true "body"
+// @Success 200 {object} middleware.Response{data=dto.AdminLoginTOTPOutput} "success"
+// @Router /admin/login/totp [POST]
+func (adminLogin *AdminLoginController) AdminLoginTOTP(c *gin.Context) {
+	params := &dto.AdminTOTPCodeInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+	_, admin, err := getPendingLogin(c)
+	if err != nil {
+		middleware.ResponseError(c, 2000, err)
+		return
+	}
+	totp, err := (&dao.AdminTOTP{}).FindByAdmin(c, global.DB, admin.Id)
+	if err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+
+	out := &dto.AdminLoginTOTPOutput{Token: admin.UserName, RecoveryCodes: []string{}}
+	if totp.Enabled() {
+		if err := totp.Verify(c, global.DB, params.Code, time.Now(), true); err != nil {
+			failPendingLogin(c, err)
+			middleware.ResponseError(c, 2002, err)
+			return
+		}
+	} else {
+		// 登录时绑定，绑定完成后才算登录成功
+		c.Set("admin", admin)
+		codes, err := enableTOTP(c, totp, params.Code)
+		if err != nil {
+			failPendingLogin(c, err)
+			middleware.ResponseError(c, 2002, err)
+			return
+		}
+		out.RecoveryCodes = codes
+	}
+
+	if err := saveAdminSession(c, admin); err != nil {
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	middleware.ResponseSuccess(c, out)
+}
+
+// AdminLoginTOTPSetup godoc
+// @Summary Admin Login TOTP Setup
+// @Description 角色要求两步验证但未绑定时，登录第一步之后生成密钥，再调用 /admin/login/totp 完成绑定
+// @Tags Admin
+// @ID /admin/login/totp_setup
+// @Accept json
+// @Produce json
+// @Success 200 {object} middleware.Response{data=dto.AdminTOTPSetupOutput} "success"
+// @Router /admin/login/totp_setup [POST]
+func (adminLogin *AdminLoginController) AdminLoginTOTPSetup(c *gin.Context) {
+	_, admin, err := getPendingLogin(c)
+	if err != nil {
+		middleware.ResponseError(c, 2000, err)
+		return
+	}
+	out, err := setupTOTP(c, admin)
+	if err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	middleware.ResponseSuccess(c, out)
+}
+
 // AdminLogin godoc
 // @Summary Admin Log out
 // @Description Admin Log out 接口
@@ -87,7 +182,69 @@ func (adminLogin *AdminLoginController) AdminLogin(c *gin.Context) {
 func (admin *AdminLoginController) AdminLogout(c *gin.Context) {
 	s := sessions.Default(c)
 	s.Delete(public.AdminSessionInfoKey)
+	s.Delete(public.AdminPendingSessionKey)
 	_ = s.Save()
 
 	middleware.ResponseSuccess(c, "Log out!")
 }
+
+// saveAdminSession 登录成功，替换等待两步验证的登录
+func saveAdminSession(c *gin.Context, admin *dao.Admin) error {
+	adminSession := &dto.AdminSessionInfo{
+		ID:             admin.Id,
+		UsernName:      admin.UserName,
+		LoginTime:      time.Now(),
+		SessionVersion: admin.SessionVersion,
+	}
+	adminSessionBinary, err := json.Marshal(adminSession)
+	if err != nil {
+		return err
+	}
+	s := sessions.Default(c)
+	s.Delete(public.AdminPendingSessionKey)
+	s.Set(public.AdminSessionInfoKey, string(adminSessionBinary))
+	return s.Save()
+}
+
+func savePendingLogin(c *gin.Context, pending *dto.AdminPendingSessionInfo) error {
+	pendingBinary, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	s := sessions.Default(c)
+	s.Delete(public.AdminSessionInfoKey)
+	s.Set(public.AdminPendingSessionKey, string(pendingBinary))
+	return s.Save()
+}
+
+// getPendingLogin 返回未过期的等待验证的登录，管理员被禁用或删除后失效
+func getPendingLogin(c *gin.Context) (*dto.AdminPendingSessionInfo, *dao.Admin, error) {
+	s := sessions.Default(c)
+	pendingInfo, ok := s.Get(public.AdminPendingSessionKey).(string)
+	if !ok || pendingInfo == "" {
+		return nil, nil, errors.New("请先输入用户名与密码")
+	}
+	pending := &dto.AdminPendingSessionInfo{}
+	if err := json.Unmarshal([]byte(pendingInfo), pending); err != nil || !time.Now().Before(pending.ExpireAt) {
+		s.Delete(public.AdminPendingSessionKey)
+		_ = s.Save()
+		return nil, nil, errors.New("登录已过期，请重新输入用户名与密码")
+	}
+	admin, err := (&dao.Admin{}).FindByUserName(c, global.DB, pending.UsernName)
+	if err != nil || admin.Id != pending.ID || admin.IsDisable == 1 {
+		s.Delete(public.AdminPendingSessionKey)
+		_ = s.Save()
+		return nil, nil, errors.New("请先输入用户名与密码")
+	}
+	return pending, admin, nil
+}
+
+// failPendingLogin 失败次数按管理员记录在数据库中，锁定后同时清除等待验证的登录
+func failPendingLogin(c *gin.Context, err error) {
+	if err != dao.ErrTOTPLocked {
+		return
+	}
+	s := sessions.Default(c)
+	s.Delete(public.AdminPendingSessionKey)
+	_ = s.Save()
+}
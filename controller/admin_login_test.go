@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/global"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/gin-gonic/contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loginClient 每个 client 有自己的 cookie，相当于一个浏览器
+type loginClient struct {
+	t      *testing.T
+	server *httptest.Server
+	client *http.Client
+}
+
+func newLoginClient(t *testing.T, server *httptest.Server) *loginClient {
+	jar, _ := cookiejar.New(nil)
+	return &loginClient{t: t, server: server, client: &http.Client{Jar: jar}}
+}
+
+// call 返回 errno 与 data，out 不为空时解析 data
+func (l *loginClient) call(method, path, body string, out interface{}) (middleware.ResponseCode, string) {
+	req, _ := http.NewRequest(method, l.server.URL+path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.client.Do(req)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	result := &struct {
+		ErrorCode middleware.ResponseCode `json:"errno"`
+		ErrorMsg  string                  `json:"errmsg"`
+		Data      json.RawMessage         `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		l.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	if out != nil && result.ErrorCode == 0 {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			l.t.Fatal(err)
+		}
+	}
+	return result.ErrorCode, result.ErrorMsg
+}
+
+func (l *loginClient) login(password string) string {
+	out := &struct {
+		TwoFactor string `json:"two_factor"`
+	}{}
+	if code, msg := l.call(http.MethodPost, "/admin/login", `{"username":"ops","password":"`+password+`"}`, out); code != 0 {
+		l.t.Fatalf("login: errno %d %s", code, msg)
+	}
+	return out.TwoFactor
+}
+
+func (l *loginClient) loggedIn() bool {
+	code, _ := l.call(http.MethodGet, "/admin/info", "", nil)
+	return code == 0
+}
+
+// newLoginServer 使用内存 session 与 sqlite，路由与 router.InitRouter 中的 /admin、/admin/info 一致
+func newLoginServer(t *testing.T) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	oldConf, oldKey := lib.ViperConfMap, os.Getenv(public.TOTPKeyEnv)
+	base := viper.New()
+	base.Set("state.driver", public.StateDriverMemory)
+	base.Set("session.secret", "test-session-secret")
+	base.Set("admin.totp_lockout", 60)
+	lib.ViperConfMap = map[string]*viper.Viper{"base": base}
+	os.Setenv(public.TOTPKeyEnv, "test-totp-key")
+	t.Cleanup(func() {
+		lib.ViperConfMap = oldConf
+		os.Setenv(public.TOTPKeyEnv, oldKey)
+	})
+	openTestDB(t)
+
+	store := middleware.NewSessionStore()
+	router := gin.New()
+	adminGroup := router.Group("/admin")
+	adminGroup.Use(sessions.Sessions("AdminSession", store), middleware.ParamValidationMiddleware())
+	RegiterAdmin(adminGroup)
+	adminInfoGroup := router.Group("/admin/info")
+	adminInfoGroup.Use(sessions.Sessions("AdminSession", store), middleware.SessionAuthMiddleware(),
+		middleware.ParamValidationMiddleware())
+	RegiterAdminInfo(adminInfoGroup)
+	AdminTOTPRegister(adminInfoGroup)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAdminLoginTwoFactor(t *testing.T) {
+	server := newLoginServer(t)
+	c := public.NewBackgroundContext()
+	admin := &dao.Admin{UserName: "ops", Role: public.RoleServiceOperator, Salt: public.NewSalt()}
+	admin.Password = public.SaltPassword(admin.Salt, "Passw0rd123")
+	if err := admin.Save(c, global.DB); err != nil {
+		t.Fatal(err)
+	}
+	apiToken := public.NewAdminToken()
+	item := &dao.AdminToken{AdminID: admin.Id, Name: "ci", TokenHash: public.HashAdminToken(apiToken), Scopes: public.PermServiceRead}
+	if err := item.Save(c, global.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	// 开启两步验证之前登录的 session 与创建的 API token 在开启后失效，开启的 session 继续有效
+	owner, other := newLoginClient(t, server), newLoginClient(t, server)
+	if owner.login("Passw0rd123") != "" || other.login("Passw0rd123") != "" {
+		t.Fatal("two factor required before it is enabled")
+	}
+	setup := &struct {
+		Secret string `json:"secret"`
+	}{}
+	if code, msg := owner.call(http.MethodPost, "/admin/info/totp/setup", "", setup); code != 0 {
+		t.Fatalf("setup: errno %d %s", code, msg)
+	}
+	stored, _ := (&dao.AdminTOTP{}).FindByAdmin(c, global.DB, admin.Id)
+	if stored.Secret == setup.Secret || !strings.HasPrefix(stored.Secret, public.TOTPEncryptedPrefix) {
+		t.Fatalf("totp secret stored as %q", stored.Secret)
+	}
+	now := time.Now()
+	code, _ := public.TOTPCode(setup.Secret, public.TOTPStep(now))
+	enabled := &struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{}
+	if errno, msg := owner.call(http.MethodPost, "/admin/info/totp/enable", `{"code":"`+code+`"}`, enabled); errno != 0 {
+		t.Fatalf("enable: errno %d %s", errno, msg)
+	}
+	if !owner.loggedIn() {
+		t.Fatal("session that enabled two factor was logged out")
+	}
+	if other.loggedIn() {
+		t.Fatal("session from before two factor still logged in")
+	}
+	if _, err := (&dao.AdminToken{}).FindByToken(c, global.DB, apiToken); err == nil {
+		t.Fatal("api token from before two factor still valid")
+	}
+
+	// 同一个验证码不能再次使用，失败次数按管理员累计，重新输入密码不会重置
+	client := newLoginClient(t, server)
+	if client.login("Passw0rd123") != public.TOTPStateVerify {
+		t.Fatal("login did not ask for two factor")
+	}
+	for i := 1; i < public.TOTPMaxFailures; i++ {
+		if errno, msg := client.call(http.MethodPost, "/admin/login/totp", `{"code":"`+code+`"}`, nil); errno == 0 || msg != dao.ErrTOTPInvalid.Error() {
+			t.Fatalf("attempt %d: errno %d %s", i, errno, msg)
+		}
+		if i == 2 {
+			client.login("Passw0rd123")
+		}
+	}
+	if errno, msg := client.call(http.MethodPost, "/admin/login/totp", `{"code":"000000"}`, nil); errno == 0 || msg != dao.ErrTOTPLocked.Error() {
+		t.Fatalf("last attempt: errno %d %s, want locked", errno, msg)
+	}
+	client.login("Passw0rd123")
+	next, _ := public.TOTPCode(setup.Secret, public.TOTPStep(now)+1)
+	if errno, msg := client.call(http.MethodPost, "/admin/login/totp", `{"code":"`+next+`"}`, nil); errno == 0 || msg != dao.ErrTOTPLocked.Error() {
+		t.Fatalf("correct code while locked: errno %d %s", errno, msg)
+	}
+	if client.loggedIn() {
+		t.Fatal("logged in while locked")
+	}
+
+	// 锁定到期后恢复码只能使用一次
+	if err := global.DB.Table(stored.TableName()).Where("admin_id=?", admin.Id).
+		UpdateColumn("locked_until", now.Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	client.login("Passw0rd123")
+	if errno, msg := client.call(http.MethodPost, "/admin/login/totp", `{"code":"`+enabled.RecoveryCodes[0]+`"}`, nil); errno != 0 {
+		t.Fatalf("recovery code: errno %d %s", errno, msg)
+	}
+	if !client.loggedIn() {
+		t.Fatal("not logged in after two factor")
+	}
+	again := newLoginClient(t, server)
+	again.login("Passw0rd123")
+	if errno, _ := again.call(http.MethodPost, "/admin/login/totp", `{"code":"`+enabled.RecoveryCodes[0]+`"}`, nil); errno == 0 {
+		t.Fatal("recovery code used twice")
+	}
+
+	// 关闭两步验证同样吊销其他 session
+	if errno, msg := owner.call(http.MethodPost, "/admin/info/totp/disable", `{"code":"`+next+`"}`, nil); errno != 0 {
+		t.Fatalf("disable: errno %d %s", errno, msg)
+	}
+	if !owner.loggedIn() || client.loggedIn() {
+		t.Fatal("disable should keep only the current session")
+	}
+}
@@ -0,0 +1,263 @@
+package controller
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/dto"
+	"github.com/JunxiHe459/gateway/global"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"strings"
+	"time"
+)
+
+type AdminTokenController struct {
+}
+
+// AdminTokenRegister 管理员管理自己的 token，admin:manage 可以查看与吊销所有管理员的 token
+func AdminTokenRegister(group *gin.RouterGroup) {
+	adminToken := &AdminTokenController{}
+	group.GET("/list", adminToken.AdminTokenList)
+	group.POST("/add", adminToken.AddAdminToken)
+	group.POST("/revoke", adminToken.RevokeAdminToken)
+}
+
+// AdminTokenList godoc
+// @Summary Admin token list
+// @Description API token 列表，不返回 token 明文
+// @Tags Admin
+// @ID /admin_token/list
+// @Accept  json
+// @Produce  json
+// @Param admin_id query int false "管理员ID，0 表示全部，只有 admin:manage 可以查看其他管理员"
+// @Success 200 {object} middleware.Response{data=dto.AdminTokenListOutput} "success"
+// @Router /admin_token/list [get]
+func (adminToken *AdminTokenController) AdminTokenList(c *gin.Context) {
+	params := &dto.AdminTokenListInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	admin := currentAdmin(c)
+	adminID := admin.Id
+	if middleware.HasPermission(c, public.PermAdminManage) {
+		adminID = params.AdminID
+	}
+	list, err := (&dao.AdminToken{}).List(c, global.DB, adminID)
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+	adminNames := map[int]string{}
+	if admins, err := (&dao.Admin{}).List(c, global.DB); err == nil {
+		for _, item := range admins {
+			adminNames[item.Id] = item.UserName
+		}
+	}
+	out := &dto.AdminTokenListOutput{List: []dto.AdminTokenItemOutput{}, Total: int64(len(list))}
+	for _, item := range list {
+		out.List = append(out.List, dto.AdminTokenItemOutput{
+			ID:          item.ID,
+			AdminID:     item.AdminID,
+			AdminName:   adminNames[item.AdminID],
+			Name:        item.Name,
+			TokenPrefix: item.TokenPrefix,
+			Scopes:      item.ScopeList(),
+			ExpiredAt:   formatOptionalTime(item.ExpiredAt),
+			LastUsedAt:  formatOptionalTime(item.LastUsedAt),
+			CreatedAt:   item.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	middleware.ResponseSuccess(c, out)
+}
+
+// AddAdminToken godoc
+// @Summary Add admin token
+// @Description 创建 API token，请求时放在 Authorization: Bearer 中，明文只在创建时返回一次
+// @Tags Admin
+// @ID /admin_token/add
+// @Accept  json
+// @Produce  json
+// @Param body body dto.AdminTokenAddInput true "body"
+// @Success 200 {object} middleware.Response{data=dto.AdminTokenAddOutput} "success"
+// @Router /admin_token/add [post]
+func (adminToken *AdminTokenController) AddAdminToken(c *gin.Context) {
+	params := &dto.AdminTokenAddInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	admin := currentAdmin(c)
+	scopes := []string{}
+	for _, scope := range strings.Split(params.Scopes, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || public.InStringSlice(scopes, scope) {
+			continue
+		}
+		if !public.ValidPermission(scope) {
+			middleware.ResponseError(c, 2002, errors.New("权限不存在: "+scope))
+			return
+		}
+		if !admin.HasPermission(scope) {
+			middleware.ResponseError(c, 2003, errors.New("不能授予当前角色没有的权限: "+scope))
+			return
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		middleware.ResponseError(c, 2002, errors.New("scopes 不能为空"))
+		return
+	}
+
+	token := public.NewAdminToken()
+	item := &dao.AdminToken{
+		AdminID:     admin.Id,
+		Name:        params.Name,
+		TokenPrefix: token[:len(public.AdminTokenPrefix)+8],
+		TokenHash:   public.HashAdminToken(token),
+		Scopes:      strings.Join(scopes, ","),
+	}
+	if params.ExpireDays > 0 {
+		expiredAt := time.Now().AddDate(0, 0, params.ExpireDays)
+		item.ExpiredAt = &expiredAt
+	}
+	tx := global.DB.Begin()
+	if err := item.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	if err := saveAuditLog(c, tx, public.AuditActionAdd, public.AuditResourceAdminToken, item.ID, item.Name,
+		nil, adminTokenSnapshot(item)); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2005, err)
+		return
+	}
+	tx.Commit()
+	middleware.ResponseSuccess(c, &dto.AdminTokenAddOutput{
+		ID:        item.ID,
+		Token:     token,
+		ExpiredAt: formatOptionalTime(item.ExpiredAt),
+	})
+}
+
+// RevokeAdminToken godoc
+// @Summary Revoke admin token
+// @Description 吊销 API token，只能吊销自己的，admin:manage 可以吊销所有管理员的
+// @Tags Admin
+// @ID /admin_token/revoke
+// @Accept  json
+// @Produce  json
+// @Param body body dto.AdminTokenRevokeInput true "body"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /admin_token/revoke [post]
+func (adminToken *AdminTokenController) RevokeAdminToken(c *gin.Context) {
+	params := &dto.AdminTokenRevokeInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	item, err := (&dao.AdminToken{}).Find(c, global.DB, &dao.AdminToken{ID: params.ID})
+	if err != nil || item.IsDelete == 1 ||
+		(item.AdminID != currentAdmin(c).Id && !middleware.HasPermission(c, public.PermAdminManage)) {
+		middleware.ResponseError(c, 2002, errors.New("token 不存在"))
+		return
+	}
+	before := adminTokenSnapshot(item)
+	item.IsDelete = 1
+	tx := global.DB.Begin()
+	if err := item.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+	if err := saveAuditLog(c, tx, public.AuditActionDelete, public.AuditResourceAdminToken, item.ID, item.Name,
+		before, adminTokenSnapshot(item)); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	tx.Commit()
+	middleware.ResponseSuccess(c, "")
+}
+
+// AdminJwtRegister 使用 API token 换取短期 JWT，不接受 session 与 JWT
+func AdminJwtRegister(group *gin.RouterGroup) {
+	adminToken := &AdminTokenController{}
+	group.POST("/jwt", middleware.RequireAdminToken(), adminToken.IssueAdminJwt)
+}
+
+// IssueAdminJwt godoc
+// @Summary Issue admin JWT
+// @Description 使用 Authorization: Bearer 中的 API token 换取 JWT，权限与有效期不超过该 API token，API token 吊销后 JWT 同时失效
+// @Tags Admin
+// @ID /admin_token/jwt
+// @Accept  json
+// @Produce  json
+// @Param body body dto.AdminJwtInput true "body"
+// @Success 200 {object} middleware.Response{data=dto.AdminJwtOutput} "success"
+// @Router /admin_token/jwt [post]
+func (adminToken *AdminTokenController) IssueAdminJwt(c *gin.Context) {
+	params := &dto.AdminJwtInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	item := c.MustGet("admin_token").(*dao.AdminToken)
+	scopes := item.ScopeList()
+	if strings.TrimSpace(params.Scopes) != "" {
+		scopes = []string{}
+		for _, scope := range strings.Split(params.Scopes, ",") {
+			scope = strings.TrimSpace(scope)
+			if scope == "" {
+				continue
+			}
+			if !item.HasScope(scope) {
+				middleware.ResponseError(c, 2002, errors.New("不能授予 API token 没有的权限: "+scope))
+				return
+			}
+			scopes = append(scopes, scope)
+		}
+		scopes = item.IntersectScopes(scopes)
+		if len(scopes) == 0 {
+			middleware.ResponseError(c, 2002, errors.New("scopes 不能为空"))
+			return
+		}
+	}
+	expireSeconds := params.ExpireSeconds
+	if expireSeconds == 0 {
+		expireSeconds = public.JwtExpires
+	}
+	expiredAt := time.Now().Add(time.Duration(expireSeconds) * time.Second)
+	if item.ExpiredAt != nil && item.ExpiredAt.Before(expiredAt) {
+		expiredAt = *item.ExpiredAt
+	}
+	token, err := public.NewAdminJwt(item.ID, item.AdminID, scopes, expiredAt)
+	if err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+	middleware.ResponseSuccess(c, &dto.AdminJwtOutput{
+		Token:     token,
+		ExpiredAt: expiredAt.Format("2006-01-02 15:04:05"),
+	})
+}
+
+func currentAdmin(c *gin.Context) *dao.Admin {
+	return c.MustGet("admin").(*dao.Admin)
+}
+
+// adminTokenSnapshot 审计日志中不记录 token_hash
+func adminTokenSnapshot(item *dao.AdminToken) map[string]interface{} {
+	snapshot := public.AuditSnapshot(item)
+	delete(snapshot, "token_hash")
+	return snapshot
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/global"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/migration"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/gorm"
+	_ "github.com/e421083458/gorm/dialects/sqlite"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openTestDB 在临时目录创建 sqlite 数据库，执行全部迁移并设置为 global.DB
+func openTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "gateway.db")+"?_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SingularTable(true)
+	if _, err := migration.Up(public.NewBackgroundContext(), db, 0); err != nil {
+		t.Fatal(err)
+	}
+	oldDB := global.DB
+	global.DB = db
+	t.Cleanup(func() {
+		global.DB = oldDB
+		db.Close()
+	})
+	return db
+}
+
+// doJSON 发送请求并返回响应中的 errno 与 data
+func doJSON(t *testing.T, router http.Handler, method, path, bearer, body string) (middleware.ResponseCode, json.RawMessage) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	out := &struct {
+		ErrorCode middleware.ResponseCode `json:"errno"`
+		Data      json.RawMessage         `json:"data"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+		t.Fatalf("%s %s: %v: %s", method, path, err, w.Body.String())
+	}
+	return out.ErrorCode, out.Data
+}
+
+func TestAdminJwtFollowsAPIToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := openTestDB(t)
+	c := public.NewBackgroundContext()
+	admin := &dao.Admin{UserName: "ci", Role: public.RoleServiceOperator}
+	if err := admin.Save(c, db); err != nil {
+		t.Fatal(err)
+	}
+	apiToken := public.NewAdminToken()
+	item := &dao.AdminToken{AdminID: admin.Id, Name: "ci", TokenHash: public.HashAdminToken(apiToken),
+		Scopes: public.PermServiceRead + "," + public.PermServiceWrite}
+	if err := item.Save(c, db); err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	group := router.Group("/admin_token")
+	group.Use(middleware.TokenAuthMiddleware(), middleware.ParamValidationMiddleware())
+	AdminJwtRegister(group)
+	probe := router.Group("/probe")
+	probe.Use(middleware.TokenAuthMiddleware(), middleware.SessionAuthMiddleware())
+	probe.GET("/read", middleware.RequirePermission(public.PermServiceRead), func(c *gin.Context) {
+		middleware.ResponseSuccess(c, "")
+	})
+	probe.GET("/write", middleware.RequirePermission(public.PermServiceWrite), func(c *gin.Context) {
+		middleware.ResponseSuccess(c, "")
+	})
+
+	if code, _ := doJSON(t, router, http.MethodPost, "/admin_token/jwt", apiToken, `{"scopes":"renter:write"}`); code == 0 {
+		t.Fatal("jwt issued with a scope the api token does not have")
+	}
+	code, data := doJSON(t, router, http.MethodPost, "/admin_token/jwt", apiToken, `{"scopes":"service:read","expire_seconds":600}`)
+	if code != 0 {
+		t.Fatalf("issue jwt: errno %d %s", code, data)
+	}
+	out := &struct {
+		Token string `json:"token"`
+	}{}
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, _ := doJSON(t, router, http.MethodGet, "/probe/read", out.Token, ""); code != 0 {
+		t.Fatalf("jwt read: errno %d", code)
+	}
+	if code, _ := doJSON(t, router, http.MethodGet, "/probe/write", out.Token, ""); code != middleware.PermissionDeniedCode {
+		t.Fatalf("jwt write: errno %d, want permission denied", code)
+	}
+	if code, _ := doJSON(t, router, http.MethodGet, "/probe/write", apiToken, ""); code != 0 {
+		t.Fatalf("api token write: errno %d", code)
+	}
+	if code, _ := doJSON(t, router, http.MethodPost, "/admin_token/jwt", out.Token, `{}`); code == 0 {
+		t.Fatal("jwt exchanged for another jwt")
+	}
+
+	// API token 吊销后 JWT 同时失效
+	item.IsDelete = 1
+	if err := item.Save(c, db); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := doJSON(t, router, http.MethodGet, "/probe/read", out.Token, ""); code == 0 {
+		t.Fatal("jwt still works after the api token was revoked")
+	}
+}
@@ -0,0 +1,249 @@
+package controller
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/dto"
+	"github.com/JunxiHe459/gateway/global"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"net/http"
+	"time"
+)
+
+type AdminTOTPController struct {
+}
+
+// AdminTOTPRegister 已登录的管理员管理自己的两步验证，注册在 /admin/info 下
+func AdminTOTPRegister(group *gin.RouterGroup) {
+	adminTOTP := &AdminTOTPController{}
+	group.POST("/totp/setup", adminTOTP.SetupTOTP)
+	group.POST("/totp/enable", adminTOTP.EnableTOTP)
+	group.POST("/totp/disable", adminTOTP.DisableTOTP)
+	group.POST("/totp/recovery_codes", adminTOTP.ResetRecoveryCodes)
+}
+
+// SetupTOTP godoc
+// @Summary Admin TOTP Setup
+// @Description 生成两步验证密钥，secret_uri 用于生成二维码，之后调用 /admin/info/totp/enable 完成绑定
+// @Tags Admin
+// @ID /admin/info/totp/setup
+// @Accept json
+// @Produce json
+// @Success 200 {object} middleware.Response{data=dto.AdminTOTPSetupOutput} "success"
+// @Router /admin/info/totp/setup [POST]
+func (adminTOTP *AdminTOTPController) SetupTOTP(c *gin.Context) {
+	out, err := setupTOTP(c, currentAdmin(c))
+	if err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	middleware.ResponseSuccess(c, out)
+}
+
+// EnableTOTP godoc
+// @Summary Admin TOTP Enable
+// @Description 输入验证器 App 中的验证码完成绑定，返回一次性恢复码
+// @Tags Admin
+// @ID /admin/info/totp/enable
+// @Accept json
+// @Produce json
+// @Param body body dto.AdminTOTPCodeInput true "body"
+// @Success 200 {object} middleware.Response{data=dto.AdminTOTPRecoveryCodesOutput} "success"
+// @Router /admin/info/totp/enable [POST]
+func (adminTOTP *AdminTOTPController) EnableTOTP(c *gin.Context) {
+	params := &dto.AdminTOTPCodeInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+	totp, err := (&dao.AdminTOTP{}).FindByAdmin(c, global.DB, currentAdmin(c).Id)
+	if err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	codes, err := enableTOTP(c, totp, params.Code)
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+	if err := renewAdminSession(c); err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+	middleware.ResponseSuccess(c, &dto.AdminTOTPRecoveryCodesOutput{RecoveryCodes: codes})
+}
+
+// DisableTOTP godoc
+// @Summary Admin TOTP Disable
+// @Description 输入验证码或恢复码关闭两步验证，角色要求两步验证时不能关闭
+// @Tags Admin
+// @ID /admin/info/totp/disable
+// @Accept json
+// @Produce json
+// @Param body body dto.AdminTOTPCodeInput true "body"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /admin/info/totp/disable [POST]
+func (adminTOTP *AdminTOTPController) DisableTOTP(c *gin.Context) {
+	params := &dto.AdminTOTPCodeInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+	admin := currentAdmin(c)
+	if public.TOTPRequired(admin.GetRole()) {
+		middleware.ResponseError(c, 2001, errors.New("当前角色必须开启两步验证"))
+		return
+	}
+	totp, err := (&dao.AdminTOTP{}).FindByAdmin(c, global.DB, admin.Id)
+	if err != nil || !totp.Enabled() {
+		middleware.ResponseError(c, 2002, errors.New("未开启两步验证"))
+		return
+	}
+	if err := totp.Verify(c, global.DB, params.Code, time.Now(), true); err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+	tx := global.DB.Begin()
+	if err := resetTOTP(c, tx, admin); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	tx.Commit()
+	if err := renewAdminSession(c); err != nil {
+		middleware.ResponseError(c, 2005, err)
+		return
+	}
+	middleware.ResponseSuccess(c, "")
+}
+
+// ResetRecoveryCodes godoc
+// @Summary Admin TOTP Recovery Codes
+// @Description 输入验证码重新生成恢复码，之前的恢复码作废
+// @Tags Admin
+// @ID /admin/info/totp/recovery_codes
+// @Accept json
+// @Produce json
+// @Param body body dto.AdminTOTPCodeInput true "body"
+// @Success 200 {object} middleware.Response{data=dto.AdminTOTPRecoveryCodesOutput} "success"
+// @Router /admin/info/totp/recovery_codes [POST]
+func (adminTOTP *AdminTOTPController) ResetRecoveryCodes(c *gin.Context) {
+	params := &dto.AdminTOTPCodeInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+	totp, err := (&dao.AdminTOTP{}).FindByAdmin(c, global.DB, currentAdmin(c).Id)
+	if err != nil || !totp.Enabled() {
+		middleware.ResponseError(c, 2001, errors.New("未开启两步验证"))
+		return
+	}
+	if err := totp.Verify(c, global.DB, params.Code, time.Now(), false); err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+	codes := totp.ResetRecoveryCodes()
+	if err := totp.SaveRecoveryCodes(c, global.DB); err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+	middleware.ResponseSuccess(c, &dto.AdminTOTPRecoveryCodesOutput{RecoveryCodes: codes})
+}
+
+// setupTOTP 生成新密钥，已开启时需要先关闭
+func setupTOTP(c *gin.Context, admin *dao.Admin) (*dto.AdminTOTPSetupOutput, error) {
+	totp, err := (&dao.AdminTOTP{}).FindByAdmin(c, global.DB, admin.Id)
+	if err != nil {
+		return nil, err
+	}
+	if totp.Enabled() {
+		return nil, errors.New("已开启两步验证")
+	}
+	secret := public.NewTOTPSecret()
+	if err := totp.SetSecret(secret); err != nil {
+		return nil, err
+	}
+	if err := totp.Save(c, global.DB); err != nil {
+		return nil, err
+	}
+	return &dto.AdminTOTPSetupOutput{
+		Secret:    secret,
+		SecretURI: public.TOTPProvisioningURI(admin.UserName, secret),
+	}, nil
+}
+
+// enableTOTP 校验绑定中的密钥并开启，返回恢复码明文
+// 开启前创建的 API token 与登录的 session 没有经过两步验证，全部吊销
+func enableTOTP(c *gin.Context, totp *dao.AdminTOTP, code string) ([]string, error) {
+	if totp.Enabled() {
+		return nil, errors.New("已开启两步验证")
+	}
+	if totp.Secret == "" {
+		return nil, errors.New("请先生成两步验证密钥")
+	}
+	if err := totp.Verify(c, global.DB, code, time.Now(), false); err != nil {
+		return nil, err
+	}
+	admin := currentAdmin(c)
+	tx := global.DB.Begin()
+	codes, err := totp.Enable(c, tx)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := revokeAdminCredentials(c, tx, admin); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := saveAuditLog(c, tx, public.AuditActionEnableTOTP, public.AuditResourceAdmin, int64(admin.Id), admin.UserName,
+		totpSnapshot(false), totpSnapshot(true)); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	tx.Commit()
+	return codes, nil
+}
+
+// resetTOTP 关闭两步验证，下次登录时按角色要求重新绑定，未开启时只清除绑定中的密钥
+// 已开启时同时吊销管理员的 API token 与 session，丢失验证器后之前的登录不再有效
+func resetTOTP(c *gin.Context, tx *gorm.DB, admin *dao.Admin) error {
+	totp, err := (&dao.AdminTOTP{}).FindByAdmin(c, tx, admin.Id)
+	if err != nil {
+		return err
+	}
+	if err := totp.DeleteByAdmin(c, tx, admin.Id); err != nil {
+		return err
+	}
+	if !totp.Enabled() {
+		return nil
+	}
+	if err := revokeAdminCredentials(c, tx, admin); err != nil {
+		return err
+	}
+	return saveAuditLog(c, tx, public.AuditActionDisableTOTP, public.AuditResourceAdmin, int64(admin.Id), admin.UserName,
+		totpSnapshot(true), totpSnapshot(false))
+}
+
+// totpSnapshot 审计日志只记录是否开启
+func totpSnapshot(enabled bool) map[string]interface{} {
+	return map[string]interface{}{"totp_enabled": enabled}
+}
+
+// revokeAdminCredentials 吊销管理员的全部 API token 与已登录的 session
+func revokeAdminCredentials(c *gin.Context, tx *gorm.DB, admin *dao.Admin) error {
+	if err := (&dao.AdminToken{}).RevokeByAdmin(c, tx, admin.Id); err != nil {
+		return err
+	}
+	return admin.RevokeSessions(c, tx)
+}
+
+// renewAdminSession 吊销 session 后，按新的版本重新保存当前请求的 session，API token 认证的请求不保存
+func renewAdminSession(c *gin.Context) error {
+	if _, ok := c.Get("admin_token"); ok {
+		return nil
+	}
+	return saveAdminSession(c, currentAdmin(c))
+}
@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/dto"
+	"github.com/JunxiHe459/gateway/global"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type AdminUserController struct {
+}
+
+func AdminUserRegister(group *gin.RouterGroup) {
+	admin := &AdminUserController{}
+	group.GET("/list", middleware.RequirePermission(public.PermAdminManage), admin.AdminUserList)
+	group.POST("/add", middleware.RequirePermission(public.PermAdminManage), admin.AddAdminUser)
+	group.POST("/update", middleware.RequirePermission(public.PermAdminManage), admin.UpdateAdminUser)
+	group.GET("/delete", middleware.RequirePermission(public.PermAdminManage), admin.DeleteAdminUser)
+}
+
+// AdminUserList godoc
+// @Summary Admin user list
+// @Description 管理员列表
+// @Tags Admin
+// @ID /admin_user/list
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} middleware.Response{data=dto.AdminUserListOutput} "success"
+// @Router /admin_user/list [get]
+func (admin *AdminUserController) AdminUserList(c *gin.Context) {
+	list, err := (&dao.Admin{}).List(c, global.DB)
+	if err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	out := &dto.AdminUserListOutput{List: []dto.AdminUserItemOutput{}, Total: int64(len(list))}
+	for _, item := range list {
+		out.List = append(out.List, dto.AdminUserItemOutput{
+			ID:        item.Id,
+			UserName:  item.UserName,
+			Role:      item.GetRole(),
+			IsDisable: item.IsDisable,
+			CreatedAt: item.CreatedAt.Format("2006-01-02 15:04:05"),
+			UpdatedAt: item.UpdatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	middleware.ResponseSuccess(c, out)
+}
+
+// AddAdminUser godoc
+// @Summary Add admin user
+// @Description 添加管理员，角色为 super_admin、service_operator、renter_manager 或 read_only
+// @Tags Admin
+// @ID /admin_user/add
+// @Accept  json
+// @Produce  json
+// @Param body body dto.AddAdminUserInput true "body"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /admin_user/add [post]
+func (admin *AdminUserController) AddAdminUser(c *gin.Context) {
+	params := &dto.AddAdminUserInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	if _, err := (&dao.Admin{}).FindByUserName(c, global.DB, params.Username); err == nil {
+		middleware.ResponseError(c, 2002, errors.New("用户名已存在"))
+		return
+	}
+
+	salt := public.NewSalt()
+	adminUser := &dao.Admin{
+		UserName: params.Username,
+		Salt:     salt,
+		Password: public.SaltPassword(salt, params.Password),
+		Role:     params.Role,
+	}
+	tx := global.DB.Begin()
+	if err := adminUser.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+	if err := saveAuditLog(c, tx, public.AuditActionAdd, public.AuditResourceAdmin, int64(adminUser.Id), adminUser.UserName,
+		nil, public.AuditSnapshot(adminUser)); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	tx.Commit()
+	middleware.ResponseSuccess(c, "")
+}
+
+// UpdateAdminUser godoc
+// @Summary Update admin user
+// @Description 修改管理员角色、禁用状态、重置密码或两步验证，必须保留至少一个可用的超级管理员
+// @Tags Admin
+// @ID /admin_user/update
+// @Accept  json
+// @Produce  json
+// @Param body body dto.UpdateAdminUserInput true "body"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /admin_user/update [post]
+func (admin *AdminUserController) UpdateAdminUser(c *gin.Context) {
+	params := &dto.UpdateAdminUserInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+
+	// 先锁住超级管理员再读取，检查与保存在同一个事务中完成
+	tx := global.DB.Begin()
+	superAdmins, err := (&dao.Admin{}).LockSuperAdmins(c, tx)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+	adminUser, err := (&dao.Admin{}).Find(c, tx, &dao.Admin{Id: params.ID})
+	if err != nil || adminUser.IsDelete == 1 {
+		tx.Rollback()
+		middleware.ResponseError(c, 2002, errors.New("管理员不存在"))
+		return
+	}
+	losesSuperAdmin := adminUser.GetRole() == public.RoleSuperAdmin && adminUser.IsDisable == 0 &&
+		(params.Role != public.RoleSuperAdmin || params.IsDisable == 1)
+	if losesSuperAdmin {
+		if err := checkLastSuperAdmin(superAdmins); err != nil {
+			tx.Rollback()
+			middleware.ResponseError(c, 2003, err)
+			return
+		}
+	}
+
+	before := public.AuditSnapshot(adminUser)
+	adminUser.Role = params.Role
+	adminUser.IsDisable = params.IsDisable
+	if params.Password != "" {
+		adminUser.Salt = public.NewSalt()
+		adminUser.Password = public.SaltPassword(adminUser.Salt, params.Password)
+	}
+	if err := adminUser.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	if err := saveAuditLog(c, tx, public.AuditActionUpdate, public.AuditResourceAdmin, int64(adminUser.Id), adminUser.UserName,
+		before, public.AuditSnapshot(adminUser)); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2005, err)
+		return
+	}
+	if params.ResetTOTP == 1 {
+		if err := resetTOTP(c, tx, adminUser); err != nil {
+			tx.Rollback()
+			middleware.ResponseError(c, 2006, err)
+			return
+		}
+	}
+	tx.Commit()
+	middleware.ResponseSuccess(c, "")
+}
+
+// DeleteAdminUser godoc
+// @Summary Delete admin user
+// @Description 删除管理员，不能删除自己
+// @Tags Admin
+// @ID /admin_user/delete
+// @Accept  json
+// @Produce  json
+// @Param id query int true "管理员ID"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /admin_user/delete [get]
+func (admin *AdminUserController) DeleteAdminUser(c *gin.Context) {
+	params := &dto.DeleteAdminUserInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	if current, ok := c.Get("admin"); ok && current.(*dao.Admin).Id == params.ID {
+		middleware.ResponseError(c, 2002, errors.New("不能删除自己"))
+		return
+	}
+
+	tx := global.DB.Begin()
+	superAdmins, err := (&dao.Admin{}).LockSuperAdmins(c, tx)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	adminUser, err := (&dao.Admin{}).Find(c, tx, &dao.Admin{Id: params.ID})
+	if err != nil || adminUser.IsDelete == 1 {
+		tx.Rollback()
+		middleware.ResponseError(c, 2003, errors.New("管理员不存在"))
+		return
+	}
+	if adminUser.GetRole() == public.RoleSuperAdmin && adminUser.IsDisable == 0 {
+		if err := checkLastSuperAdmin(superAdmins); err != nil {
+			tx.Rollback()
+			middleware.ResponseError(c, 2004, err)
+			return
+		}
+	}
+	before := public.AuditSnapshot(adminUser)
+	adminUser.IsDelete = 1
+	if err := adminUser.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2005, err)
+		return
+	}
+	if err := saveAuditLog(c, tx, public.AuditActionDelete, public.AuditResourceAdmin, int64(adminUser.Id), adminUser.UserName,
+		before, public.AuditSnapshot(adminUser)); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2006, err)
+		return
+	}
+	tx.Commit()
+	middleware.ResponseSuccess(c, "")
+}
+
+// checkLastSuperAdmin superAdmins 为 LockSuperAdmins 在同一事务中返回的数量
+func checkLastSuperAdmin(superAdmins int) error {
+	if superAdmins <= 1 {
+		return errors.New("至少保留一个可用的超级管理员")
+	}
+	return nil
+}
+
+// ResetAdminPassword 供命令行找回管理员，enable 为 true 时同时解除禁用，resetTwoFactor 为 true 时同时重置两步验证
+func ResetAdminPassword(c *gin.Context, tx *gorm.DB, userName, password string, enable, resetTwoFactor bool) error {
+	adminUser, err := (&dao.Admin{}).FindByUserName(c, tx, userName)
+	if err != nil {
+		return errors.New("管理员不存在")
+	}
+	before := public.AuditSnapshot(adminUser)
+	adminUser.Salt = public.NewSalt()
+	adminUser.Password = public.SaltPassword(adminUser.Salt, password)
+	if enable {
+		adminUser.IsDisable = 0
+	}
+	if err := adminUser.Save(c, tx); err != nil {
+		return err
+	}
+	if err := saveAuditLog(c, tx, public.AuditActionChangePassword, public.AuditResourceAdmin, int64(adminUser.Id), adminUser.UserName,
+		before, public.AuditSnapshot(adminUser)); err != nil {
+		return err
+	}
+	if resetTwoFactor {
+		return resetTOTP(c, tx, adminUser)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package controller
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/dto"
+	"github.com/JunxiHe459/gateway/global"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type AlertRuleController struct {
+}
+
+func AlertRuleRegister(group *gin.RouterGroup) {
+	alert := &AlertRuleController{}
+	group.GET("/rule_list", middleware.RequirePermission(public.PermAlertRead), alert.AlertRuleList)
+	group.POST("/add_rule", middleware.RequirePermission(public.PermAlertWrite), alert.AddAlertRule)
+	group.POST("/update_rule", middleware.RequirePermission(public.PermAlertWrite), alert.UpdateAlertRule)
+	group.GET("/delete_rule", middleware.RequirePermission(public.PermAlertWrite), alert.DeleteAlertRule)
+	group.GET("/active", middleware.RequirePermission(public.PermAlertRead), alert.AlertActive)
+}
+
+// AlertRuleList godoc
+// @Summary Alert rule list
+// @Description 告警规则列表
+// @Tags Alert
+// @ID /alert/rule_list
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} middleware.Response{data=dto.AlertRuleListOutput} "success"
+// @Router /alert/rule_list [get]
+func (alert *AlertRuleController) AlertRuleList(c *gin.Context) {
+	alertRule := &dao.AlertRule{}
+	list, total, err := alertRule.List(c, global.DB, false)
+	if err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+
+	outputList := []dto.AlertRuleItemOutput{}
+	for _, item := range list {
+		outputList = append(outputList, dto.AlertRuleItemOutput{
+			ID:           item.ID,
+			Name:         item.Name,
+			RuleType:     item.RuleType,
+			RuleTypeName: public.AlertTypeMap[item.RuleType],
+			ServiceID:    item.ServiceID,
+			RenterID:     item.RenterID,
+			Target:       item.Target,
+			Threshold:    item.Threshold,
+			ForSeconds:   item.ForSeconds,
+			WebhookURL:   item.WebhookURL,
+			Enabled:      item.Enabled,
+		})
+	}
+	middleware.ResponseSuccess(c, dto.AlertRuleListOutput{
+		List:  outputList,
+		Total: total,
+	})
+}
+
+// AddAlertRule godoc
+// @Summary Add alert rule
+// @Description 添加告警规则，支持服务错误率、租户日请求量、节点健康与证书过期
+// @Tags Alert
+// @ID /alert/add_rule
+// @Accept  json
+// @Produce  json
+// @Param body body dto.AddAlertRuleInput true "body"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /alert/add_rule [post]
+func (alert *AlertRuleController) AddAlertRule(c *gin.Context) {
+	params := &dto.AddAlertRuleInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+
+	alertRule := &dao.AlertRule{
+		Name:       params.Name,
+		RuleType:   params.RuleType,
+		ServiceID:  params.ServiceID,
+		RenterID:   params.RenterID,
+		Target:     params.Target,
+		Threshold:  params.Threshold,
+		ForSeconds: params.ForSeconds,
+		WebhookURL: params.WebhookURL,
+		Enabled:    params.Enabled,
+	}
+	if err := checkAlertRule(c, alertRule); err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+	if err := alertRule.Save(c, global.DB); err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+	middleware.ResponseSuccess(c, "")
+}
+
+// UpdateAlertRule godoc
+// @Summary Update alert rule
+// @Description 修改告警规则
+// @Tags Alert
+// @ID /alert/update_rule
+// @Accept  json
+// @Produce  json
+// @Param body body dto.UpdateAlertRuleInput true "body"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /alert/update_rule [post]
+func (alert *AlertRuleController) UpdateAlertRule(c *gin.Context) {
+	params := &dto.UpdateAlertRuleInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+
+	search := &dao.AlertRule{ID: params.ID}
+	alertRule, err := search.Find(c, global.DB, search)
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+	alertRule.Name = params.Name
+	alertRule.RuleType = params.RuleType
+	alertRule.ServiceID = params.ServiceID
+	alertRule.RenterID = params.RenterID
+	alertRule.Target = params.Target
+	alertRule.Threshold = params.Threshold
+	alertRule.ForSeconds = params.ForSeconds
+	alertRule.WebhookURL = params.WebhookURL
+	alertRule.Enabled = params.Enabled
+	if err := checkAlertRule(c, alertRule); err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+	if err := alertRule.Save(c, global.DB); err != nil {
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	middleware.ResponseSuccess(c, "")
+}
+
+// DeleteAlertRule godoc
+// @Summary Delete alert rule
+// @Description 删除告警规则，已触发的告警在下一轮评估时发送 resolved
+// @Tags Alert
+// @ID /alert/delete_rule
+// @Accept  json
+// @Produce  json
+// @Param id query int true "告警规则ID"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /alert/delete_rule [get]
+func (alert *AlertRuleController) DeleteAlertRule(c *gin.Context) {
+	params := &dto.DeleteAlertRuleInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+
+	search := &dao.AlertRule{ID: params.ID}
+	alertRule, err := search.Find(c, global.DB, search)
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+	alertRule.IsDelete = 1
+	if err := alertRule.Save(c, global.DB); err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+	middleware.ResponseSuccess(c, "")
+}
+
+// AlertActive godoc
+// @Summary Active alerts
+// @Description 当前 pending 与 firing 的告警
+// @Tags Alert
+// @ID /alert/active
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} middleware.Response{data=dto.AlertActiveOutput} "success"
+// @Router /alert/active [get]
+func (alert *AlertRuleController) AlertActive(c *gin.Context) {
+	out := &dto.AlertActiveOutput{List: []dto.AlertActiveItemOutput{}}
+	for _, state := range dao.AlertManagerHandler.Active() {
+		item := dto.AlertActiveItemOutput{
+			Fingerprint: state.Fingerprint,
+			RuleID:      state.Rule.ID,
+			RuleName:    state.Rule.Name,
+			RuleType:    public.AlertTypeMap[state.Rule.RuleType],
+			Subject:     state.Subject,
+			Status:      state.Status,
+			Value:       state.Value,
+			Threshold:   state.Rule.Threshold,
+			Message:     state.Message,
+			PendingAt:   state.PendingAt.Format("2006-01-02 15:04:05"),
+		}
+		if !state.FiredAt.IsZero() {
+			item.FiredAt = state.FiredAt.Format("2006-01-02 15:04:05")
+		}
+		out.List = append(out.List, item)
+	}
+	middleware.ResponseSuccess(c, out)
+}
+
+// checkAlertRule 检查规则类型需要的字段
+func checkAlertRule(c *gin.Context, alertRule *dao.AlertRule) error {
+	switch alertRule.RuleType {
+	case public.AlertTypeErrorRate, public.AlertTypeRenterQpd:
+		if alertRule.Threshold <= 0 || alertRule.Threshold > 100 {
+			return errors.New("threshold 为百分比，取值 (0, 100]")
+		}
+	case public.AlertTypeCertExpiry:
+		if alertRule.Target == "" {
+			return errors.New("证书过期规则需要设置 target")
+		}
+		if alertRule.Threshold <= 0 {
+			return errors.New("证书过期规则需要设置 threshold 天数")
+		}
+	}
+	if alertRule.ServiceID != 0 {
+		serviceInfo := &dao.ServiceInfo{ID: alertRule.ServiceID}
+		if _, err := serviceInfo.Find(c, global.DB, serviceInfo); err != nil {
+			return errors.New("服务不存在")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/dto"
+	"github.com/JunxiHe459/gateway/global"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+)
+
+type AuditController struct {
+}
+
+func AuditRegister(group *gin.RouterGroup) {
+	audit := &AuditController{}
+	group.GET("/list", middleware.RequirePermission(public.PermAuditRead), audit.AuditList)
+}
+
+// AuditList godoc
+// @Summary Audit log list
+// @Description 配置变更审计日志，按操作时间倒序
+// @Tags Audit
+// @ID /audit/list
+// @Accept  json
+// @Produce  json
+// @Param page_number query int true "页码"
+// @Param page_size query int true "每页条数"
+// @Param admin_id query int false "管理员ID"
+// @Param resource_type query string false "对象类型 service/renter/admin/admin_token"
+// @Param resource_id query int false "对象ID"
+// @Param action query string false "操作 add/update/delete/change_password/rollback/enable_totp/disable_totp"
+// @Param start_date query string false "开始日期 2006-01-02"
+// @Param end_date query string false "结束日期 2006-01-02"
+// @Success 200 {object} middleware.Response{data=dto.AuditListOutput} "success"
+// @Router /audit/list [get]
+func (audit *AuditController) AuditList(c *gin.Context) {
+	params := &dto.AuditListInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	list, total, err := (&dao.AuditLog{}).PageList(c, global.DB, params)
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+
+	out := &dto.AuditListOutput{List: []dto.AuditItemOutput{}, Total: total}
+	for _, item := range list {
+		outItem := dto.AuditItemOutput{
+			ID:           item.ID,
+			AdminID:      item.AdminID,
+			AdminName:    item.AdminName,
+			Action:       item.Action,
+			ResourceType: item.ResourceType,
+			ResourceID:   item.ResourceID,
+			ResourceName: item.ResourceName,
+			Endpoint:     item.Endpoint,
+			ClientIP:     item.ClientIP,
+			CreatedAt:    item.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+		_ = json.Unmarshal([]byte(item.Before), &outItem.Before)
+		_ = json.Unmarshal([]byte(item.After), &outItem.After)
+		_ = json.Unmarshal([]byte(item.Diff), &outItem.Diff)
+		out.List = append(out.List, outItem)
+	}
+	middleware.ResponseSuccess(c, out)
+}
+
+// saveAuditLog 与配置变更在同一个事务中写入，写入失败时调用方回滚整个变更
+// before 与 after 为 public.AuditSnapshot 的结果，新增时 before 为 nil，删除时 after 为删除后的状态
+func saveAuditLog(c *gin.Context, tx *gorm.DB, action, resourceType string, resourceID int64, resourceName string,
+	before, after map[string]interface{}) error {
+	beforeJSON, afterJSON, diffJSON := public.AuditRecord(before, after)
+	auditLog := &dao.AuditLog{
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		ResourceName: resourceName,
+		Endpoint:     public.AuditEndpointCLI,
+		Before:       beforeJSON,
+		After:        afterJSON,
+		Diff:         diffJSON,
+	}
+	// 命令行构造的 context 没有 Request
+	if c.Request != nil {
+		auditLog.Endpoint = c.Request.Method + " " + c.Request.URL.Path
+		auditLog.ClientIP = c.ClientIP()
+	}
+	if adminInterface, ok := c.Get("admin"); ok {
+		admin := adminInterface.(*dao.Admin)
+		auditLog.AdminID = admin.Id
+		auditLog.AdminName = admin.UserName
+	}
+	return auditLog.Save(c, tx)
+}
@@ -9,15 +9,20 @@ import (
 	"github.com/e421083458/golang_common/lib"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
+	"math"
+	"sort"
+	"time"
 )
 
 type DashboardController struct{}
 
 func DashboardRegister(group *gin.RouterGroup) {
 	service := &DashboardController{}
-	group.GET("/panel_group_data", service.PanelGroupData)
-	group.GET("/flow_stat", service.FlowStat)
-	group.GET("/service_stat", service.ServiceStat)
+	group.GET("/panel_group_data", middleware.RequirePermission(public.PermDashboardRead), service.PanelGroupData)
+	group.GET("/flow_stat", middleware.RequirePermission(public.PermDashboardRead), service.FlowStat)
+	group.GET("/service_stat", middleware.RequirePermission(public.PermDashboardRead), service.ServiceStat)
+	group.GET("/top", middleware.RequirePermission(public.PermDashboardRead), service.Top)
+	group.GET("/anomalies", middleware.RequirePermission(public.PermDashboardRead), service.Anomalies)
 }
 
 // PanelGroupData godoc
@@ -42,12 +47,23 @@ func (service *DashboardController) PanelGroupData(c *gin.Context) {
 
 	renterInfo := dao.Renter{}
 	_, total_renter, err := renterInfo.GetRenterList(c, global.DB, &dto.RenterListInput{PageNumber: 1, PageSize: 1})
+	if err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+
+	counter, err := public.FlowCounterHandler.GetCounter(public.FlowTotal)
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+	todayRequestNum, _ := counter.GetDayData(public.GetGinTraceContext(c), time.Now())
 
 	out := &dto.PanelGroupDataOutput{
 		ServiceNum:      total_service,
 		RenterNum:       total_renter,
-		CurrentQPS:      0, // TODO: 之后添加
-		TodayRequestNum: 0, // TODO： 之后添加
+		CurrentQPS:      counter.QPS,
+		TodayRequestNum: todayRequestNum,
 	}
 	middleware.ResponseSuccess(c, out)
 }
@@ -62,31 +78,30 @@ func (service *DashboardController) PanelGroupData(c *gin.Context) {
 // @Success 200 {object} middleware.Response{data=dto.ServiceStatsOutput} "success"
 // @Router /dashboard/flow_stat [get]
 func (service *DashboardController) FlowStat(c *gin.Context) {
-	//counter, err := public.FlowCounterHandler.GetCounter(public.FlowTotal)
-	//if err != nil {
-	//	middleware.ResponseError(c, 2001, err)
-	//	return
-	//}
-	//todayList := []int{}
-	//currentTime := time.Now()
-	//for i := 0; i <= currentTime.Hour(); i++ {
-	//	dateTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), i, 0, 0, 0, lib.TimeLocation)
-	//	hourData, _ := counter.GetHourData(dateTime)
-	//	todayList = append(todayList, int(hourData))
-	//}
-	//
-	//yesterdayList := []int{}
-	//yesterTime := currentTime.Add(-1 * time.Duration(time.Hour*24))
-	//for i := 0; i <= 23; i++ {
-	//	dateTime := time.Date(yesterTime.Year(), yesterTime.Month(), yesterTime.Day(), i, 0, 0, 0, lib.TimeLocation)
-	//	hourData, _ := counter.GetHourData(dateTime)
-	//	yesterdayList = append(yesterdayList, int(hourData))
-	//}
-	//middleware.ResponseSuccess(c, &dto.ServiceStatsOutput{
-	//	Today:     todayList,
-	//	Yesterday: yesterdayList,
-	//})
-	middleware.ResponseSuccess(c, &dto.ServiceStatsOutput{})
+	counter, err := public.FlowCounterHandler.GetCounter(public.FlowTotal)
+	if err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	todayList := []int{}
+	currentTime := time.Now()
+	for i := 0; i <= currentTime.In(lib.TimeLocation).Hour(); i++ {
+		dateTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), i, 0, 0, 0, lib.TimeLocation)
+		hourData, _ := counter.GetHourData(public.GetGinTraceContext(c), dateTime)
+		todayList = append(todayList, int(hourData))
+	}
+
+	yesterdayList := []int{}
+	yesterTime := currentTime.Add(-1 * time.Duration(time.Hour*24))
+	for i := 0; i <= 23; i++ {
+		dateTime := time.Date(yesterTime.Year(), yesterTime.Month(), yesterTime.Day(), i, 0, 0, 0, lib.TimeLocation)
+		hourData, _ := counter.GetHourData(public.GetGinTraceContext(c), dateTime)
+		yesterdayList = append(yesterdayList, int(hourData))
+	}
+	middleware.ResponseSuccess(c, &dto.ServiceStatsOutput{
+		Today:     todayList,
+		Yesterday: yesterdayList,
+	})
 }
 
 //ServiceStat godoc
@@ -128,3 +143,201 @@ func (service *DashboardController) ServiceStat(c *gin.Context) {
 	}
 	middleware.ResponseSuccess(c, out)
 }
+
+// Top godoc
+// @Summary Top-N
+// @Description 按QPS、错误率或延迟排序的服务/租户排行
+// @Tags Dashboard
+// @ID /dashboard/top
+// @Accept  json
+// @Produce  json
+// @Param dimension query string true "统计维度 service 或 renter"
+// @Param metric query string true "排序指标 qps、error_rate 或 latency"
+// @Param window query int true "统计窗口(小时) 1-24"
+// @Param limit query int false "返回条数，默认10"
+// @Success 200 {object} middleware.Response{data=dto.DashTopOutput} "success"
+// @Router /dashboard/top [get]
+func (service *DashboardController) Top(c *gin.Context) {
+	params := &dto.DashTopInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	if params.Limit == 0 {
+		params.Limit = 10
+	}
+
+	var names []string
+	var err error
+	prefix, errorPrefix := public.FlowServicePrefix, public.FlowServiceErrorPrefix
+	if params.Dimension == public.DashDimensionService {
+		serviceInfo := &dao.ServiceInfo{}
+		names, err = serviceInfo.ListNames(c, global.DB)
+	} else {
+		prefix, errorPrefix = public.FlowAppPrefix, public.FlowAppErrorPrefix
+		renterInfo := &dao.Renter{}
+		names, err = renterInfo.ListIDs(c, global.DB)
+	}
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+
+	currentTime := time.Now()
+	// 当前小时只统计已经过去的部分
+	seconds := int64(params.Window-1)*3600 + int64(currentTime.Sub(currentTime.Truncate(time.Hour)).Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	trace := public.GetGinTraceContext(c)
+	list := []dto.DashTopItemOutput{}
+	for _, name := range names {
+		requestNum, err := public.FlowCounterHandler.Lookup(prefix+name).GetWindowData(trace, currentTime, params.Window)
+		if err != nil {
+			middleware.ResponseError(c, 2004, err)
+			return
+		}
+		item := dto.DashTopItemOutput{
+			Name:       name,
+			RequestNum: requestNum,
+			QPS:        float64(requestNum) / float64(seconds),
+		}
+		// 错误数与耗时单独记录，请求量为 0 时也要查询
+		item.ErrorNum, err = public.FlowCounterHandler.Lookup(errorPrefix+name).GetWindowData(trace, currentTime, params.Window)
+		if err != nil {
+			middleware.ResponseError(c, 2004, err)
+			return
+		}
+		if requestNum > 0 {
+			item.ErrorRate = float64(item.ErrorNum) / float64(requestNum)
+		}
+		percentiles, err := public.LatencyCounterHandler.Lookup(prefix+name).GetWindowPercentiles(trace, currentTime, params.Window, 0.5, 0.95, 0.99)
+		if err != nil {
+			middleware.ResponseError(c, 2004, err)
+			return
+		}
+		item.P50, item.P95, item.P99 = percentiles[0], percentiles[1], percentiles[2]
+		list = append(list, item)
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		switch params.Metric {
+		case public.DashMetricErrorRate:
+			if list[i].ErrorRate != list[j].ErrorRate {
+				return list[i].ErrorRate > list[j].ErrorRate
+			}
+		case public.DashMetricLatency:
+			if list[i].P95 != list[j].P95 {
+				return list[i].P95 > list[j].P95
+			}
+		}
+		return list[i].RequestNum > list[j].RequestNum
+	})
+	if len(list) > params.Limit {
+		list = list[:params.Limit]
+	}
+	middleware.ResponseSuccess(c, &dto.DashTopOutput{
+		Dimension: params.Dimension,
+		Metric:    params.Metric,
+		Window:    params.Window,
+		List:      list,
+	})
+}
+
+// Anomalies godoc
+// @Summary Anomalies
+// @Description 与前几天同一小时相比，请求量或错误率异常的服务
+// @Tags Dashboard
+// @ID /dashboard/anomalies
+// @Accept  json
+// @Produce  json
+// @Param days query int false "基线天数 1-7，默认7"
+// @Param threshold query number false "偏离阈值(标准差倍数)，默认3"
+// @Success 200 {object} middleware.Response{data=dto.DashAnomalyOutput} "success"
+// @Router /dashboard/anomalies [get]
+func (service *DashboardController) Anomalies(c *gin.Context) {
+	params := &dto.DashAnomalyInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	if params.Days == 0 {
+		params.Days = public.AnomalyBaselineDaysDefault
+	}
+	if params.Threshold == 0 {
+		params.Threshold = public.AnomalyThresholdDefault
+	}
+
+	serviceInfo := &dao.ServiceInfo{}
+	serviceNames, err := serviceInfo.ListNames(c, global.DB)
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+
+	// 检测上一个完整的小时
+	hour := time.Now().Truncate(time.Hour).Add(-1 * time.Hour)
+	trace := public.GetGinTraceContext(c)
+	out := &dto.DashAnomalyOutput{
+		Hour: hour.In(lib.TimeLocation).Format("2006-01-02 15:00"),
+		List: []dto.DashAnomalyItemOutput{},
+	}
+	for _, serviceName := range serviceNames {
+		counter := public.FlowCounterHandler.Lookup(public.FlowServicePrefix + serviceName)
+		errorCounter := public.FlowCounterHandler.Lookup(public.FlowServiceErrorPrefix + serviceName)
+		requestNum, _, err := counter.LookupHourData(trace, hour)
+		if err != nil {
+			middleware.ResponseError(c, 2004, err)
+			return
+		}
+		errorNum, _, _ := errorCounter.LookupHourData(trace, hour)
+
+		trafficBaseline := []float64{}
+		errorRateBaseline := []float64{}
+		for day := 1; day <= params.Days; day++ {
+			dayTime := hour.Add(-time.Duration(day) * 24 * time.Hour)
+			dayRequestNum, ok, err := counter.LookupHourData(trace, dayTime)
+			if err != nil {
+				middleware.ResponseError(c, 2004, err)
+				return
+			}
+			if !ok {
+				continue
+			}
+			trafficBaseline = append(trafficBaseline, float64(dayRequestNum))
+			if dayRequestNum >= public.AnomalyMinRequests {
+				dayErrorNum, _, _ := errorCounter.LookupHourData(trace, dayTime)
+				errorRateBaseline = append(errorRateBaseline, float64(dayErrorNum)/float64(dayRequestNum))
+			}
+		}
+
+		// 请求量很小时波动没有意义
+		mean, score, anomalous := public.TrafficAnomaly(float64(requestNum), trafficBaseline, params.Threshold)
+		if anomalous && (requestNum >= public.AnomalyMinRequests || mean >= public.AnomalyMinRequests) {
+			out.List = append(out.List, dto.DashAnomalyItemOutput{
+				ServiceName: serviceName,
+				Metric:      public.AnomalyMetricTraffic,
+				Value:       float64(requestNum),
+				Baseline:    mean,
+				Score:       score,
+			})
+		}
+		if requestNum >= public.AnomalyMinRequests {
+			errorRate := float64(errorNum) / float64(requestNum)
+			mean, score, anomalous := public.ErrorRateAnomaly(errorRate, errorRateBaseline, params.Threshold)
+			if anomalous {
+				out.List = append(out.List, dto.DashAnomalyItemOutput{
+					ServiceName: serviceName,
+					Metric:      public.AnomalyMetricErrorRate,
+					Value:       errorRate,
+					Baseline:    mean,
+					Score:       score,
+				})
+			}
+		}
+	}
+	sort.SliceStable(out.List, func(i, j int) bool {
+		return math.Abs(out.List[i].Score) > math.Abs(out.List[j].Score)
+	})
+	middleware.ResponseSuccess(c, out)
+}
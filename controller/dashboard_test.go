@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/gin-gonic/gin"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// stubCounterStore 代替 redis，只支持按 key 读写
+type stubCounterStore struct {
+	values map[string]int64
+}
+
+func (s *stubCounterStore) Incr(trace *lib.TraceContext, incrs ...public.CounterIncr) error {
+	return nil
+}
+
+func (s *stubCounterStore) Get(trace *lib.TraceContext, key string) (int64, bool, error) {
+	value, ok := s.values[key]
+	return value, ok, nil
+}
+
+func (s *stubCounterStore) MGet(trace *lib.TraceContext, keys ...string) ([]int64, error) {
+	counts := make([]int64, len(keys))
+	for index, key := range keys {
+		counts[index] = s.values[key]
+	}
+	return counts, nil
+}
+
+func (s *stubCounterStore) HGetAll(trace *lib.TraceContext, key string) (map[string]int64, error) {
+	return map[string]int64{}, nil
+}
+
+func (s *stubCounterStore) Scan(trace *lib.TraceContext, prefix string) (map[string]int64, error) {
+	return map[string]int64{}, nil
+}
+
+func TestFlowStatReturnsTodayAndYesterday(t *testing.T) {
+	oldStore, oldLocation := public.CounterStoreHandler, lib.TimeLocation
+	defer func() {
+		public.CounterStoreHandler, lib.TimeLocation = oldStore, oldLocation
+	}()
+	lib.TimeLocation = time.Local
+	now := time.Now()
+	yesterday := now.Add(-24 * time.Hour)
+	hourKey := func(t time.Time, hour int) string {
+		return fmt.Sprintf("%s_%s%02d_%s", public.RedisFlowHourKey, t.Format("20060102"), hour, public.FlowTotal)
+	}
+	public.CounterStoreHandler = &stubCounterStore{values: map[string]int64{
+		hourKey(now, 0):          11,
+		hourKey(now, now.Hour()): 22,
+		hourKey(yesterday, 23):   33,
+	}}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/dashboard/flow_stat", nil)
+	(&DashboardController{}).FlowStat(c)
+
+	out := struct {
+		Data struct {
+			Today     []int `json:"today"`
+			Yesterday []int `json:"yesterday"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err, w.Body.String())
+	}
+	if len(out.Data.Today) != now.Hour()+1 || len(out.Data.Yesterday) != 24 {
+		t.Fatalf("unexpected lengths: %+v", out.Data)
+	}
+	if out.Data.Today[0] != 11 && now.Hour() != 0 {
+		t.Fatalf("today[0] = %d, want 11", out.Data.Today[0])
+	}
+	if out.Data.Today[now.Hour()] != 22 || out.Data.Yesterday[23] != 33 {
+		t.Fatalf("unexpected data: %+v", out.Data)
+	}
+}
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/dto"
+	"github.com/JunxiHe459/gateway/global"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"time"
+)
+
+type FaultRuleController struct {
+}
+
+func FaultRuleRegister(group *gin.RouterGroup) {
+	fault := &FaultRuleController{}
+	group.GET("/fault_list", middleware.RequirePermission(public.PermServiceRead), fault.FaultRuleList)
+	group.POST("/add_fault", middleware.RequirePermission(public.PermServiceWrite), fault.AddFaultRule)
+	group.POST("/delete_fault", middleware.RequirePermission(public.PermServiceWrite), fault.DeleteFaultRule)
+}
+
+// FaultRuleList godoc
+// @Summary Fault rule list
+// @Description 服务故障注入规则列表，只返回未过期的规则
+// @Tags Fault Injection
+// @ID /service/fault_list
+// @Accept  json
+// @Produce  json
+// @Param service_id query int true "服务ID"
+// @Success 200 {object} middleware.Response{data=dto.FaultRuleListOutput} "success"
+// @Router /service/fault_list [get]
+func (fault *FaultRuleController) FaultRuleList(c *gin.Context) {
+	params := &dto.FaultRuleListInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+
+	faultRule := &dao.FaultRule{}
+	list, total, err := faultRule.ListByServiceID(c, global.DB, params.ServiceID)
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+
+	outputList := []dto.FaultRuleItemOutput{}
+	for _, item := range list {
+		outputList = append(outputList, dto.FaultRuleItemOutput{
+			ID:            item.ID,
+			ServiceID:     item.ServiceID,
+			FaultType:     item.FaultType,
+			FaultTypeName: public.FaultTypeMap[item.FaultType],
+			DelayMs:       item.DelayMs,
+			DelayJitterMs: item.DelayJitterMs,
+			AbortCode:     item.AbortCode,
+			GrpcCode:      item.GrpcCode,
+			Percentage:    item.Percentage,
+			RenterID:      item.RenterID,
+			HeaderMatch:   item.HeaderMatch,
+			ExpireAt:      item.ExpireAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	middleware.ResponseSuccess(c, dto.FaultRuleListOutput{
+		List:  outputList,
+		Total: total,
+	})
+}
+
+// AddFaultRule godoc
+// @Summary Add fault rule
+// @Description 添加故障注入规则，支持延迟、中断与连接重置
+// @Tags Fault Injection
+// @ID /service/add_fault
+// @Accept  json
+// @Produce  json
+// @Param body body dto.AddFaultRuleInput true "body"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /service/add_fault [post]
+func (fault *FaultRuleController) AddFaultRule(c *gin.Context) {
+	params := &dto.AddFaultRuleInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+
+	serviceInfo := &dao.ServiceInfo{ID: params.ServiceID}
+	serviceDetail, err := serviceInfo.GetServiceDetail(c, global.DB, serviceInfo)
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+
+	switch params.FaultType {
+	case public.FaultTypeDelay:
+		if params.DelayMs == 0 && params.DelayJitterMs == 0 {
+			middleware.ResponseError(c, 2003, errors.New("延迟故障需要设置 delay_ms 或 delay_jitter_ms"))
+			return
+		}
+	case public.FaultTypeAbort:
+		if serviceDetail.Info.LoadType == public.LoadTypeHTTP && params.AbortCode == 0 {
+			middleware.ResponseError(c, 2003, errors.New("HTTP 服务的中断故障需要设置 abort_code"))
+			return
+		}
+		if serviceDetail.Info.LoadType == public.LoadTypeGRPC && params.GrpcCode == 0 {
+			middleware.ResponseError(c, 2003, errors.New("GRPC 服务的中断故障需要设置 grpc_code"))
+			return
+		}
+	}
+	// tcp 连接上没有租户与 header 信息，这类条件永远不会命中
+	if serviceDetail.Info.LoadType == public.LoadTypeTCP && (params.RenterID != "" || params.HeaderMatch != "") {
+		middleware.ResponseError(c, 2003, errors.New("TCP 服务不支持 renter_id 与 header_match 条件"))
+		return
+	}
+
+	faultRule := &dao.FaultRule{
+		ServiceID:     params.ServiceID,
+		FaultType:     params.FaultType,
+		DelayMs:       params.DelayMs,
+		DelayJitterMs: params.DelayJitterMs,
+		AbortCode:     params.AbortCode,
+		GrpcCode:      params.GrpcCode,
+		Percentage:    params.Percentage,
+		RenterID:      params.RenterID,
+		HeaderMatch:   params.HeaderMatch,
+		ExpireAt:      time.Now().Add(time.Duration(params.ExpireMinutes) * time.Minute),
+	}
+	if err := faultRule.Save(c, global.DB); err != nil {
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	if err := dao.FaultRuleManagerHandler.Load(); err != nil {
+		middleware.ResponseError(c, 2005, err)
+		return
+	}
+	middleware.ResponseSuccess(c, "")
+}
+
+// DeleteFaultRule godoc
+// @Summary Delete fault rule
+// @Description 删除故障注入规则
+// @Tags Fault Injection
+// @ID /service/delete_fault
+// @Accept  json
+// @Produce  json
+// @Param body body dto.DeleteFaultRuleInput true "body"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /service/delete_fault [post]
+func (fault *FaultRuleController) DeleteFaultRule(c *gin.Context) {
+	params := &dto.DeleteFaultRuleInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+
+	search := &dao.FaultRule{ID: params.ID}
+	faultRule, err := search.Find(c, global.DB, search)
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+	faultRule.IsDelete = 1
+	if err := faultRule.Save(c, global.DB); err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+	if err := dao.FaultRuleManagerHandler.Load(); err != nil {
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	middleware.ResponseSuccess(c, "")
+}
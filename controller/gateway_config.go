@@ -0,0 +1,789 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/dto"
+	"github.com/JunxiHe459/gateway/global"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type GatewayConfigController struct {
+}
+
+func GatewayConfigRegister(group *gin.RouterGroup) {
+	config := &GatewayConfigController{}
+	group.GET("/export", middleware.RequirePermission(public.PermServiceRead),
+		middleware.RequirePermission(public.PermRenterRead), config.ExportConfig)
+	group.POST("/import", middleware.RequirePermission(public.PermServiceWrite),
+		middleware.RequirePermission(public.PermRenterWrite), config.ImportConfig)
+}
+
+// ExportConfig godoc
+// @Summary Export gateway config
+// @Description 导出所有服务与租户配置，租户密钥默认脱敏，有 renter:write 权限且 show_secret=1 时导出原文
+// @Tags Config
+// @ID /config/export
+// @Accept  json
+// @Produce  plain
+// @Param format query string false "yaml 或 json，默认 yaml"
+// @Param show_secret query int false "1 表示导出租户密钥原文"
+// @Success 200 {string} string "配置文档"
+// @Router /config/export [get]
+func (config *GatewayConfigController) ExportConfig(c *gin.Context) {
+	params := &dto.GatewayConfigExportInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	if params.Format == "" {
+		params.Format = public.GatewayConfigFormatYAML
+	}
+	showSecret := params.ShowSecret == 1 && middleware.HasPermission(c, public.PermRenterWrite)
+	doc, err := ExportGatewayConfig(c, global.DB, showSecret)
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+	data, err := EncodeGatewayConfig(doc, params.Format)
+	if err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+	contentType := "application/json; charset=utf-8"
+	if params.Format == public.GatewayConfigFormatYAML {
+		contentType = "application/x-yaml; charset=utf-8"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "gateway."+params.Format))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ImportConfig godoc
+// @Summary Import gateway config
+// @Description 按文档创建或修改服务与租户，相同内容重复导入不产生变更；dry_run=1 时在事务中执行后回滚，只返回变更
+// @Tags Config
+// @ID /config/import
+// @Accept  plain
+// @Produce  json
+// @Param dry_run query int false "1 表示只返回变更"
+// @Param prune query int false "1 表示软删除文档中不存在的服务与租户"
+// @Param body body dto.GatewayConfig true "yaml 或 json 配置文档"
+// @Success 200 {object} middleware.Response{data=dto.GatewayConfigImportOutput} "success"
+// @Router /config/import [post]
+func (config *GatewayConfigController) ImportConfig(c *gin.Context) {
+	params := &dto.GatewayConfigImportInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+	doc, err := DecodeGatewayConfig(body)
+	if err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	tx = tx.Begin()
+	changes, err := ApplyGatewayConfig(c, tx, doc, params.Prune == 1)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2005, err)
+		return
+	}
+	if params.DryRun == 1 {
+		tx.Rollback()
+	} else {
+		tx.Commit()
+		NotifyGatewayConfigChanges(changes)
+	}
+	middleware.ResponseSuccess(c, &dto.GatewayConfigImportOutput{DryRun: params.DryRun == 1, Changes: changes})
+}
+
+// EncodeGatewayConfig format 为 yaml 或 json
+func EncodeGatewayConfig(doc *dto.GatewayConfig, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case public.GatewayConfigFormatJSON:
+		return append(data, '\n'), nil
+	case public.GatewayConfigFormatYAML:
+		return public.JSONToYAML(data)
+	}
+	return nil, errors.Errorf("unknown format %q", format)
+}
+
+// DecodeGatewayConfig 同时支持 yaml 与 json，未知字段报错，避免拼写错误被静默忽略
+func DecodeGatewayConfig(data []byte) (*dto.GatewayConfig, error) {
+	raw, err := public.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	doc := &dto.GatewayConfig{}
+	if err := decoder.Decode(doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != public.GatewayConfigVersion {
+		return nil, errors.Errorf("unsupported config version %d, expect %d", doc.Version, public.GatewayConfigVersion)
+	}
+	return doc, nil
+}
+
+// ExportGatewayConfig showSecret 为 false 时租户密钥替换为 public.RedactedValue
+func ExportGatewayConfig(c *gin.Context, tx *gorm.DB, showSecret bool) (*dto.GatewayConfig, error) {
+	doc := &dto.GatewayConfig{
+		Version:    public.GatewayConfigVersion,
+		ExportedAt: time.Now().Format("2006-01-02 15:04:05"),
+		Services:   []dto.GatewayServiceConfig{},
+		Renters:    []dto.GatewayRenterConfig{},
+	}
+	serviceList, err := (&dao.ServiceInfo{}).ListAll(c, tx)
+	if err != nil {
+		return nil, err
+	}
+	for index := range serviceList {
+		info := &serviceList[index]
+		serviceDetail, err := info.GetServiceDetail(c, tx, info)
+		if err != nil {
+			return nil, err
+		}
+		doc.Services = append(doc.Services, serviceToConfig(serviceDetail))
+	}
+	renterList, err := (&dao.Renter{}).ListAll(c, tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, renter := range renterList {
+		item := renterToConfig(&renter)
+		if !showSecret {
+			item.Secret = public.RedactedValue
+		}
+		doc.Renters = append(doc.Renters, item)
+	}
+	return doc, nil
+}
+
+// ApplyGatewayConfig 在调用方的事务中按文档修改配置，只返回有变化的对象
+// 写入与接口使用相同的 dao Save、冲突检查、版本与审计日志，调用方提交后再调用 NotifyGatewayConfigChanges
+func ApplyGatewayConfig(c *gin.Context, tx *gorm.DB, doc *dto.GatewayConfig, prune bool) ([]dto.GatewayConfigChange, error) {
+	if err := ValidateGatewayConfig(doc); err != nil {
+		return nil, err
+	}
+	changes := []dto.GatewayConfigChange{}
+
+	serviceList, err := (&dao.ServiceInfo{}).ListAll(c, tx)
+	if err != nil {
+		return nil, err
+	}
+	serviceMap := map[string]*dao.ServiceInfo{}
+	for index := range serviceList {
+		serviceMap[serviceList[index].ServiceName] = &serviceList[index]
+	}
+	for _, item := range doc.Services {
+		change, err := applyServiceConfig(c, tx, serviceMap[item.Name], item)
+		if err != nil {
+			return nil, errors.Wrapf(err, "service %s", item.Name)
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+		delete(serviceMap, item.Name)
+	}
+	if prune {
+		for index := range serviceList {
+			info := &serviceList[index]
+			if _, ok := serviceMap[info.ServiceName]; !ok {
+				continue
+			}
+			change, err := deleteServiceConfig(c, tx, info)
+			if err != nil {
+				return nil, errors.Wrapf(err, "service %s", info.ServiceName)
+			}
+			changes = append(changes, *change)
+		}
+	}
+
+	renterList, err := (&dao.Renter{}).ListAll(c, tx)
+	if err != nil {
+		return nil, err
+	}
+	renterMap := map[string]*dao.Renter{}
+	for index := range renterList {
+		renterMap[renterList[index].RenterID] = &renterList[index]
+	}
+	for _, item := range doc.Renters {
+		change, err := applyRenterConfig(c, tx, renterMap[item.RenterID], item)
+		if err != nil {
+			return nil, errors.Wrapf(err, "renter %s", item.RenterID)
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+		delete(renterMap, item.RenterID)
+	}
+	if prune {
+		for index := range renterList {
+			renter := &renterList[index]
+			if _, ok := renterMap[renter.RenterID]; !ok {
+				continue
+			}
+			change, err := deleteRenterConfig(c, tx, renter)
+			if err != nil {
+				return nil, errors.Wrapf(err, "renter %s", renter.RenterID)
+			}
+			changes = append(changes, *change)
+		}
+	}
+	return changes, nil
+}
+
+// NotifyGatewayConfigChanges 事务提交后通知代理重新加载变更的服务
+func NotifyGatewayConfigChanges(changes []dto.GatewayConfigChange) {
+	for _, change := range changes {
+		if change.ResourceType != public.AuditResourceService {
+			continue
+		}
+		public.PublishServiceChange(&public.ServiceChangeEvent{
+			ServiceID:   change.ResourceID,
+			ServiceName: change.Name,
+			Action:      change.Action,
+			Version:     change.Version,
+		})
+	}
+}
+
+func applyServiceConfig(c *gin.Context, tx *gorm.DB, info *dao.ServiceInfo, item dto.GatewayServiceConfig) (*dto.GatewayConfigChange, error) {
+	loadType, _ := parseLoadType(item.LoadType)
+	normalizeServiceConfig(&item, loadType)
+	change := &dto.GatewayConfigChange{ResourceType: public.AuditResourceService, Name: item.Name}
+
+	if info == nil {
+		serviceDetail := &dao.ServiceDetail{
+			Info:          &dao.ServiceInfo{LoadType: loadType},
+			HTTPRule:      &dao.HttpRule{},
+			TCPRule:       &dao.TcpRule{},
+			GRPCRule:      &dao.GrpcRule{},
+			AccessControl: &dao.AccessControl{},
+			LoadBalance:   &dao.LoadBalance{},
+		}
+		if err := saveServiceConfig(c, tx, serviceDetail, item); err != nil {
+			return nil, err
+		}
+		version, err := recordServiceChange(c, tx, public.AuditActionAdd, serviceDetail.Info, nil)
+		if err != nil {
+			return nil, err
+		}
+		change.Action = public.AuditActionAdd
+		change.ResourceID = serviceDetail.Info.ID
+		change.Version = version.Version
+		change.Diff = public.RedactAuditDiff(public.AuditDiff(nil, public.AuditSnapshot(item)))
+		return change, nil
+	}
+
+	serviceDetail, err := info.GetServiceDetail(c, tx, info)
+	if err != nil {
+		return nil, err
+	}
+	diff := public.AuditDiff(public.AuditSnapshot(serviceToConfig(serviceDetail)), public.AuditSnapshot(item))
+	if len(diff) == 0 {
+		return nil, nil
+	}
+	if info.LoadType != loadType {
+		return nil, errors.New("load_type cannot be changed, delete the service first")
+	}
+	before, err := snapshotService(c, tx, serviceDetail)
+	if err != nil {
+		return nil, err
+	}
+	if err := saveServiceConfig(c, tx, serviceDetail, item); err != nil {
+		return nil, err
+	}
+	version, err := recordServiceChange(c, tx, public.AuditActionUpdate, info, before)
+	if err != nil {
+		return nil, err
+	}
+	change.Action = public.AuditActionUpdate
+	change.ResourceID = info.ID
+	change.Version = version.Version
+	change.Diff = public.RedactAuditDiff(diff)
+	return change, nil
+}
+
+// DeleteGatewayService 按名称软删除服务，调用方提交后再调用 NotifyGatewayConfigChanges
+func DeleteGatewayService(c *gin.Context, tx *gorm.DB, name string) (*dto.GatewayConfigChange, error) {
+	serviceList, err := (&dao.ServiceInfo{}).ListAll(c, tx)
+	if err != nil {
+		return nil, err
+	}
+	for index := range serviceList {
+		if serviceList[index].ServiceName == name {
+			return deleteServiceConfig(c, tx, &serviceList[index])
+		}
+	}
+	return nil, errors.Errorf("service %s not found", name)
+}
+
+// DeleteGatewayRenter 按 renter_id 软删除租户
+func DeleteGatewayRenter(c *gin.Context, tx *gorm.DB, renterID string) (*dto.GatewayConfigChange, error) {
+	renterList, err := (&dao.Renter{}).ListAll(c, tx)
+	if err != nil {
+		return nil, err
+	}
+	for index := range renterList {
+		if renterList[index].RenterID == renterID {
+			return deleteRenterConfig(c, tx, &renterList[index])
+		}
+	}
+	return nil, errors.Errorf("renter %s not found", renterID)
+}
+
+func deleteServiceConfig(c *gin.Context, tx *gorm.DB, info *dao.ServiceInfo) (*dto.GatewayConfigChange, error) {
+	serviceDetail, err := info.GetServiceDetail(c, tx, info)
+	if err != nil {
+		return nil, err
+	}
+	before := public.AuditSnapshot(serviceDetail)
+	info.IsDelete = 1
+	if err := info.Save(c, tx); err != nil {
+		return nil, err
+	}
+	if err := saveAuditLog(c, tx, public.AuditActionDelete, public.AuditResourceService, info.ID, info.ServiceName,
+		before, public.AuditSnapshot(serviceDetail)); err != nil {
+		return nil, err
+	}
+	return &dto.GatewayConfigChange{
+		ResourceType: public.AuditResourceService,
+		ResourceID:   info.ID,
+		Name:         info.ServiceName,
+		Action:       public.AuditActionDelete,
+		Diff:         map[string]public.AuditChange{},
+	}, nil
+}
+
+// saveServiceConfig 把文档写入服务的各行记录，新服务先保存 info 得到 id
+func saveServiceConfig(c *gin.Context, tx *gorm.DB, serviceDetail *dao.ServiceDetail, item dto.GatewayServiceConfig) error {
+	info := serviceDetail.Info
+	info.ServiceName = item.Name
+	info.ServiceDesc = item.Desc
+	if err := info.Save(c, tx); err != nil {
+		return err
+	}
+
+	switch info.LoadType {
+	case public.LoadTypeHTTP:
+		if err := checkHTTPRuleConflict(c, tx, info.ID, item.HTTPRule.RuleType, item.HTTPRule.Rule); err != nil {
+			return err
+		}
+		if err := checkHTTPRouteConflict(c, tx, info.ID, item.HTTPRule.RuleType, item.HTTPRule.Rule, item.HTTPRoutes); err != nil {
+			return err
+		}
+		httpRule := serviceDetail.HTTPRule
+		httpRule.ServiceID = info.ID
+		httpRule.RuleType = item.HTTPRule.RuleType
+		httpRule.Rule = item.HTTPRule.Rule
+		httpRule.NeedHttps = item.HTTPRule.NeedHttps
+		httpRule.NeedStripUri = item.HTTPRule.NeedStripUri
+		httpRule.NeedWebsocket = item.HTTPRule.NeedWebsocket
+		httpRule.UrlRewrite = item.HTTPRule.UrlRewrite
+		httpRule.HeaderTransfer = item.HTTPRule.HeaderTransfer
+		httpRule.RequestTransform = item.HTTPRule.RequestTransform
+		httpRule.ResponseTransform = item.HTTPRule.ResponseTransform
+		httpRule.TransformMaxBody = item.HTTPRule.TransformMaxBody
+		httpRule.NeedCors = item.HTTPRule.NeedCors
+		httpRule.CorsAllowOrigins = item.HTTPRule.CorsAllowOrigins
+		httpRule.CorsAllowMethods = item.HTTPRule.CorsAllowMethods
+		httpRule.CorsAllowHeaders = item.HTTPRule.CorsAllowHeaders
+		httpRule.CorsAllowCredentials = item.HTTPRule.CorsAllowCredentials
+		httpRule.CorsMaxAge = item.HTTPRule.CorsMaxAge
+		httpRule.NeedCompress = item.HTTPRule.NeedCompress
+		httpRule.CompressMinSize = item.HTTPRule.CompressMinSize
+		httpRule.CompressTypes = item.HTTPRule.CompressTypes
+		if err := httpRule.Save(c, tx); err != nil {
+			return err
+		}
+		if err := (&dao.HttpRoute{}).DeleteByServiceID(c, tx, info.ID); err != nil {
+			return err
+		}
+		if err := saveHTTPRoutes(c, tx, info.ID, item.HTTPRoutes); err != nil {
+			return err
+		}
+	case public.LoadTypeTCP:
+		if err := checkPortConflict(c, tx, info.ID, item.TCPRule.Port); err != nil {
+			return err
+		}
+		tcpRule := serviceDetail.TCPRule
+		tcpRule.ServiceID = info.ID
+		tcpRule.Port = item.TCPRule.Port
+		if err := tcpRule.Save(c, tx); err != nil {
+			return err
+		}
+	case public.LoadTypeGRPC:
+		if err := checkPortConflict(c, tx, info.ID, item.GRPCRule.Port); err != nil {
+			return err
+		}
+		grpcRule := serviceDetail.GRPCRule
+		grpcRule.ServiceID = info.ID
+		grpcRule.Port = item.GRPCRule.Port
+		grpcRule.HeaderTransfer = item.GRPCRule.HeaderTransfer
+		if err := grpcRule.Save(c, tx); err != nil {
+			return err
+		}
+	}
+
+	accessControl := serviceDetail.AccessControl
+	accessControl.ServiceID = info.ID
+	accessControl.OpenAuth = item.AccessControl.OpenAuth
+	accessControl.BlackList = item.AccessControl.BlackList
+	accessControl.WhiteList = item.AccessControl.WhiteList
+	accessControl.WhiteHostName = item.AccessControl.WhiteHostName
+	accessControl.ClientIPFlowLimit = item.AccessControl.ClientIPFlowLimit
+	accessControl.ServiceFlowLimit = item.AccessControl.ServiceFlowLimit
+	if err := accessControl.Save(c, tx); err != nil {
+		return err
+	}
+
+	loadBalance := serviceDetail.LoadBalance
+	loadBalance.ServiceID = info.ID
+	loadBalance.RoundType = item.LoadBalance.RoundType
+	loadBalance.IpList = item.LoadBalance.IpList
+	loadBalance.WeightList = item.LoadBalance.WeightList
+	loadBalance.ForbidList = item.LoadBalance.ForbidList
+	loadBalance.UpstreamConnectTimeout = item.LoadBalance.UpstreamConnectTimeout
+	loadBalance.UpstreamHeaderTimeout = item.LoadBalance.UpstreamHeaderTimeout
+	loadBalance.UpstreamIdleTimeout = item.LoadBalance.UpstreamIdleTimeout
+	loadBalance.UpstreamMaxIdle = item.LoadBalance.UpstreamMaxIdle
+	return loadBalance.Save(c, tx)
+}
+
+func deleteRenterConfig(c *gin.Context, tx *gorm.DB, renter *dao.Renter) (*dto.GatewayConfigChange, error) {
+	before := public.AuditSnapshot(renter)
+	renter.IsDelete = 1
+	if err := renter.Save(c, tx); err != nil {
+		return nil, err
+	}
+	if err := saveAuditLog(c, tx, public.AuditActionDelete, public.AuditResourceRenter, renter.ID, renter.RenterID,
+		before, public.AuditSnapshot(renter)); err != nil {
+		return nil, err
+	}
+	return &dto.GatewayConfigChange{
+		ResourceType: public.AuditResourceRenter,
+		ResourceID:   renter.ID,
+		Name:         renter.RenterID,
+		Action:       public.AuditActionDelete,
+		Diff:         map[string]public.AuditChange{},
+	}, nil
+}
+
+func applyRenterConfig(c *gin.Context, tx *gorm.DB, renter *dao.Renter, item dto.GatewayRenterConfig) (*dto.GatewayConfigChange, error) {
+	change := &dto.GatewayConfigChange{ResourceType: public.AuditResourceRenter, Name: item.RenterID}
+	if item.Secret == "" || (item.Secret == public.RedactedValue && renter == nil) {
+		item.Secret = public.MD5(item.RenterID)
+	}
+	if item.Secret == public.RedactedValue {
+		item.Secret = renter.Secret
+	}
+
+	var before map[string]interface{}
+	change.Action = public.AuditActionAdd
+	if renter != nil {
+		current := renterToConfig(renter)
+		change.Diff = public.RedactAuditDiff(public.AuditDiff(public.AuditSnapshot(current), public.AuditSnapshot(item)))
+		if len(change.Diff) == 0 {
+			return nil, nil
+		}
+		before = public.AuditSnapshot(renter)
+		change.Action = public.AuditActionUpdate
+	} else {
+		renter = &dao.Renter{RenterID: item.RenterID}
+		change.Diff = public.RedactAuditDiff(public.AuditDiff(nil, public.AuditSnapshot(item)))
+	}
+	renter.Name = item.Name
+	renter.Secret = item.Secret
+	renter.WhiteIPS = item.WhiteIPS
+	renter.Qpd = item.Qpd
+	renter.Qps = item.Qps
+	if err := renter.Save(c, tx); err != nil {
+		return nil, err
+	}
+	if err := saveAuditLog(c, tx, change.Action, public.AuditResourceRenter, renter.ID, renter.RenterID,
+		before, public.AuditSnapshot(renter)); err != nil {
+		return nil, err
+	}
+	change.ResourceID = renter.ID
+	return change, nil
+}
+
+// ValidateGatewayConfig 写入前检查整个文档，避免执行到一半才发现错误，不访问数据库
+// 每一项按添加服务与租户接口的参数规则校验，导入与接口写入的数据满足相同的约束
+func ValidateGatewayConfig(doc *dto.GatewayConfig) error {
+	val, trans := middleware.NewValidator()
+	serviceNames := map[string]bool{}
+	for index, item := range doc.Services {
+		if item.Name == "" {
+			return errors.Errorf("services[%d]: name is required", index)
+		}
+		if serviceNames[item.Name] {
+			return errors.Errorf("service %s: duplicate name", item.Name)
+		}
+		serviceNames[item.Name] = true
+		loadType, ok := parseLoadType(item.LoadType)
+		if !ok {
+			return errors.Errorf("service %s: unknown load_type %q", item.Name, item.LoadType)
+		}
+		switch loadType {
+		case public.LoadTypeHTTP:
+			if item.HTTPRule == nil {
+				return errors.Errorf("service %s: http_rule is required", item.Name)
+			}
+		case public.LoadTypeTCP:
+			if item.TCPRule == nil {
+				return errors.Errorf("service %s: tcp_rule is required", item.Name)
+			}
+		case public.LoadTypeGRPC:
+			if item.GRPCRule == nil {
+				return errors.Errorf("service %s: grpc_rule is required", item.Name)
+			}
+		}
+		if err := public.ValidateParams(val, trans, serviceConfigToInput(item, loadType)); err != nil {
+			return errors.Wrapf(err, "service %s", item.Name)
+		}
+		if len(strings.Split(item.LoadBalance.IpList, ",")) != len(strings.Split(item.LoadBalance.WeightList, ",")) {
+			return errors.Errorf("service %s: ip_list should have same length as weight_list", item.Name)
+		}
+	}
+	renterIDs := map[string]bool{}
+	for index, item := range doc.Renters {
+		if item.RenterID == "" {
+			return errors.Errorf("renters[%d]: renter_id is required", index)
+		}
+		if renterIDs[item.RenterID] {
+			return errors.Errorf("renter %s: duplicate renter_id", item.RenterID)
+		}
+		renterIDs[item.RenterID] = true
+		params := &dto.AddRenterHttpInput{
+			RenterID: item.RenterID,
+			Name:     item.Name,
+			Secret:   item.Secret,
+			WhiteIPS: item.WhiteIPS,
+			Qpd:      item.Qpd,
+			Qps:      item.Qps,
+		}
+		if err := public.ValidateParams(val, trans, params); err != nil {
+			return errors.Wrapf(err, "renter %s", item.RenterID)
+		}
+	}
+	return nil
+}
+
+// serviceConfigToInput 把文档中的服务转换为对应添加接口的参数，调用方已检查 load_type 对应的规则不为空
+func serviceConfigToInput(item dto.GatewayServiceConfig, loadType int) interface{} {
+	switch loadType {
+	case public.LoadTypeTCP:
+		return &dto.ServiceAddTcpInput{
+			ServiceName:       item.Name,
+			ServiceDesc:       item.Desc,
+			Port:              item.TCPRule.Port,
+			OpenAuth:          item.AccessControl.OpenAuth,
+			BlackList:         item.AccessControl.BlackList,
+			WhiteList:         item.AccessControl.WhiteList,
+			WhiteHostName:     item.AccessControl.WhiteHostName,
+			ClientIPFlowLimit: item.AccessControl.ClientIPFlowLimit,
+			ServiceFlowLimit:  item.AccessControl.ServiceFlowLimit,
+			RoundType:         item.LoadBalance.RoundType,
+			IpList:            item.LoadBalance.IpList,
+			WeightList:        item.LoadBalance.WeightList,
+			ForbidList:        item.LoadBalance.ForbidList,
+		}
+	case public.LoadTypeGRPC:
+		return &dto.ServiceAddGrpcInput{
+			ServiceName:       item.Name,
+			ServiceDesc:       item.Desc,
+			Port:              item.GRPCRule.Port,
+			HeaderTransfer:    item.GRPCRule.HeaderTransfer,
+			OpenAuth:          item.AccessControl.OpenAuth,
+			BlackList:         item.AccessControl.BlackList,
+			WhiteList:         item.AccessControl.WhiteList,
+			WhiteHostName:     item.AccessControl.WhiteHostName,
+			ClientIPFlowLimit: item.AccessControl.ClientIPFlowLimit,
+			ServiceFlowLimit:  item.AccessControl.ServiceFlowLimit,
+			RoundType:         item.LoadBalance.RoundType,
+			IpList:            item.LoadBalance.IpList,
+			WeightList:        item.LoadBalance.WeightList,
+			ForbidList:        item.LoadBalance.ForbidList,
+		}
+	}
+	httpRule := item.HTTPRule
+	return &dto.ServiceAddHTTPInput{
+		ServiceName:            item.Name,
+		ServiceDesc:            item.Desc,
+		RuleType:               httpRule.RuleType,
+		Rule:                   httpRule.Rule,
+		NeedHttps:              httpRule.NeedHttps,
+		NeedStripUri:           httpRule.NeedStripUri,
+		NeedWebsocket:          httpRule.NeedWebsocket,
+		UrlRewrite:             httpRule.UrlRewrite,
+		HeaderTransfer:         httpRule.HeaderTransfer,
+		RequestTransform:       httpRule.RequestTransform,
+		ResponseTransform:      httpRule.ResponseTransform,
+		TransformMaxBody:       httpRule.TransformMaxBody,
+		NeedCors:               httpRule.NeedCors,
+		CorsAllowOrigins:       httpRule.CorsAllowOrigins,
+		CorsAllowMethods:       httpRule.CorsAllowMethods,
+		CorsAllowHeaders:       httpRule.CorsAllowHeaders,
+		CorsAllowCredentials:   httpRule.CorsAllowCredentials,
+		CorsMaxAge:             httpRule.CorsMaxAge,
+		NeedCompress:           httpRule.NeedCompress,
+		CompressMinSize:        httpRule.CompressMinSize,
+		CompressTypes:          httpRule.CompressTypes,
+		Routes:                 item.HTTPRoutes,
+		OpenAuth:               item.AccessControl.OpenAuth,
+		BlackList:              item.AccessControl.BlackList,
+		WhiteList:              item.AccessControl.WhiteList,
+		ClientIPFlowLimit:      item.AccessControl.ClientIPFlowLimit,
+		ServiceFlowLimit:       item.AccessControl.ServiceFlowLimit,
+		RoundType:              item.LoadBalance.RoundType,
+		IpList:                 item.LoadBalance.IpList,
+		WeightList:             item.LoadBalance.WeightList,
+		UpstreamConnectTimeout: item.LoadBalance.UpstreamConnectTimeout,
+		UpstreamHeaderTimeout:  item.LoadBalance.UpstreamHeaderTimeout,
+		UpstreamIdleTimeout:    item.LoadBalance.UpstreamIdleTimeout,
+		UpstreamMaxIdle:        item.LoadBalance.UpstreamMaxIdle,
+	}
+}
+
+func serviceToConfig(serviceDetail *dao.ServiceDetail) dto.GatewayServiceConfig {
+	item := dto.GatewayServiceConfig{
+		Name:     serviceDetail.Info.ServiceName,
+		Desc:     serviceDetail.Info.ServiceDesc,
+		LoadType: strings.ToLower(public.LoadTypeMap[serviceDetail.Info.LoadType]),
+		AccessControl: dto.GatewayAccessControlConfig{
+			OpenAuth:          serviceDetail.AccessControl.OpenAuth,
+			BlackList:         serviceDetail.AccessControl.BlackList,
+			WhiteList:         serviceDetail.AccessControl.WhiteList,
+			WhiteHostName:     serviceDetail.AccessControl.WhiteHostName,
+			ClientIPFlowLimit: serviceDetail.AccessControl.ClientIPFlowLimit,
+			ServiceFlowLimit:  serviceDetail.AccessControl.ServiceFlowLimit,
+		},
+		LoadBalance: dto.GatewayLoadBalanceConfig{
+			RoundType:              serviceDetail.LoadBalance.RoundType,
+			IpList:                 serviceDetail.LoadBalance.IpList,
+			WeightList:             serviceDetail.LoadBalance.WeightList,
+			ForbidList:             serviceDetail.LoadBalance.ForbidList,
+			UpstreamConnectTimeout: serviceDetail.LoadBalance.UpstreamConnectTimeout,
+			UpstreamHeaderTimeout:  serviceDetail.LoadBalance.UpstreamHeaderTimeout,
+			UpstreamIdleTimeout:    serviceDetail.LoadBalance.UpstreamIdleTimeout,
+			UpstreamMaxIdle:        serviceDetail.LoadBalance.UpstreamMaxIdle,
+		},
+	}
+	switch serviceDetail.Info.LoadType {
+	case public.LoadTypeHTTP:
+		httpRule := serviceDetail.HTTPRule
+		item.HTTPRule = &dto.GatewayHTTPRuleConfig{
+			RuleType:             httpRule.RuleType,
+			Rule:                 httpRule.Rule,
+			NeedHttps:            httpRule.NeedHttps,
+			NeedStripUri:         httpRule.NeedStripUri,
+			NeedWebsocket:        httpRule.NeedWebsocket,
+			UrlRewrite:           httpRule.UrlRewrite,
+			HeaderTransfer:       httpRule.HeaderTransfer,
+			RequestTransform:     httpRule.RequestTransform,
+			ResponseTransform:    httpRule.ResponseTransform,
+			TransformMaxBody:     httpRule.TransformMaxBody,
+			NeedCors:             httpRule.NeedCors,
+			CorsAllowOrigins:     httpRule.CorsAllowOrigins,
+			CorsAllowMethods:     httpRule.CorsAllowMethods,
+			CorsAllowHeaders:     httpRule.CorsAllowHeaders,
+			CorsAllowCredentials: httpRule.CorsAllowCredentials,
+			CorsMaxAge:           httpRule.CorsMaxAge,
+			NeedCompress:         httpRule.NeedCompress,
+			CompressMinSize:      httpRule.CompressMinSize,
+			CompressTypes:        httpRule.CompressTypes,
+		}
+		for _, route := range serviceDetail.HTTPRoutes {
+			item.HTTPRoutes = append(item.HTTPRoutes, dto.HTTPRouteInput{
+				Path:        route.Path,
+				Methods:     route.Methods,
+				HeaderMatch: route.HeaderMatch,
+				QueryMatch:  route.QueryMatch,
+				Priority:    route.Priority,
+			})
+		}
+	case public.LoadTypeTCP:
+		item.TCPRule = &dto.GatewayTCPRuleConfig{Port: serviceDetail.TCPRule.Port}
+	case public.LoadTypeGRPC:
+		item.GRPCRule = &dto.GatewayGRPCRuleConfig{
+			Port:           serviceDetail.GRPCRule.Port,
+			HeaderTransfer: serviceDetail.GRPCRule.HeaderTransfer,
+		}
+	}
+	return item
+}
+
+func renterToConfig(renter *dao.Renter) dto.GatewayRenterConfig {
+	return dto.GatewayRenterConfig{
+		RenterID: renter.RenterID,
+		Name:     renter.Name,
+		Secret:   renter.Secret,
+		WhiteIPS: renter.WhiteIPS,
+		Qpd:      renter.Qpd,
+		Qps:      renter.Qps,
+	}
+}
+
+// normalizeServiceConfig 去掉与负载类型无关的规则，使文档与导出结果可以直接比较
+func normalizeServiceConfig(item *dto.GatewayServiceConfig, loadType int) {
+	item.LoadType = strings.ToLower(public.LoadTypeMap[loadType])
+	if loadType != public.LoadTypeHTTP {
+		item.HTTPRule = nil
+		item.HTTPRoutes = nil
+	}
+	if len(item.HTTPRoutes) == 0 {
+		item.HTTPRoutes = nil
+	}
+	if loadType != public.LoadTypeTCP {
+		item.TCPRule = nil
+	}
+	if loadType != public.LoadTypeGRPC {
+		item.GRPCRule = nil
+	}
+}
+
+func parseLoadType(name string) (int, bool) {
+	for loadType, loadTypeName := range public.LoadTypeMap {
+		if strings.EqualFold(loadTypeName, name) {
+			return loadType, true
+		}
+	}
+	return 0, false
+}
+
+// checkHTTPRuleConflict 域名或前缀已被其他服务使用
+func checkHTTPRuleConflict(c *gin.Context, tx *gorm.DB, serviceID int64, ruleType int, rule string) error {
+	search := &dao.HttpRule{RuleType: ruleType, Rule: rule}
+	if exist, err := search.Find(c, tx, search); err == nil && exist.ServiceID != serviceID {
+		return errors.New("Http url or domain name Already Exists")
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"github.com/JunxiHe459/gateway/dto"
+	"strings"
+	"testing"
+)
+
+func validGatewayConfig() *dto.GatewayConfig {
+	return &dto.GatewayConfig{
+		Services: []dto.GatewayServiceConfig{
+			{
+				Name:        "http_demo",
+				Desc:        "http demo",
+				LoadType:    "http",
+				HTTPRule:    &dto.GatewayHTTPRuleConfig{Rule: "/demo"},
+				HTTPRoutes:  []dto.HTTPRouteInput{{Path: "/users/:id", Methods: "GET"}},
+				LoadBalance: dto.GatewayLoadBalanceConfig{IpList: "127.0.0.1:8080", WeightList: "50"},
+			},
+			{
+				Name:        "tcp_demo",
+				Desc:        "tcp demo",
+				LoadType:    "tcp",
+				TCPRule:     &dto.GatewayTCPRuleConfig{Port: 8101},
+				LoadBalance: dto.GatewayLoadBalanceConfig{IpList: "127.0.0.1:6379", WeightList: "50"},
+			},
+		},
+		Renters: []dto.GatewayRenterConfig{{RenterID: "renter_demo", Name: "demo"}},
+	}
+}
+
+func TestValidateGatewayConfig(t *testing.T) {
+	if err := ValidateGatewayConfig(validGatewayConfig()); err != nil {
+		t.Fatalf("valid config: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(doc *dto.GatewayConfig)
+		want   string
+	}{
+		{"service name", func(doc *dto.GatewayConfig) { doc.Services[0].Name = "bad name" }, "service bad name"},
+		{"service desc", func(doc *dto.GatewayConfig) { doc.Services[0].Desc = "" }, "service http_demo"},
+		{"route path", func(doc *dto.GatewayConfig) { doc.Services[0].HTTPRoutes[0].Path = "users" }, "service http_demo"},
+		{"cors methods", func(doc *dto.GatewayConfig) { doc.Services[0].HTTPRule.CorsAllowMethods = "get" }, "service http_demo"},
+		{"weight list", func(doc *dto.GatewayConfig) { doc.Services[0].LoadBalance.WeightList = "abc" }, "service http_demo"},
+		{"tcp ip list", func(doc *dto.GatewayConfig) { doc.Services[1].LoadBalance.IpList = "127.0.0.1" }, "service tcp_demo"},
+		{"tcp port", func(doc *dto.GatewayConfig) { doc.Services[1].TCPRule.Port = 80 }, "service tcp_demo"},
+		{"tcp rule", func(doc *dto.GatewayConfig) { doc.Services[1].TCPRule = nil }, "tcp_rule is required"},
+		{"renter name", func(doc *dto.GatewayConfig) { doc.Renters[0].Name = "" }, "renter renter_demo"},
+	}
+	for _, test := range tests {
+		doc := validGatewayConfig()
+		test.modify(doc)
+		err := ValidateGatewayConfig(doc)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: err = %v, want %q", test.name, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type MetricsController struct{}
+
+func MetricsRegister(router *gin.Engine) {
+	metrics := &MetricsController{}
+	router.GET("/metrics", middleware.MetricsAuthMiddleware(), metrics.Metrics)
+}
+
+// Metrics godoc
+// @Summary Prometheus Metrics
+// @Description prometheus 指标，需要 base.metrics.token 对应的 bearer token，未配置 token 时只允许 allow_ip 访问
+// @Tags Metrics
+// @ID /metrics
+// @Produce  plain
+// @Param Authorization header string false "Bearer token"
+// @Success 200 {string} string "success"
+// @Router /metrics [get]
+func (metrics *MetricsController) Metrics(c *gin.Context) {
+	dao.LoadBalancerHandler.UpdateHealthMetrics()
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := public.MetricsRegistryHandler.Write(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"encoding/csv"
+	"fmt"
 	"github.com/JunxiHe459/gateway/dao"
 	"github.com/JunxiHe459/gateway/dto"
 	"github.com/JunxiHe459/gateway/global"
@@ -9,18 +11,23 @@ import (
 	"github.com/e421083458/golang_common/lib"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
+	"net/http"
+	"strconv"
 	"time"
 )
 
 //RenterControllerRegister admin路由注册
 func RenterRegister(router *gin.RouterGroup) {
 	admin := APPController{}
-	router.GET("/renter_list", admin.RenterList)
-	router.GET("/renter_detail", admin.RenterDetail)
-	router.GET("/renter_stats", admin.RenterStats)
-	router.GET("/delete_renter", admin.DeleteRenter)
-	router.POST("/add_renter", admin.AddRenter)
-	router.POST("/update_renter", admin.UpdateRenter)
+	router.GET("/renter_list", middleware.RequirePermission(public.PermRenterRead), admin.RenterList)
+	router.GET("/renter_detail", middleware.RequirePermission(public.PermRenterRead), admin.RenterDetail)
+	router.GET("/renter_stats", middleware.RequirePermission(public.PermRenterRead), admin.RenterStats)
+	router.GET("/renter_usage", middleware.RequirePermission(public.PermRenterRead), admin.RenterUsage)
+	router.GET("/renter_top_services", middleware.RequirePermission(public.PermRenterRead), admin.RenterTopServices)
+	router.GET("/renter_usage_csv", middleware.RequirePermission(public.PermRenterRead), admin.RenterUsageCSV)
+	router.GET("/delete_renter", middleware.RequirePermission(public.PermRenterWrite), admin.DeleteRenter)
+	router.POST("/add_renter", middleware.RequirePermission(public.PermRenterWrite), admin.AddRenter)
+	router.POST("/update_renter", middleware.RequirePermission(public.PermRenterWrite), admin.UpdateRenter)
 }
 
 type APPController struct {
@@ -51,8 +58,13 @@ func (admin *APPController) RenterList(c *gin.Context) {
 		return
 	}
 
+	// 没有写权限的管理员看不到租户密钥
+	canSeeSecret := middleware.HasPermission(c, public.PermRenterWrite)
 	outputList := []dto.RenterListItemOutput{}
 	for _, item := range list {
+		if !canSeeSecret {
+			item.Secret = public.RedactedValue
+		}
 		appCounter, err := public.FlowCounterHandler.GetCounter(public.FlowAppPrefix + item.RenterID)
 		if err != nil {
 			middleware.ResponseError(c, 2003, err)
@@ -103,6 +115,9 @@ func (admin *APPController) RenterDetail(c *gin.Context) {
 		middleware.ResponseError(c, 2002, err)
 		return
 	}
+	if !middleware.HasPermission(c, public.PermRenterWrite) {
+		detail.Secret = public.RedactedValue
+	}
 	middleware.ResponseSuccess(c, detail)
 	return
 }
@@ -131,11 +146,21 @@ func (admin *APPController) DeleteRenter(c *gin.Context) {
 		middleware.ResponseError(c, 2002, err)
 		return
 	}
+	before := public.AuditSnapshot(info)
+	tx := global.DB.Begin()
 	info.IsDelete = 1
-	if err := info.Save(c, global.DB); err != nil {
+	if err := info.Save(c, tx); err != nil {
+		tx.Rollback()
 		middleware.ResponseError(c, 2003, err)
 		return
 	}
+	if err := saveAuditLog(c, tx, public.AuditActionDelete, public.AuditResourceRenter, info.ID, info.RenterID,
+		before, public.AuditSnapshot(info)); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	tx.Commit()
 	middleware.ResponseSuccess(c, "")
 	return
 }
@@ -168,7 +193,7 @@ func (admin *APPController) AddRenter(c *gin.Context) {
 	if params.Secret == "" {
 		params.Secret = public.MD5(params.RenterID)
 	}
-	tx := global.DB
+	tx := global.DB.Begin()
 	info := &dao.Renter{
 		RenterID: params.RenterID,
 		Name:     params.Name,
@@ -178,9 +203,17 @@ func (admin *APPController) AddRenter(c *gin.Context) {
 		Qpd:      params.Qpd,
 	}
 	if err := info.Save(c, tx); err != nil {
+		tx.Rollback()
 		middleware.ResponseError(c, 2003, err)
 		return
 	}
+	if err := saveAuditLog(c, tx, public.AuditActionAdd, public.AuditResourceRenter, info.ID, info.RenterID,
+		nil, public.AuditSnapshot(info)); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	tx.Commit()
 	middleware.ResponseSuccess(c, "")
 	return
 }
@@ -212,15 +245,25 @@ func (admin *APPController) UpdateRenter(c *gin.Context) {
 	if params.Secret == "" {
 		params.Secret = public.MD5(params.RenterID)
 	}
+	before := public.AuditSnapshot(info)
 	info.Name = params.Name
 	info.Secret = params.Secret
 	info.WhiteIPS = params.WhiteIPS
 	info.Qps = params.Qps
 	info.Qpd = params.Qpd
-	if err := info.Save(c, global.DB); err != nil {
+	tx := global.DB.Begin()
+	if err := info.Save(c, tx); err != nil {
+		tx.Rollback()
 		middleware.ResponseError(c, 2003, err)
 		return
 	}
+	if err := saveAuditLog(c, tx, public.AuditActionUpdate, public.AuditResourceRenter, info.ID, info.RenterID,
+		before, public.AuditSnapshot(info)); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	tx.Commit()
 	middleware.ResponseSuccess(c, "")
 	return
 }
@@ -262,7 +305,7 @@ func (admin *APPController) RenterStats(c *gin.Context) {
 	currentTime := time.Now()
 	for i := 0; i <= time.Now().In(lib.TimeLocation).Hour(); i++ {
 		dateTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), i, 0, 0, 0, lib.TimeLocation)
-		hourData, _ := counter.GetHourData(dateTime)
+		hourData, _ := counter.GetHourData(public.GetGinTraceContext(c), dateTime)
 		todayStat = append(todayStat, hourData)
 	}
 
@@ -271,7 +314,7 @@ func (admin *APPController) RenterStats(c *gin.Context) {
 	yesterTime := currentTime.Add(-1 * time.Duration(time.Hour*24))
 	for i := 0; i <= 23; i++ {
 		dateTime := time.Date(yesterTime.Year(), yesterTime.Month(), yesterTime.Day(), i, 0, 0, 0, lib.TimeLocation)
-		hourData, _ := counter.GetHourData(dateTime)
+		hourData, _ := counter.GetHourData(public.GetGinTraceContext(c), dateTime)
 		yesterdayStat = append(yesterdayStat, hourData)
 	}
 	stat := dto.StatisticsOutput{
@@ -281,3 +324,155 @@ func (admin *APPController) RenterStats(c *gin.Context) {
 	middleware.ResponseSuccess(c, stat)
 	return
 }
+
+// RenterUsage godoc
+// @Summary Renter Usage
+// @Description 租户历史用量，按天与服务汇总
+// @Tags Renter Management
+// @ID /renter/renter_usage
+// @Accept  json
+// @Produce  json
+// @Param id query string true "租户ID"
+// @Param start_date query string true "开始日期 2006-01-02"
+// @Param end_date query string true "结束日期 2006-01-02"
+// @Success 200 {object} middleware.Response{data=dto.RenterUsageOutput} "success"
+// @Router /renter/renter_usage [get]
+func (admin *APPController) RenterUsage(c *gin.Context) {
+	params := &dto.RenterUsageInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	if params.StartDate > params.EndDate {
+		middleware.ResponseError(c, 2002, errors.New("start_date 不能晚于 end_date"))
+		return
+	}
+	search := &dao.Renter{ID: params.ID}
+	detail, err := search.Find(c, global.DB, search)
+	if err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+
+	usage := &dao.RenterUsage{}
+	list, err := usage.ListByRange(c, global.DB, detail.RenterID, params.StartDate, params.EndDate)
+	if err != nil {
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	out := &dto.RenterUsageOutput{
+		RenterID:  detail.RenterID,
+		StartDate: params.StartDate,
+		EndDate:   params.EndDate,
+		Daily:     []dto.RenterUsageDailyOutput{},
+		List:      []dto.RenterUsageItemOutput{},
+	}
+	for _, item := range list {
+		out.Total += item.RequestCount
+		if len(out.Daily) == 0 || out.Daily[len(out.Daily)-1].Day != item.Day {
+			out.Daily = append(out.Daily, dto.RenterUsageDailyOutput{Day: item.Day})
+		}
+		out.Daily[len(out.Daily)-1].RequestCount += item.RequestCount
+		out.List = append(out.List, dto.RenterUsageItemOutput{
+			Day:          item.Day,
+			ServiceName:  item.ServiceName,
+			RequestCount: item.RequestCount,
+		})
+	}
+	middleware.ResponseSuccess(c, out)
+}
+
+// RenterTopServices godoc
+// @Summary Renter Top Services
+// @Description 租户调用量最多的服务
+// @Tags Renter Management
+// @ID /renter/renter_top_services
+// @Accept  json
+// @Produce  json
+// @Param id query string true "租户ID"
+// @Param start_date query string true "开始日期 2006-01-02"
+// @Param end_date query string true "结束日期 2006-01-02"
+// @Param limit query int false "返回条数，默认10"
+// @Success 200 {object} middleware.Response{data=dto.RenterTopServicesOutput} "success"
+// @Router /renter/renter_top_services [get]
+func (admin *APPController) RenterTopServices(c *gin.Context) {
+	params := &dto.RenterTopServicesInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	if params.StartDate > params.EndDate {
+		middleware.ResponseError(c, 2002, errors.New("start_date 不能晚于 end_date"))
+		return
+	}
+	if params.Limit == 0 {
+		params.Limit = 10
+	}
+	search := &dao.Renter{ID: params.ID}
+	detail, err := search.Find(c, global.DB, search)
+	if err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+
+	usage := &dao.RenterUsage{}
+	list, err := usage.TopServices(c, global.DB, detail.RenterID, params.StartDate, params.EndDate, params.Limit)
+	if err != nil {
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	out := &dto.RenterTopServicesOutput{List: []dto.RenterTopServiceItemOutput{}}
+	for _, item := range list {
+		out.List = append(out.List, dto.RenterTopServiceItemOutput{
+			ServiceName:  item.ServiceName,
+			RequestCount: item.RequestCount,
+		})
+	}
+	middleware.ResponseSuccess(c, out)
+}
+
+// RenterUsageCSV godoc
+// @Summary Renter Usage CSV
+// @Description 导出租户历史用量 csv
+// @Tags Renter Management
+// @ID /renter/renter_usage_csv
+// @Produce  text/csv
+// @Param id query string true "租户ID"
+// @Param start_date query string true "开始日期 2006-01-02"
+// @Param end_date query string true "结束日期 2006-01-02"
+// @Success 200 {string} string "csv"
+// @Router /renter/renter_usage_csv [get]
+func (admin *APPController) RenterUsageCSV(c *gin.Context) {
+	params := &dto.RenterUsageInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	if params.StartDate > params.EndDate {
+		middleware.ResponseError(c, 2002, errors.New("start_date 不能晚于 end_date"))
+		return
+	}
+	search := &dao.Renter{ID: params.ID}
+	detail, err := search.Find(c, global.DB, search)
+	if err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+
+	usage := &dao.RenterUsage{}
+	list, err := usage.ListByRange(c, global.DB, detail.RenterID, params.StartDate, params.EndDate)
+	if err != nil {
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	fileName := fmt.Sprintf("renter_usage_%s_%s_%s.csv", detail.RenterID, params.StartDate, params.EndDate)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Status(http.StatusOK)
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"day", "renter_id", "service_name", "request_count"})
+	for _, item := range list {
+		writer.Write([]string{item.Day, item.RenterID, item.ServiceName, strconv.FormatInt(item.RequestCount, 10)})
+	}
+	writer.Flush()
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/dto"
+	"github.com/JunxiHe459/gateway/global"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"net"
+	"time"
+)
+
+type RequestTailController struct {
+}
+
+func RequestTailRegister(group *gin.RouterGroup) {
+	tail := &RequestTailController{}
+	group.GET("/tail", middleware.RequirePermission(public.PermServiceRead), tail.RequestTail)
+}
+
+// RequestTail godoc
+// @Summary Request tail
+// @Description 以 SSE 实时推送服务的代理请求，header 已脱敏，到达时长后自动结束
+// @Tags Service
+// @ID /service/tail
+// @Accept  json
+// @Produce  text/event-stream
+// @Param service_id query int true "服务ID"
+// @Param renter_id query string false "租户ID"
+// @Param status query string false "状态码，如 502 或 5xx"
+// @Param path query string false "路径前缀"
+// @Param client_ip query string false "客户端ip"
+// @Param duration query int false "持续时长, 单位s, 默认60, 最长600"
+// @Success 200 {object} public.RequestTailEvent "event: request"
+// @Router /service/tail [get]
+func (tail *RequestTailController) RequestTail(c *gin.Context) {
+	params := &dto.RequestTailInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	if params.Duration == 0 {
+		params.Duration = public.RequestTailDurationDefault
+	}
+
+	serviceInfo := &dao.ServiceInfo{ID: params.ServiceID}
+	serviceInfo, err := serviceInfo.Find(c, global.DB, serviceInfo)
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+	serviceName := serviceInfo.ServiceName
+	if err := public.RequestTailHandler.Watch(serviceName); err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+	sub, err := public.RequestTailHandler.Subscribe(serviceName)
+	if err != nil {
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	defer sub.Close()
+
+	// 管理端 http server 有 write_timeout，接管连接后自行控制写超时
+	conn, rw, err := c.Writer.Hijack()
+	if err != nil {
+		middleware.ResponseError(c, 2005, err)
+		return
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Time{})
+	write := func(event string, data interface{}) bool {
+		payload, _ := json.Marshal(data)
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if _, err := fmt.Fprintf(rw, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+			return false
+		}
+		return rw.Flush() == nil
+	}
+	rw.WriteString("HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\nCache-Control: no-cache\r\nConnection: close\r\nX-Accel-Buffering: no\r\n\r\n")
+	if !write("start", map[string]interface{}{"service": serviceName, "duration": params.Duration}) {
+		return
+	}
+
+	filter := &public.RequestTailFilter{
+		RenterID: params.RenterID,
+		Status:   params.Status,
+		Path:     params.Path,
+		ClientIP: params.ClientIP,
+	}
+	closed := make(chan struct{})
+	go watchTailConnClosed(conn, closed)
+	deadline := time.After(time.Duration(params.Duration) * time.Second)
+	watchTicker := time.NewTicker(public.RequestTailWatchTTL / 2 * time.Second)
+	defer watchTicker.Stop()
+	for {
+		select {
+		case event, ok := <-sub.Events:
+			if !ok {
+				write("end", map[string]string{"reason": "subscription closed"})
+				return
+			}
+			if filter.Match(event) && !write("request", event) {
+				return
+			}
+		case <-watchTicker.C:
+			public.RequestTailHandler.Watch(serviceName)
+			if !write("ping", map[string]int64{"time": time.Now().Unix()}) {
+				return
+			}
+		case <-closed:
+			return
+		case <-deadline:
+			write("end", map[string]string{"reason": "timeout"})
+			return
+		}
+	}
+}
+
+// watchTailConnClosed 客户端断开时读取返回错误
+func watchTailConnClosed(conn net.Conn, closed chan struct{}) {
+	buf := make([]byte, 1)
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			close(closed)
+			return
+		}
+	}
+}
@@ -20,18 +20,22 @@ type ServiceController struct {
 
 func RegisterService(group *gin.RouterGroup) {
 	service := &ServiceController{}
-	group.GET("service_list", service.ServiceList)
-	group.GET("delete", service.DeleteService)
-
-	group.POST("add_http", service.AddHTTPService)
-	group.POST("update_http", service.UpdateHTTPService)
-	group.POST("add_tcp", service.ServiceAddTcp)
-	group.POST("update_tcp", service.ServiceUpdateTcp)
-	group.POST("add_grpc", service.ServiceAddGRPC)
-	group.POST("update_grpc", service.ServiceUpdateGRPC)
-
-	group.GET("service_details", service.ServiceDetail)
-	group.GET("service_stats", service.ServiceStats)
+	group.GET("service_list", middleware.RequirePermission(public.PermServiceRead), service.ServiceList)
+	group.GET("delete", middleware.RequirePermission(public.PermServiceWrite), service.DeleteService)
+
+	group.POST("add_http", middleware.RequirePermission(public.PermServiceWrite), service.AddHTTPService)
+	group.POST("update_http", middleware.RequirePermission(public.PermServiceWrite), service.UpdateHTTPService)
+	group.POST("add_tcp", middleware.RequirePermission(public.PermServiceWrite), service.ServiceAddTcp)
+	group.POST("update_tcp", middleware.RequirePermission(public.PermServiceWrite), service.ServiceUpdateTcp)
+	group.POST("add_grpc", middleware.RequirePermission(public.PermServiceWrite), service.ServiceAddGRPC)
+	group.POST("update_grpc", middleware.RequirePermission(public.PermServiceWrite), service.ServiceUpdateGRPC)
+
+	group.GET("service_details", middleware.RequirePermission(public.PermServiceRead), service.ServiceDetail)
+	group.GET("service_stats", middleware.RequirePermission(public.PermServiceRead), service.ServiceStats)
+
+	group.GET("version_list", middleware.RequirePermission(public.PermServiceRead), service.ServiceVersionList)
+	group.GET("version_diff", middleware.RequirePermission(public.PermServiceRead), service.ServiceVersionDiff)
+	group.POST("rollback", middleware.RequirePermission(public.PermServiceWrite), service.ServiceRollback)
 }
 
 // Service godoc
@@ -114,6 +118,10 @@ func (s *ServiceController) ServiceList(c *gin.Context) {
 			serviceAddress = fmt.Sprintf("%s:%d", clusterIp, serviceDetail.GRPCRule.Port)
 		}
 
+		// 只读查询，列表中没有流量的服务不会因此注册计数器
+		serviceCounter := public.FlowCounterHandler.Lookup(public.FlowServicePrefix + item.ServiceName)
+		serviceQPD, _ := serviceCounter.GetDayData(public.GetGinTraceContext(c), time.Now())
+
 		ipList := serviceDetail.LoadBalance.GetIPListByModel()
 		singleService := dto.SingleService{
 			ID:             item.ID,
@@ -121,8 +129,8 @@ func (s *ServiceController) ServiceList(c *gin.Context) {
 			ServiceName:    item.ServiceName,
 			ServiceDesc:    item.ServiceDesc,
 			ServiceAddress: serviceAddress,
-			QPS:            0,
-			QPD:            0,
+			QPS:            serviceCounter.QPS,
+			QPD:            serviceQPD,
 			TotalNode:      len(ipList),
 		}
 		serviceList = append(serviceList, singleService)
@@ -173,14 +181,33 @@ func (s *ServiceController) DeleteService(c *gin.Context) {
 	//	middleware.ResponseError(c, 2001, err)
 	//}
 
+	serviceDetail, err := service.GetServiceDetail(c, global.DB, service)
+	if err != nil {
+		middleware.ResponseError(c, 2000, err)
+		return
+	}
+	before := public.AuditSnapshot(serviceDetail)
+
 	// 软删除
+	tx := global.DB.Begin()
 	service.IsDelete = 1
-	err = service.Save(c, global.DB)
+	// after 快照取自 serviceDetail，不依赖 Info 与 service 是同一个对象
+	serviceDetail.Info.IsDelete = 1
+	err = service.Save(c, tx)
 	if err != nil {
+		tx.Rollback()
 		println("Save Service Error: ", err.Error())
 		middleware.ResponseError(c, 2001, err)
 		return
 	}
+	if err := saveAuditLog(c, tx, public.AuditActionDelete, public.AuditResourceService, service.ID, service.ServiceName,
+		before, public.AuditSnapshot(serviceDetail)); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+	tx.Commit()
+	notifyServiceChange(service, public.AuditActionDelete, 0)
 
 	middleware.ResponseSuccess(c, "Deleted")
 }
@@ -236,7 +263,7 @@ func (s *ServiceController) AddHTTPService(c *gin.Context) {
 	_, err = httpService.Find(c, tx, httpService)
 	if err == nil {
 		tx.Rollback()
-		println("Http url or domain name Already Exists: ", err.Error())
+		println("Http url or domain name Already Exists")
 		middleware.ResponseError(c, 400, errors.New("Http url or domain name Already Exists"))
 		return
 	}
@@ -250,6 +277,13 @@ func (s *ServiceController) AddHTTPService(c *gin.Context) {
 		return
 	}
 
+	// 检查路由是否与其他服务冲突
+	if err := checkHTTPRouteConflict(c, tx, 0, params.RuleType, params.Rule, params.Routes); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 400, err)
+		return
+	}
+
 	// 创建 service 本体到 serviceinfo 中
 	serviceinfo := &dao.ServiceInfo{
 		ServiceName: params.ServiceName,
@@ -268,13 +302,28 @@ func (s *ServiceController) AddHTTPService(c *gin.Context) {
 
 	// 关联 http_rule
 	httpRule := &dao.HttpRule{
-		ServiceID:      id,
-		RuleType:       params.RuleType,
-		NeedHttps:      params.NeedHttps,
-		NeedStripUri:   params.NeedStripUri,
-		NeedWebsocket:  params.NeedWebsocket,
-		UrlRewrite:     params.UrlRewrite,
-		HeaderTransfer: params.HeaderTransfer,
+		ServiceID:         id,
+		RuleType:          params.RuleType,
+		Rule:              params.Rule,
+		NeedHttps:         params.NeedHttps,
+		NeedStripUri:      params.NeedStripUri,
+		NeedWebsocket:     params.NeedWebsocket,
+		UrlRewrite:        params.UrlRewrite,
+		HeaderTransfer:    params.HeaderTransfer,
+		RequestTransform:  params.RequestTransform,
+		ResponseTransform: params.ResponseTransform,
+		TransformMaxBody:  params.TransformMaxBody,
+
+		NeedCors:             params.NeedCors,
+		CorsAllowOrigins:     params.CorsAllowOrigins,
+		CorsAllowMethods:     params.CorsAllowMethods,
+		CorsAllowHeaders:     params.CorsAllowHeaders,
+		CorsAllowCredentials: params.CorsAllowCredentials,
+		CorsMaxAge:           params.CorsMaxAge,
+
+		NeedCompress:    params.NeedCompress,
+		CompressMinSize: params.CompressMinSize,
+		CompressTypes:   params.CompressTypes,
 	}
 	err = httpRule.Save(c, tx)
 	if err != nil {
@@ -320,8 +369,24 @@ func (s *ServiceController) AddHTTPService(c *gin.Context) {
 		return
 	}
 
+	// 关联 http_route
+	if err := saveHTTPRoutes(c, tx, id, params.Routes); err != nil {
+		tx.Rollback()
+		println("Save http route error: ", err.Error())
+		middleware.ResponseError(c, 400, errors.New("Save http route error"))
+		return
+	}
+
+	version, err := recordServiceChange(c, tx, public.AuditActionAdd, serviceinfo, nil)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 400, err)
+		return
+	}
+
 	// 提交事务
 	tx.Commit()
+	notifyServiceChange(serviceinfo, public.AuditActionAdd, version.Version)
 	middleware.ResponseSuccess(c, "New HTTP serviced added")
 }
 
@@ -347,7 +412,7 @@ func (s *ServiceController) UpdateHTTPService(c *gin.Context) {
 	}
 
 	if len(strings.Split(params.IpList, ",")) != len(strings.Split(params.WeightList, ",")) {
-		println("IP list should have same length as weight list: ", err.Error())
+		println("IP list should have same length as weight list")
 		middleware.ResponseError(c, 400, errors.New("IP list should have same length as weight list"))
 		return
 	}
@@ -374,7 +439,21 @@ func (s *ServiceController) UpdateHTTPService(c *gin.Context) {
 		return
 	}
 
+	// 检查路由是否与其他服务冲突
+	if err := checkHTTPRouteConflict(c, tx, serviceDetail.Info.ID, params.RuleType, params.Rule, params.Routes); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 400, err)
+		return
+	}
+	before, err := snapshotService(c, tx, serviceDetail)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 400, err)
+		return
+	}
+
 	info := serviceDetail.Info
+	oldServiceName := info.ServiceName
 	info.ServiceDesc = params.ServiceDesc
 	info.ServiceName = params.ServiceName
 	if err := info.Save(c, tx); err != nil {
@@ -392,6 +471,18 @@ func (s *ServiceController) UpdateHTTPService(c *gin.Context) {
 	httpRule.NeedWebsocket = params.NeedWebsocket
 	httpRule.UrlRewrite = params.UrlRewrite
 	httpRule.HeaderTransfer = params.HeaderTransfer
+	httpRule.RequestTransform = params.RequestTransform
+	httpRule.ResponseTransform = params.ResponseTransform
+	httpRule.TransformMaxBody = params.TransformMaxBody
+	httpRule.NeedCors = params.NeedCors
+	httpRule.CorsAllowOrigins = params.CorsAllowOrigins
+	httpRule.CorsAllowMethods = params.CorsAllowMethods
+	httpRule.CorsAllowHeaders = params.CorsAllowHeaders
+	httpRule.CorsAllowCredentials = params.CorsAllowCredentials
+	httpRule.CorsMaxAge = params.CorsMaxAge
+	httpRule.NeedCompress = params.NeedCompress
+	httpRule.CompressMinSize = params.CompressMinSize
+	httpRule.CompressTypes = params.CompressTypes
 	if err := httpRule.Save(c, tx); err != nil {
 		tx.Rollback()
 		println("Save http rule error: ", err.Error())
@@ -427,11 +518,143 @@ func (s *ServiceController) UpdateHTTPService(c *gin.Context) {
 		return
 	}
 
+	// 先删除旧路由再保存新路由
+	httpRoute := &dao.HttpRoute{}
+	if err := httpRoute.DeleteByServiceID(c, tx, info.ID); err != nil {
+		tx.Rollback()
+		println("Delete http route error: ", err.Error())
+		middleware.ResponseError(c, 400, err)
+		return
+	}
+	if err := saveHTTPRoutes(c, tx, info.ID, params.Routes); err != nil {
+		tx.Rollback()
+		println("Save http route error: ", err.Error())
+		middleware.ResponseError(c, 400, err)
+		return
+	}
+	version, err := recordServiceChange(c, tx, public.AuditActionUpdate, info, before)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 400, err)
+		return
+	}
+
 	// 提交事务
 	tx.Commit()
+	notifyServiceRename(info, oldServiceName, public.AuditActionUpdate, version.Version)
 	middleware.ResponseSuccess(c, "HTTP service updated")
 }
 
+// snapshotService 在修改前调用，服务还没有版本时先保存修改前的配置，返回审计日志的 before
+func snapshotService(c *gin.Context, tx *gorm.DB, serviceDetail *dao.ServiceDetail) (map[string]interface{}, error) {
+	if err := (&dao.ServiceVersion{}).EnsureBaseline(c, tx, serviceDetail); err != nil {
+		return nil, err
+	}
+	return public.AuditSnapshot(serviceDetail), nil
+}
+
+// recordServiceChange 在事务提交前读取服务当前的完整配置，保存为新版本并写入审计日志
+func recordServiceChange(c *gin.Context, tx *gorm.DB, action string, info *dao.ServiceInfo, before map[string]interface{}) (*dao.ServiceVersion, error) {
+	serviceDetail, err := info.GetServiceDetail(c, tx, info)
+	if err != nil {
+		return nil, err
+	}
+	version := &dao.ServiceVersion{Action: action}
+	if adminInterface, ok := c.Get("admin"); ok {
+		admin := adminInterface.(*dao.Admin)
+		version.AdminID = admin.Id
+		version.AdminName = admin.UserName
+	}
+	if err := version.Create(c, tx, serviceDetail); err != nil {
+		return nil, err
+	}
+	if err := saveAuditLog(c, tx, action, public.AuditResourceService, info.ID, info.ServiceName,
+		before, public.AuditSnapshot(serviceDetail)); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// notifyServiceChange 事务提交后通知代理重新加载服务
+func notifyServiceChange(info *dao.ServiceInfo, action string, version int64) {
+	notifyServiceRename(info, info.ServiceName, action, version)
+}
+
+// notifyServiceRename 同 notifyServiceChange，oldServiceName 为修改前的名称，改名时代理同时清理旧名称的缓存
+func notifyServiceRename(info *dao.ServiceInfo, oldServiceName string, action string, version int64) {
+	event := &public.ServiceChangeEvent{
+		ServiceID:   info.ID,
+		ServiceName: info.ServiceName,
+		Action:      action,
+		Version:     version,
+	}
+	if oldServiceName != info.ServiceName {
+		event.OldServiceName = oldServiceName
+	}
+	public.PublishServiceChange(event)
+}
+
+// checkHTTPRouteConflict 检查新路由之间以及与其他服务的路由是否冲突
+// 只和接入方式与接入规则（域名或前缀）都相同的服务比较
+func checkHTTPRouteConflict(c *gin.Context, tx *gorm.DB, serviceID int64, ruleType int, rule string, routes []dto.HTTPRouteInput) error {
+	if len(routes) == 0 {
+		return nil
+	}
+	newRoutes := []*dao.HttpRoute{}
+	for _, item := range routes {
+		newRoute := &dao.HttpRoute{
+			Path:        item.Path,
+			Methods:     item.Methods,
+			HeaderMatch: item.HeaderMatch,
+			QueryMatch:  item.QueryMatch,
+			Priority:    item.Priority,
+		}
+		for _, other := range newRoutes {
+			if newRoute.ConflictWith(other) {
+				return fmt.Errorf("route %s %s conflicts with route %s %s in the same service",
+					newRoute.Methods, newRoute.Path, other.Methods, other.Path)
+			}
+		}
+		newRoutes = append(newRoutes, newRoute)
+	}
+
+	httpRoute := &dao.HttpRoute{}
+	existRoutes, err := httpRoute.ListAll(c, tx)
+	if err != nil {
+		return err
+	}
+	for _, exist := range existRoutes {
+		// 接入时按最长前缀或域名只会选中一个服务，接入规则不同的服务不会竞争同一个请求
+		if exist.ServiceID == serviceID || exist.RuleType != ruleType || exist.Rule != rule {
+			continue
+		}
+		for _, newRoute := range newRoutes {
+			if newRoute.ConflictWith(&exist.HttpRoute) {
+				return fmt.Errorf("route %s %s conflicts with route %s %s of service %s",
+					newRoute.Methods, newRoute.Path, exist.Methods, exist.Path, exist.ServiceName)
+			}
+		}
+	}
+	return nil
+}
+
+func saveHTTPRoutes(c *gin.Context, tx *gorm.DB, serviceID int64, routes []dto.HTTPRouteInput) error {
+	for _, item := range routes {
+		httpRoute := &dao.HttpRoute{
+			ServiceID:   serviceID,
+			Path:        item.Path,
+			Methods:     item.Methods,
+			HeaderMatch: item.HeaderMatch,
+			QueryMatch:  item.QueryMatch,
+			Priority:    item.Priority,
+		}
+		if err := httpRoute.Save(c, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Service godoc
 // @Summary Details of a service
 // @Description 服务详情
@@ -491,29 +714,64 @@ func (service *ServiceController) ServiceStats(c *gin.Context) {
 	}
 
 	//读取基本信息
-	//serviceInfo := &dao.ServiceInfo{ID: params.ID}
-	//serviceInfo, err = serviceInfo.Find(c, global.DB, serviceInfo)
-	//if err != nil {
-	//	println("Find Service Info error: ", err.Error())
-	//	middleware.ResponseError(c, 400, err)
-	//	return
-	//}
-	//serviceDetail, err := serviceInfo.GetServiceDetail(c, global.DB, serviceInfo)
-	//if err != nil {
-	//	println("Get service detail error: ", err.Error())
-	//	middleware.ResponseError(c, 400, err)
-	//	return
-	//}
+	serviceInfo := &dao.ServiceInfo{ID: params.ID}
+	serviceInfo, err = serviceInfo.Find(c, global.DB, serviceInfo)
+	if err != nil {
+		println("Find Service Info error: ", err.Error())
+		middleware.ResponseError(c, 400, err)
+		return
+	}
+
+	counter := public.FlowCounterHandler.Lookup(public.FlowServicePrefix + serviceInfo.ServiceName)
+
+	//今日流量全天小时级访问统计
+	today := []int{}
+	currentTime := time.Now()
+	for i := 0; i <= currentTime.In(lib.TimeLocation).Hour(); i++ {
+		dateTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), i, 0, 0, 0, lib.TimeLocation)
+		hourData, _ := counter.GetHourData(public.GetGinTraceContext(c), dateTime)
+		today = append(today, int(hourData))
+	}
+
+	//昨日流量全天小时级访问统计
+	yesterday := []int{}
+	yesterTime := currentTime.Add(-1 * time.Duration(time.Hour*24))
+	for i := 0; i <= 23; i++ {
+		dateTime := time.Date(yesterTime.Year(), yesterTime.Month(), yesterTime.Day(), i, 0, 0, 0, lib.TimeLocation)
+		hourData, _ := counter.GetHourData(public.GetGinTraceContext(c), dateTime)
+		yesterday = append(yesterday, int(hourData))
+	}
 
-	var today []int
-	for i := 0; i <= time.Now().Hour(); i++ {
-		today = append(today, 0)
+	// 今日错误率
+	todayRequestNum, _ := counter.GetDayData(public.GetGinTraceContext(c), currentTime)
+	errorCounter := public.FlowCounterHandler.Lookup(public.FlowServiceErrorPrefix + serviceInfo.ServiceName)
+	todayErrorNum, _ := errorCounter.GetDayData(public.GetGinTraceContext(c), currentTime)
+	errorRate := 0.0
+	if todayRequestNum > 0 {
+		errorRate = float64(todayErrorNum) / float64(todayRequestNum)
 	}
-	//var yesterday [24]int
+
+	// 延迟分位数
+	latencyCounter := public.LatencyCounterHandler.Lookup(public.FlowServicePrefix + serviceInfo.ServiceName)
+	percentiles, err := latencyCounter.GetPercentiles(public.GetGinTraceContext(c), currentTime, 0.5, 0.95, 0.99)
+	if err != nil {
+		percentiles = []int64{0, 0, 0}
+	}
+
+	// 压缩节省的字节数
+	savedCounter := public.FlowCounterHandler.Lookup(public.CompressSavedPrefix + serviceInfo.ServiceName)
+	compressSaved, _ := savedCounter.GetDayData(public.GetGinTraceContext(c), currentTime)
 
 	middleware.ResponseSuccess(c, &dto.ServiceStatsOutput{
-		Today: today,
-		//Yesterday: yesterday,
+		Today:              today,
+		Yesterday:          yesterday,
+		QPS:                counter.QPS,
+		TodayRequestNum:    todayRequestNum,
+		ErrorRate:          errorRate,
+		P50:                percentiles[0],
+		P95:                percentiles[1],
+		P99:                percentiles[2],
+		CompressSavedBytes: compressSaved,
 	})
 }
 
@@ -614,7 +872,14 @@ func (admin *ServiceController) ServiceAddTcp(c *gin.Context) {
 		middleware.ResponseError(c, 2009, err)
 		return
 	}
+	version, err := recordServiceChange(c, tx, public.AuditActionAdd, info, nil)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2010, err)
+		return
+	}
 	tx.Commit()
+	notifyServiceChange(info, public.AuditActionAdd, version.Version)
 	middleware.ResponseSuccess(c, "")
 	return
 }
@@ -653,6 +918,12 @@ func (admin *ServiceController) ServiceUpdateTcp(c *gin.Context) {
 		return
 	}
 
+	before, err := snapshotService(c, tx, detail)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
 	info := detail.Info
 	info.ServiceDesc = params.ServiceDesc
 	if err := info.Save(c, tx); err != nil {
@@ -704,7 +975,14 @@ func (admin *ServiceController) ServiceUpdateTcp(c *gin.Context) {
 		middleware.ResponseError(c, 2006, err)
 		return
 	}
+	version, err := recordServiceChange(c, tx, public.AuditActionUpdate, info, before)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2007, err)
+		return
+	}
 	tx.Commit()
+	notifyServiceChange(info, public.AuditActionUpdate, version.Version)
 	middleware.ResponseSuccess(c, "")
 	return
 }
@@ -808,7 +1086,14 @@ func (admin *ServiceController) ServiceAddGRPC(c *gin.Context) {
 		middleware.ResponseError(c, 2009, err)
 		return
 	}
+	version, err := recordServiceChange(c, tx, public.AuditActionAdd, info, nil)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2010, err)
+		return
+	}
 	tx.Commit()
+	notifyServiceChange(info, public.AuditActionAdd, version.Version)
 	middleware.ResponseSuccess(c, "")
 	return
 }
@@ -847,6 +1132,12 @@ func (admin *ServiceController) ServiceUpdateGRPC(c *gin.Context) {
 		return
 	}
 
+	before, err := snapshotService(c, tx, detail)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
 	info := detail.Info
 	info.ServiceDesc = params.ServiceDesc
 	if err := info.Save(c, tx); err != nil {
@@ -899,7 +1190,14 @@ func (admin *ServiceController) ServiceUpdateGRPC(c *gin.Context) {
 		middleware.ResponseError(c, 2007, err)
 		return
 	}
+	version, err := recordServiceChange(c, tx, public.AuditActionUpdate, info, before)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2008, err)
+		return
+	}
 	tx.Commit()
+	notifyServiceChange(info, public.AuditActionUpdate, version.Version)
 	middleware.ResponseSuccess(c, "")
 	return
 }
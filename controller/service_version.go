@@ -0,0 +1,277 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/dto"
+	"github.com/JunxiHe459/gateway/global"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+)
+
+// ServiceVersionList godoc
+// @Summary Service version list
+// @Description 服务配置版本列表，每次添加、修改与回滚都会产生一个新版本
+// @Tags Service Management
+// @ID /service/version_list
+// @Accept json
+// @Produce json
+// @Param id query int true "服务ID"
+// @Param page_number query int true "页码"
+// @Param page_size query int true "每页条数"
+// @Success 200 {object} middleware.Response{data=dto.ServiceVersionListOutput} "success"
+// @Router /service/version_list [get]
+func (s *ServiceController) ServiceVersionList(c *gin.Context) {
+	params := &dto.ServiceVersionListInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	serviceVersion := &dao.ServiceVersion{}
+	list, total, err := serviceVersion.PageList(c, global.DB, params)
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+	current, err := serviceVersion.Latest(c, global.DB, params.ID)
+	if err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+
+	out := &dto.ServiceVersionListOutput{List: []dto.ServiceVersionItemOutput{}, Total: total, Current: current}
+	for _, item := range list {
+		out.List = append(out.List, dto.ServiceVersionItemOutput{
+			Version:   item.Version,
+			Action:    item.Action,
+			AdminID:   item.AdminID,
+			AdminName: item.AdminName,
+			CreatedAt: item.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	middleware.ResponseSuccess(c, out)
+}
+
+// ServiceVersionDiff godoc
+// @Summary Service version diff
+// @Description 比较服务的两个配置版本，to 为空时与最新版本比较
+// @Tags Service Management
+// @ID /service/version_diff
+// @Accept json
+// @Produce json
+// @Param id query int true "服务ID"
+// @Param from query int true "旧版本"
+// @Param to query int false "新版本"
+// @Success 200 {object} middleware.Response{data=dto.ServiceVersionDiffOutput} "success"
+// @Router /service/version_diff [get]
+func (s *ServiceController) ServiceVersionDiff(c *gin.Context) {
+	params := &dto.ServiceVersionDiffInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+	if params.To == 0 {
+		latest, err := (&dao.ServiceVersion{}).Latest(c, global.DB, params.ID)
+		if err != nil {
+			middleware.ResponseError(c, 2002, err)
+			return
+		}
+		params.To = latest
+	}
+	from, err := findServiceVersion(c, global.DB, params.ID, params.From)
+	if err != nil {
+		middleware.ResponseError(c, 2003, err)
+		return
+	}
+	to, err := findServiceVersion(c, global.DB, params.ID, params.To)
+	if err != nil {
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	middleware.ResponseSuccess(c, &dto.ServiceVersionDiffOutput{
+		ID:   params.ID,
+		From: params.From,
+		To:   params.To,
+		Diff: public.AuditDiff(public.AuditSnapshot(from), public.AuditSnapshot(to)),
+	})
+}
+
+// ServiceRollback godoc
+// @Summary Roll back a service
+// @Description 在一个事务中把服务恢复到指定版本的配置，并产生一个新版本
+// @Tags Service Management
+// @ID /service/rollback
+// @Accept json
+// @Produce json
+// @Param body body dto.ServiceRollbackInput true "body"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /service/rollback [post]
+func (s *ServiceController) ServiceRollback(c *gin.Context) {
+	params := &dto.ServiceRollbackInput{}
+	if err := params.BindParam(c); err != nil {
+		middleware.ResponseError(c, 2001, err)
+		return
+	}
+
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 2002, err)
+		return
+	}
+	tx = tx.Begin()
+
+	serviceInfo := &dao.ServiceInfo{ID: params.ID}
+	serviceDetail, err := serviceInfo.GetServiceDetail(c, tx, serviceInfo)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2003, errors.New("Service Not Exists"))
+		return
+	}
+	// 已删除的服务不能通过回滚恢复配置
+	if serviceDetail.Info.IsDelete == 1 {
+		tx.Rollback()
+		middleware.ResponseError(c, 2003, errors.New("Service Not Exists"))
+		return
+	}
+	oldServiceName := serviceDetail.Info.ServiceName
+	target, err := findServiceVersion(c, tx, params.ID, params.Version)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2004, err)
+		return
+	}
+	before, err := snapshotService(c, tx, serviceDetail)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2005, err)
+		return
+	}
+	if err := restoreServiceDetail(c, tx, serviceDetail, target); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2006, err)
+		return
+	}
+	version, err := recordServiceChange(c, tx, public.AuditActionRollback, serviceDetail.Info, before)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 2007, err)
+		return
+	}
+	tx.Commit()
+	notifyServiceRename(serviceDetail.Info, oldServiceName, public.AuditActionRollback, version.Version)
+	middleware.ResponseSuccess(c, fmt.Sprintf("Rolled back to version %d", params.Version))
+}
+
+func findServiceVersion(c *gin.Context, tx *gorm.DB, serviceID, version int64) (*dao.ServiceDetail, error) {
+	search := &dao.ServiceVersion{ServiceID: serviceID, Version: version}
+	serviceVersion, err := search.Find(c, tx, search)
+	if err != nil {
+		return nil, fmt.Errorf("version %d of service %d not found", version, serviceID)
+	}
+	return serviceVersion.ServiceDetail()
+}
+
+// restoreServiceDetail 把 target 的配置写回当前服务的各行记录，写入前重新做添加服务时的冲突检查
+func restoreServiceDetail(c *gin.Context, tx *gorm.DB, current, target *dao.ServiceDetail) error {
+	serviceID := current.Info.ID
+	if target.Info == nil || target.HTTPRule == nil || target.TCPRule == nil || target.GRPCRule == nil ||
+		target.AccessControl == nil || target.LoadBalance == nil {
+		return errors.New("version snapshot is incomplete")
+	}
+	if target.Info.LoadType != current.Info.LoadType {
+		return errors.New("cannot roll back to a version with a different load type")
+	}
+	if target.Info.ServiceName != current.Info.ServiceName {
+		search := &dao.ServiceInfo{ServiceName: target.Info.ServiceName}
+		if exist, err := search.Find(c, tx, search); err == nil && exist.ID != serviceID {
+			return errors.New("Service Already Exists")
+		}
+	}
+
+	info := current.Info
+	info.ServiceName = target.Info.ServiceName
+	info.ServiceDesc = target.Info.ServiceDesc
+	if err := info.Save(c, tx); err != nil {
+		return err
+	}
+
+	switch current.Info.LoadType {
+	case public.LoadTypeHTTP:
+		if err := checkHTTPRuleConflict(c, tx, serviceID, target.HTTPRule.RuleType, target.HTTPRule.Rule); err != nil {
+			return err
+		}
+		routes := []dto.HTTPRouteInput{}
+		for _, item := range target.HTTPRoutes {
+			routes = append(routes, dto.HTTPRouteInput{
+				Path:        item.Path,
+				Methods:     item.Methods,
+				HeaderMatch: item.HeaderMatch,
+				QueryMatch:  item.QueryMatch,
+				Priority:    item.Priority,
+			})
+		}
+		if err := checkHTTPRouteConflict(c, tx, serviceID, target.HTTPRule.RuleType, target.HTTPRule.Rule, routes); err != nil {
+			return err
+		}
+		httpRule := target.HTTPRule
+		httpRule.ID = current.HTTPRule.ID
+		httpRule.ServiceID = serviceID
+		if err := httpRule.Save(c, tx); err != nil {
+			return err
+		}
+		if err := (&dao.HttpRoute{}).DeleteByServiceID(c, tx, serviceID); err != nil {
+			return err
+		}
+		if err := saveHTTPRoutes(c, tx, serviceID, routes); err != nil {
+			return err
+		}
+	case public.LoadTypeTCP:
+		if err := checkPortConflict(c, tx, serviceID, target.TCPRule.Port); err != nil {
+			return err
+		}
+		tcpRule := target.TCPRule
+		tcpRule.ID = current.TCPRule.ID
+		tcpRule.ServiceID = serviceID
+		if err := tcpRule.Save(c, tx); err != nil {
+			return err
+		}
+	case public.LoadTypeGRPC:
+		if err := checkPortConflict(c, tx, serviceID, target.GRPCRule.Port); err != nil {
+			return err
+		}
+		grpcRule := target.GRPCRule
+		grpcRule.ID = current.GRPCRule.ID
+		grpcRule.ServiceID = serviceID
+		if err := grpcRule.Save(c, tx); err != nil {
+			return err
+		}
+	}
+
+	accessControl := target.AccessControl
+	accessControl.ID = current.AccessControl.ID
+	accessControl.ServiceID = serviceID
+	if err := accessControl.Save(c, tx); err != nil {
+		return err
+	}
+	loadBalance := target.LoadBalance
+	loadBalance.ID = current.LoadBalance.ID
+	loadBalance.ServiceID = serviceID
+	return loadBalance.Save(c, tx)
+}
+
+// checkPortConflict tcp 与 grpc 服务共用端口空间
+func checkPortConflict(c *gin.Context, tx *gorm.DB, serviceID int64, port int) error {
+	tcpSearch := &dao.TcpRule{Port: port}
+	if exist, err := tcpSearch.Find(c, tx, tcpSearch); err == nil && exist.ServiceID != serviceID {
+		return errors.New("服务端口被占用")
+	}
+	grpcSearch := &dao.GrpcRule{Port: port}
+	if exist, err := grpcSearch.Find(c, tx, grpcSearch); err == nil && exist.ServiceID != serviceID {
+		return errors.New("服务端口被占用")
+	}
+	return nil
+}
@@ -16,7 +16,11 @@ type Admin struct {
 	UserName  string `json:"user_name" gorm:"column:user_name" description:"管理员用户名"`
 	Salt      string `json:"salt" gorm:"column:salt" description:"盐"`
 	Password  string `json:"password" gorm:"column:password" description:"密码"`
+	Role      string `json:"role" gorm:"column:role" description:"角色 super_admin/service_operator/renter_manager/read_only"`
+	IsDisable int    `json:"is_disable" gorm:"column:is_disable" description:"是否禁用"`
 	IsDelete  int    `json:"is_delete" gorm:"column:is_delete" description:"是否删除"`
+	// 增加后之前登录的 session 全部失效，见 middleware.SessionAuthMiddleware
+	SessionVersion int `json:"session_version" gorm:"column:session_version" description:"session 版本"`
 }
 
 func (admin *Admin) TableName() string {
@@ -33,6 +37,16 @@ func (admin *Admin) Find(c *gin.Context, db *gorm.DB, search *Admin) (*Admin, er
 	return out, nil
 }
 
+// FindByUserName 查找未删除的管理员
+func (admin *Admin) FindByUserName(c *gin.Context, db *gorm.DB, userName string) (*Admin, error) {
+	out := &Admin{}
+	err := db.SetCtx(public.GetGinTraceContext(c)).Where("user_name=? and is_delete=0", userName).Find(out).Error
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (admin *Admin) Save(c *gin.Context, db *gorm.DB) error {
 	err := db.SetCtx(public.GetGinTraceContext(c)).Save(admin).Error
 	if err != nil {
@@ -42,17 +56,71 @@ func (admin *Admin) Save(c *gin.Context, db *gorm.DB) error {
 	return nil
 }
 
+// RevokeSessions 使管理员已登录的 session 全部失效，之后保存的 session 使用新的版本
+func (admin *Admin) RevokeSessions(c *gin.Context, db *gorm.DB) error {
+	db = db.SetCtx(public.GetGinTraceContext(c))
+	err := db.Table(admin.TableName()).Where("id=?", admin.Id).
+		UpdateColumn("session_version", gorm.Expr("session_version+1")).Error
+	if err != nil {
+		return err
+	}
+	out := &Admin{}
+	if err := db.Where("id=?", admin.Id).Find(out).Error; err != nil {
+		return err
+	}
+	admin.SessionVersion = out.SessionVersion
+	return nil
+}
+
+// GetRole 旧数据由迁移补齐角色，仍然为空时按最小权限视为只读
+func (admin *Admin) GetRole() string {
+	if admin.Role == "" {
+		return public.RoleReadOnly
+	}
+	return admin.Role
+}
+
+func (admin *Admin) HasPermission(permission string) bool {
+	return public.RoleHasPermission(admin.GetRole(), permission)
+}
+
+// List 返回所有未删除的管理员
+func (admin *Admin) List(c *gin.Context, db *gorm.DB) ([]Admin, error) {
+	list := []Admin{}
+	err := db.SetCtx(public.GetGinTraceContext(c)).Where("is_delete=0").Order("id asc").Find(&list).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return list, nil
+}
+
+// LockSuperAdmins 在事务中返回未删除且未禁用的超级管理员数量
+// mysql 使用 FOR UPDATE 锁住这些行，sqlite 的写事务开始时已经持有数据库写锁
+// 并发的降级、禁用或删除会排队执行，保证至少保留一个
+func (admin *Admin) LockSuperAdmins(c *gin.Context, tx *gorm.DB) (int, error) {
+	query := tx.SetCtx(public.GetGinTraceContext(c)).Where("is_delete=0 and is_disable=0 and role=?", public.RoleSuperAdmin)
+	if tx.Dialect().GetName() == "mysql" {
+		query = query.Set("gorm:query_option", "FOR UPDATE")
+	}
+	list := []Admin{}
+	if err := query.Find(&list).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return 0, err
+	}
+	return len(list), nil
+}
+
 func (admin *Admin) LoginAndCheck(c *gin.Context, db *gorm.DB,
 	param *dto.AdminLoginInput) (*Admin, error) {
 
 	// 得到数据库中，username 对应的 password
-	adminInfo, err := admin.Find(c, db, &Admin{
-		UserName: param.Username,
-		IsDelete: 0,
-	})
+	// IsDelete 是零值，gorm 按结构体查询时会忽略，需要显式写条件
+	adminInfo, err := admin.FindByUserName(c, db, param.Username)
 	if err != nil {
 		return nil, errors.New("Unable to find user")
 	}
+	if adminInfo.IsDisable == 1 {
+		return nil, errors.New("User is disabled")
+	}
 
 	// 校验 password
 	salted_password := public.SaltPassword(adminInfo.Salt, param.Password)
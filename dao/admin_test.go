@@ -0,0 +1,56 @@
+package dao_test
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/migration"
+	"github.com/JunxiHe459/gateway/public"
+	"testing"
+)
+
+func TestAdminRoleBackfill(t *testing.T) {
+	db := openTestDBAt(t, 4)
+	c := public.NewBackgroundContext()
+	if err := db.Exec("INSERT INTO gateway_admin (user_name, salt, password) VALUES ('legacy', '', '')").Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migration.Up(c, db, 0); err != nil {
+		t.Fatal(err)
+	}
+	admin, err := (&dao.Admin{}).FindByUserName(c, db, "legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if admin.Role != public.RoleSuperAdmin {
+		t.Errorf("legacy admin role = %q, want %s", admin.Role, public.RoleSuperAdmin)
+	}
+
+	// 迁移之后新增的空角色按只读处理
+	if (&dao.Admin{}).GetRole() != public.RoleReadOnly {
+		t.Error("empty role should be read_only")
+	}
+}
+
+func TestLockSuperAdmins(t *testing.T) {
+	db := openTestDB(t)
+	c := public.NewBackgroundContext()
+	admins := []*dao.Admin{
+		{UserName: "root", Role: public.RoleSuperAdmin},
+		{UserName: "disabled", Role: public.RoleSuperAdmin, IsDisable: 1},
+		{UserName: "ops", Role: public.RoleServiceOperator},
+		{UserName: "empty"},
+	}
+	for _, admin := range admins {
+		if err := admin.Save(c, db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tx := db.Begin()
+	defer tx.Rollback()
+	count, err := (&dao.Admin{}).LockSuperAdmins(c, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("super admins = %d, want 1", count)
+	}
+}
@@ -0,0 +1,104 @@
+package dao
+
+import (
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"time"
+)
+
+type AdminToken struct {
+	ID          int64      `json:"id" gorm:"primary_key"`
+	AdminID     int        `json:"admin_id" gorm:"column:admin_id" description:"所属管理员id"`
+	Name        string     `json:"name" gorm:"column:name" description:"名称"`
+	TokenPrefix string     `json:"token_prefix" gorm:"column:token_prefix" description:"token 前缀，用于辨认"`
+	TokenHash   string     `json:"token_hash" gorm:"column:token_hash" description:"token 的 sha256"`
+	Scopes      string     `json:"scopes" gorm:"column:scopes" description:"权限，逗号间隔"`
+	ExpiredAt   *time.Time `json:"expired_at" gorm:"column:expired_at" description:"过期时间，为空表示不过期"`
+	LastUsedAt  *time.Time `json:"last_used_at" gorm:"column:last_used_at" description:"最近使用时间"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"column:created_at" description:"添加时间"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"column:updated_at" description:"更新时间"`
+	IsDelete    int8       `json:"is_delete" gorm:"column:is_delete" description:"是否已吊销；0：否；1：是"`
+}
+
+func (t *AdminToken) TableName() string {
+	return "gateway_admin_token"
+}
+
+func (t *AdminToken) Find(c *gin.Context, tx *gorm.DB, search *AdminToken) (*AdminToken, error) {
+	model := &AdminToken{}
+	err := tx.SetCtx(public.GetGinTraceContext(c)).Where(search).Find(model).Error
+	return model, err
+}
+
+// FindByToken 按明文 token 查找未吊销的 token
+func (t *AdminToken) FindByToken(c *gin.Context, tx *gorm.DB, token string) (*AdminToken, error) {
+	model := &AdminToken{}
+	err := tx.SetCtx(public.GetGinTraceContext(c)).Where("token_hash=? and is_delete=0", public.HashAdminToken(token)).
+		Find(model).Error
+	return model, err
+}
+
+func (t *AdminToken) Save(c *gin.Context, tx *gorm.DB) error {
+	if err := tx.SetCtx(public.GetGinTraceContext(c)).Save(t).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// List 返回未吊销的 token，adminID 为 0 时返回所有管理员的
+func (t *AdminToken) List(c *gin.Context, tx *gorm.DB, adminID int) ([]AdminToken, error) {
+	list := []AdminToken{}
+	query := tx.SetCtx(public.GetGinTraceContext(c)).Table(t.TableName()).Where("is_delete=0")
+	if adminID != 0 {
+		query = query.Where("admin_id=?", adminID)
+	}
+	err := query.Order("id desc").Find(&list).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return list, nil
+}
+
+// RevokeByAdmin 吊销管理员的全部 token
+func (t *AdminToken) RevokeByAdmin(c *gin.Context, tx *gorm.DB, adminID int) error {
+	return tx.SetCtx(public.GetGinTraceContext(c)).Table(t.TableName()).Where("admin_id=? and is_delete=0", adminID).
+		UpdateColumn("is_delete", 1).Error
+}
+
+// Touch 更新最近使用时间，距上次更新不足 AdminTokenTouchInterval 时跳过，不修改 updated_at
+func (t *AdminToken) Touch(c *gin.Context, tx *gorm.DB, now time.Time) error {
+	if t.LastUsedAt != nil && now.Sub(*t.LastUsedAt) < public.AdminTokenTouchInterval*time.Second {
+		return nil
+	}
+	t.LastUsedAt = &now
+	return tx.SetCtx(public.GetGinTraceContext(c)).Model(t).UpdateColumn("last_used_at", now).Error
+}
+
+func (t *AdminToken) Expired(now time.Time) bool {
+	return t.ExpiredAt != nil && !now.Before(*t.ExpiredAt)
+}
+
+func (t *AdminToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(t.Scopes, ",")
+}
+
+// HasScope token 的权限还要受所属管理员当前角色的限制，见 middleware.RequirePermission
+func (t *AdminToken) HasScope(permission string) bool {
+	return public.InStringSlice(t.ScopeList(), permission)
+}
+
+// IntersectScopes 返回 scopes 中同时属于 token 的权限
+func (t *AdminToken) IntersectScopes(scopes []string) []string {
+	out := []string{}
+	for _, scope := range scopes {
+		if t.HasScope(scope) && !public.InStringSlice(out, scope) {
+			out = append(out, scope)
+		}
+	}
+	return out
+}
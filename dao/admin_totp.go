@@ -0,0 +1,192 @@
+package dao
+
+import (
+	"errors"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"time"
+)
+
+// AdminTOTP 每个管理员一条，绑定中的记录 IsEnable 为 0，重新绑定时覆盖密钥
+type AdminTOTP struct {
+	ID             int64     `json:"id" gorm:"primary_key"`
+	AdminID        int       `json:"admin_id" gorm:"column:admin_id" description:"管理员id"`
+	Secret         string    `json:"secret" gorm:"column:secret" description:"加密后的 base32 密钥，见 public.EncryptTOTPSecret"`
+	IsEnable       int8      `json:"is_enable" gorm:"column:is_enable" description:"是否已启用；0：绑定中；1：已启用"`
+	LastStep       int64     `json:"last_step" gorm:"column:last_step" description:"最近一次使用的时间步"`
+	RecoveryCodes  string    `json:"recovery_codes" gorm:"column:recovery_codes" description:"未使用的恢复码 sha256，逗号间隔"`
+	FailedAttempts int       `json:"failed_attempts" gorm:"column:failed_attempts" description:"连续失败次数"`
+	LockedUntil    time.Time `json:"locked_until" gorm:"column:locked_until" description:"锁定到期时间"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at" description:"添加时间"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"column:updated_at" description:"更新时间"`
+}
+
+func (t *AdminTOTP) TableName() string {
+	return "gateway_admin_totp"
+}
+
+// FindByAdmin 没有记录时返回 IsEnable 为 0 的空记录
+func (t *AdminTOTP) FindByAdmin(c *gin.Context, tx *gorm.DB, adminID int) (*AdminTOTP, error) {
+	model := &AdminTOTP{}
+	err := tx.SetCtx(public.GetGinTraceContext(c)).Where("admin_id=?", adminID).Find(model).Error
+	if err == gorm.ErrRecordNotFound {
+		return &AdminTOTP{AdminID: adminID}, nil
+	}
+	return model, err
+}
+
+func (t *AdminTOTP) Save(c *gin.Context, tx *gorm.DB) error {
+	if err := tx.SetCtx(public.GetGinTraceContext(c)).Save(t).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteByAdmin 关闭或重置两步验证
+func (t *AdminTOTP) DeleteByAdmin(c *gin.Context, tx *gorm.DB, adminID int) error {
+	return tx.SetCtx(public.GetGinTraceContext(c)).Where("admin_id=?", adminID).Delete(&AdminTOTP{}).Error
+}
+
+// Enabled 已完成绑定
+func (t *AdminTOTP) Enabled() bool {
+	return t.ID != 0 && t.IsEnable == 1
+}
+
+var (
+	ErrTOTPInvalid = errors.New("验证码错误")
+	ErrTOTPLocked  = errors.New("验证码错误次数过多，请稍后再试")
+)
+
+// SetSecret 加密保存新密钥，清除之前的使用记录
+func (t *AdminTOTP) SetSecret(secret string) error {
+	encrypted, err := public.EncryptTOTPSecret(secret)
+	if err != nil {
+		return err
+	}
+	t.Secret = encrypted
+	t.LastStep = 0
+	t.RecoveryCodes = ""
+	return nil
+}
+
+func (t *AdminTOTP) Locked(now time.Time) bool {
+	return now.Before(t.LockedUntil)
+}
+
+// Verify 校验验证码，allowRecovery 时也接受恢复码，恢复码使用后作废
+// 时间步与恢复码按读取时的值条件更新，并发请求中同一个验证码只有一个能通过
+// 失败次数按管理员累计，达到 TOTPMaxFailures 后锁定 TOTPLockout，重新输入密码不会重置
+func (t *AdminTOTP) Verify(c *gin.Context, tx *gorm.DB, code string, now time.Time, allowRecovery bool) error {
+	if t.ID == 0 || t.Secret == "" {
+		return ErrTOTPInvalid
+	}
+	if t.Locked(now) {
+		return ErrTOTPLocked
+	}
+	secret, err := public.DecryptTOTPSecret(t.Secret)
+	if err != nil {
+		return err
+	}
+	db := tx.SetCtx(public.GetGinTraceContext(c)).Table(t.TableName())
+	if step, ok := public.MatchTOTP(secret, code, now); ok && step > t.LastStep {
+		updates := map[string]interface{}{"last_step": step, "failed_attempts": 0}
+		// 加密之前保存的明文密钥在使用时改为加密保存
+		if !strings.HasPrefix(t.Secret, public.TOTPEncryptedPrefix) {
+			if encrypted, err := public.EncryptTOTPSecret(secret); err == nil {
+				updates["secret"] = encrypted
+			}
+		}
+		result := db.Where("id=? AND last_step<?", t.ID, step).UpdateColumns(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			t.LastStep = step
+			t.FailedAttempts = 0
+			return nil
+		}
+	} else if allowRecovery && t.RecoveryCodes != "" {
+		hash := public.HashRecoveryCode(code)
+		remain := []string{}
+		used := false
+		for _, item := range strings.Split(t.RecoveryCodes, ",") {
+			if !used && item == hash {
+				used = true
+				continue
+			}
+			remain = append(remain, item)
+		}
+		if used {
+			result := db.Where("id=? AND recovery_codes=?", t.ID, t.RecoveryCodes).
+				UpdateColumns(map[string]interface{}{"recovery_codes": strings.Join(remain, ","), "failed_attempts": 0})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 1 {
+				t.RecoveryCodes = strings.Join(remain, ",")
+				t.FailedAttempts = 0
+				return nil
+			}
+		}
+	}
+	return t.fail(c, tx, now)
+}
+
+// fail 累计失败次数，达到上限时锁定并清零
+func (t *AdminTOTP) fail(c *gin.Context, tx *gorm.DB, now time.Time) error {
+	db := tx.SetCtx(public.GetGinTraceContext(c)).Table(t.TableName())
+	if err := db.Where("id=?", t.ID).UpdateColumn("failed_attempts", gorm.Expr("failed_attempts+1")).Error; err != nil {
+		return err
+	}
+	result := db.Where("id=? AND failed_attempts>=?", t.ID, public.TOTPMaxFailures).
+		UpdateColumns(map[string]interface{}{"failed_attempts": 0, "locked_until": now.Add(public.TOTPLockout())})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 1 {
+		return ErrTOTPLocked
+	}
+	return ErrTOTPInvalid
+}
+
+// Enable 完成绑定并保存新的恢复码，已经开启时返回错误
+func (t *AdminTOTP) Enable(c *gin.Context, tx *gorm.DB) ([]string, error) {
+	codes := t.ResetRecoveryCodes()
+	result := tx.SetCtx(public.GetGinTraceContext(c)).Table(t.TableName()).Where("id=? AND is_enable=0", t.ID).
+		UpdateColumns(map[string]interface{}{"is_enable": 1, "recovery_codes": t.RecoveryCodes})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return nil, errors.New("已开启两步验证")
+	}
+	t.IsEnable = 1
+	return codes, nil
+}
+
+// SaveRecoveryCodes 只更新恢复码，不覆盖并发修改的时间步与失败次数
+func (t *AdminTOTP) SaveRecoveryCodes(c *gin.Context, tx *gorm.DB) error {
+	return tx.SetCtx(public.GetGinTraceContext(c)).Table(t.TableName()).Where("id=?", t.ID).
+		UpdateColumn("recovery_codes", t.RecoveryCodes).Error
+}
+
+// ResetRecoveryCodes 生成新的恢复码，返回明文，只保存 sha256
+func (t *AdminTOTP) ResetRecoveryCodes() []string {
+	codes := public.NewRecoveryCodes()
+	hashes := []string{}
+	for _, code := range codes {
+		hashes = append(hashes, public.HashRecoveryCode(code))
+	}
+	t.RecoveryCodes = strings.Join(hashes, ",")
+	return codes
+}
+
+// RecoveryCodeCount 剩余可用的恢复码数量
+func (t *AdminTOTP) RecoveryCodeCount() int {
+	if t.RecoveryCodes == "" {
+		return 0
+	}
+	return len(strings.Split(t.RecoveryCodes, ","))
+}
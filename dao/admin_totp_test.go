@@ -0,0 +1,132 @@
+package dao_test
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useTOTPConf 设置两步验证密钥的加密 key 与锁定时长
+func useTOTPConf(t *testing.T) {
+	oldConf, oldKey := lib.ViperConfMap, os.Getenv(public.TOTPKeyEnv)
+	base := viper.New()
+	base.Set("admin.totp_lockout", 60)
+	lib.ViperConfMap = map[string]*viper.Viper{"base": base}
+	os.Setenv(public.TOTPKeyEnv, "test-totp-key")
+	t.Cleanup(func() {
+		lib.ViperConfMap = oldConf
+		os.Setenv(public.TOTPKeyEnv, oldKey)
+	})
+}
+
+// newTestTOTP 保存一个已开启的两步验证，返回明文密钥
+func newTestTOTP(t *testing.T, c *gin.Context, db *gorm.DB) (*dao.AdminTOTP, string) {
+	secret := public.NewTOTPSecret()
+	totp := &dao.AdminTOTP{AdminID: 1, IsEnable: 1}
+	if err := totp.SetSecret(secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := totp.Save(c, db); err != nil {
+		t.Fatal(err)
+	}
+	return totp, secret
+}
+
+func loadTestTOTP(t *testing.T, c *gin.Context, db *gorm.DB) *dao.AdminTOTP {
+	totp, err := (&dao.AdminTOTP{}).FindByAdmin(c, db, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return totp
+}
+
+func TestAdminTOTPSecretEncrypted(t *testing.T) {
+	useTOTPConf(t)
+	db := openTestDB(t)
+	c := public.NewBackgroundContext()
+	_, secret := newTestTOTP(t, c, db)
+	stored := loadTestTOTP(t, c, db)
+	if stored.Secret == secret || !strings.HasPrefix(stored.Secret, public.TOTPEncryptedPrefix) {
+		t.Fatalf("secret stored as %q", stored.Secret)
+	}
+
+	// 加密之前保存的明文密钥仍然可用，使用后改为加密保存
+	if err := db.Table(stored.TableName()).Where("id=?", stored.ID).UpdateColumn("secret", secret).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	code, _ := public.TOTPCode(secret, public.TOTPStep(now))
+	if err := loadTestTOTP(t, c, db).Verify(c, db, code, now, false); err != nil {
+		t.Fatal(err)
+	}
+	if stored := loadTestTOTP(t, c, db); !strings.HasPrefix(stored.Secret, public.TOTPEncryptedPrefix) {
+		t.Fatalf("legacy secret not encrypted after use: %q", stored.Secret)
+	}
+}
+
+// 两个请求读取到同一条记录后提交同一个验证码，只有一个通过
+func TestAdminTOTPVerifyOnce(t *testing.T) {
+	useTOTPConf(t)
+	db := openTestDB(t)
+	c := public.NewBackgroundContext()
+	totp, secret := newTestTOTP(t, c, db)
+	codes := totp.ResetRecoveryCodes()
+	if err := totp.SaveRecoveryCodes(c, db); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	code, _ := public.TOTPCode(secret, public.TOTPStep(now))
+	first, second := loadTestTOTP(t, c, db), loadTestTOTP(t, c, db)
+	if err := first.Verify(c, db, code, now, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Verify(c, db, code, now, true); err != dao.ErrTOTPInvalid {
+		t.Fatalf("replayed code: err = %v", err)
+	}
+
+	first, second = loadTestTOTP(t, c, db), loadTestTOTP(t, c, db)
+	if err := first.Verify(c, db, codes[0], now, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Verify(c, db, codes[0], now, true); err != dao.ErrTOTPInvalid {
+		t.Fatalf("reused recovery code: err = %v", err)
+	}
+	if count := loadTestTOTP(t, c, db).RecoveryCodeCount(); count != public.TOTPRecoveryCodeCount-1 {
+		t.Fatalf("recovery codes left = %d", count)
+	}
+}
+
+func TestAdminTOTPLockout(t *testing.T) {
+	useTOTPConf(t)
+	db := openTestDB(t)
+	c := public.NewBackgroundContext()
+	_, secret := newTestTOTP(t, c, db)
+
+	now := time.Now()
+	for i := 1; i <= public.TOTPMaxFailures; i++ {
+		err := loadTestTOTP(t, c, db).Verify(c, db, "000000", now, true)
+		if i < public.TOTPMaxFailures && err != dao.ErrTOTPInvalid {
+			t.Fatalf("attempt %d: err = %v", i, err)
+		}
+		if i == public.TOTPMaxFailures && err != dao.ErrTOTPLocked {
+			t.Fatalf("attempt %d: err = %v, want locked", i, err)
+		}
+	}
+	code, _ := public.TOTPCode(secret, public.TOTPStep(now))
+	if err := loadTestTOTP(t, c, db).Verify(c, db, code, now, true); err != dao.ErrTOTPLocked {
+		t.Fatalf("correct code while locked: err = %v", err)
+	}
+	later := now.Add(61 * time.Second)
+	code, _ = public.TOTPCode(secret, public.TOTPStep(later))
+	if err := loadTestTOTP(t, c, db).Verify(c, db, code, later, true); err != nil {
+		t.Fatalf("after lockout: err = %v", err)
+	}
+}
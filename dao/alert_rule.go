@@ -0,0 +1,494 @@
+package dao
+
+import (
+	"crypto/tls"
+	"fmt"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type AlertRule struct {
+	ID         int64     `json:"id" gorm:"primary_key"`
+	Name       string    `json:"name" gorm:"column:name" description:"规则名称"`
+	RuleType   int       `json:"rule_type" gorm:"column:rule_type" description:"规则类型 0=error_rate 1=renter_qpd 2=upstream_unhealthy 3=cert_expiry"`
+	ServiceID  int64     `json:"service_id" gorm:"column:service_id" description:"服务id，0 表示全部服务"`
+	RenterID   string    `json:"renter_id" gorm:"column:renter_id" description:"租户id，为空表示全部设置了日请求量限制的租户"`
+	Target     string    `json:"target" gorm:"column:target" description:"证书检查地址 host:port，多条逗号间隔"`
+	Threshold  float64   `json:"threshold" gorm:"column:threshold" description:"阈值 error_rate 与 renter_qpd 为百分比，cert_expiry 为天数"`
+	ForSeconds int       `json:"for_seconds" gorm:"column:for_seconds" description:"条件持续多久后触发, 单位s"`
+	WebhookURL string    `json:"webhook_url" gorm:"column:webhook_url" description:"通知地址，为空时使用 base.alert.webhook_url"`
+	Enabled    int8      `json:"enabled" gorm:"column:enabled" description:"是否启用；0：否；1：是"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at" description:"添加时间"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"column:updated_at" description:"更新时间"`
+	IsDelete   int8      `json:"is_delete" gorm:"column:is_delete" description:"是否已删除；0：否；1：是"`
+}
+
+func (t *AlertRule) TableName() string {
+	return "gateway_alert_rule"
+}
+
+func (t *AlertRule) Find(c *gin.Context, tx *gorm.DB, search *AlertRule) (*AlertRule, error) {
+	model := &AlertRule{}
+	err := tx.SetCtx(public.GetGinTraceContext(c)).Where(search).Find(model).Error
+	return model, err
+}
+
+func (t *AlertRule) Save(c *gin.Context, tx *gorm.DB) error {
+	if err := tx.SetCtx(public.GetGinTraceContext(c)).Save(t).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// List 返回所有未删除的规则，enabledOnly 为 true 时只返回启用的规则
+func (t *AlertRule) List(c *gin.Context, tx *gorm.DB, enabledOnly bool) (list []AlertRule, count int64, err error) {
+	query := tx.SetCtx(public.GetGinTraceContext(c))
+	query = query.Table(t.TableName()).Select("*").Where("is_delete=0")
+	if enabledOnly {
+		query = query.Where("enabled=1")
+	}
+	err = query.Order("id desc").Find(&list).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, 0, err
+	}
+	errCount := query.Count(&count).Error
+	if errCount != nil {
+		return nil, 0, errCount
+	}
+	return list, count, nil
+}
+
+// AlertState 一条规则命中的一个对象(服务、租户、节点或证书地址)的告警状态
+type AlertState struct {
+	Fingerprint string
+	Rule        AlertRule
+	Subject     string
+	Value       float64
+	Message     string
+	Status      string
+	PendingAt   time.Time
+	FiredAt     time.Time
+	NotifiedAt  time.Time
+}
+
+type alertHit struct {
+	Subject string
+	Value   float64
+	Message string
+}
+
+type flowSample struct {
+	Time   time.Time
+	Total  int64
+	Errors int64
+}
+
+var AlertManagerHandler *AlertManager
+
+func init() {
+	AlertManagerHandler = NewAlertManager()
+}
+
+// AlertManager 定时评估告警规则，状态只保存在内存中，多实例部署时只应在一个实例上开启
+type AlertManager struct {
+	Interval       time.Duration
+	RateWindow     time.Duration
+	RepeatInterval time.Duration
+	WebhookURL     string
+	States         map[string]*AlertState
+	samples        map[string][]flowSample
+	Locker         sync.RWMutex
+	once           sync.Once
+}
+
+func NewAlertManager() *AlertManager {
+	return &AlertManager{
+		Interval:       public.AlertEvaluateIntervalDefault * time.Second,
+		RateWindow:     public.AlertRateWindowDefault * time.Second,
+		RepeatInterval: public.AlertRepeatIntervalDefault * time.Second,
+		States:         map[string]*AlertState{},
+		samples:        map[string][]flowSample{},
+	}
+}
+
+// Start 读取 base.alert 配置并启动评估协程，未开启时直接返回
+func (s *AlertManager) Start() {
+	if !lib.GetBoolConf("base.alert.on") {
+		return
+	}
+	s.once.Do(func() {
+		if interval := lib.GetIntConf("base.alert.evaluate_interval"); interval > 0 {
+			s.Interval = time.Duration(interval) * time.Second
+		}
+		if window := lib.GetIntConf("base.alert.rate_window"); window > 0 {
+			s.RateWindow = time.Duration(window) * time.Second
+		}
+		if lib.GetConf("base.alert.repeat_interval") != nil {
+			s.RepeatInterval = time.Duration(lib.GetIntConf("base.alert.repeat_interval")) * time.Second
+		}
+		s.WebhookURL = lib.GetStringConf("base.alert.webhook_url")
+		go func() {
+			defer func() {
+				if err := recover(); err != nil {
+					fmt.Println(err)
+				}
+			}()
+			ticker := time.NewTicker(s.Interval)
+			for {
+				if err := s.Evaluate(time.Now()); err != nil {
+					fmt.Println("AlertManager Evaluate err", err)
+				}
+				<-ticker.C
+			}
+		}()
+	})
+}
+
+// Evaluate 评估所有启用的规则
+// 条件持续 ForSeconds 后由 pending 转为 firing 并发送通知，之后每 RepeatInterval 重复一次
+// 条件消失时已 firing 的告警发送 resolved 通知；规则评估出错时保留原状态，避免误发 resolved
+func (s *AlertManager) Evaluate(now time.Time) error {
+	c := public.NewBackgroundContext()
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		return err
+	}
+	rule := &AlertRule{}
+	rules, _, err := rule.List(c, tx, true)
+	if err != nil {
+		return err
+	}
+
+	hits := map[string]alertHit{}
+	ruleMap := map[string]AlertRule{}
+	failedRules := map[int64]bool{}
+	for _, rule := range rules {
+		ruleHits, err := s.evaluateRule(c, tx, &rule, now)
+		if err != nil {
+			fmt.Println("AlertManager evaluate rule", rule.ID, "err", err)
+			failedRules[rule.ID] = true
+			continue
+		}
+		for _, hit := range ruleHits {
+			fingerprint := fmt.Sprintf("%d:%s", rule.ID, hit.Subject)
+			hits[fingerprint] = hit
+			ruleMap[fingerprint] = rule
+		}
+	}
+
+	notifications := []*AlertState{}
+	s.Locker.Lock()
+	for fingerprint, hit := range hits {
+		state, ok := s.States[fingerprint]
+		if !ok {
+			state = &AlertState{
+				Fingerprint: fingerprint,
+				Subject:     hit.Subject,
+				Status:      public.AlertStatusPending,
+				PendingAt:   now,
+			}
+			s.States[fingerprint] = state
+		}
+		state.Rule = ruleMap[fingerprint]
+		state.Value = hit.Value
+		state.Message = hit.Message
+		switch state.Status {
+		case public.AlertStatusPending:
+			if now.Sub(state.PendingAt) >= time.Duration(state.Rule.ForSeconds)*time.Second {
+				state.Status = public.AlertStatusFiring
+				state.FiredAt = now
+				state.NotifiedAt = now
+				notifications = append(notifications, s.copyState(state))
+			}
+		case public.AlertStatusFiring:
+			if s.RepeatInterval > 0 && now.Sub(state.NotifiedAt) >= s.RepeatInterval {
+				state.NotifiedAt = now
+				notifications = append(notifications, s.copyState(state))
+			}
+		}
+	}
+	for fingerprint, state := range s.States {
+		if _, ok := hits[fingerprint]; ok || failedRules[state.Rule.ID] {
+			continue
+		}
+		delete(s.States, fingerprint)
+		if state.Status == public.AlertStatusFiring {
+			state.Status = public.AlertStatusResolved
+			notifications = append(notifications, state)
+		}
+	}
+	s.Locker.Unlock()
+
+	for _, state := range notifications {
+		s.notify(state, now)
+	}
+	return nil
+}
+
+// Active 返回 pending 与 firing 的告警
+func (s *AlertManager) Active() []AlertState {
+	s.Locker.RLock()
+	defer s.Locker.RUnlock()
+	list := []AlertState{}
+	for _, state := range s.States {
+		list = append(list, *state)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].PendingAt.Before(list[j].PendingAt)
+	})
+	return list
+}
+
+func (s *AlertManager) copyState(state *AlertState) *AlertState {
+	out := *state
+	return &out
+}
+
+func (s *AlertManager) notify(state *AlertState, now time.Time) {
+	url := state.Rule.WebhookURL
+	if url == "" {
+		url = s.WebhookURL
+	}
+	if url == "" {
+		fmt.Println("AlertManager no webhook for rule", state.Rule.ID, state.Status, state.Message)
+		return
+	}
+	notification := &public.AlertNotification{
+		Status:      state.Status,
+		Fingerprint: state.Fingerprint,
+		RuleID:      state.Rule.ID,
+		RuleName:    state.Rule.Name,
+		RuleType:    public.AlertTypeMap[state.Rule.RuleType],
+		Subject:     state.Subject,
+		Value:       state.Value,
+		Threshold:   state.Rule.Threshold,
+		Message:     state.Message,
+		StartsAt:    state.FiredAt.Format(time.RFC3339),
+	}
+	if state.Status == public.AlertStatusResolved {
+		notification.ResolvedAt = now.Format(time.RFC3339)
+	}
+	go func() {
+		if err := public.SendAlertWebhook(url, notification); err != nil {
+			fmt.Println("AlertManager SendAlertWebhook err", err)
+		}
+	}()
+}
+
+func (s *AlertManager) evaluateRule(c *gin.Context, tx *gorm.DB, rule *AlertRule, now time.Time) ([]alertHit, error) {
+	switch rule.RuleType {
+	case public.AlertTypeErrorRate:
+		return s.evaluateErrorRate(c, tx, rule, now)
+	case public.AlertTypeRenterQpd:
+		return s.evaluateRenterQpd(c, tx, rule, now)
+	case public.AlertTypeUpstream:
+		return s.evaluateUpstream(c, tx, rule)
+	case public.AlertTypeCertExpiry:
+		return s.evaluateCertExpiry(rule, now)
+	}
+	return nil, fmt.Errorf("unknown rule_type %d", rule.RuleType)
+}
+
+func (s *AlertManager) listServices(c *gin.Context, tx *gorm.DB, serviceID int64) ([]ServiceInfo, error) {
+	if serviceID != 0 {
+		serviceInfo, err := (&ServiceInfo{}).Find(c, tx, &ServiceInfo{ID: serviceID})
+		if err != nil {
+			return nil, err
+		}
+		return []ServiceInfo{*serviceInfo}, nil
+	}
+	return (&ServiceInfo{}).ListAll(c, tx)
+}
+
+// evaluateErrorRate 每次评估记录服务当天的请求数与 5xx 数，用 RateWindow 内的增量计算错误率
+func (s *AlertManager) evaluateErrorRate(c *gin.Context, tx *gorm.DB, rule *AlertRule, now time.Time) ([]alertHit, error) {
+	services, err := s.listServices(c, tx, rule.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	hits := []alertHit{}
+	for _, serviceInfo := range services {
+		sample, err := s.sampleService(serviceInfo.ServiceName, now)
+		if err != nil {
+			return nil, err
+		}
+		oldest := s.samples[serviceInfo.ServiceName][0]
+		total := sample.Total - oldest.Total
+		if now.Sub(oldest.Time) < s.RateWindow || total < public.AlertErrorRateMinRequests {
+			continue
+		}
+		errorRate := float64(sample.Errors-oldest.Errors) / float64(total) * 100
+		if errorRate > rule.Threshold {
+			hits = append(hits, alertHit{
+				Subject: serviceInfo.ServiceName,
+				Value:   errorRate,
+				Message: fmt.Sprintf("服务 %s 最近 %v 5xx 错误率 %.2f%% 超过 %.2f%%", serviceInfo.ServiceName, s.RateWindow, errorRate, rule.Threshold),
+			})
+		}
+	}
+	return hits, nil
+}
+
+// sampleService 同一轮评估中多条规则共用一个采样；计数跨天清零时丢弃旧采样
+func (s *AlertManager) sampleService(serviceName string, now time.Time) (flowSample, error) {
+	samples := s.samples[serviceName]
+	if len(samples) > 0 && samples[len(samples)-1].Time.Equal(now) {
+		return samples[len(samples)-1], nil
+	}
+	total, err := public.FlowCounterHandler.Lookup(public.FlowServicePrefix+serviceName).GetDayData(nil, now)
+	if err != nil {
+		return flowSample{}, err
+	}
+	errors, err := public.FlowCounterHandler.Lookup(public.FlowServiceErrorPrefix+serviceName).GetDayData(nil, now)
+	if err != nil {
+		return flowSample{}, err
+	}
+	sample := flowSample{Time: now, Total: total, Errors: errors}
+
+	if len(samples) > 0 && (total < samples[len(samples)-1].Total || errors < samples[len(samples)-1].Errors) {
+		samples = nil
+	}
+	samples = append(samples, sample)
+	// 保留窗口起点之前最近的一个采样
+	for len(samples) > 1 && now.Sub(samples[1].Time) >= s.RateWindow {
+		samples = samples[1:]
+	}
+	s.samples[serviceName] = samples
+	return sample, nil
+}
+
+func (s *AlertManager) evaluateRenterQpd(c *gin.Context, tx *gorm.DB, rule *AlertRule, now time.Time) ([]alertHit, error) {
+	renters, err := (&Renter{}).ListAll(c, tx)
+	if err != nil {
+		return nil, err
+	}
+	hits := []alertHit{}
+	for _, renter := range renters {
+		if renter.Qpd <= 0 || (rule.RenterID != "" && rule.RenterID != renter.RenterID) {
+			continue
+		}
+		today, err := public.FlowCounterHandler.Lookup(public.FlowAppPrefix+renter.RenterID).GetDayData(nil, now)
+		if err != nil {
+			return nil, err
+		}
+		percent := float64(today) / float64(renter.Qpd) * 100
+		if percent >= rule.Threshold {
+			hits = append(hits, alertHit{
+				Subject: renter.RenterID,
+				Value:   percent,
+				Message: fmt.Sprintf("租户 %s 今日请求量 %d 已达日限额 %d 的 %.1f%%", renter.RenterID, today, renter.Qpd, percent),
+			})
+		}
+	}
+	return hits, nil
+}
+
+// evaluateUpstream 与负载均衡的 tcpchk 一致，节点端口无法握手视为不健康
+// 所有节点并发探测，同时进行的连接数不超过 AlertUpstreamDialConcurrency
+func (s *AlertManager) evaluateUpstream(c *gin.Context, tx *gorm.DB, rule *AlertRule) ([]alertHit, error) {
+	services, err := s.listServices(c, tx, rule.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	type upstreamNode struct {
+		ServiceName string
+		IP          string
+		Timeout     time.Duration
+	}
+	nodes := []upstreamNode{}
+	for _, serviceInfo := range services {
+		loadBalance, err := (&LoadBalance{}).Find(c, tx, &LoadBalance{ServiceID: serviceInfo.ID})
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				continue
+			}
+			return nil, err
+		}
+		timeout := time.Duration(loadBalance.CheckTimeout) * time.Second
+		if timeout <= 0 {
+			timeout = 2 * time.Second
+		}
+		for _, ip := range loadBalance.GetIPListByModel() {
+			if ip == "" {
+				continue
+			}
+			nodes = append(nodes, upstreamNode{ServiceName: serviceInfo.ServiceName, IP: ip, Timeout: timeout})
+		}
+	}
+
+	dialErrors := make([]error, len(nodes))
+	limiter := make(chan struct{}, public.AlertUpstreamDialConcurrency)
+	wg := sync.WaitGroup{}
+	for index, node := range nodes {
+		wg.Add(1)
+		limiter <- struct{}{}
+		go func(index int, node upstreamNode) {
+			defer func() {
+				<-limiter
+				wg.Done()
+			}()
+			conn, err := net.DialTimeout("tcp", node.IP, node.Timeout)
+			if err != nil {
+				dialErrors[index] = err
+				return
+			}
+			conn.Close()
+		}(index, node)
+	}
+	wg.Wait()
+
+	hits := []alertHit{}
+	for index, node := range nodes {
+		if dialErrors[index] == nil {
+			continue
+		}
+		hits = append(hits, alertHit{
+			Subject: node.ServiceName + "/" + node.IP,
+			Value:   0,
+			Message: fmt.Sprintf("服务 %s 节点 %s 不健康: %v", node.ServiceName, node.IP, dialErrors[index]),
+		})
+	}
+	return hits, nil
+}
+
+// evaluateCertExpiry 连接 Target 中的地址读取证书有效期，连接失败不触发告警
+func (s *AlertManager) evaluateCertExpiry(rule *AlertRule, now time.Time) ([]alertHit, error) {
+	hits := []alertHit{}
+	for _, target := range strings.Split(rule.Target, ",") {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		host, _, err := net.SplitHostPort(target)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", target,
+			&tls.Config{ServerName: host, InsecureSkipVerify: true})
+		if err != nil {
+			fmt.Println("AlertManager cert dial", target, "err", err)
+			continue
+		}
+		certs := conn.ConnectionState().PeerCertificates
+		conn.Close()
+		if len(certs) == 0 {
+			continue
+		}
+		days := certs[0].NotAfter.Sub(now).Hours() / 24
+		if days < rule.Threshold {
+			hits = append(hits, alertHit{
+				Subject: target,
+				Value:   days,
+				Message: fmt.Sprintf("%s 的证书将于 %s 过期，剩余 %.1f 天", target, certs[0].NotAfter.In(lib.TimeLocation).Format("2006-01-02 15:04:05"), days),
+			})
+		}
+	}
+	return hits, nil
+}
@@ -0,0 +1,66 @@
+package dao
+
+import (
+	"encoding/json"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlertManagerCertExpiryNotification(t *testing.T) {
+	oldLocation := lib.TimeLocation
+	lib.TimeLocation = time.UTC
+	defer func() {
+		lib.TimeLocation = oldLocation
+	}()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	target := strings.TrimPrefix(server.URL, "https://")
+	certDays := server.Certificate().NotAfter.Sub(time.Now()).Hours() / 24
+
+	manager := NewAlertManager()
+	now := time.Now()
+	rule := &AlertRule{ID: 1, Name: "cert", RuleType: public.AlertTypeCertExpiry, Target: target, Threshold: certDays - 1}
+	if hits, err := manager.evaluateCertExpiry(rule, now); err != nil || len(hits) != 0 {
+		t.Fatalf("hits = %+v, err = %v, want none", hits, err)
+	}
+	rule.Threshold = certDays + 1
+	hits, err := manager.evaluateCertExpiry(rule, now)
+	if err != nil || len(hits) != 1 || hits[0].Subject != target {
+		t.Fatalf("hits = %+v, err = %v, want one for %s", hits, err, target)
+	}
+
+	received := make(chan public.AlertNotification, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notification := public.AlertNotification{}
+		if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
+			t.Error(err)
+		}
+		received <- notification
+	}))
+	defer receiver.Close()
+	manager.WebhookURL = receiver.URL
+	manager.notify(&AlertState{
+		Fingerprint: "1:" + target,
+		Rule:        *rule,
+		Subject:     target,
+		Value:       hits[0].Value,
+		Status:      public.AlertStatusFiring,
+		FiredAt:     now,
+	}, now)
+
+	select {
+	case notification := <-received:
+		if notification.Status != public.AlertStatusFiring || notification.RuleType != "cert_expiry" ||
+			notification.Fingerprint != "1:"+target {
+			t.Errorf("unexpected notification %+v", notification)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no notification")
+	}
+}
@@ -0,0 +1,119 @@
+package dao_test
+
+import (
+	"encoding/json"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/migration"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/e421083458/gorm"
+	_ "github.com/e421083458/gorm/dialects/sqlite"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTestDB 在临时目录创建 sqlite 数据库，执行全部迁移并注册为 default 连接
+func openTestDB(t *testing.T) *gorm.DB {
+	return openTestDBAt(t, 0)
+}
+
+// openTestDBAt 只执行到 target 版本，target 为 0 时执行全部迁移
+func openTestDBAt(t *testing.T, target int) *gorm.DB {
+	db, err := gorm.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "gateway.db")+"?_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SingularTable(true)
+	if _, err := migration.Up(public.NewBackgroundContext(), db, target); err != nil {
+		t.Fatal(err)
+	}
+	oldPool := lib.GORMMapPool
+	lib.GORMMapPool = map[string]*gorm.DB{"default": db}
+	t.Cleanup(func() {
+		lib.GORMMapPool = oldPool
+		db.Close()
+	})
+	return db
+}
+
+func TestAlertManagerUpstreamFiringAndResolved(t *testing.T) {
+	db := openTestDB(t)
+	c := public.NewBackgroundContext()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	service := &dao.ServiceInfo{ServiceName: "alert_demo", LoadType: public.LoadTypeHTTP}
+	if err := service.Save(c, db); err != nil {
+		t.Fatal(err)
+	}
+	loadBalance := &dao.LoadBalance{ServiceID: service.ID, CheckTimeout: 1, IpList: addr, WeightList: "50"}
+	if err := loadBalance.Save(c, db); err != nil {
+		t.Fatal(err)
+	}
+	rule := &dao.AlertRule{Name: "upstream", RuleType: public.AlertTypeUpstream, Enabled: 1}
+	if err := rule.Save(c, db); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan public.AlertNotification, 4)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notification := public.AlertNotification{}
+		if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
+			t.Error(err)
+		}
+		received <- notification
+	}))
+	defer receiver.Close()
+
+	manager := dao.NewAlertManager()
+	manager.WebhookURL = receiver.URL
+	wait := func(status string) public.AlertNotification {
+		select {
+		case notification := <-received:
+			if notification.Status != status {
+				t.Fatalf("got %s notification, want %s", notification.Status, status)
+			}
+			return notification
+		case <-time.After(3 * time.Second):
+			t.Fatalf("no %s notification", status)
+		}
+		return public.AlertNotification{}
+	}
+
+	if err := manager.Evaluate(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	firing := wait(public.AlertStatusFiring)
+	if firing.Subject != "alert_demo/"+addr || firing.RuleType != "upstream_unhealthy" {
+		t.Errorf("unexpected firing notification %+v", firing)
+	}
+	if active := manager.Active(); len(active) != 1 {
+		t.Fatalf("active alerts = %d, want 1", len(active))
+	}
+
+	// 节点恢复后发送 resolved，fingerprint 与 firing 相同
+	listener, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skip("upstream port reused:", err)
+	}
+	defer listener.Close()
+	if err := manager.Evaluate(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	resolved := wait(public.AlertStatusResolved)
+	if resolved.Fingerprint != firing.Fingerprint || resolved.ResolvedAt == "" {
+		t.Errorf("unexpected resolved notification %+v", resolved)
+	}
+	if active := manager.Active(); len(active) != 0 {
+		t.Errorf("active alerts = %d, want 0", len(active))
+	}
+}
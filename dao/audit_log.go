@@ -0,0 +1,74 @@
+package dao
+
+import (
+	"github.com/JunxiHe459/gateway/dto"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+type AuditLog struct {
+	ID           int64     `json:"id" gorm:"primary_key"`
+	AdminID      int       `json:"admin_id" gorm:"column:admin_id" description:"管理员id"`
+	AdminName    string    `json:"admin_name" gorm:"column:admin_name" description:"管理员用户名"`
+	Action       string    `json:"action" gorm:"column:action" description:"操作 add/update/delete/change_password"`
+	ResourceType string    `json:"resource_type" gorm:"column:resource_type" description:"对象类型 service/renter/admin"`
+	ResourceID   int64     `json:"resource_id" gorm:"column:resource_id" description:"对象id"`
+	ResourceName string    `json:"resource_name" gorm:"column:resource_name" description:"对象名称"`
+	Endpoint     string    `json:"endpoint" gorm:"column:endpoint" description:"请求方法与路径"`
+	ClientIP     string    `json:"client_ip" gorm:"column:client_ip" description:"客户端ip"`
+	Before       string    `json:"before" gorm:"column:before_snapshot" description:"修改前快照 json，已脱敏"`
+	After        string    `json:"after" gorm:"column:after_snapshot" description:"修改后快照 json，已脱敏"`
+	Diff         string    `json:"diff" gorm:"column:diff" description:"变化字段 json"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at" description:"操作时间"`
+}
+
+func (t *AuditLog) TableName() string {
+	return "gateway_admin_audit_log"
+}
+
+func (t *AuditLog) Save(c *gin.Context, tx *gorm.DB) error {
+	if err := tx.SetCtx(public.GetGinTraceContext(c)).Save(t).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// PageList 按操作时间倒序分页，日期按本地时区取整天
+func (t *AuditLog) PageList(c *gin.Context, tx *gorm.DB, params *dto.AuditListInput) ([]AuditLog, int64, error) {
+	var list []AuditLog
+	var count int64
+	offset := (params.PageNumber - 1) * params.PageSize
+	query := tx.SetCtx(public.GetGinTraceContext(c))
+	query = query.Table(t.TableName()).Select("*")
+	if params.AdminID != 0 {
+		query = query.Where("admin_id=?", params.AdminID)
+	}
+	if params.ResourceType != "" {
+		query = query.Where("resource_type=?", params.ResourceType)
+	}
+	if params.ResourceID != 0 {
+		query = query.Where("resource_id=?", params.ResourceID)
+	}
+	if params.Action != "" {
+		query = query.Where("action=?", params.Action)
+	}
+	if params.StartDate != "" {
+		start, _ := time.ParseInLocation(public.UsageDateFormat, params.StartDate, lib.TimeLocation)
+		query = query.Where("created_at>=?", start)
+	}
+	if params.EndDate != "" {
+		end, _ := time.ParseInLocation(public.UsageDateFormat, params.EndDate, lib.TimeLocation)
+		query = query.Where("created_at<?", end.AddDate(0, 0, 1))
+	}
+	err := query.Limit(params.PageSize).Offset(offset).Order("id desc").Find(&list).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, 0, err
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, count, nil
+}
@@ -0,0 +1,158 @@
+package dao
+
+import (
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type FaultRule struct {
+	ID            int64     `json:"id" gorm:"primary_key"`
+	ServiceID     int64     `json:"service_id" gorm:"column:service_id" description:"服务id"`
+	FaultType     int       `json:"fault_type" gorm:"column:fault_type" description:"故障类型 0=delay 1=abort 2=reset"`
+	DelayMs       int       `json:"delay_ms" gorm:"column:delay_ms" description:"固定延迟, 单位ms"`
+	DelayJitterMs int       `json:"delay_jitter_ms" gorm:"column:delay_jitter_ms" description:"随机延迟上限, 单位ms"`
+	AbortCode     int       `json:"abort_code" gorm:"column:abort_code" description:"abort 时返回的 http 状态码"`
+	GrpcCode      int       `json:"grpc_code" gorm:"column:grpc_code" description:"abort 时返回的 grpc code"`
+	Percentage    int       `json:"percentage" gorm:"column:percentage" description:"命中流量百分比 0-100"`
+	RenterID      string    `json:"renter_id" gorm:"column:renter_id" description:"只对该租户生效，为空表示全部租户"`
+	HeaderMatch   string    `json:"header_match" gorm:"column:header_match" description:"header匹配 格式: headname headvalue, 多条逗号间隔"`
+	ExpireAt      time.Time `json:"expire_at" gorm:"column:expire_at" description:"过期时间"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at" description:"添加时间"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"column:updated_at" description:"更新时间"`
+	IsDelete      int8      `json:"is_delete" gorm:"column:is_delete" description:"是否已删除；0：否；1：是"`
+}
+
+func (t *FaultRule) TableName() string {
+	return "gateway_service_fault_rule"
+}
+
+func (t *FaultRule) Find(c *gin.Context, tx *gorm.DB, search *FaultRule) (*FaultRule, error) {
+	model := &FaultRule{}
+	err := tx.SetCtx(public.GetGinTraceContext(c)).Where(search).Find(model).Error
+	return model, err
+}
+
+func (t *FaultRule) Save(c *gin.Context, tx *gorm.DB) error {
+	if err := tx.SetCtx(public.GetGinTraceContext(c)).Save(t).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListByServiceID 返回服务下所有未删除且未过期的故障规则
+// serviceID 为 0 时返回所有服务的规则
+func (t *FaultRule) ListByServiceID(c *gin.Context, tx *gorm.DB, serviceID int64) (list []FaultRule, count int64, err error) {
+	query := tx.SetCtx(public.GetGinTraceContext(c))
+	query = query.Table(t.TableName()).Select("*")
+	query = query.Where("is_delete=0 and expire_at>?", time.Now())
+	if serviceID != 0 {
+		query = query.Where("service_id=?", serviceID)
+	}
+	err = query.Order("id desc").Find(&list).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, 0, err
+	}
+	errCount := query.Count(&count).Error
+	if errCount != nil {
+		return nil, 0, errCount
+	}
+	return list, count, nil
+}
+
+// Match 判断请求是否满足规则的租户与 header 条件
+func (t *FaultRule) Match(renterID string, header http.Header) bool {
+	if t.IsDelete == 1 || !time.Now().Before(t.ExpireAt) {
+		return false
+	}
+	if t.RenterID != "" && t.RenterID != renterID {
+		return false
+	}
+	if t.HeaderMatch != "" {
+		for _, item := range strings.Split(t.HeaderMatch, ",") {
+			items := strings.Split(item, " ")
+			if len(items) != 2 || header.Get(items[0]) != items[1] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Hit 按照 Percentage 抽样决定本次请求是否注入故障
+func (t *FaultRule) Hit() bool {
+	if t.Percentage <= 0 {
+		return false
+	}
+	if t.Percentage >= 100 {
+		return true
+	}
+	return rand.Intn(100) < t.Percentage
+}
+
+// Delay 固定延迟加上 [0, DelayJitterMs) 的随机延迟
+func (t *FaultRule) Delay() time.Duration {
+	delay := t.DelayMs
+	if t.DelayJitterMs > 0 {
+		delay += rand.Intn(t.DelayJitterMs)
+	}
+	return time.Duration(delay) * time.Millisecond
+}
+
+var FaultRuleManagerHandler *FaultRuleManager
+
+func init() {
+	FaultRuleManagerHandler = NewFaultRuleManager()
+}
+
+type FaultRuleManager struct {
+	FaultRuleMap map[int64][]*FaultRule
+	Locker       sync.RWMutex
+}
+
+func NewFaultRuleManager() *FaultRuleManager {
+	return &FaultRuleManager{
+		FaultRuleMap: map[int64][]*FaultRule{},
+		Locker:       sync.RWMutex{},
+	}
+}
+
+// Load 从数据库重新加载所有生效中的故障规则，代理启动、规则变更后与定时刷新时调用
+func (s *FaultRuleManager) Load() error {
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		return err
+	}
+	faultRule := &FaultRule{}
+	list, _, err := faultRule.ListByServiceID(public.NewBackgroundContext(), tx, 0)
+	if err != nil {
+		return err
+	}
+	ruleMap := map[int64][]*FaultRule{}
+	for _, listItem := range list {
+		tmpItem := listItem
+		ruleMap[listItem.ServiceID] = append(ruleMap[listItem.ServiceID], &tmpItem)
+	}
+	s.Locker.Lock()
+	defer s.Locker.Unlock()
+	s.FaultRuleMap = ruleMap
+	return nil
+}
+
+// GetFaultRule 返回第一条匹配并命中抽样的规则，没有则返回 nil
+func (s *FaultRuleManager) GetFaultRule(serviceID int64, renterID string, header http.Header) *FaultRule {
+	s.Locker.RLock()
+	defer s.Locker.RUnlock()
+	for _, rule := range s.FaultRuleMap[serviceID] {
+		if rule.Match(renterID, header) && rule.Hit() {
+			return rule
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package dao
+
+import (
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+type HttpRoute struct {
+	ID          int64  `json:"id" gorm:"primary_key"`
+	ServiceID   int64  `json:"service_id" gorm:"column:service_id" description:"服务id"`
+	Path        string `json:"path" gorm:"column:path" description:"路径模式 支持 /users/:id 与 /static/*filepath"`
+	Methods     string `json:"methods" gorm:"column:methods" description:"允许的方法, 逗号间隔, 为空表示全部"`
+	HeaderMatch string `json:"header_match" gorm:"column:header_match" description:"header匹配 格式: headname headvalue, 多条逗号间隔"`
+	QueryMatch  string `json:"query_match" gorm:"column:query_match" description:"query匹配 格式: key=value, 多条逗号间隔"`
+	Priority    int    `json:"priority" gorm:"column:priority" description:"优先级, 越大越先匹配"`
+	IsDelete    int8   `json:"is_delete" gorm:"column:is_delete" description:"是否已删除；0：否；1：是"`
+}
+
+// 跨服务冲突检测时使用，带上路由所属服务的接入信息
+type HttpRouteItem struct {
+	HttpRoute
+	ServiceName string `json:"service_name" gorm:"column:service_name"`
+	RuleType    int    `json:"rule_type" gorm:"column:rule_type"`
+	Rule        string `json:"rule" gorm:"column:rule"`
+}
+
+func (t *HttpRoute) TableName() string {
+	return "gateway_service_http_route"
+}
+
+func (t *HttpRoute) Find(c *gin.Context, tx *gorm.DB, search *HttpRoute) (*HttpRoute, error) {
+	model := &HttpRoute{}
+	err := tx.SetCtx(public.GetGinTraceContext(c)).Where(search).Find(model).Error
+	return model, err
+}
+
+func (t *HttpRoute) Save(c *gin.Context, tx *gorm.DB) error {
+	if err := tx.SetCtx(public.GetGinTraceContext(c)).Save(t).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListByServiceID 按优先级从高到低返回服务的路由
+func (t *HttpRoute) ListByServiceID(c *gin.Context, tx *gorm.DB, serviceID int64) (list []HttpRoute, count int64, err error) {
+	query := tx.SetCtx(public.GetGinTraceContext(c))
+	query = query.Table(t.TableName()).Select("*")
+	query = query.Where("service_id=? and is_delete=0", serviceID)
+	err = query.Order("priority desc, id asc").Find(&list).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, 0, err
+	}
+	errCount := query.Count(&count).Error
+	if errCount != nil {
+		return nil, 0, errCount
+	}
+	return list, count, nil
+}
+
+// DeleteByServiceID 软删除服务下的所有路由，更新服务时先删后加
+func (t *HttpRoute) DeleteByServiceID(c *gin.Context, tx *gorm.DB, serviceID int64) error {
+	return tx.SetCtx(public.GetGinTraceContext(c)).Table(t.TableName()).
+		Where("service_id=? and is_delete=0", serviceID).Update("is_delete", 1).Error
+}
+
+// ListAll 返回所有未删除 http 服务的路由
+func (t *HttpRoute) ListAll(c *gin.Context, tx *gorm.DB) (list []HttpRouteItem, err error) {
+	info := &ServiceInfo{}
+	httpRule := &HttpRule{}
+	err = tx.SetCtx(public.GetGinTraceContext(c)).Table(t.TableName() + " r").
+		Select("r.*, i.service_name, h.rule_type, h.rule").
+		Joins("join " + info.TableName() + " i on i.id=r.service_id").
+		Joins("join " + httpRule.TableName() + " h on h.service_id=r.service_id").
+		Where("r.is_delete=0 and i.is_delete=0").
+		Scan(&list).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Match 判断请求是否命中路由，命中时返回路径参数
+func (t *HttpRoute) Match(req *http.Request) (map[string]string, bool) {
+	if t.Methods != "" && !public.InStringSlice(strings.Split(t.Methods, ","), req.Method) {
+		return nil, false
+	}
+	if t.HeaderMatch != "" {
+		for _, item := range strings.Split(t.HeaderMatch, ",") {
+			items := strings.Split(item, " ")
+			if len(items) != 2 || req.Header.Get(items[0]) != items[1] {
+				return nil, false
+			}
+		}
+	}
+	if t.QueryMatch != "" {
+		query := req.URL.Query()
+		for _, item := range strings.Split(t.QueryMatch, ",") {
+			items := strings.SplitN(item, "=", 2)
+			if len(items) != 2 || query.Get(items[0]) != items[1] {
+				return nil, false
+			}
+		}
+	}
+	return public.MatchPath(t.Path, req.URL.Path)
+}
+
+// ConflictWith 同优先级、方法有交集、路径可能重叠且匹配条件相同时，无法确定请求该走哪条路由
+func (t *HttpRoute) ConflictWith(other *HttpRoute) bool {
+	return t.Priority == other.Priority &&
+		t.HeaderMatch == other.HeaderMatch &&
+		t.QueryMatch == other.QueryMatch &&
+		public.MethodOverlap(t.Methods, other.Methods) &&
+		public.PathOverlap(t.Path, other.Path)
+}
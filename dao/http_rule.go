@@ -16,6 +16,21 @@ type HttpRule struct {
 	NeedStripUri   int    `json:"need_strip_uri" gorm:"column:need_strip_uri" description:"启用strip_uri 1=启用"`
 	UrlRewrite     string `json:"url_rewrite" gorm:"column:url_rewrite" description:"url重写功能，每行一个	"`
 	HeaderTransfer string `json:"header_transfer" gorm:"column:header_transfer" description:"header转换支持增加(add)、删除(del)、修改(edit) 格式: add headname headvalue	"`
+
+	RequestTransform  string `json:"request_transform" gorm:"column:request_transform" description:"请求转换 支持 add/del/rename json字段 与 header 模板 格式: add field value"`
+	ResponseTransform string `json:"response_transform" gorm:"column:response_transform" description:"响应转换 格式同请求转换"`
+	TransformMaxBody  int    `json:"transform_max_body" gorm:"column:transform_max_body" description:"参与转换的最大 body, 单位byte, 超过则原样透传 0=默认1MB"`
+
+	NeedCors             int    `json:"need_cors" gorm:"column:need_cors" description:"启用跨域 1=启用"`
+	CorsAllowOrigins     string `json:"cors_allow_origins" gorm:"column:cors_allow_origins" description:"允许的 origin, 逗号间隔, 支持 * 通配"`
+	CorsAllowMethods     string `json:"cors_allow_methods" gorm:"column:cors_allow_methods" description:"允许的方法, 逗号间隔"`
+	CorsAllowHeaders     string `json:"cors_allow_headers" gorm:"column:cors_allow_headers" description:"允许的 header, 逗号间隔"`
+	CorsAllowCredentials int    `json:"cors_allow_credentials" gorm:"column:cors_allow_credentials" description:"允许携带 cookie 1=允许"`
+	CorsMaxAge           int    `json:"cors_max_age" gorm:"column:cors_max_age" description:"预检结果缓存时间, 单位s"`
+
+	NeedCompress    int    `json:"need_compress" gorm:"column:need_compress" description:"启用响应压缩 gzip/br 1=启用"`
+	CompressMinSize int    `json:"compress_min_size" gorm:"column:compress_min_size" description:"最小压缩大小, 单位byte 0=默认1024"`
+	CompressTypes   string `json:"compress_types" gorm:"column:compress_types" description:"可压缩的 content-type 前缀, 逗号间隔"`
 }
 
 func (t *HttpRule) TableName() string {
@@ -1,6 +1,7 @@
 package dao
 
 import (
+	"crypto/tls"
 	"fmt"
 	"github.com/JunxiHe459/gateway/public"
 	"github.com/e421083458/go_gateway/reverse_proxy/load_balance"
@@ -9,6 +10,8 @@ import (
 
 	"github.com/e421083458/gorm"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/net/http2"
+	"log"
 	"net"
 	"net/http"
 	"strings"
@@ -42,6 +45,10 @@ type LoadBalancer struct {
 type LoadBalancerItem struct {
 	LoadBanlance load_balance.LoadBalance
 	ServiceName  string
+	LoadType     int
+	IpList       []string
+	Format       string
+	CheckConf    *load_balance.LoadBalanceCheckConf
 }
 
 var LoadBalancerHandler = &LoadBalancer{}
@@ -84,10 +91,18 @@ func init() {
 }
 
 func (lbr *LoadBalancer) GetLoadBalancer(service *ServiceDetail) (load_balance.LoadBalance, error) {
-	for _, lbrItem := range lbr.LoadBanlanceSlice {
-		if lbrItem.ServiceName == service.Info.ServiceName {
-			return lbrItem.LoadBanlance, nil
-		}
+	lbr.Locker.RLock()
+	lbrItem, ok := lbr.LoadBanlanceMap[service.Info.ServiceName]
+	lbr.Locker.RUnlock()
+	if ok {
+		return lbrItem.LoadBanlance, nil
+	}
+
+	lbr.Locker.Lock()
+	defer lbr.Locker.Unlock()
+	// 并发请求可能已经创建
+	if lbrItem, ok := lbr.LoadBanlanceMap[service.Info.ServiceName]; ok {
+		return lbrItem.LoadBanlance, nil
 	}
 	schema := "http://"
 	if service.HTTPRule.NeedHttps == 1 {
@@ -100,10 +115,15 @@ func (lbr *LoadBalancer) GetLoadBalancer(service *ServiceDetail) (load_balance.L
 	weightList := service.LoadBalance.GetWeightListByModel()
 	ipConf := map[string]string{}
 	for ipIndex, ipItem := range ipList {
-		ipConf[ipItem] = weightList[ipIndex]
+		weight := "50"
+		if ipIndex < len(weightList) {
+			weight = weightList[ipIndex]
+		}
+		ipConf[ipItem] = weight
 	}
 	//fmt.Println("ipConf", ipConf)
-	mConf, err := load_balance.NewLoadBalanceCheckConf(fmt.Sprintf("%s%s", schema, "%s"), ipConf)
+	format := fmt.Sprintf("%s%s", schema, "%s")
+	mConf, err := load_balance.NewLoadBalanceCheckConf(format, ipConf)
 	if err != nil {
 		return nil, err
 	}
@@ -113,13 +133,58 @@ func (lbr *LoadBalancer) GetLoadBalancer(service *ServiceDetail) (load_balance.L
 	lbItem := &LoadBalancerItem{
 		LoadBanlance: lb,
 		ServiceName:  service.Info.ServiceName,
+		LoadType:     service.Info.LoadType,
+		IpList:       ipList,
+		Format:       format,
+		CheckConf:    mConf,
 	}
 	lbr.LoadBanlanceSlice = append(lbr.LoadBanlanceSlice, lbItem)
+	lbr.LoadBanlanceMap[service.Info.ServiceName] = lbItem
+	return lb, nil
+}
 
+// LoadAll 启动时为所有服务创建负载均衡，健康检查立即开始，upstream 健康指标不必等到第一个请求
+func (lbr *LoadBalancer) LoadAll(services []*ServiceDetail) {
+	for _, service := range services {
+		if _, err := lbr.GetLoadBalancer(service); err != nil {
+			log.Printf(" [ERROR] load balancer %s err:%v\n", service.Info.ServiceName, err)
+		}
+	}
+}
+
+// Remove 删除服务缓存的负载均衡，配置变更后下一次请求按新配置重建
+func (lbr *LoadBalancer) Remove(serviceName string) {
 	lbr.Locker.Lock()
 	defer lbr.Locker.Unlock()
-	lbr.LoadBanlanceMap[service.Info.ServiceName] = lbItem
-	return lb, nil
+	slice := []*LoadBalancerItem{}
+	for _, lbrItem := range lbr.LoadBanlanceSlice {
+		if lbrItem.ServiceName != serviceName {
+			slice = append(slice, lbrItem)
+		}
+	}
+	lbr.LoadBanlanceSlice = slice
+	delete(lbr.LoadBanlanceMap, serviceName)
+}
+
+// UpdateHealthMetrics 按负载均衡的健康检查结果刷新 upstream 健康指标
+func (lbr *LoadBalancer) UpdateHealthMetrics() {
+	lbr.Locker.RLock()
+	defer lbr.Locker.RUnlock()
+	public.MetricUpstreamHealth.Reset()
+	for _, lbItem := range lbr.LoadBanlanceMap {
+		activeMap := map[string]bool{}
+		for _, conf := range lbItem.CheckConf.GetConf() {
+			activeMap[strings.Split(conf, ",")[0]] = true
+		}
+		loadType := public.MetricsLoadTypeLabel(lbItem.LoadType)
+		for _, ip := range lbItem.IpList {
+			health := 0.0
+			if activeMap[fmt.Sprintf(lbItem.Format, ip)] {
+				health = 1
+			}
+			public.MetricUpstreamHealth.Set(health, lbItem.ServiceName, loadType, ip)
+		}
+	}
 }
 
 var TransportorHandler *Transportor
@@ -131,7 +196,7 @@ type Transportor struct {
 }
 
 type TransportItem struct {
-	Trans       *http.Transport
+	Trans       http.RoundTripper
 	ServiceName string
 }
 
@@ -147,48 +212,92 @@ func init() {
 	TransportorHandler = NewTransportor()
 }
 
-func (t *Transportor) GetTrans(service *ServiceDetail) (*http.Transport, error) {
-	for _, transItem := range t.TransportSlice {
-		if transItem.ServiceName == service.Info.ServiceName {
-			return transItem.Trans, nil
-		}
+// GetTrans 返回服务的上游连接池，grpc 服务使用 h2c 连接
+func (t *Transportor) GetTrans(service *ServiceDetail) (http.RoundTripper, error) {
+	t.Locker.RLock()
+	transItem, ok := t.TransportMap[service.Info.ServiceName]
+	t.Locker.RUnlock()
+	if ok {
+		return transItem.Trans, nil
+	}
+
+	t.Locker.Lock()
+	defer t.Locker.Unlock()
+	if transItem, ok := t.TransportMap[service.Info.ServiceName]; ok {
+		return transItem.Trans, nil
 	}
 
 	//todo 优化点5
-	if service.LoadBalance.UpstreamConnectTimeout == 0 {
-		service.LoadBalance.UpstreamConnectTimeout = 30
+	connectTimeout := service.LoadBalance.UpstreamConnectTimeout
+	if connectTimeout == 0 {
+		connectTimeout = 30
+	}
+	maxIdle := service.LoadBalance.UpstreamMaxIdle
+	if maxIdle == 0 {
+		maxIdle = 100
 	}
-	if service.LoadBalance.UpstreamMaxIdle == 0 {
-		service.LoadBalance.UpstreamMaxIdle = 100
+	idleTimeout := service.LoadBalance.UpstreamIdleTimeout
+	if idleTimeout == 0 {
+		idleTimeout = 90
 	}
-	if service.LoadBalance.UpstreamIdleTimeout == 0 {
-		service.LoadBalance.UpstreamIdleTimeout = 90
+	headerTimeout := service.LoadBalance.UpstreamHeaderTimeout
+	if headerTimeout == 0 {
+		headerTimeout = 30
 	}
-	if service.LoadBalance.UpstreamHeaderTimeout == 0 {
-		service.LoadBalance.UpstreamHeaderTimeout = 30
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(connectTimeout) * time.Second,
+		KeepAlive: 30 * time.Second,
+		DualStack: true,
 	}
-	trans := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout:   time.Duration(service.LoadBalance.UpstreamConnectTimeout) * time.Second,
-			KeepAlive: 30 * time.Second,
-			DualStack: true,
-		}).DialContext,
-		ForceAttemptHTTP2:     true,
-		MaxIdleConns:          service.LoadBalance.UpstreamMaxIdle,
-		IdleConnTimeout:       time.Duration(service.LoadBalance.UpstreamIdleTimeout) * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ResponseHeaderTimeout: time.Duration(service.LoadBalance.UpstreamHeaderTimeout) * time.Second,
+
+	var trans http.RoundTripper
+	if service.Info.LoadType == public.LoadTypeGRPC {
+		// 上游是明文 http2 (h2c)，DialTLS 直接建立 tcp 连接
+		trans = &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return dialer.Dial(network, addr)
+			},
+		}
+	} else {
+		trans = &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          maxIdle,
+			IdleConnTimeout:       time.Duration(idleTimeout) * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: time.Duration(headerTimeout) * time.Second,
+		}
 	}
 
+	// 上游请求挂在代理请求的 span 下
+	trans = &public.TraceTransport{Base: trans}
+
 	//save to map and slice
-	transItem := &TransportItem{
+	transItem = &TransportItem{
 		Trans:       trans,
 		ServiceName: service.Info.ServiceName,
 	}
 	t.TransportSlice = append(t.TransportSlice, transItem)
-	t.Locker.Lock()
-	defer t.Locker.Unlock()
 	t.TransportMap[service.Info.ServiceName] = transItem
 	return trans, nil
 }
+
+// Remove 删除服务缓存的连接池，空闲连接随之关闭
+func (t *Transportor) Remove(serviceName string) {
+	t.Locker.Lock()
+	defer t.Locker.Unlock()
+	slice := []*TransportItem{}
+	for _, transItem := range t.TransportSlice {
+		if transItem.ServiceName != serviceName {
+			slice = append(slice, transItem)
+			continue
+		}
+		if closer, ok := transItem.Trans.(interface{ CloseIdleConnections() }); ok {
+			closer.CloseIdleConnections()
+		}
+	}
+	t.TransportSlice = slice
+	delete(t.TransportMap, serviceName)
+}
@@ -6,7 +6,6 @@ import (
 	"github.com/e421083458/golang_common/lib"
 	"github.com/e421083458/gorm"
 	"github.com/gin-gonic/gin"
-	"net/http/httptest"
 	"sync"
 	"time"
 )
@@ -61,11 +60,30 @@ func (t *Renter) GetRenterList(c *gin.Context, tx *gorm.DB, params *dto.RenterLi
 	}
 	errCount := query.Count(&count).Error
 	if errCount != nil {
-		return nil, 0, err
+		return nil, 0, errCount
 	}
 	return list, count, nil
 }
 
+// ListAll 返回所有未删除的租户，按 id 升序
+func (t *Renter) ListAll(c *gin.Context, tx *gorm.DB) ([]Renter, error) {
+	var list []Renter
+	err := tx.SetCtx(public.GetGinTraceContext(c)).Table(t.TableName()).Where("is_delete=0").Order("id asc").Find(&list).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListIDs 只查询未删除租户的 renter_id
+func (t *Renter) ListIDs(c *gin.Context, tx *gorm.DB) (ids []string, err error) {
+	err = tx.SetCtx(public.GetGinTraceContext(c)).Table(t.TableName()).Where("is_delete=0").Order("id asc").Pluck("renter_id", &ids).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return ids, nil
+}
+
 var RenterManagerHandler *RenterManager
 
 func init() {
@@ -90,31 +108,46 @@ func NewRenterManager() *RenterManager {
 }
 
 func (s *RenterManager) GetRenterList() []*Renter {
-	return s.RenterList
+	s.Locker.RLock()
+	defer s.Locker.RUnlock()
+	return append([]*Renter{}, s.RenterList...)
+}
+
+func (s *RenterManager) GetRenter(renterID string) (*Renter, bool) {
+	s.Locker.RLock()
+	defer s.Locker.RUnlock()
+	renter, ok := s.RenterMap[renterID]
+	return renter, ok
 }
 
 func (s *RenterManager) LoadOnce() error {
 	s.init.Do(func() {
-		renterInfo := &Renter{}
-		c, _ := gin.CreateTestContext(httptest.NewRecorder())
-		tx, err := lib.GetGormPool("default")
-		if err != nil {
-			s.err = err
-			return
-		}
-		params := &dto.RenterListInput{PageNumber: 1, PageSize: 99999}
-		list, _, err := renterInfo.GetRenterList(c, tx, params)
-		if err != nil {
-			s.err = err
-			return
-		}
-		s.Locker.Lock()
-		defer s.Locker.Unlock()
-		for _, listItem := range list {
-			tmpItem := listItem
-			s.RenterMap[listItem.RenterID] = &tmpItem
-			s.RenterList = append(s.RenterList, &tmpItem)
-		}
+		s.err = s.Load()
 	})
 	return s.err
 }
+
+// Load 从数据库重新加载所有未删除的租户，整体替换缓存
+func (s *RenterManager) Load() error {
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		return err
+	}
+	renterInfo := &Renter{}
+	list, err := renterInfo.ListAll(public.NewBackgroundContext(), tx)
+	if err != nil {
+		return err
+	}
+	renterMap := map[string]*Renter{}
+	renterList := []*Renter{}
+	for _, listItem := range list {
+		tmpItem := listItem
+		renterMap[listItem.RenterID] = &tmpItem
+		renterList = append(renterList, &tmpItem)
+	}
+	s.Locker.Lock()
+	defer s.Locker.Unlock()
+	s.RenterMap = renterMap
+	s.RenterList = renterList
+	return nil
+}
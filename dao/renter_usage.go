@@ -0,0 +1,189 @@
+package dao
+
+import (
+	"fmt"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RenterUsage 租户每个服务每天的请求量，由 redis 日计数汇总而来，保留若干个月
+type RenterUsage struct {
+	ID           int64     `json:"id" gorm:"primary_key"`
+	RenterID     string    `json:"renter_id" gorm:"column:renter_id" description:"租户id"`
+	ServiceName  string    `json:"service_name" gorm:"column:service_name" description:"服务名称"`
+	Day          string    `json:"day" gorm:"column:day" description:"日期 格式: 2006-01-02"`
+	RequestCount int64     `json:"request_count" gorm:"column:request_count" description:"请求量"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at" description:"添加时间"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"column:updated_at" description:"更新时间"`
+}
+
+// 按服务或按天聚合后的用量
+type RenterUsageSum struct {
+	ServiceName  string `json:"service_name" gorm:"column:service_name"`
+	Day          string `json:"day" gorm:"column:day"`
+	RequestCount int64  `json:"request_count" gorm:"column:request_count"`
+}
+
+func (t *RenterUsage) TableName() string {
+	return "gateway_renter_usage_day"
+}
+
+func (t *RenterUsage) Find(c *gin.Context, tx *gorm.DB, search *RenterUsage) (*RenterUsage, error) {
+	model := &RenterUsage{}
+	err := tx.SetCtx(public.GetGinTraceContext(c)).Where(search).Find(model).Error
+	return model, err
+}
+
+func (t *RenterUsage) Save(c *gin.Context, tx *gorm.DB) error {
+	if err := tx.SetCtx(public.GetGinTraceContext(c)).Save(t).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// renterUsageUpsertSQL 依赖 (renter_id, service_name, day) 唯一索引，插入与更新在一条语句内完成
+var renterUsageUpsertSQL = map[string]string{
+	"mysql": "INSERT INTO gateway_renter_usage_day (renter_id, service_name, day, request_count, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?) " +
+		"ON DUPLICATE KEY UPDATE request_count=VALUES(request_count), updated_at=VALUES(updated_at)",
+	"sqlite3": "INSERT INTO gateway_renter_usage_day (renter_id, service_name, day, request_count, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?) " +
+		"ON CONFLICT (renter_id, service_name, day) DO UPDATE SET request_count=excluded.request_count, updated_at=excluded.updated_at",
+}
+
+// Upsert redis 中的日计数只增不减，直接覆盖当天的请求量
+func (t *RenterUsage) Upsert(c *gin.Context, tx *gorm.DB) error {
+	statement, ok := renterUsageUpsertSQL[tx.Dialect().GetName()]
+	if !ok {
+		return errors.Errorf("renter usage upsert does not support %s", tx.Dialect().GetName())
+	}
+	now := time.Now()
+	return tx.SetCtx(public.GetGinTraceContext(c)).
+		Exec(statement, t.RenterID, t.ServiceName, t.Day, t.RequestCount, now, now).Error
+}
+
+// ListByRange 返回租户在 [startDay, endDay] 之间每个服务每天的用量
+func (t *RenterUsage) ListByRange(c *gin.Context, tx *gorm.DB, renterID, startDay, endDay string) (list []RenterUsage, err error) {
+	err = tx.SetCtx(public.GetGinTraceContext(c)).Table(t.TableName()).
+		Where("renter_id=? and day>=? and day<=?", renterID, startDay, endDay).
+		Order("day asc, service_name asc").Find(&list).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return list, nil
+}
+
+// TopServices 按请求量从高到低返回租户调用最多的服务
+func (t *RenterUsage) TopServices(c *gin.Context, tx *gorm.DB, renterID, startDay, endDay string, limit int) (list []RenterUsageSum, err error) {
+	err = tx.SetCtx(public.GetGinTraceContext(c)).Table(t.TableName()).
+		Select("service_name, sum(request_count) as request_count").
+		Where("renter_id=? and day>=? and day<=?", renterID, startDay, endDay).
+		Group("service_name").Order("request_count desc").Limit(limit).
+		Scan(&list).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return list, nil
+}
+
+// DeleteBefore 删除 day 之前的数据
+func (t *RenterUsage) DeleteBefore(c *gin.Context, tx *gorm.DB, day string) error {
+	return tx.SetCtx(public.GetGinTraceContext(c)).Where("day<?", day).Delete(&RenterUsage{}).Error
+}
+
+var RenterUsageManagerHandler *RenterUsageManager
+
+func init() {
+	RenterUsageManagerHandler = NewRenterUsageManager()
+}
+
+type RenterUsageManager struct {
+	Interval        time.Duration
+	RetentionMonths int
+	once            sync.Once
+}
+
+func NewRenterUsageManager() *RenterUsageManager {
+	return &RenterUsageManager{
+		Interval:        public.RenterUsageRollupIntervalDefault * time.Second,
+		RetentionMonths: public.RenterUsageRetentionMonthsDefault,
+	}
+}
+
+// Start 定时把今天与昨天的 redis 计数汇总到数据库，并清理过期数据
+// 昨天的数据在 redis 中保留两天，跨天后再汇总一次保证完整
+func (s *RenterUsageManager) Start() {
+	s.once.Do(func() {
+		if interval := lib.GetIntConf("base.usage.rollup_interval"); interval > 0 {
+			s.Interval = time.Duration(interval) * time.Second
+		}
+		if months := lib.GetIntConf("base.usage.retention_months"); months > 0 {
+			s.RetentionMonths = months
+		}
+		go func() {
+			defer func() {
+				if err := recover(); err != nil {
+					fmt.Println(err)
+				}
+			}()
+			ticker := time.NewTicker(s.Interval)
+			for {
+				now := time.Now()
+				for _, day := range []time.Time{now.Add(-24 * time.Hour), now} {
+					if err := s.Rollup(day); err != nil {
+						fmt.Println("RenterUsageManager Rollup err", err)
+					}
+				}
+				if err := s.Cleanup(now); err != nil {
+					fmt.Println("RenterUsageManager Cleanup err", err)
+				}
+				<-ticker.C
+			}
+		}()
+	})
+}
+
+// Rollup 汇总某一天的 租户+服务 请求量
+func (s *RenterUsageManager) Rollup(day time.Time) error {
+	c := public.NewBackgroundContext()
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		return err
+	}
+	counts, err := public.ScanDayData(nil, day, public.FlowRenterServicePrefix)
+	if err != nil {
+		return err
+	}
+	dayStr := day.In(lib.TimeLocation).Format(public.UsageDateFormat)
+	for name, count := range counts {
+		index := strings.LastIndex(name, ":")
+		if index <= 0 {
+			continue
+		}
+		usage := &RenterUsage{
+			RenterID:     name[:index],
+			ServiceName:  name[index+1:],
+			Day:          dayStr,
+			RequestCount: count,
+		}
+		if err := usage.Upsert(c, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RenterUsageManager) Cleanup(now time.Time) error {
+	c := public.NewBackgroundContext()
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		return err
+	}
+	day := now.In(lib.TimeLocation).AddDate(0, -s.RetentionMonths, 0).Format(public.UsageDateFormat)
+	usage := &RenterUsage{}
+	return usage.DeleteBefore(c, tx, day)
+}
@@ -1,10 +1,29 @@
 package dao
 
+import (
+	"github.com/JunxiHe459/gateway/public"
+	"log"
+)
+
 type ServiceDetail struct {
 	Info          *ServiceInfo   `json:"info" description:"基本信息"`
 	HTTPRule      *HttpRule      `json:"http_rule" description:"http_rule"`
+	HTTPRoutes    []HttpRoute    `json:"http_routes" description:"http_routes"`
 	TCPRule       *TcpRule       `json:"tcp_rule" description:"tcp_rule"`
 	GRPCRule      *GrpcRule      `json:"grpc_rule" description:"grpc_rule"`
 	LoadBalance   *LoadBalance   `json:"load_balance" description:"load_balance"`
 	AccessControl *AccessControl `json:"access_control" description:"access_control"`
 }
+
+// ServiceChangeHandler 代理进程通过 public.WatchServiceChange 订阅，重新加载服务列表并清理变更服务的缓存，下一次请求按新配置重建
+// 改名时新旧名称的缓存都清理，tcp/grpc 监听由调用方按新的服务列表调整
+func ServiceChangeHandler(event *public.ServiceChangeEvent) {
+	if err := ServiceManagerHandler.Load(); err != nil {
+		log.Printf(" [ERROR] reload services err:%v\n", err)
+	}
+	for _, serviceName := range event.ServiceNames() {
+		LoadBalancerHandler.Remove(serviceName)
+		TransportorHandler.Remove(serviceName)
+		public.CircuitBreakerHandler.Remove(serviceName)
+	}
+}
@@ -27,7 +27,7 @@ func (service *ServiceInfo) TableName() string {
 func (service *ServiceInfo) Find(c *gin.Context, db *gorm.DB, search *ServiceInfo) (*ServiceInfo, error) {
 	out := &ServiceInfo{}
 	fmt.Printf("%+v\n", search)
-	err := db.SetCtx(public.GetGinTraceContext(c)).Where("is_delete = ? AND service_name = ?", 0, search.ServiceName).First(out).Error
+	err := db.SetCtx(public.GetGinTraceContext(c)).Where("is_delete = ?", 0).Where(search).First(out).Error
 	fmt.Printf("%+v\n", out)
 	if err != nil {
 		println("hahha")
@@ -76,6 +76,24 @@ func (service *ServiceInfo) GetPageList(c *gin.Context, db *gorm.DB, param *dto.
 	return
 }
 
+// ListAll 返回所有未删除的服务，按 id 升序
+func (service *ServiceInfo) ListAll(c *gin.Context, db *gorm.DB) (list []ServiceInfo, err error) {
+	err = db.SetCtx(public.GetGinTraceContext(c)).Table(service.TableName()).Where("is_delete=0").Order("id asc").Find(&list).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListNames 只查询未删除服务的名称
+func (service *ServiceInfo) ListNames(c *gin.Context, db *gorm.DB) (names []string, err error) {
+	err = db.SetCtx(public.GetGinTraceContext(c)).Table(service.TableName()).Where("is_delete=0").Order("id asc").Pluck("service_name", &names).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return names, nil
+}
+
 func (service *ServiceInfo) GetServiceDetail(c *gin.Context, db *gorm.DB, info *ServiceInfo) (detail *ServiceDetail, err error) {
 	if info.ServiceName == "" {
 		search, err := service.Find(c, global.DB, info)
@@ -91,6 +109,12 @@ func (service *ServiceInfo) GetServiceDetail(c *gin.Context, db *gorm.DB, info *
 		return
 	}
 
+	route := &HttpRoute{}
+	routes, _, err := route.ListByServiceID(c, db, info.ID)
+	if err != nil {
+		return
+	}
+
 	tcp := &TcpRule{ServiceID: info.ID}
 	tcp, err = tcp.Find(c, db, tcp)
 	if err != nil && err != gorm.ErrRecordNotFound {
@@ -118,6 +142,7 @@ func (service *ServiceInfo) GetServiceDetail(c *gin.Context, db *gorm.DB, info *
 	detail = &ServiceDetail{
 		Info:          info,
 		HTTPRule:      http,
+		HTTPRoutes:    routes,
 		TCPRule:       tcp,
 		GRPCRule:      grpc,
 		AccessControl: access,
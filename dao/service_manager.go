@@ -0,0 +1,136 @@
+package dao
+
+import (
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"net"
+	"strings"
+	"sync"
+)
+
+var ServiceManagerHandler *ServiceManager
+
+func init() {
+	ServiceManagerHandler = NewServiceManager()
+}
+
+// ServiceManager 代理使用的服务配置缓存，Load 整体替换，读取时不会看到加载了一半的数据
+type ServiceManager struct {
+	ServiceMap   map[string]*ServiceDetail
+	ServiceSlice []*ServiceDetail
+	Locker       sync.RWMutex
+	init         sync.Once
+	err          error
+}
+
+func NewServiceManager() *ServiceManager {
+	return &ServiceManager{
+		ServiceMap:   map[string]*ServiceDetail{},
+		ServiceSlice: []*ServiceDetail{},
+		Locker:       sync.RWMutex{},
+		init:         sync.Once{},
+	}
+}
+
+func (s *ServiceManager) LoadOnce() error {
+	s.init.Do(func() {
+		s.err = s.Load()
+	})
+	return s.err
+}
+
+// Load 从数据库重新加载所有未删除的服务
+func (s *ServiceManager) Load() error {
+	c := public.NewBackgroundContext()
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		return err
+	}
+	serviceInfo := &ServiceInfo{}
+	list, err := serviceInfo.ListAll(c, tx)
+	if err != nil {
+		return err
+	}
+	serviceMap := map[string]*ServiceDetail{}
+	serviceSlice := []*ServiceDetail{}
+	for _, listItem := range list {
+		tmpItem := listItem
+		serviceDetail, err := serviceInfo.GetServiceDetail(c, tx, &tmpItem)
+		if err != nil {
+			return err
+		}
+		serviceMap[listItem.ServiceName] = serviceDetail
+		serviceSlice = append(serviceSlice, serviceDetail)
+	}
+	s.Locker.Lock()
+	defer s.Locker.Unlock()
+	s.ServiceMap = serviceMap
+	s.ServiceSlice = serviceSlice
+	return nil
+}
+
+func (s *ServiceManager) GetServiceList() []*ServiceDetail {
+	s.Locker.RLock()
+	defer s.Locker.RUnlock()
+	return append([]*ServiceDetail{}, s.ServiceSlice...)
+}
+
+func (s *ServiceManager) GetService(serviceName string) (*ServiceDetail, bool) {
+	s.Locker.RLock()
+	defer s.Locker.RUnlock()
+	serviceDetail, ok := s.ServiceMap[serviceName]
+	return serviceDetail, ok
+}
+
+func (s *ServiceManager) GetTcpServiceList() []*ServiceDetail {
+	return s.getServiceListByLoadType(public.LoadTypeTCP)
+}
+
+func (s *ServiceManager) GetGrpcServiceList() []*ServiceDetail {
+	return s.getServiceListByLoadType(public.LoadTypeGRPC)
+}
+
+func (s *ServiceManager) getServiceListByLoadType(loadType int) []*ServiceDetail {
+	list := []*ServiceDetail{}
+	for _, serviceItem := range s.GetServiceList() {
+		if serviceItem.Info.LoadType == loadType {
+			list = append(list, serviceItem)
+		}
+	}
+	return list
+}
+
+// HTTPAccessMode 按请求匹配 http 服务，域名规则优先，前缀规则取最长的一条
+func (s *ServiceManager) HTTPAccessMode(c *gin.Context) (*ServiceDetail, error) {
+	host := c.Request.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	path := c.Request.URL.Path
+
+	var matched *ServiceDetail
+	for _, serviceItem := range s.GetServiceList() {
+		if serviceItem.Info.LoadType != public.LoadTypeHTTP {
+			continue
+		}
+		switch serviceItem.HTTPRule.RuleType {
+		case public.HTTPDomain:
+			if serviceItem.HTTPRule.Rule == host {
+				return serviceItem, nil
+			}
+		case public.HTTPPrefixURL:
+			if serviceItem.HTTPRule.Rule == "" || !strings.HasPrefix(path, serviceItem.HTTPRule.Rule) {
+				continue
+			}
+			if matched == nil || len(serviceItem.HTTPRule.Rule) > len(matched.HTTPRule.Rule) {
+				matched = serviceItem
+			}
+		}
+	}
+	if matched == nil {
+		return nil, errors.New("not matched service")
+	}
+	return matched, nil
+}
@@ -0,0 +1,91 @@
+package dao_test
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/public"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestServiceChangeHandlerReloadsRenamedService(t *testing.T) {
+	db := openTestDB(t)
+	c := public.NewBackgroundContext()
+
+	service := &dao.ServiceInfo{ServiceName: "change_demo", LoadType: public.LoadTypeHTTP}
+	if err := service.Save(c, db); err != nil {
+		t.Fatal(err)
+	}
+	loadBalance := &dao.LoadBalance{ServiceID: service.ID, IpList: "127.0.0.1:80", WeightList: "50"}
+	if err := loadBalance.Save(c, db); err != nil {
+		t.Fatal(err)
+	}
+	dao.ServiceChangeHandler(&public.ServiceChangeEvent{ServiceID: service.ID, ServiceName: service.ServiceName})
+	if _, ok := dao.ServiceManagerHandler.GetService("change_demo"); !ok {
+		t.Fatal("added service was not loaded")
+	}
+
+	service.ServiceName = "change_demo_renamed"
+	if err := service.Save(c, db); err != nil {
+		t.Fatal(err)
+	}
+	dao.ServiceChangeHandler(&public.ServiceChangeEvent{ServiceID: service.ID,
+		ServiceName: service.ServiceName, OldServiceName: "change_demo"})
+	if _, ok := dao.ServiceManagerHandler.GetService("change_demo"); ok {
+		t.Fatal("old service name is still loaded")
+	}
+	if _, ok := dao.ServiceManagerHandler.GetService("change_demo_renamed"); !ok {
+		t.Fatal("renamed service was not loaded")
+	}
+}
+
+func TestServiceVersionCreateConcurrent(t *testing.T) {
+	db := openTestDB(t)
+	c := public.NewBackgroundContext()
+
+	service := &dao.ServiceInfo{ServiceName: "version_demo", LoadType: public.LoadTypeHTTP}
+	if err := service.Save(c, db); err != nil {
+		t.Fatal(err)
+	}
+	detail := &dao.ServiceDetail{Info: service}
+
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx := db.Begin()
+			version := &dao.ServiceVersion{Action: public.AuditActionUpdate}
+			if err := version.Create(c, tx, detail); err != nil {
+				tx.Rollback()
+				errs <- err
+				return
+			}
+			errs <- tx.Commit().Error
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	versions := []int64{}
+	if err := db.Table((&dao.ServiceVersion{}).TableName()).Where("service_id=?", service.ID).
+		Pluck("version", &versions).Error; err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	if len(versions) != writers {
+		t.Fatalf("versions = %v, want %d rows", versions, writers)
+	}
+	for index, version := range versions {
+		if version != int64(index+1) {
+			t.Fatalf("versions = %v, want 1..%d", versions, writers)
+		}
+	}
+}
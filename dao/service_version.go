@@ -0,0 +1,113 @@
+package dao
+
+import (
+	"encoding/json"
+	"github.com/JunxiHe459/gateway/dto"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+// ServiceVersion 服务每次保存后的完整配置，只追加不修改，回滚时按快照恢复
+type ServiceVersion struct {
+	ID        int64     `json:"id" gorm:"primary_key"`
+	ServiceID int64     `json:"service_id" gorm:"column:service_id" description:"服务id"`
+	Version   int64     `json:"version" gorm:"column:version" description:"版本号，每个服务从 1 开始递增"`
+	Action    string    `json:"action" gorm:"column:action" description:"产生版本的操作 baseline/add/update/rollback"`
+	Detail    string    `json:"detail" gorm:"column:detail" description:"ServiceDetail json"`
+	AdminID   int       `json:"admin_id" gorm:"column:admin_id" description:"管理员id"`
+	AdminName string    `json:"admin_name" gorm:"column:admin_name" description:"管理员用户名"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at" description:"添加时间"`
+}
+
+func (t *ServiceVersion) TableName() string {
+	return "gateway_service_version"
+}
+
+func (t *ServiceVersion) Find(c *gin.Context, tx *gorm.DB, search *ServiceVersion) (*ServiceVersion, error) {
+	model := &ServiceVersion{}
+	err := tx.SetCtx(public.GetGinTraceContext(c)).Where(search).First(model).Error
+	return model, err
+}
+
+// Create 取当前最大版本号加一后写入，需要在保存服务的同一个事务中调用
+func (t *ServiceVersion) Create(c *gin.Context, tx *gorm.DB, detail *ServiceDetail) error {
+	raw, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+	if err := t.lockService(c, tx, detail.Info.ID); err != nil {
+		return err
+	}
+	latest, err := t.Latest(c, tx, detail.Info.ID)
+	if err != nil {
+		return err
+	}
+	t.ID = 0
+	t.ServiceID = detail.Info.ID
+	t.Version = latest + 1
+	t.Detail = string(raw)
+	return tx.SetCtx(public.GetGinTraceContext(c)).Create(t).Error
+}
+
+// EnsureBaseline 服务还没有任何版本时，把修改前的配置保存为第一个版本，保证第一次修改也能回滚
+func (t *ServiceVersion) EnsureBaseline(c *gin.Context, tx *gorm.DB, detail *ServiceDetail) error {
+	if err := t.lockService(c, tx, detail.Info.ID); err != nil {
+		return err
+	}
+	latest, err := t.Latest(c, tx, detail.Info.ID)
+	if err != nil || latest > 0 {
+		return err
+	}
+	baseline := &ServiceVersion{Action: public.ServiceVersionBaseline}
+	return baseline.Create(c, tx, detail)
+}
+
+// lockService 计算版本号前锁住服务行，同一个服务的并发保存排队执行，不会算出相同的版本号
+// mysql 使用 FOR UPDATE，sqlite 的写事务开始时已经持有数据库写锁
+func (t *ServiceVersion) lockService(c *gin.Context, tx *gorm.DB, serviceID int64) error {
+	if tx.Dialect().GetName() != "mysql" {
+		return nil
+	}
+	return tx.SetCtx(public.GetGinTraceContext(c)).Set("gorm:query_option", "FOR UPDATE").
+		Where("id=?", serviceID).First(&ServiceInfo{}).Error
+}
+
+// Latest 返回服务当前最大的版本号，没有版本时为 0
+func (t *ServiceVersion) Latest(c *gin.Context, tx *gorm.DB, serviceID int64) (int64, error) {
+	var latest int64
+	row := tx.SetCtx(public.GetGinTraceContext(c)).Table(t.TableName()).
+		Where("service_id=?", serviceID).Select("ifnull(max(version),0)").Row()
+	if err := row.Scan(&latest); err != nil {
+		return 0, err
+	}
+	return latest, nil
+}
+
+// PageList 按版本号倒序分页，不返回快照内容
+func (t *ServiceVersion) PageList(c *gin.Context, tx *gorm.DB, params *dto.ServiceVersionListInput) ([]ServiceVersion, int64, error) {
+	var list []ServiceVersion
+	var count int64
+	offset := (params.PageNumber - 1) * params.PageSize
+	query := tx.SetCtx(public.GetGinTraceContext(c))
+	query = query.Table(t.TableName()).Where("service_id=?", params.ID)
+	err := query.Select("id, service_id, version, action, admin_id, admin_name, created_at").
+		Limit(params.PageSize).Offset(offset).Order("version desc").Find(&list).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, 0, err
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, count, nil
+}
+
+// ServiceDetail 解析版本快照
+func (t *ServiceVersion) ServiceDetail() (*ServiceDetail, error) {
+	detail := &ServiceDetail{}
+	if err := json.Unmarshal([]byte(t.Detail), detail); err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
@@ -0,0 +1,67 @@
+package dao
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/e421083458/gorm"
+	_ "github.com/e421083458/gorm/dialects/sqlite"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// 存储后端由 base.storage.driver 选择，默认 mysql 读取 mysql_map.toml
+// sqlite3 使用单个数据库文件，适合单机部署与集成测试，连接同样注册为 default，dao 层无需区分
+// sqlite3 驱动依赖 cgo，编译时需要 CGO_ENABLED=1 与 C 编译器；只使用 mysql 时可以用 CGO_ENABLED=0 编译，此时选择 sqlite3 会在启动时报错
+const (
+	StorageDriverMysql  = "mysql"
+	StorageDriverSqlite = "sqlite3"
+
+	defaultSqlitePath = "./gateway.db"
+)
+
+// StorageDriver 返回配置的存储后端
+func StorageDriver() string {
+	if driver := lib.GetStringConf("base.storage.driver"); driver != "" {
+		return driver
+	}
+	return StorageDriverMysql
+}
+
+// InitStorage 按配置初始化 default 数据库连接
+func InitStorage() error {
+	switch driver := StorageDriver(); driver {
+	case StorageDriverMysql:
+		return lib.InitDBPool(lib.GetConfPath("mysql_map"))
+	case StorageDriverSqlite:
+		path := lib.GetStringConf("base.storage.sqlite_path")
+		if path == "" {
+			path = defaultSqlitePath
+		}
+		return initSqlitePool(path)
+	default:
+		return errors.Errorf("unknown storage driver %s", driver)
+	}
+}
+
+// initSqlitePool 写事务使用 immediate 锁，busy_timeout 内等待其他连接的写锁而不是直接报 database is locked
+// DBMapPool 与 GORMMapPool 共用 gorm 的连接池，连接数与超时设置只有一份
+func initSqlitePool(path string) error {
+	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate", path)
+	dbgorm, err := gorm.Open(StorageDriverSqlite, dsn)
+	if err != nil {
+		return err
+	}
+	dbpool := dbgorm.DB()
+	dbgorm.SingularTable(true)
+	dbgorm.LogMode(true)
+	dbgorm.LogCtx(true)
+	dbgorm.SetLogger(&lib.MysqlGormLogger{Trace: lib.NewTrace()})
+	dbgorm.DB().SetConnMaxLifetime(time.Hour)
+
+	lib.DBMapPool = map[string]*sql.DB{"default": dbpool}
+	lib.GORMMapPool = map[string]*gorm.DB{"default": dbgorm}
+	lib.DBDefaultPool = dbpool
+	lib.GORMDefaultPool = dbgorm
+	return nil
+}
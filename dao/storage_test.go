@@ -0,0 +1,97 @@
+package dao_test
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/migration"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/spf13/viper"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// useSqliteStorage 按 base.storage 配置初始化 sqlite 连接，结束后恢复原来的配置与连接
+func useSqliteStorage(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "gateway.db")
+	oldConf, oldDBPool, oldGormPool := lib.ViperConfMap, lib.DBMapPool, lib.GORMMapPool
+	oldDBDefault, oldGormDefault := lib.DBDefaultPool, lib.GORMDefaultPool
+	base := viper.New()
+	base.Set("storage.driver", dao.StorageDriverSqlite)
+	base.Set("storage.sqlite_path", path)
+	lib.ViperConfMap = map[string]*viper.Viper{"base": base}
+	t.Cleanup(func() {
+		if db, ok := lib.GORMMapPool["default"]; ok {
+			db.Close()
+		}
+		lib.ViperConfMap, lib.DBMapPool, lib.GORMMapPool = oldConf, oldDBPool, oldGormPool
+		lib.DBDefaultPool, lib.GORMDefaultPool = oldDBDefault, oldGormDefault
+	})
+	if err := dao.InitStorage(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInitStorageSqliteSharesPool(t *testing.T) {
+	useSqliteStorage(t)
+	db, err := lib.GetGormPool("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.DB() != lib.DBMapPool["default"] || lib.DBDefaultPool != lib.DBMapPool["default"] {
+		t.Fatal("sql and gorm pools are not shared")
+	}
+	var journalMode string
+	if err := lib.DBMapPool["default"].QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		t.Fatal(err)
+	}
+	if journalMode != "wal" {
+		t.Fatalf("journal_mode = %s, want wal", journalMode)
+	}
+}
+
+func TestInitStorageSqliteConcurrentWrites(t *testing.T) {
+	useSqliteStorage(t)
+	db, err := lib.GetGormPool("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := public.NewBackgroundContext()
+	if _, err := migration.Up(c, db, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// 写事务使用 immediate 锁并等待 busy_timeout，并发写入不会报 database is locked
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tx := db.Begin()
+			renter := &dao.Renter{RenterID: "renter_" + string(rune('a'+i)), Name: "demo"}
+			if err := renter.Save(c, tx); err != nil {
+				tx.Rollback()
+				errs <- err
+				return
+			}
+			errs <- tx.Commit().Error
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := (&dao.Renter{}).ListAll(c, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != writers {
+		t.Fatalf("%d renters saved, want %d", len(list), writers)
+	}
+}
@@ -93,9 +93,9 @@ var doc = `{
                 }
             }
         },
-        "/admin/login": {
+        "/admin/info/totp/disable": {
             "post": {
-                "description": "Admin Login 接口",
+                "description": "输入验证码或恢复码关闭两步验证，角色要求两步验证时不能关闭",
                 "consumes": [
                     "application/json"
                 ],
@@ -105,8 +105,8 @@ var doc = `{
                 "tags": [
                     "Admin"
                 ],
-                "summary": "Admin Login",
-                "operationId": "/admin/login",
+                "summary": "Admin TOTP Disable",
+                "operationId": "/admin/info/totp/disable",
                 "parameters": [
                     {
                         "description": "body",
@@ -114,7 +114,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.AdminLoginInput"
+                            "$ref": "#/definitions/dto.AdminTOTPCodeInput"
                         }
                     }
                 ],
@@ -130,7 +130,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/dto.AdminLoginOutput"
+                                            "type": "string"
                                         }
                                     }
                                 }
@@ -140,9 +140,9 @@ var doc = `{
                 }
             }
         },
-        "/admin/logout": {
-            "get": {
-                "description": "Admin Log out 接口",
+        "/admin/info/totp/enable": {
+            "post": {
+                "description": "输入验证器 App 中的验证码完成绑定，返回一次性恢复码",
                 "consumes": [
                     "application/json"
                 ],
@@ -152,44 +152,19 @@ var doc = `{
                 "tags": [
                     "Admin"
                 ],
-                "summary": "Admin Log out",
-                "operationId": "/admin/logout",
-                "responses": {
-                    "200": {
-                        "description": "success",
+                "summary": "Admin TOTP Enable",
+                "operationId": "/admin/info/totp/enable",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
                         "schema": {
-                            "allOf": [
-                                {
-                                    "$ref": "#/definitions/middleware.Response"
-                                },
-                                {
-                                    "type": "object",
-                                    "properties": {
-                                        "data": {
-                                            "type": "string"
-                                        }
-                                    }
-                                }
-                            ]
+                            "$ref": "#/definitions/dto.AdminTOTPCodeInput"
                         }
                     }
-                }
-            }
-        },
-        "/dashboard/flow_stat": {
-            "get": {
-                "description": "流量统计",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Dashboard"
                 ],
-                "summary": "Flow Stats",
-                "operationId": "/dashboard/flow_stat",
                 "responses": {
                     "200": {
                         "description": "success",
@@ -202,7 +177,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/dto.ServiceStatsOutput"
+                                            "$ref": "#/definitions/dto.AdminTOTPRecoveryCodesOutput"
                                         }
                                     }
                                 }
@@ -212,9 +187,9 @@ var doc = `{
                 }
             }
         },
-        "/dashboard/panel_group_data": {
-            "get": {
-                "description": "指标统计",
+        "/admin/info/totp/recovery_codes": {
+            "post": {
+                "description": "输入验证码重新生成恢复码，之前的恢复码作废",
                 "consumes": [
                     "application/json"
                 ],
@@ -222,10 +197,21 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Dashboard"
+                    "Admin"
+                ],
+                "summary": "Admin TOTP Recovery Codes",
+                "operationId": "/admin/info/totp/recovery_codes",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AdminTOTPCodeInput"
+                        }
+                    }
                 ],
-                "summary": "General Stats",
-                "operationId": "/dashboard/panel_group_data",
                 "responses": {
                     "200": {
                         "description": "success",
@@ -238,7 +224,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/dto.PanelGroupDataOutput"
+                                            "$ref": "#/definitions/dto.AdminTOTPRecoveryCodesOutput"
                                         }
                                     }
                                 }
@@ -248,9 +234,9 @@ var doc = `{
                 }
             }
         },
-        "/dashboard/service_stat": {
-            "get": {
-                "description": "服务统计",
+        "/admin/info/totp/setup": {
+            "post": {
+                "description": "生成两步验证密钥，secret_uri 用于生成二维码，之后调用 /admin/info/totp/enable 完成绑定",
                 "consumes": [
                     "application/json"
                 ],
@@ -258,10 +244,10 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Dashboard"
+                    "Admin"
                 ],
-                "summary": "Service Stats",
-                "operationId": "/dashboard/service_stat",
+                "summary": "Admin TOTP Setup",
+                "operationId": "/admin/info/totp/setup",
                 "responses": {
                     "200": {
                         "description": "success",
@@ -274,7 +260,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/dto.DashServiceStatOutput"
+                                            "$ref": "#/definitions/dto.AdminTOTPSetupOutput"
                                         }
                                     }
                                 }
@@ -284,9 +270,9 @@ var doc = `{
                 }
             }
         },
-        "/renter/add_renter": {
+        "/admin/login": {
             "post": {
-                "description": "租户添加",
+                "description": "Admin Login 接口，开启两步验证或角色要求两步验证时 two_factor 不为空，需要继续调用 /admin/login/totp",
                 "consumes": [
                     "application/json"
                 ],
@@ -294,10 +280,10 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Renter Management"
+                    "Admin"
                 ],
-                "summary": "Add Renter",
-                "operationId": "/renter/add_renter",
+                "summary": "Admin Login",
+                "operationId": "/admin/login",
                 "parameters": [
                     {
                         "description": "body",
@@ -305,7 +291,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.AddRenterHttpInput"
+                            "$ref": "#/definitions/dto.AdminLoginInput"
                         }
                     }
                 ],
@@ -321,7 +307,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "string"
+                                            "$ref": "#/definitions/dto.AdminLoginOutput"
                                         }
                                     }
                                 }
@@ -331,9 +317,9 @@ var doc = `{
                 }
             }
         },
-        "/renter/delete_renter": {
-            "get": {
-                "description": "租户删除",
+        "/admin/login/totp": {
+            "post": {
+                "description": "登录第二步，输入验证码或恢复码；角色要求两步验证但未绑定时，先调用 /admin/login/totp_setup，验证通过后返回恢复码",
                 "consumes": [
                     "application/json"
                 ],
@@ -341,17 +327,19 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Renter Management"
+                    "Admin"
                 ],
-                "summary": "Delete Renter",
-                "operationId": "/renter/delete_renter",
+                "summary": "Admin Login TOTP",
+                "operationId": "/admin/login/totp",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "租户ID",
-                        "name": "id",
-                        "in": "query",
-                        "required": true
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AdminTOTPCodeInput"
+                        }
                     }
                 ],
                 "responses": {
@@ -366,7 +354,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "string"
+                                            "$ref": "#/definitions/dto.AdminLoginTOTPOutput"
                                         }
                                     }
                                 }
@@ -376,9 +364,9 @@ var doc = `{
                 }
             }
         },
-        "/renter/renter_detail": {
-            "get": {
-                "description": "租户详情",
+        "/admin/login/totp_setup": {
+            "post": {
+                "description": "角色要求两步验证但未绑定时，登录第一步之后生成密钥，再调用 /admin/login/totp 完成绑定",
                 "consumes": [
                     "application/json"
                 ],
@@ -386,19 +374,10 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Renter Management"
-                ],
-                "summary": "Renter Info",
-                "operationId": "/renter/renter_detail",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "租户ID",
-                        "name": "id",
-                        "in": "query",
-                        "required": true
-                    }
+                    "Admin"
                 ],
+                "summary": "Admin Login TOTP Setup",
+                "operationId": "/admin/login/totp_setup",
                 "responses": {
                     "200": {
                         "description": "success",
@@ -411,7 +390,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/dao.Renter"
+                                            "$ref": "#/definitions/dto.AdminTOTPSetupOutput"
                                         }
                                     }
                                 }
@@ -421,9 +400,9 @@ var doc = `{
                 }
             }
         },
-        "/renter/renter_list": {
+        "/admin/logout": {
             "get": {
-                "description": "租户列表",
+                "description": "Admin Log out 接口",
                 "consumes": [
                     "application/json"
                 ],
@@ -431,32 +410,10 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Renter Management"
-                ],
-                "summary": "Renter list",
-                "operationId": "/renter/renter_list",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "关键词",
-                        "name": "info",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "每页多少条",
-                        "name": "page_size",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "页码",
-                        "name": "page_no",
-                        "in": "query",
-                        "required": true
-                    }
+                    "Admin"
                 ],
+                "summary": "Admin Log out",
+                "operationId": "/admin/logout",
                 "responses": {
                     "200": {
                         "description": "success",
@@ -469,7 +426,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/dto.RenterListOutput"
+                                            "type": "string"
                                         }
                                     }
                                 }
@@ -479,9 +436,9 @@ var doc = `{
                 }
             }
         },
-        "/renter/renter_stats": {
-            "get": {
-                "description": "租户统计",
+        "/admin_token/add": {
+            "post": {
+                "description": "创建 API token，请求时放在 Authorization: Bearer 中，明文只在创建时返回一次",
                 "consumes": [
                     "application/json"
                 ],
@@ -489,17 +446,19 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Renter Management"
+                    "Admin"
                 ],
-                "summary": "Renter Stats",
-                "operationId": "/renter/renter_stats",
+                "summary": "Add admin token",
+                "operationId": "/admin_token/add",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "租户ID",
-                        "name": "id",
-                        "in": "query",
-                        "required": true
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AdminTokenAddInput"
+                        }
                     }
                 ],
                 "responses": {
@@ -514,7 +473,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/dto.StatisticsOutput"
+                                            "$ref": "#/definitions/dto.AdminTokenAddOutput"
                                         }
                                     }
                                 }
@@ -524,9 +483,9 @@ var doc = `{
                 }
             }
         },
-        "/renter/update_renter": {
+        "/admin_token/jwt": {
             "post": {
-                "description": "租户更新",
+                "description": "使用 Authorization: Bearer 中的 API token 换取 JWT，权限与有效期不超过该 API token，API token 吊销后 JWT 同时失效",
                 "consumes": [
                     "application/json"
                 ],
@@ -534,10 +493,10 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Renter Management"
+                    "Admin"
                 ],
-                "summary": "Update Renter",
-                "operationId": "/renter/update_renter",
+                "summary": "Issue admin JWT",
+                "operationId": "/admin_token/jwt",
                 "parameters": [
                     {
                         "description": "body",
@@ -545,7 +504,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.UpdateRenterHttpInput"
+                            "$ref": "#/definitions/dto.AdminJwtInput"
                         }
                     }
                 ],
@@ -561,7 +520,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "string"
+                                            "$ref": "#/definitions/dto.AdminJwtOutput"
                                         }
                                     }
                                 }
@@ -571,9 +530,9 @@ var doc = `{
                 }
             }
         },
-        "/service/add_grpc": {
-            "post": {
-                "description": "grpc服务添加",
+        "/admin_token/list": {
+            "get": {
+                "description": "API token 列表，不返回 token 明文",
                 "consumes": [
                     "application/json"
                 ],
@@ -581,19 +540,16 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Service Management"
+                    "Admin"
                 ],
-                "summary": "Add a new GRPC service",
-                "operationId": "/service/service_add_grpc",
+                "summary": "Admin token list",
+                "operationId": "/admin_token/list",
                 "parameters": [
                     {
-                        "description": "body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.ServiceAddGrpcInput"
-                        }
+                        "type": "integer",
+                        "description": "管理员ID，0 表示全部，只有 admin:manage 可以查看其他管理员",
+                        "name": "admin_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -608,7 +564,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "string"
+                                            "$ref": "#/definitions/dto.AdminTokenListOutput"
                                         }
                                     }
                                 }
@@ -618,9 +574,9 @@ var doc = `{
                 }
             }
         },
-        "/service/add_http": {
+        "/admin_token/revoke": {
             "post": {
-                "description": "添加一个新的 HTTP 服务",
+                "description": "吊销 API token，只能吊销自己的，admin:manage 可以吊销所有管理员的",
                 "consumes": [
                     "application/json"
                 ],
@@ -628,10 +584,10 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Service Management"
+                    "Admin"
                 ],
-                "summary": "Add a new HTTP Service",
-                "operationId": "/service/add_http",
+                "summary": "Revoke admin token",
+                "operationId": "/admin_token/revoke",
                 "parameters": [
                     {
                         "description": "body",
@@ -639,7 +595,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.ServiceAddHTTPInput"
+                            "$ref": "#/definitions/dto.AdminTokenRevokeInput"
                         }
                     }
                 ],
@@ -655,7 +611,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/dto.ServiceListOutput"
+                                            "type": "string"
                                         }
                                     }
                                 }
@@ -665,9 +621,9 @@ var doc = `{
                 }
             }
         },
-        "/service/add_tcp": {
+        "/admin_user/add": {
             "post": {
-                "description": "tcp服务添加",
+                "description": "添加管理员，角色为 super_admin、service_operator、renter_manager 或 read_only",
                 "consumes": [
                     "application/json"
                 ],
@@ -675,10 +631,10 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Service Management"
+                    "Admin"
                 ],
-                "summary": "Add a new TCP service",
-                "operationId": "/service/service_add_tcp",
+                "summary": "Add admin user",
+                "operationId": "/admin_user/add",
                 "parameters": [
                     {
                         "description": "body",
@@ -686,7 +642,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.ServiceAddTcpInput"
+                            "$ref": "#/definitions/dto.AddAdminUserInput"
                         }
                     }
                 ],
@@ -712,9 +668,9 @@ var doc = `{
                 }
             }
         },
-        "/service/delete": {
+        "/admin_user/delete": {
             "get": {
-                "description": "删除一个服务",
+                "description": "删除管理员，不能删除自己",
                 "consumes": [
                     "application/json"
                 ],
@@ -722,14 +678,14 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Service Management"
+                    "Admin"
                 ],
-                "summary": "Delete service",
-                "operationId": "/service/delete",
+                "summary": "Delete admin user",
+                "operationId": "/admin_user/delete",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "服务 ID",
+                        "description": "管理员ID",
                         "name": "id",
                         "in": "query",
                         "required": true
@@ -757,9 +713,9 @@ var doc = `{
                 }
             }
         },
-        "/service/service_details": {
+        "/admin_user/list": {
             "get": {
-                "description": "服务详情",
+                "description": "管理员列表",
                 "consumes": [
                     "application/json"
                 ],
@@ -767,19 +723,10 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Service Management"
-                ],
-                "summary": "Details of a service",
-                "operationId": "/service/service_details",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "ID",
-                        "name": "ID",
-                        "in": "query",
-                        "required": true
-                    }
+                    "Admin"
                 ],
+                "summary": "Admin user list",
+                "operationId": "/admin_user/list",
                 "responses": {
                     "200": {
                         "description": "success",
@@ -792,7 +739,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/dao.ServiceDetail"
+                                            "$ref": "#/definitions/dto.AdminUserListOutput"
                                         }
                                     }
                                 }
@@ -802,9 +749,9 @@ var doc = `{
                 }
             }
         },
-        "/service/service_list": {
-            "get": {
-                "description": "服务列表",
+        "/admin_user/update": {
+            "post": {
+                "description": "修改管理员角色、禁用状态、重置密码或两步验证，必须保留至少一个可用的超级管理员",
                 "consumes": [
                     "application/json"
                 ],
@@ -812,30 +759,19 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Service Management"
+                    "Admin"
                 ],
-                "summary": "Service List",
-                "operationId": "/service/service_list",
+                "summary": "Update admin user",
+                "operationId": "/admin_user/update",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "关键词",
-                        "name": "keyword",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "每页个数",
-                        "name": "page_size",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "总页数",
-                        "name": "page_number",
-                        "in": "query",
-                        "required": true
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateAdminUserInput"
+                        }
                     }
                 ],
                 "responses": {
@@ -850,7 +786,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/dto.ServiceListOutput"
+                                            "type": "string"
                                         }
                                     }
                                 }
@@ -860,9 +796,9 @@ var doc = `{
                 }
             }
         },
-        "/service/service_stats": {
+        "/alert/active": {
             "get": {
-                "description": "流量数据",
+                "description": "当前 pending 与 firing 的告警",
                 "consumes": [
                     "application/json"
                 ],
@@ -870,19 +806,10 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Service Management"
-                ],
-                "summary": "Network Flow statistics",
-                "operationId": "/service/service_detail",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "ID",
-                        "name": "ID",
-                        "in": "query",
-                        "required": true
-                    }
+                    "Alert"
                 ],
+                "summary": "Active alerts",
+                "operationId": "/alert/active",
                 "responses": {
                     "200": {
                         "description": "success",
@@ -895,7 +822,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/dto.ServiceStatsOutput"
+                                            "$ref": "#/definitions/dto.AlertActiveOutput"
                                         }
                                     }
                                 }
@@ -905,9 +832,9 @@ var doc = `{
                 }
             }
         },
-        "/service/update_grpc": {
+        "/alert/add_rule": {
             "post": {
-                "description": "grpc服务更新",
+                "description": "添加告警规则，支持服务错误率、租户日请求量、节点健康与证书过期",
                 "consumes": [
                     "application/json"
                 ],
@@ -915,10 +842,10 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Service Management"
+                    "Alert"
                 ],
-                "summary": "Update an existing GRPC service",
-                "operationId": "/service/service_update_grpc",
+                "summary": "Add alert rule",
+                "operationId": "/alert/add_rule",
                 "parameters": [
                     {
                         "description": "body",
@@ -926,7 +853,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.ServiceUpdateGrpcInput"
+                            "$ref": "#/definitions/dto.AddAlertRuleInput"
                         }
                     }
                 ],
@@ -952,9 +879,9 @@ var doc = `{
                 }
             }
         },
-        "/service/update_tcp": {
-            "post": {
-                "description": "tcp服务更新",
+        "/alert/delete_rule": {
+            "get": {
+                "description": "删除告警规则，已触发的告警在下一轮评估时发送 resolved",
                 "consumes": [
                     "application/json"
                 ],
@@ -962,21 +889,55 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Service Management"
+                    "Alert"
                 ],
-                "summary": "Update an existing TCP service",
-                "operationId": "/service/service_update_tcp",
+                "summary": "Delete alert rule",
+                "operationId": "/alert/delete_rule",
                 "parameters": [
                     {
-                        "description": "body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
+                        "type": "integer",
+                        "description": "告警规则ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
                         "schema": {
-                            "$ref": "#/definitions/dto.ServiceUpdateTcpInput"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     }
+                }
+            }
+        },
+        "/alert/rule_list": {
+            "get": {
+                "description": "告警规则列表",
+                "consumes": [
+                    "application/json"
                 ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Alert"
+                ],
+                "summary": "Alert rule list",
+                "operationId": "/alert/rule_list",
                 "responses": {
                     "200": {
                         "description": "success",
@@ -989,7 +950,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "string"
+                                            "$ref": "#/definitions/dto.AlertRuleListOutput"
                                         }
                                     }
                                 }
@@ -999,9 +960,9 @@ var doc = `{
                 }
             }
         },
-        "/service_update": {
+        "/alert/update_rule": {
             "post": {
-                "description": "更新一个 HTTP 服务",
+                "description": "修改告警规则",
                 "consumes": [
                     "application/json"
                 ],
@@ -1009,10 +970,10 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Service Management"
+                    "Alert"
                 ],
-                "summary": "Update an existing HTTP Service",
-                "operationId": "/service/update_http",
+                "summary": "Update alert rule",
+                "operationId": "/alert/update_rule",
                 "parameters": [
                     {
                         "description": "body",
@@ -1020,7 +981,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.ServiceAddHTTPInput"
+                            "$ref": "#/definitions/dto.UpdateAlertRuleInput"
                         }
                     }
                 ],
@@ -1036,7 +997,7 @@ var doc = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/dto.ServiceListOutput"
+                                            "type": "string"
                                         }
                                     }
                                 }
@@ -1045,235 +1006,2927 @@ var doc = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "dao.AccessControl": {
-            "type": "object",
-            "properties": {
-                "black_list": {
-                    "type": "string"
-                },
-                "clientip_flow_limit": {
-                    "type": "integer"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "open_auth": {
-                    "type": "integer"
-                },
-                "service_flow_limit": {
-                    "type": "integer"
-                },
-                "service_id": {
-                    "type": "integer"
-                },
-                "white_host_name": {
-                    "type": "string"
-                },
-                "white_list": {
-                    "type": "string"
+        },
+        "/audit/list": {
+            "get": {
+                "description": "配置变更审计日志，按操作时间倒序",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Audit"
+                ],
+                "summary": "Audit log list",
+                "operationId": "/audit/list",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "页码",
+                        "name": "page_number",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页条数",
+                        "name": "page_size",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "管理员ID",
+                        "name": "admin_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "对象类型 service/renter/admin/admin_token",
+                        "name": "resource_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "对象ID",
+                        "name": "resource_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "操作 add/update/delete/change_password/rollback/enable_totp/disable_totp",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始日期 2006-01-02",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束日期 2006-01-02",
+                        "name": "end_date",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.AuditListOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
                 }
             }
         },
-        "dao.GrpcRule": {
-            "type": "object",
-            "properties": {
-                "header_transfer": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "port": {
-                    "type": "integer"
-                },
-                "service_id": {
-                    "type": "integer"
+        "/config/export": {
+            "get": {
+                "description": "导出所有服务与租户配置，租户密钥默认脱敏，有 renter:write 权限且 show_secret=1 时导出原文",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Config"
+                ],
+                "summary": "Export gateway config",
+                "operationId": "/config/export",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "yaml 或 json，默认 yaml",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "1 表示导出租户密钥原文",
+                        "name": "show_secret",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "配置文档",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
                 }
             }
         },
-        "dao.HttpRule": {
-            "type": "object",
-            "properties": {
-                "header_transfer": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "need_https": {
-                    "type": "integer"
-                },
-                "need_strip_uri": {
-                    "type": "integer"
-                },
-                "need_websocket": {
-                    "type": "integer"
-                },
-                "rule": {
-                    "type": "string"
-                },
+        "/config/import": {
+            "post": {
+                "description": "按文档创建或修改服务与租户，相同内容重复导入不产生变更；dry_run=1 时在事务中执行后回滚，只返回变更",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Config"
+                ],
+                "summary": "Import gateway config",
+                "operationId": "/config/import",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "1 表示只返回变更",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "1 表示软删除文档中不存在的服务与租户",
+                        "name": "prune",
+                        "in": "query"
+                    },
+                    {
+                        "description": "yaml 或 json 配置文档",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.GatewayConfig"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.GatewayConfigImportOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/dashboard/anomalies": {
+            "get": {
+                "description": "与前几天同一小时相比，请求量或错误率异常的服务",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Dashboard"
+                ],
+                "summary": "Anomalies",
+                "operationId": "/dashboard/anomalies",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "基线天数 1-7，默认7",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "偏离阈值(标准差倍数)，默认3",
+                        "name": "threshold",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.DashAnomalyOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/dashboard/flow_stat": {
+            "get": {
+                "description": "流量统计",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Dashboard"
+                ],
+                "summary": "Flow Stats",
+                "operationId": "/dashboard/flow_stat",
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.ServiceStatsOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/dashboard/panel_group_data": {
+            "get": {
+                "description": "指标统计",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Dashboard"
+                ],
+                "summary": "General Stats",
+                "operationId": "/dashboard/panel_group_data",
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.PanelGroupDataOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/dashboard/service_stat": {
+            "get": {
+                "description": "服务统计",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Dashboard"
+                ],
+                "summary": "Service Stats",
+                "operationId": "/dashboard/service_stat",
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.DashServiceStatOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/dashboard/top": {
+            "get": {
+                "description": "按QPS、错误率或延迟排序的服务/租户排行",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Dashboard"
+                ],
+                "summary": "Top-N",
+                "operationId": "/dashboard/top",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "统计维度 service 或 renter",
+                        "name": "dimension",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "排序指标 qps、error_rate 或 latency",
+                        "name": "metric",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "统计窗口(小时) 1-24",
+                        "name": "window",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "返回条数，默认10",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.DashTopOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "prometheus 指标，需要 base.metrics.token 对应的 bearer token，未配置 token 时只允许 allow_ip 访问",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Prometheus Metrics",
+                "operationId": "/metrics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/renter/add_renter": {
+            "post": {
+                "description": "租户添加",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Renter Management"
+                ],
+                "summary": "Add Renter",
+                "operationId": "/renter/add_renter",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AddRenterHttpInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/renter/delete_renter": {
+            "get": {
+                "description": "租户删除",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Renter Management"
+                ],
+                "summary": "Delete Renter",
+                "operationId": "/renter/delete_renter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "租户ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/renter/renter_detail": {
+            "get": {
+                "description": "租户详情",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Renter Management"
+                ],
+                "summary": "Renter Info",
+                "operationId": "/renter/renter_detail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "租户ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dao.Renter"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/renter/renter_list": {
+            "get": {
+                "description": "租户列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Renter Management"
+                ],
+                "summary": "Renter list",
+                "operationId": "/renter/renter_list",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "关键词",
+                        "name": "info",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "每页多少条",
+                        "name": "page_size",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "页码",
+                        "name": "page_no",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.RenterListOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/renter/renter_stats": {
+            "get": {
+                "description": "租户统计",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Renter Management"
+                ],
+                "summary": "Renter Stats",
+                "operationId": "/renter/renter_stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "租户ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.StatisticsOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/renter/renter_top_services": {
+            "get": {
+                "description": "租户调用量最多的服务",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Renter Management"
+                ],
+                "summary": "Renter Top Services",
+                "operationId": "/renter/renter_top_services",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "租户ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始日期 2006-01-02",
+                        "name": "start_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束日期 2006-01-02",
+                        "name": "end_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "返回条数，默认10",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.RenterTopServicesOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/renter/renter_usage": {
+            "get": {
+                "description": "租户历史用量，按天与服务汇总",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Renter Management"
+                ],
+                "summary": "Renter Usage",
+                "operationId": "/renter/renter_usage",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "租户ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始日期 2006-01-02",
+                        "name": "start_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束日期 2006-01-02",
+                        "name": "end_date",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.RenterUsageOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/renter/renter_usage_csv": {
+            "get": {
+                "description": "导出租户历史用量 csv",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "Renter Management"
+                ],
+                "summary": "Renter Usage CSV",
+                "operationId": "/renter/renter_usage_csv",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "租户ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始日期 2006-01-02",
+                        "name": "start_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束日期 2006-01-02",
+                        "name": "end_date",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "csv",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/renter/update_renter": {
+            "post": {
+                "description": "租户更新",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Renter Management"
+                ],
+                "summary": "Update Renter",
+                "operationId": "/renter/update_renter",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateRenterHttpInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/add_fault": {
+            "post": {
+                "description": "添加故障注入规则，支持延迟、中断与连接重置",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fault Injection"
+                ],
+                "summary": "Add fault rule",
+                "operationId": "/service/add_fault",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AddFaultRuleInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/add_grpc": {
+            "post": {
+                "description": "grpc服务添加",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service Management"
+                ],
+                "summary": "Add a new GRPC service",
+                "operationId": "/service/service_add_grpc",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceAddGrpcInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/add_http": {
+            "post": {
+                "description": "添加一个新的 HTTP 服务",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service Management"
+                ],
+                "summary": "Add a new HTTP Service",
+                "operationId": "/service/add_http",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceAddHTTPInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.ServiceListOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/add_tcp": {
+            "post": {
+                "description": "tcp服务添加",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service Management"
+                ],
+                "summary": "Add a new TCP service",
+                "operationId": "/service/service_add_tcp",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceAddTcpInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/delete": {
+            "get": {
+                "description": "删除一个服务",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service Management"
+                ],
+                "summary": "Delete service",
+                "operationId": "/service/delete",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "服务 ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/delete_fault": {
+            "post": {
+                "description": "删除故障注入规则",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fault Injection"
+                ],
+                "summary": "Delete fault rule",
+                "operationId": "/service/delete_fault",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.DeleteFaultRuleInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/fault_list": {
+            "get": {
+                "description": "服务故障注入规则列表，只返回未过期的规则",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fault Injection"
+                ],
+                "summary": "Fault rule list",
+                "operationId": "/service/fault_list",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "服务ID",
+                        "name": "service_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.FaultRuleListOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/rollback": {
+            "post": {
+                "description": "在一个事务中把服务恢复到指定版本的配置，并产生一个新版本",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service Management"
+                ],
+                "summary": "Roll back a service",
+                "operationId": "/service/rollback",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceRollbackInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_details": {
+            "get": {
+                "description": "服务详情",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service Management"
+                ],
+                "summary": "Details of a service",
+                "operationId": "/service/service_details",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "ID",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dao.ServiceDetail"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_list": {
+            "get": {
+                "description": "服务列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service Management"
+                ],
+                "summary": "Service List",
+                "operationId": "/service/service_list",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "关键词",
+                        "name": "keyword",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页个数",
+                        "name": "page_size",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "总页数",
+                        "name": "page_number",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.ServiceListOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_stats": {
+            "get": {
+                "description": "流量数据",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service Management"
+                ],
+                "summary": "Network Flow statistics",
+                "operationId": "/service/service_detail",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "ID",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.ServiceStatsOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/tail": {
+            "get": {
+                "description": "以 SSE 实时推送服务的代理请求，header 已脱敏，到达时长后自动结束",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Service"
+                ],
+                "summary": "Request tail",
+                "operationId": "/service/tail",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "服务ID",
+                        "name": "service_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "租户ID",
+                        "name": "renter_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "状态码，如 502 或 5xx",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "路径前缀",
+                        "name": "path",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "客户端ip",
+                        "name": "client_ip",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "持续时长, 单位s, 默认60, 最长600",
+                        "name": "duration",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "event: request",
+                        "schema": {
+                            "$ref": "#/definitions/public.RequestTailEvent"
+                        }
+                    }
+                }
+            }
+        },
+        "/service/update_grpc": {
+            "post": {
+                "description": "grpc服务更新",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service Management"
+                ],
+                "summary": "Update an existing GRPC service",
+                "operationId": "/service/service_update_grpc",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceUpdateGrpcInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/update_tcp": {
+            "post": {
+                "description": "tcp服务更新",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service Management"
+                ],
+                "summary": "Update an existing TCP service",
+                "operationId": "/service/service_update_tcp",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceUpdateTcpInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/version_diff": {
+            "get": {
+                "description": "比较服务的两个配置版本，to 为空时与最新版本比较",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service Management"
+                ],
+                "summary": "Service version diff",
+                "operationId": "/service/version_diff",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "服务ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "旧版本",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "新版本",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.ServiceVersionDiffOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/version_list": {
+            "get": {
+                "description": "服务配置版本列表，每次添加、修改与回滚都会产生一个新版本",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service Management"
+                ],
+                "summary": "Service version list",
+                "operationId": "/service/version_list",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "服务ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "页码",
+                        "name": "page_number",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页条数",
+                        "name": "page_size",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.ServiceVersionListOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service_update": {
+            "post": {
+                "description": "更新一个 HTTP 服务",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service Management"
+                ],
+                "summary": "Update an existing HTTP Service",
+                "operationId": "/service/update_http",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceAddHTTPInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.ServiceListOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dao.AccessControl": {
+            "type": "object",
+            "properties": {
+                "black_list": {
+                    "type": "string"
+                },
+                "clientip_flow_limit": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "open_auth": {
+                    "type": "integer"
+                },
+                "service_flow_limit": {
+                    "type": "integer"
+                },
+                "service_id": {
+                    "type": "integer"
+                },
+                "white_host_name": {
+                    "type": "string"
+                },
+                "white_list": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.GrpcRule": {
+            "type": "object",
+            "properties": {
+                "header_transfer": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "service_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.HttpRoute": {
+            "type": "object",
+            "properties": {
+                "header_match": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_delete": {
+                    "type": "integer"
+                },
+                "methods": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "query_match": {
+                    "type": "string"
+                },
+                "service_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.HttpRule": {
+            "type": "object",
+            "properties": {
+                "compress_min_size": {
+                    "type": "integer"
+                },
+                "compress_types": {
+                    "type": "string"
+                },
+                "cors_allow_credentials": {
+                    "type": "integer"
+                },
+                "cors_allow_headers": {
+                    "type": "string"
+                },
+                "cors_allow_methods": {
+                    "type": "string"
+                },
+                "cors_allow_origins": {
+                    "type": "string"
+                },
+                "cors_max_age": {
+                    "type": "integer"
+                },
+                "header_transfer": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "need_compress": {
+                    "type": "integer"
+                },
+                "need_cors": {
+                    "type": "integer"
+                },
+                "need_https": {
+                    "type": "integer"
+                },
+                "need_strip_uri": {
+                    "type": "integer"
+                },
+                "need_websocket": {
+                    "type": "integer"
+                },
+                "request_transform": {
+                    "type": "string"
+                },
+                "response_transform": {
+                    "type": "string"
+                },
+                "rule": {
+                    "type": "string"
+                },
+                "rule_type": {
+                    "type": "integer"
+                },
+                "service_id": {
+                    "type": "integer"
+                },
+                "transform_max_body": {
+                    "type": "integer"
+                },
+                "url_rewrite": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.LoadBalance": {
+            "type": "object",
+            "properties": {
+                "check_interval": {
+                    "type": "integer"
+                },
+                "check_method": {
+                    "type": "integer"
+                },
+                "check_timeout": {
+                    "type": "integer"
+                },
+                "forbid_list": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip_list": {
+                    "type": "string"
+                },
+                "round_type": {
+                    "type": "integer"
+                },
+                "service_id": {
+                    "type": "integer"
+                },
+                "upstream_connect_timeout": {
+                    "type": "integer"
+                },
+                "upstream_header_timeout": {
+                    "type": "integer"
+                },
+                "upstream_idle_timeout": {
+                    "type": "integer"
+                },
+                "upstream_max_idle": {
+                    "type": "integer"
+                },
+                "weight_list": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.Renter": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_delete": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "qpd": {
+                    "type": "integer"
+                },
+                "qps": {
+                    "type": "integer"
+                },
+                "renter_id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "white_ips": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.ServiceDetail": {
+            "type": "object",
+            "properties": {
+                "access_control": {
+                    "$ref": "#/definitions/dao.AccessControl"
+                },
+                "grpc_rule": {
+                    "$ref": "#/definitions/dao.GrpcRule"
+                },
+                "http_routes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.HttpRoute"
+                    }
+                },
+                "http_rule": {
+                    "$ref": "#/definitions/dao.HttpRule"
+                },
+                "info": {
+                    "$ref": "#/definitions/dao.ServiceInfo"
+                },
+                "load_balance": {
+                    "$ref": "#/definitions/dao.LoadBalance"
+                },
+                "tcp_rule": {
+                    "$ref": "#/definitions/dao.TcpRule"
+                }
+            }
+        },
+        "dao.ServiceInfo": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_delete": {
+                    "type": "integer"
+                },
+                "load_type": {
+                    "type": "integer"
+                },
+                "service_desc": {
+                    "type": "string"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.TcpRule": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "service_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.AddAdminUserInput": {
+            "type": "object",
+            "required": [
+                "password",
+                "role",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "read_only"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "oncall"
+                }
+            }
+        },
+        "dto.AddAlertRuleInput": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "enabled": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "for_seconds": {
+                    "type": "integer",
+                    "example": 300
+                },
+                "name": {
+                    "type": "string",
+                    "example": "服务错误率"
+                },
+                "renter_id": {
+                    "type": "string"
+                },
+                "rule_type": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "service_id": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "target": {
+                    "type": "string"
+                },
+                "threshold": {
+                    "type": "number",
+                    "example": 5
+                },
+                "webhook_url": {
+                    "type": "string",
+                    "example": "http://127.0.0.1:9000/alert"
+                }
+            }
+        },
+        "dto.AddFaultRuleInput": {
+            "type": "object",
+            "required": [
+                "expire_minutes",
+                "percentage",
+                "service_id"
+            ],
+            "properties": {
+                "abort_code": {
+                    "type": "integer",
+                    "example": 503
+                },
+                "delay_jitter_ms": {
+                    "type": "integer"
+                },
+                "delay_ms": {
+                    "type": "integer"
+                },
+                "expire_minutes": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "fault_type": {
+                    "type": "integer"
+                },
+                "grpc_code": {
+                    "type": "integer",
+                    "example": 14
+                },
+                "header_match": {
+                    "type": "string"
+                },
+                "percentage": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "renter_id": {
+                    "type": "string"
+                },
+                "service_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.AddRenterHttpInput": {
+            "type": "object",
+            "required": [
+                "name",
+                "renter_id"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "qpd": {
+                    "type": "integer"
+                },
+                "qps": {
+                    "type": "integer"
+                },
+                "renter_id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "white_ips": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AdminJwtInput": {
+            "type": "object",
+            "properties": {
+                "expire_seconds": {
+                    "type": "integer",
+                    "example": 600
+                },
+                "scopes": {
+                    "type": "string",
+                    "example": "service:read"
+                }
+            }
+        },
+        "dto.AdminJwtOutput": {
+            "type": "object",
+            "properties": {
+                "expired_at": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AdminLoginInput": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "password"
+                },
+                "username": {
+                    "description": "如果 validate 设置为 required，则会用 gin 提供的 validator 去进行校验\n可以在 validate 后面添加自定义验证器, 参见 middleware.validator.go",
+                    "type": "string",
+                    "example": "admin"
+                }
+            }
+        },
+        "dto.AdminLoginOutput": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string"
+                },
+                "two_factor": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AdminLoginTOTPOutput": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AdminTOTPCodeInput": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "dto.AdminTOTPRecoveryCodesOutput": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dto.AdminTOTPSetupOutput": {
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "type": "string"
+                },
+                "secret_uri": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AdminTokenAddInput": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expire_days": {
+                    "type": "integer",
+                    "example": 90
+                },
+                "name": {
+                    "type": "string",
+                    "example": "ci"
+                },
+                "scopes": {
+                    "type": "string",
+                    "example": "service:read,service:write"
+                }
+            }
+        },
+        "dto.AdminTokenAddOutput": {
+            "type": "object",
+            "properties": {
+                "expired_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AdminTokenItemOutput": {
+            "type": "object",
+            "properties": {
+                "admin_id": {
+                    "type": "integer"
+                },
+                "admin_name": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expired_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token_prefix": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AdminTokenListOutput": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AdminTokenItemOutput"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.AdminTokenRevokeInput": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "dto.AdminUserItemOutput": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_disable": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AdminUserListOutput": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AdminUserItemOutput"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.AlertActiveItemOutput": {
+            "type": "object",
+            "properties": {
+                "fingerprint": {
+                    "type": "string"
+                },
+                "fired_at": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "pending_at": {
+                    "type": "string"
+                },
+                "rule_id": {
+                    "type": "integer"
+                },
+                "rule_name": {
+                    "type": "string"
+                },
+                "rule_type": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "threshold": {
+                    "type": "number"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
+        "dto.AlertActiveOutput": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AlertActiveItemOutput"
+                    }
+                }
+            }
+        },
+        "dto.AlertRuleItemOutput": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "integer"
+                },
+                "for_seconds": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "renter_id": {
+                    "type": "string"
+                },
                 "rule_type": {
                     "type": "integer"
                 },
-                "service_id": {
+                "rule_type_name": {
+                    "type": "string"
+                },
+                "service_id": {
+                    "type": "integer"
+                },
+                "target": {
+                    "type": "string"
+                },
+                "threshold": {
+                    "type": "number"
+                },
+                "webhook_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AlertRuleListOutput": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AlertRuleItemOutput"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.AuditItemOutput": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "admin_id": {
+                    "type": "integer"
+                },
+                "admin_name": {
+                    "type": "string"
+                },
+                "after": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "before": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "client_ip": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "diff": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "resource_id": {
+                    "type": "integer"
+                },
+                "resource_name": {
+                    "type": "string"
+                },
+                "resource_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AuditListOutput": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AuditItemOutput"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.DashAnomalyItemOutput": {
+            "type": "object",
+            "properties": {
+                "baseline": {
+                    "type": "number"
+                },
+                "metric": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
+        "dto.DashAnomalyOutput": {
+            "type": "object",
+            "properties": {
+                "hour": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.DashAnomalyItemOutput"
+                    }
+                }
+            }
+        },
+        "dto.DashServiceStatItemOutput": {
+            "type": "object",
+            "properties": {
+                "load_type": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.DashServiceStatOutput": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.DashServiceStatItemOutput"
+                    }
+                },
+                "legend": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dto.DashTopItemOutput": {
+            "type": "object",
+            "properties": {
+                "error_num": {
+                    "type": "integer"
+                },
+                "error_rate": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "p50": {
+                    "type": "integer"
+                },
+                "p95": {
                     "type": "integer"
                 },
-                "url_rewrite": {
+                "p99": {
+                    "type": "integer"
+                },
+                "qps": {
+                    "type": "number"
+                },
+                "request_num": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.DashTopOutput": {
+            "type": "object",
+            "properties": {
+                "dimension": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.DashTopItemOutput"
+                    }
+                },
+                "metric": {
                     "type": "string"
+                },
+                "window": {
+                    "type": "integer"
                 }
             }
         },
-        "dao.LoadBalance": {
+        "dto.DeleteFaultRuleInput": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.FaultRuleItemOutput": {
             "type": "object",
             "properties": {
-                "check_interval": {
+                "abort_code": {
                     "type": "integer"
                 },
-                "check_method": {
+                "delay_jitter_ms": {
                     "type": "integer"
                 },
-                "check_timeout": {
+                "delay_ms": {
                     "type": "integer"
                 },
-                "forbid_list": {
+                "expire_at": {
                     "type": "string"
                 },
-                "id": {
+                "fault_type": {
                     "type": "integer"
                 },
-                "ip_list": {
+                "fault_type_name": {
                     "type": "string"
                 },
-                "round_type": {
+                "grpc_code": {
                     "type": "integer"
                 },
-                "service_id": {
-                    "type": "integer"
+                "header_match": {
+                    "type": "string"
                 },
-                "upstream_connect_timeout": {
+                "id": {
                     "type": "integer"
                 },
-                "upstream_header_timeout": {
+                "percentage": {
                     "type": "integer"
                 },
-                "upstream_idle_timeout": {
-                    "type": "integer"
+                "renter_id": {
+                    "type": "string"
                 },
-                "upstream_max_idle": {
+                "service_id": {
                     "type": "integer"
-                },
-                "weight_list": {
-                    "type": "string"
                 }
             }
         },
-        "dao.Renter": {
+        "dto.FaultRuleListOutput": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "type": "string"
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.FaultRuleItemOutput"
+                    }
                 },
-                "id": {
+                "total": {
                     "type": "integer"
+                }
+            }
+        },
+        "dto.GatewayAccessControlConfig": {
+            "type": "object",
+            "properties": {
+                "black_list": {
+                    "type": "string"
                 },
-                "is_delete": {
+                "clientip_flow_limit": {
                     "type": "integer"
                 },
-                "name": {
-                    "type": "string"
-                },
-                "qpd": {
+                "open_auth": {
                     "type": "integer"
                 },
-                "qps": {
+                "service_flow_limit": {
                     "type": "integer"
                 },
-                "renter_id": {
+                "white_host_name": {
                     "type": "string"
                 },
-                "secret": {
+                "white_list": {
                     "type": "string"
-                },
-                "updated_at": {
+                }
+            }
+        },
+        "dto.GatewayConfig": {
+            "type": "object",
+            "properties": {
+                "exported_at": {
                     "type": "string"
                 },
-                "white_ips": {
-                    "type": "string"
+                "renters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.GatewayRenterConfig"
+                    }
+                },
+                "services": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.GatewayServiceConfig"
+                    }
+                },
+                "version": {
+                    "type": "integer"
                 }
             }
         },
-        "dao.ServiceDetail": {
+        "dto.GatewayConfigChange": {
             "type": "object",
             "properties": {
-                "access_control": {
-                    "$ref": "#/definitions/dao.AccessControl"
+                "action": {
+                    "type": "string"
                 },
-                "grpc_rule": {
-                    "$ref": "#/definitions/dao.GrpcRule"
+                "diff": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/public.AuditChange"
+                    }
                 },
-                "http_rule": {
-                    "$ref": "#/definitions/dao.HttpRule"
+                "name": {
+                    "type": "string"
                 },
-                "info": {
-                    "$ref": "#/definitions/dao.ServiceInfo"
+                "resource_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.GatewayConfigImportOutput": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.GatewayConfigChange"
+                    }
                 },
-                "load_balance": {
-                    "$ref": "#/definitions/dao.LoadBalance"
+                "dry_run": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "dto.GatewayGRPCRuleConfig": {
+            "type": "object",
+            "properties": {
+                "header_transfer": {
+                    "type": "string"
                 },
-                "tcp_rule": {
-                    "$ref": "#/definitions/dao.TcpRule"
+                "port": {
+                    "type": "integer"
                 }
             }
         },
-        "dao.ServiceInfo": {
+        "dto.GatewayHTTPRuleConfig": {
             "type": "object",
             "properties": {
-                "createdAt": {
+                "compress_min_size": {
+                    "type": "integer"
+                },
+                "compress_types": {
                     "type": "string"
                 },
-                "id": {
+                "cors_allow_credentials": {
                     "type": "integer"
                 },
-                "is_delete": {
+                "cors_allow_headers": {
+                    "type": "string"
+                },
+                "cors_allow_methods": {
+                    "type": "string"
+                },
+                "cors_allow_origins": {
+                    "type": "string"
+                },
+                "cors_max_age": {
                     "type": "integer"
                 },
-                "load_type": {
+                "header_transfer": {
+                    "type": "string"
+                },
+                "need_compress": {
                     "type": "integer"
                 },
-                "service_desc": {
+                "need_cors": {
+                    "type": "integer"
+                },
+                "need_https": {
+                    "type": "integer"
+                },
+                "need_strip_uri": {
+                    "type": "integer"
+                },
+                "need_websocket": {
+                    "type": "integer"
+                },
+                "request_transform": {
                     "type": "string"
                 },
-                "service_name": {
+                "response_transform": {
                     "type": "string"
                 },
-                "updatedAt": {
+                "rule": {
+                    "type": "string"
+                },
+                "rule_type": {
+                    "type": "integer"
+                },
+                "transform_max_body": {
+                    "type": "integer"
+                },
+                "url_rewrite": {
                     "type": "string"
                 }
             }
         },
-        "dao.TcpRule": {
+        "dto.GatewayLoadBalanceConfig": {
             "type": "object",
             "properties": {
-                "id": {
+                "forbid_list": {
+                    "type": "string"
+                },
+                "ip_list": {
+                    "type": "string"
+                },
+                "round_type": {
                     "type": "integer"
                 },
-                "port": {
+                "upstream_connect_timeout": {
                     "type": "integer"
                 },
-                "service_id": {
+                "upstream_header_timeout": {
+                    "type": "integer"
+                },
+                "upstream_idle_timeout": {
+                    "type": "integer"
+                },
+                "upstream_max_idle": {
                     "type": "integer"
+                },
+                "weight_list": {
+                    "type": "string"
                 }
             }
         },
-        "dto.AddRenterHttpInput": {
+        "dto.GatewayRenterConfig": {
             "type": "object",
-            "required": [
-                "name",
-                "renter_id"
-            ],
             "properties": {
                 "name": {
                     "type": "string"
@@ -1295,60 +3948,72 @@ var doc = `{
                 }
             }
         },
-        "dto.AdminLoginInput": {
+        "dto.GatewayServiceConfig": {
             "type": "object",
-            "required": [
-                "password",
-                "username"
-            ],
             "properties": {
-                "password": {
-                    "type": "string",
-                    "example": "password"
+                "access_control": {
+                    "$ref": "#/definitions/dto.GatewayAccessControlConfig"
                 },
-                "username": {
-                    "description": "如果 validate 设置为 required，则会用 gin 提供的 validator 去进行校验\n可以在 validate 后面添加自定义验证器, 参见 middleware.validator.go",
-                    "type": "string",
-                    "example": "admin"
-                }
-            }
-        },
-        "dto.AdminLoginOutput": {
-            "type": "object",
-            "properties": {
-                "token": {
+                "desc": {
                     "type": "string"
-                }
-            }
-        },
-        "dto.DashServiceStatItemOutput": {
-            "type": "object",
-            "properties": {
+                },
+                "grpc_rule": {
+                    "$ref": "#/definitions/dto.GatewayGRPCRuleConfig"
+                },
+                "http_routes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.HTTPRouteInput"
+                    }
+                },
+                "http_rule": {
+                    "$ref": "#/definitions/dto.GatewayHTTPRuleConfig"
+                },
+                "load_balance": {
+                    "$ref": "#/definitions/dto.GatewayLoadBalanceConfig"
+                },
                 "load_type": {
-                    "type": "integer"
+                    "type": "string"
                 },
                 "name": {
                     "type": "string"
                 },
-                "value": {
+                "tcp_rule": {
+                    "$ref": "#/definitions/dto.GatewayTCPRuleConfig"
+                }
+            }
+        },
+        "dto.GatewayTCPRuleConfig": {
+            "type": "object",
+            "properties": {
+                "port": {
                     "type": "integer"
                 }
             }
         },
-        "dto.DashServiceStatOutput": {
+        "dto.HTTPRouteInput": {
             "type": "object",
+            "required": [
+                "path"
+            ],
             "properties": {
-                "data": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/dto.DashServiceStatItemOutput"
-                    }
+                "header_match": {
+                    "type": "string"
                 },
-                "legend": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
+                "methods": {
+                    "type": "string",
+                    "example": "GET,POST"
+                },
+                "path": {
+                    "type": "string",
+                    "example": "/users/:id"
+                },
+                "priority": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "query_match": {
+                    "type": "string"
                 }
             }
         },
@@ -1424,6 +4089,82 @@ var doc = `{
                 }
             }
         },
+        "dto.RenterTopServiceItemOutput": {
+            "type": "object",
+            "properties": {
+                "request_count": {
+                    "type": "integer"
+                },
+                "service_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.RenterTopServicesOutput": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.RenterTopServiceItemOutput"
+                    }
+                }
+            }
+        },
+        "dto.RenterUsageDailyOutput": {
+            "type": "object",
+            "properties": {
+                "day": {
+                    "type": "string"
+                },
+                "request_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.RenterUsageItemOutput": {
+            "type": "object",
+            "properties": {
+                "day": {
+                    "type": "string"
+                },
+                "request_count": {
+                    "type": "integer"
+                },
+                "service_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.RenterUsageOutput": {
+            "type": "object",
+            "properties": {
+                "daily": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.RenterUsageDailyOutput"
+                    }
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.RenterUsageItemOutput"
+                    }
+                },
+                "renter_id": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
         "dto.ServiceAddGrpcInput": {
             "type": "object",
             "required": [
@@ -1496,6 +4237,34 @@ var doc = `{
                     "description": "客户端ip限流",
                     "type": "integer"
                 },
+                "compress_min_size": {
+                    "description": "最小压缩大小, 单位byte",
+                    "type": "integer"
+                },
+                "compress_types": {
+                    "description": "可压缩的content-type",
+                    "type": "string"
+                },
+                "cors_allow_credentials": {
+                    "description": "允许携带cookie",
+                    "type": "integer"
+                },
+                "cors_allow_headers": {
+                    "description": "允许的header",
+                    "type": "string"
+                },
+                "cors_allow_methods": {
+                    "description": "允许的方法",
+                    "type": "string"
+                },
+                "cors_allow_origins": {
+                    "description": "允许的origin",
+                    "type": "string"
+                },
+                "cors_max_age": {
+                    "description": "预检缓存时间, 单位s",
+                    "type": "integer"
+                },
                 "header_transfer": {
                     "description": "header转换",
                     "type": "string"
@@ -1504,6 +4273,14 @@ var doc = `{
                     "description": "ip列表",
                     "type": "string"
                 },
+                "need_compress": {
+                    "description": "启用响应压缩",
+                    "type": "integer"
+                },
+                "need_cors": {
+                    "description": "启用跨域",
+                    "type": "integer"
+                },
                 "need_https": {
                     "description": "支持https",
                     "type": "integer"
@@ -1520,10 +4297,25 @@ var doc = `{
                     "description": "关键词",
                     "type": "integer"
                 },
+                "request_transform": {
+                    "description": "请求转换",
+                    "type": "string"
+                },
+                "response_transform": {
+                    "description": "响应转换",
+                    "type": "string"
+                },
                 "round_type": {
                     "description": "轮询方式",
                     "type": "integer"
                 },
+                "routes": {
+                    "description": "路由列表",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.HTTPRouteInput"
+                    }
+                },
                 "rule": {
                     "description": "域名或者前缀",
                     "type": "string"
@@ -1544,6 +4336,10 @@ var doc = `{
                     "description": "服务名",
                     "type": "string"
                 },
+                "transform_max_body": {
+                    "description": "参与转换的最大body",
+                    "type": "integer"
+                },
                 "upstream_connect_timeout": {
                     "description": "建立连接超时, 单位s",
                     "type": "integer"
@@ -1646,15 +4442,60 @@ var doc = `{
                 }
             }
         },
+        "dto.ServiceRollbackInput": {
+            "type": "object",
+            "required": [
+                "id",
+                "version"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
         "dto.ServiceStatsOutput": {
             "type": "object",
             "properties": {
+                "compress_saved_bytes": {
+                    "description": "今日压缩节省的字节数",
+                    "type": "integer"
+                },
+                "error_rate": {
+                    "description": "今日5xx错误率",
+                    "type": "number"
+                },
+                "p50": {
+                    "description": "近一到两小时延迟p50, 单位ms",
+                    "type": "integer"
+                },
+                "p95": {
+                    "description": "近一到两小时延迟p95, 单位ms",
+                    "type": "integer"
+                },
+                "p99": {
+                    "description": "近一到两小时延迟p99, 单位ms",
+                    "type": "integer"
+                },
+                "qps": {
+                    "description": "当前qps",
+                    "type": "integer"
+                },
                 "today": {
                     "type": "array",
                     "items": {
                         "type": "integer"
                     }
                 },
+                "today_request_num": {
+                    "description": "今日请求数",
+                    "type": "integer"
+                },
                 "yesterday": {
                     "type": "array",
                     "items": {
@@ -1776,6 +4617,63 @@ var doc = `{
                 }
             }
         },
+        "dto.ServiceVersionDiffOutput": {
+            "type": "object",
+            "properties": {
+                "diff": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/public.AuditChange"
+                    }
+                },
+                "from": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ServiceVersionItemOutput": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "admin_id": {
+                    "type": "integer"
+                },
+                "admin_name": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ServiceVersionListOutput": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "type": "integer"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ServiceVersionItemOutput"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
         "dto.SingleService": {
             "type": "object",
             "properties": {
@@ -1834,6 +4732,79 @@ var doc = `{
                 }
             }
         },
+        "dto.UpdateAdminUserInput": {
+            "type": "object",
+            "required": [
+                "id",
+                "role"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "is_disable": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "password": {
+                    "type": "string"
+                },
+                "reset_totp": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "role": {
+                    "type": "string",
+                    "example": "read_only"
+                }
+            }
+        },
+        "dto.UpdateAlertRuleInput": {
+            "type": "object",
+            "required": [
+                "id",
+                "name"
+            ],
+            "properties": {
+                "enabled": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "for_seconds": {
+                    "type": "integer",
+                    "example": 300
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "服务错误率"
+                },
+                "renter_id": {
+                    "type": "string"
+                },
+                "rule_type": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "service_id": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "target": {
+                    "type": "string"
+                },
+                "threshold": {
+                    "type": "number",
+                    "example": 5
+                },
+                "webhook_url": {
+                    "type": "string",
+                    "example": "http://127.0.0.1:9000/alert"
+                }
+            }
+        },
         "dto.UpdateRenterHttpInput": {
             "type": "object",
             "required": [
@@ -1884,6 +4855,73 @@ var doc = `{
                     "type": "object"
                 }
             }
+        },
+        "public.AuditChange": {
+            "type": "object",
+            "properties": {
+                "after": {
+                    "type": "object"
+                },
+                "before": {
+                    "type": "object"
+                }
+            }
+        },
+        "public.RequestTailEvent": {
+            "type": "object",
+            "properties": {
+                "client_ip": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "integer"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "renter": {
+                    "type": "string"
+                },
+                "request_bytes": {
+                    "type": "integer"
+                },
+                "request_header": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "response_bytes": {
+                    "type": "integer"
+                },
+                "response_header": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "service": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "time": {
+                    "type": "string"
+                },
+                "trace_id": {
+                    "type": "string"
+                },
+                "upstream": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
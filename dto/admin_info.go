@@ -13,6 +13,11 @@ type AdminInfoOutput struct {
 	Avatar       string    `json:"avatar"`
 	Introduction string    `json:"introduction"`
 	Roles        []string  `json:"roles"`
+	Permissions  []string  `json:"permissions"`
+	// 两步验证是否已开启、当前角色是否必须开启，以及剩余的恢复码数量
+	TOTPEnabled       bool `json:"totp_enabled"`
+	TOTPRequired      bool `json:"totp_required"`
+	RecoveryCodeCount int  `json:"recovery_code_count"`
 }
 
 type ChangePasswordInput struct {
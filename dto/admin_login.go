@@ -13,14 +13,31 @@ type AdminLoginInput struct {
 	Password string `json:"password" form:"password" validate:"required" example:"password"`
 }
 
+// TwoFactor 为空表示已登录，verify 需要调用 /admin/login/totp 输入验证码，setup 需要先绑定
 type AdminLoginOutput struct {
-	Token string `json:"token" form:"token"`
+	Token     string `json:"token" form:"token"`
+	TwoFactor string `json:"two_factor" form:"two_factor"`
 }
 
+// AdminLoginTOTPOutput 登录时完成绑定才返回恢复码
+type AdminLoginTOTPOutput struct {
+	Token         string   `json:"token" form:"token"`
+	RecoveryCodes []string `json:"recovery_codes" form:"recovery_codes"`
+}
+
+// AdminSessionInfo SessionVersion 与管理员当前的版本不同时 session 失效
 type AdminSessionInfo struct {
+	ID             int       `json:"id"`
+	UsernName      string    `json:"username"`
+	LoginTime      time.Time `json:"login_time"`
+	SessionVersion int       `json:"session_version"`
+}
+
+// AdminPendingSessionInfo 密码校验通过、等待两步验证的登录
+type AdminPendingSessionInfo struct {
 	ID        int       `json:"id"`
 	UsernName string    `json:"username"`
-	LoginTime time.Time `json:"login_time"`
+	ExpireAt  time.Time `json:"expire_at"`
 }
 
 //
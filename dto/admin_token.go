@@ -0,0 +1,72 @@
+package dto
+
+import (
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminTokenListInput struct {
+	AdminID int `json:"admin_id" form:"admin_id" comment:"管理员ID，0 表示全部，只有 admin:manage 可以查看其他管理员" example:"0" validate:"min=0"`
+}
+
+type AdminTokenListOutput struct {
+	List  []AdminTokenItemOutput `json:"list" form:"list" comment:"token列表"`
+	Total int64                  `json:"total" form:"total" comment:"总数"`
+}
+
+// ExpiredAt 与 LastUsedAt 为空表示不过期、未使用
+type AdminTokenItemOutput struct {
+	ID          int64    `json:"id"`
+	AdminID     int      `json:"admin_id"`
+	AdminName   string   `json:"admin_name"`
+	Name        string   `json:"name"`
+	TokenPrefix string   `json:"token_prefix"`
+	Scopes      []string `json:"scopes"`
+	ExpiredAt   string   `json:"expired_at"`
+	LastUsedAt  string   `json:"last_used_at"`
+	CreatedAt   string   `json:"created_at"`
+}
+
+type AdminTokenAddInput struct {
+	Name       string `json:"name" form:"name" comment:"名称" example:"ci" validate:"required,max=255"`
+	Scopes     string `json:"scopes" form:"scopes" comment:"权限，逗号间隔，不能超过当前角色的权限" example:"service:read,service:write" validate:"required"`
+	ExpireDays int    `json:"expire_days" form:"expire_days" comment:"有效天数，0 表示不过期" example:"90" validate:"min=0,max=3650"`
+}
+
+// Token 只在创建时返回一次
+type AdminTokenAddOutput struct {
+	ID        int64  `json:"id"`
+	Token     string `json:"token"`
+	ExpiredAt string `json:"expired_at"`
+}
+
+type AdminTokenRevokeInput struct {
+	ID int64 `json:"id" form:"id" comment:"tokenID" example:"1" validate:"required"`
+}
+
+// AdminJwtInput scopes 为空时使用 API token 的全部 scopes
+type AdminJwtInput struct {
+	Scopes        string `json:"scopes" form:"scopes" comment:"权限，逗号间隔，不能超过 API token 的 scopes" example:"service:read"`
+	ExpireSeconds int    `json:"expire_seconds" form:"expire_seconds" comment:"有效期，单位s，0 表示最长 3600" example:"600" validate:"min=0,max=3600"`
+}
+
+type AdminJwtOutput struct {
+	Token     string `json:"token"`
+	ExpiredAt string `json:"expired_at"`
+}
+
+func (params *AdminTokenListInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
+
+func (params *AdminTokenAddInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
+
+func (params *AdminTokenRevokeInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
+
+func (params *AdminJwtInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+)
+
+// Code 为验证器 App 中的 6 位验证码，登录与关闭时也可以使用恢复码
+type AdminTOTPCodeInput struct {
+	Code string `json:"code" form:"code" comment:"验证码" example:"123456" validate:"required,max=32"`
+}
+
+// SecretURI 为 otpauth:// 地址，用于生成二维码
+type AdminTOTPSetupOutput struct {
+	Secret    string `json:"secret" form:"secret"`
+	SecretURI string `json:"secret_uri" form:"secret_uri"`
+}
+
+// RecoveryCodes 只返回一次，每个只能使用一次
+type AdminTOTPRecoveryCodesOutput struct {
+	RecoveryCodes []string `json:"recovery_codes" form:"recovery_codes"`
+}
+
+func (param *AdminTOTPCodeInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, param)
+}
@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminUserListOutput struct {
+	List  []AdminUserItemOutput `json:"list" form:"list" comment:"管理员列表"`
+	Total int64                 `json:"total" form:"total" comment:"管理员总数"`
+}
+
+type AdminUserItemOutput struct {
+	ID        int    `json:"id"`
+	UserName  string `json:"user_name"`
+	Role      string `json:"role"`
+	IsDisable int    `json:"is_disable"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type AddAdminUserInput struct {
+	Username string `json:"username" form:"username" comment:"用户名" example:"oncall" validate:"required,valid_username"`
+	Password string `json:"password" form:"password" comment:"密码" example:"" validate:"required,min=8"`
+	Role     string `json:"role" form:"role" comment:"角色" example:"read_only" validate:"required,oneof=super_admin service_operator renter_manager read_only"`
+}
+
+// Password 为空时不修改密码
+type UpdateAdminUserInput struct {
+	ID        int    `json:"id" form:"id" comment:"管理员ID" example:"" validate:"required"`
+	Password  string `json:"password" form:"password" comment:"密码" example:"" validate:"omitempty,min=8"`
+	Role      string `json:"role" form:"role" comment:"角色" example:"read_only" validate:"required,oneof=super_admin service_operator renter_manager read_only"`
+	IsDisable int    `json:"is_disable" form:"is_disable" comment:"是否禁用" example:"0" validate:"min=0,max=1"`
+	ResetTOTP int    `json:"reset_totp" form:"reset_totp" comment:"是否重置两步验证，用于丢失验证器" example:"0" validate:"min=0,max=1"`
+}
+
+type DeleteAdminUserInput struct {
+	ID int `json:"id" form:"id" comment:"管理员ID" validate:"required"`
+}
+
+func (params *AddAdminUserInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
+
+func (params *UpdateAdminUserInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
+
+func (params *DeleteAdminUserInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
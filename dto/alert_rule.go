@@ -0,0 +1,85 @@
+package dto
+
+import (
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+)
+
+type AlertRuleListOutput struct {
+	List  []AlertRuleItemOutput `json:"list" form:"list" comment:"告警规则列表"`
+	Total int64                 `json:"total" form:"total" comment:"告警规则总数"`
+}
+
+type AlertRuleItemOutput struct {
+	ID           int64   `json:"id"`
+	Name         string  `json:"name"`
+	RuleType     int     `json:"rule_type"`
+	RuleTypeName string  `json:"rule_type_name"`
+	ServiceID    int64   `json:"service_id"`
+	RenterID     string  `json:"renter_id"`
+	Target       string  `json:"target"`
+	Threshold    float64 `json:"threshold"`
+	ForSeconds   int     `json:"for_seconds"`
+	WebhookURL   string  `json:"webhook_url"`
+	Enabled      int8    `json:"enabled"`
+}
+
+// 阈值含义随规则类型变化: error_rate 与 renter_qpd 为百分比，cert_expiry 为剩余天数，upstream_unhealthy 不使用
+type AddAlertRuleInput struct {
+	Name       string  `json:"name" form:"name" comment:"规则名称" example:"服务错误率" validate:"required,max=255"`
+	RuleType   int     `json:"rule_type" form:"rule_type" comment:"规则类型 0=error_rate 1=renter_qpd 2=upstream_unhealthy 3=cert_expiry" example:"0" validate:"min=0,max=3"`
+	ServiceID  int64   `json:"service_id" form:"service_id" comment:"服务ID，0 表示全部服务" example:"0" validate:"min=0"`
+	RenterID   string  `json:"renter_id" form:"renter_id" comment:"租户ID，为空表示全部租户" example:"" validate:""`
+	Target     string  `json:"target" form:"target" comment:"证书检查地址 host:port，多条逗号间隔" example:"" validate:"omitempty,valid_ipportlist"`
+	Threshold  float64 `json:"threshold" form:"threshold" comment:"阈值" example:"5" validate:"min=0"`
+	ForSeconds int     `json:"for_seconds" form:"for_seconds" comment:"条件持续多久后触发, 单位s" example:"300" validate:"min=0,max=86400"`
+	WebhookURL string  `json:"webhook_url" form:"webhook_url" comment:"通知地址" example:"http://127.0.0.1:9000/alert" validate:"omitempty,url"`
+	Enabled    int8    `json:"enabled" form:"enabled" comment:"是否启用" example:"1" validate:"min=0,max=1"`
+}
+
+type UpdateAlertRuleInput struct {
+	ID         int64   `json:"id" form:"id" comment:"告警规则ID" example:"" validate:"required"`
+	Name       string  `json:"name" form:"name" comment:"规则名称" example:"服务错误率" validate:"required,max=255"`
+	RuleType   int     `json:"rule_type" form:"rule_type" comment:"规则类型 0=error_rate 1=renter_qpd 2=upstream_unhealthy 3=cert_expiry" example:"0" validate:"min=0,max=3"`
+	ServiceID  int64   `json:"service_id" form:"service_id" comment:"服务ID，0 表示全部服务" example:"0" validate:"min=0"`
+	RenterID   string  `json:"renter_id" form:"renter_id" comment:"租户ID，为空表示全部租户" example:"" validate:""`
+	Target     string  `json:"target" form:"target" comment:"证书检查地址 host:port，多条逗号间隔" example:"" validate:"omitempty,valid_ipportlist"`
+	Threshold  float64 `json:"threshold" form:"threshold" comment:"阈值" example:"5" validate:"min=0"`
+	ForSeconds int     `json:"for_seconds" form:"for_seconds" comment:"条件持续多久后触发, 单位s" example:"300" validate:"min=0,max=86400"`
+	WebhookURL string  `json:"webhook_url" form:"webhook_url" comment:"通知地址" example:"http://127.0.0.1:9000/alert" validate:"omitempty,url"`
+	Enabled    int8    `json:"enabled" form:"enabled" comment:"是否启用" example:"1" validate:"min=0,max=1"`
+}
+
+type DeleteAlertRuleInput struct {
+	ID int64 `json:"id" form:"id" comment:"告警规则ID" validate:"required"`
+}
+
+type AlertActiveOutput struct {
+	List []AlertActiveItemOutput `json:"list" form:"list" comment:"告警列表"`
+}
+
+type AlertActiveItemOutput struct {
+	Fingerprint string  `json:"fingerprint"`
+	RuleID      int64   `json:"rule_id"`
+	RuleName    string  `json:"rule_name"`
+	RuleType    string  `json:"rule_type"`
+	Subject     string  `json:"subject"`
+	Status      string  `json:"status"`
+	Value       float64 `json:"value"`
+	Threshold   float64 `json:"threshold"`
+	Message     string  `json:"message"`
+	PendingAt   string  `json:"pending_at"`
+	FiredAt     string  `json:"fired_at"`
+}
+
+func (params *AddAlertRuleInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
+
+func (params *UpdateAlertRuleInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
+
+func (params *DeleteAlertRuleInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
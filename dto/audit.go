@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+)
+
+type AuditListInput struct {
+	PageNumber   int    `json:"page_number" form:"page_number" comment:"页码" example:"1" validate:"required,min=1,max=999"`
+	PageSize     int    `json:"page_size" form:"page_size" comment:"每页条数" example:"20" validate:"required,min=1,max=999"`
+	AdminID      int    `json:"admin_id" form:"admin_id" comment:"管理员ID" example:"0" validate:"min=0"`
+	ResourceType string `json:"resource_type" form:"resource_type" comment:"对象类型" example:"service" validate:"omitempty,oneof=service renter admin admin_token"`
+	ResourceID   int64  `json:"resource_id" form:"resource_id" comment:"对象ID" example:"0" validate:"min=0"`
+	Action       string `json:"action" form:"action" comment:"操作" example:"update" validate:"omitempty,oneof=add update delete change_password rollback enable_totp disable_totp"`
+	StartDate    string `json:"start_date" form:"start_date" comment:"开始日期" example:"2020-06-01" validate:"omitempty,valid_date"`
+	EndDate      string `json:"end_date" form:"end_date" comment:"结束日期" example:"2020-06-30" validate:"omitempty,valid_date"`
+}
+
+type AuditListOutput struct {
+	List  []AuditItemOutput `json:"list" form:"list" comment:"审计日志列表"`
+	Total int64             `json:"total" form:"total" comment:"总数"`
+}
+
+type AuditItemOutput struct {
+	ID           int64                  `json:"id"`
+	AdminID      int                    `json:"admin_id"`
+	AdminName    string                 `json:"admin_name"`
+	Action       string                 `json:"action"`
+	ResourceType string                 `json:"resource_type"`
+	ResourceID   int64                  `json:"resource_id"`
+	ResourceName string                 `json:"resource_name"`
+	Endpoint     string                 `json:"endpoint"`
+	ClientIP     string                 `json:"client_ip"`
+	Before       map[string]interface{} `json:"before"`
+	After        map[string]interface{} `json:"after"`
+	Diff         map[string]interface{} `json:"diff"`
+	CreatedAt    string                 `json:"created_at"`
+}
+
+func (params *AuditListInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
@@ -1,5 +1,10 @@
 package dto
 
+import (
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+)
+
 type PanelGroupDataOutput struct {
 	ServiceNum      int64 `json:"service_num"`
 	RenterNum       int64 `json:"renter_num"`
@@ -17,3 +22,54 @@ type DashServiceStatOutput struct {
 	Legend []string                    `json:"legend"`
 	Data   []DashServiceStatItemOutput `json:"data"`
 }
+
+type DashTopInput struct {
+	Dimension string `json:"dimension" form:"dimension" comment:"统计维度" example:"service" validate:"required,oneof=service renter"`
+	Metric    string `json:"metric" form:"metric" comment:"排序指标" example:"qps" validate:"required,oneof=qps error_rate latency"`
+	Window    int    `json:"window" form:"window" comment:"统计窗口(小时)" example:"1" validate:"required,min=1,max=24"`
+	Limit     int    `json:"limit" form:"limit" comment:"返回条数" example:"10" validate:"min=0,max=100"`
+}
+
+type DashTopOutput struct {
+	Dimension string              `json:"dimension" form:"dimension" comment:"统计维度"`
+	Metric    string              `json:"metric" form:"metric" comment:"排序指标"`
+	Window    int                 `json:"window" form:"window" comment:"统计窗口(小时)"`
+	List      []DashTopItemOutput `json:"list" form:"list" comment:"排行列表"`
+}
+
+type DashTopItemOutput struct {
+	Name       string  `json:"name" form:"name" comment:"服务名称或租户id"`
+	RequestNum int64   `json:"request_num" form:"request_num" comment:"窗口内请求量"`
+	QPS        float64 `json:"qps" form:"qps" comment:"窗口内平均QPS"`
+	ErrorNum   int64   `json:"error_num" form:"error_num" comment:"窗口内5xx错误数"`
+	ErrorRate  float64 `json:"error_rate" form:"error_rate" comment:"窗口内5xx错误率"`
+	P50        int64   `json:"p50" form:"p50" comment:"延迟P50(ms)"`
+	P95        int64   `json:"p95" form:"p95" comment:"延迟P95(ms)"`
+	P99        int64   `json:"p99" form:"p99" comment:"延迟P99(ms)"`
+}
+
+type DashAnomalyInput struct {
+	Days      int     `json:"days" form:"days" comment:"基线天数" example:"7" validate:"min=0,max=7"`
+	Threshold float64 `json:"threshold" form:"threshold" comment:"偏离阈值(标准差倍数)" example:"3" validate:"min=0"`
+}
+
+type DashAnomalyOutput struct {
+	Hour string                  `json:"hour" form:"hour" comment:"检测的小时"`
+	List []DashAnomalyItemOutput `json:"list" form:"list" comment:"异常列表"`
+}
+
+type DashAnomalyItemOutput struct {
+	ServiceName string  `json:"service_name" form:"service_name" comment:"服务名称"`
+	Metric      string  `json:"metric" form:"metric" comment:"异常指标 traffic 或 error_rate"`
+	Value       float64 `json:"value" form:"value" comment:"当前值"`
+	Baseline    float64 `json:"baseline" form:"baseline" comment:"基线均值"`
+	Score       float64 `json:"score" form:"score" comment:"偏离的标准差倍数"`
+}
+
+func (params *DashTopInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
+
+func (params *DashAnomalyInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
@@ -0,0 +1,60 @@
+package dto
+
+import (
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+)
+
+type FaultRuleListInput struct {
+	ServiceID int64 `json:"service_id" form:"service_id" comment:"服务ID" validate:"required"`
+}
+
+type FaultRuleListOutput struct {
+	List  []FaultRuleItemOutput `json:"list" form:"list" comment:"故障规则列表"`
+	Total int64                 `json:"total" form:"total" comment:"故障规则总数"`
+}
+
+type FaultRuleItemOutput struct {
+	ID            int64  `json:"id"`
+	ServiceID     int64  `json:"service_id"`
+	FaultType     int    `json:"fault_type"`
+	FaultTypeName string `json:"fault_type_name"`
+	DelayMs       int    `json:"delay_ms"`
+	DelayJitterMs int    `json:"delay_jitter_ms"`
+	AbortCode     int    `json:"abort_code"`
+	GrpcCode      int    `json:"grpc_code"`
+	Percentage    int    `json:"percentage"`
+	RenterID      string `json:"renter_id"`
+	HeaderMatch   string `json:"header_match"`
+	ExpireAt      string `json:"expire_at"`
+}
+
+// 故障规则必须设置有效时长，最长 7 天，避免忘记关闭
+type AddFaultRuleInput struct {
+	ServiceID     int64  `json:"service_id" form:"service_id" comment:"服务ID" example:"" validate:"required"`
+	FaultType     int    `json:"fault_type" form:"fault_type" comment:"故障类型 0=delay 1=abort 2=reset" example:"" validate:"min=0,max=2"`
+	DelayMs       int    `json:"delay_ms" form:"delay_ms" comment:"固定延迟, 单位ms" example:"" validate:"min=0,max=60000"`
+	DelayJitterMs int    `json:"delay_jitter_ms" form:"delay_jitter_ms" comment:"随机延迟上限, 单位ms" example:"" validate:"min=0,max=60000"`
+	AbortCode     int    `json:"abort_code" form:"abort_code" comment:"abort 时返回的 http 状态码" example:"503" validate:"omitempty,min=100,max=599"`
+	GrpcCode      int    `json:"grpc_code" form:"grpc_code" comment:"abort 时返回的 grpc code" example:"14" validate:"min=0,max=16"`
+	Percentage    int    `json:"percentage" form:"percentage" comment:"命中流量百分比" example:"10" validate:"required,min=1,max=100"`
+	RenterID      string `json:"renter_id" form:"renter_id" comment:"租户ID" example:"" validate:""`
+	HeaderMatch   string `json:"header_match" form:"header_match" comment:"header匹配" example:"" validate:"valid_header_match"`
+	ExpireMinutes int    `json:"expire_minutes" form:"expire_minutes" comment:"有效时长, 单位分钟" example:"30" validate:"required,min=1,max=10080"`
+}
+
+type DeleteFaultRuleInput struct {
+	ID int64 `json:"id" form:"id" comment:"故障规则ID" validate:"required"`
+}
+
+func (params *FaultRuleListInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
+
+func (params *AddFaultRuleInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
+
+func (params *DeleteFaultRuleInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
@@ -0,0 +1,121 @@
+package dto
+
+import (
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+)
+
+// GatewayConfig 导入导出的配置文档，服务按 name、租户按 renter_id 匹配，不包含数据库 id
+type GatewayConfig struct {
+	Version    int                    `json:"version"`
+	ExportedAt string                 `json:"exported_at,omitempty"`
+	Services   []GatewayServiceConfig `json:"services"`
+	Renters    []GatewayRenterConfig  `json:"renters"`
+}
+
+type GatewayServiceConfig struct {
+	Name          string                     `json:"name"`
+	Desc          string                     `json:"desc"`
+	LoadType      string                     `json:"load_type" comment:"http/tcp/grpc"`
+	HTTPRule      *GatewayHTTPRuleConfig     `json:"http_rule,omitempty"`
+	HTTPRoutes    []HTTPRouteInput           `json:"http_routes,omitempty"`
+	TCPRule       *GatewayTCPRuleConfig      `json:"tcp_rule,omitempty"`
+	GRPCRule      *GatewayGRPCRuleConfig     `json:"grpc_rule,omitempty"`
+	AccessControl GatewayAccessControlConfig `json:"access_control"`
+	LoadBalance   GatewayLoadBalanceConfig   `json:"load_balance"`
+}
+
+type GatewayHTTPRuleConfig struct {
+	RuleType             int    `json:"rule_type"`
+	Rule                 string `json:"rule"`
+	NeedHttps            int    `json:"need_https"`
+	NeedStripUri         int    `json:"need_strip_uri"`
+	NeedWebsocket        int    `json:"need_websocket"`
+	UrlRewrite           string `json:"url_rewrite"`
+	HeaderTransfer       string `json:"header_transfer"`
+	RequestTransform     string `json:"request_transform"`
+	ResponseTransform    string `json:"response_transform"`
+	TransformMaxBody     int    `json:"transform_max_body"`
+	NeedCors             int    `json:"need_cors"`
+	CorsAllowOrigins     string `json:"cors_allow_origins"`
+	CorsAllowMethods     string `json:"cors_allow_methods"`
+	CorsAllowHeaders     string `json:"cors_allow_headers"`
+	CorsAllowCredentials int    `json:"cors_allow_credentials"`
+	CorsMaxAge           int    `json:"cors_max_age"`
+	NeedCompress         int    `json:"need_compress"`
+	CompressMinSize      int    `json:"compress_min_size"`
+	CompressTypes        string `json:"compress_types"`
+}
+
+type GatewayTCPRuleConfig struct {
+	Port int `json:"port"`
+}
+
+type GatewayGRPCRuleConfig struct {
+	Port           int    `json:"port"`
+	HeaderTransfer string `json:"header_transfer"`
+}
+
+type GatewayAccessControlConfig struct {
+	OpenAuth          int    `json:"open_auth"`
+	BlackList         string `json:"black_list"`
+	WhiteList         string `json:"white_list"`
+	WhiteHostName     string `json:"white_host_name"`
+	ClientIPFlowLimit int    `json:"clientip_flow_limit"`
+	ServiceFlowLimit  int    `json:"service_flow_limit"`
+}
+
+type GatewayLoadBalanceConfig struct {
+	RoundType              int    `json:"round_type"`
+	IpList                 string `json:"ip_list"`
+	WeightList             string `json:"weight_list"`
+	ForbidList             string `json:"forbid_list"`
+	UpstreamConnectTimeout int    `json:"upstream_connect_timeout"`
+	UpstreamHeaderTimeout  int    `json:"upstream_header_timeout"`
+	UpstreamIdleTimeout    int    `json:"upstream_idle_timeout"`
+	UpstreamMaxIdle        int    `json:"upstream_max_idle"`
+}
+
+// GatewayRenterConfig secret 为 public.RedactedValue 时导入保留原值
+type GatewayRenterConfig struct {
+	RenterID string `json:"renter_id"`
+	Name     string `json:"name"`
+	Secret   string `json:"secret"`
+	WhiteIPS string `json:"white_ips"`
+	Qpd      int64  `json:"qpd"`
+	Qps      int64  `json:"qps"`
+}
+
+type GatewayConfigExportInput struct {
+	Format     string `json:"format" form:"format" comment:"文档格式" example:"yaml" validate:"omitempty,oneof=yaml json"`
+	ShowSecret int    `json:"show_secret" form:"show_secret" comment:"导出租户密钥原文" example:"0" validate:"min=0,max=1"`
+}
+
+type GatewayConfigImportInput struct {
+	DryRun int `json:"dry_run" form:"dry_run" comment:"只返回变更不写入" example:"1"`
+	Prune  int `json:"prune" form:"prune" comment:"软删除文档中不存在的服务与租户" example:"0"`
+}
+
+type GatewayConfigImportOutput struct {
+	DryRun  bool                  `json:"dry_run"`
+	Changes []GatewayConfigChange `json:"changes"`
+}
+
+// GatewayConfigChange ResourceID 与 Version 只用于提交后通知代理，不返回给调用方
+type GatewayConfigChange struct {
+	ResourceType string                        `json:"resource_type"`
+	ResourceID   int64                         `json:"-"`
+	Name         string                        `json:"name"`
+	Action       string                        `json:"action"`
+	Version      int64                         `json:"-"`
+	Diff         map[string]public.AuditChange `json:"diff"`
+}
+
+func (params *GatewayConfigExportInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
+
+// BindParam body 为配置文档，参数只从 query 读取
+func (params *GatewayConfigImportInput) BindParam(c *gin.Context) error {
+	return c.ShouldBindQuery(params)
+}
@@ -41,6 +41,48 @@ type StatisticsOutput struct {
 	Yesterday []int64 `json:"yesterday" form:"yesterday" comment:"昨日统计" validate:"required"`
 }
 
+type RenterUsageInput struct {
+	ID        int64  `json:"id" form:"id" comment:"租户ID" validate:"required"`
+	StartDate string `json:"start_date" form:"start_date" comment:"开始日期" example:"2020-06-01" validate:"required,valid_date"`
+	EndDate   string `json:"end_date" form:"end_date" comment:"结束日期" example:"2020-06-30" validate:"required,valid_date"`
+}
+
+type RenterTopServicesInput struct {
+	ID        int64  `json:"id" form:"id" comment:"租户ID" validate:"required"`
+	StartDate string `json:"start_date" form:"start_date" comment:"开始日期" example:"2020-06-01" validate:"required,valid_date"`
+	EndDate   string `json:"end_date" form:"end_date" comment:"结束日期" example:"2020-06-30" validate:"required,valid_date"`
+	Limit     int    `json:"limit" form:"limit" comment:"返回条数" example:"10" validate:"min=0,max=100"`
+}
+
+type RenterUsageOutput struct {
+	RenterID  string                   `json:"renter_id" form:"renter_id" comment:"租户id"`
+	StartDate string                   `json:"start_date" form:"start_date" comment:"开始日期"`
+	EndDate   string                   `json:"end_date" form:"end_date" comment:"结束日期"`
+	Total     int64                    `json:"total" form:"total" comment:"总请求量"`
+	Daily     []RenterUsageDailyOutput `json:"daily" form:"daily" comment:"每日请求量"`
+	List      []RenterUsageItemOutput  `json:"list" form:"list" comment:"每日每个服务的请求量"`
+}
+
+type RenterUsageDailyOutput struct {
+	Day          string `json:"day" form:"day" comment:"日期"`
+	RequestCount int64  `json:"request_count" form:"request_count" comment:"请求量"`
+}
+
+type RenterUsageItemOutput struct {
+	Day          string `json:"day" form:"day" comment:"日期"`
+	ServiceName  string `json:"service_name" form:"service_name" comment:"服务名称"`
+	RequestCount int64  `json:"request_count" form:"request_count" comment:"请求量"`
+}
+
+type RenterTopServicesOutput struct {
+	List []RenterTopServiceItemOutput `json:"list" form:"list" comment:"服务列表"`
+}
+
+type RenterTopServiceItemOutput struct {
+	ServiceName  string `json:"service_name" form:"service_name" comment:"服务名称"`
+	RequestCount int64  `json:"request_count" form:"request_count" comment:"请求量"`
+}
+
 type AddRenterHttpInput struct {
 	RenterID string `json:"renter_id" form:"renter_id" comment:"租户id" validate:"required"`
 	Name     string `json:"name" form:"name" comment:"租户名称" validate:"required"`
@@ -75,3 +117,11 @@ func (params *AddRenterHttpInput) BindParam(c *gin.Context) error {
 func (params *UpdateRenterHttpInput) BindParam(c *gin.Context) error {
 	return public.DefaultGetValidParams(c, params)
 }
+
+func (params *RenterUsageInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
+
+func (params *RenterTopServicesInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
@@ -0,0 +1,19 @@
+package dto
+
+import (
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+)
+
+type RequestTailInput struct {
+	ServiceID int64  `json:"service_id" form:"service_id" comment:"服务ID" example:"" validate:"required"`
+	RenterID  string `json:"renter_id" form:"renter_id" comment:"租户ID" example:"" validate:""`
+	Status    string `json:"status" form:"status" comment:"状态码" example:"5xx" validate:"omitempty,valid_status_filter"`
+	Path      string `json:"path" form:"path" comment:"路径前缀" example:"/api" validate:""`
+	ClientIP  string `json:"client_ip" form:"client_ip" comment:"客户端ip" example:"" validate:"omitempty,ip"`
+	Duration  int    `json:"duration" form:"duration" comment:"持续时长, 单位s" example:"60" validate:"min=0,max=600"`
+}
+
+func (params *RequestTailInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
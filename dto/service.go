@@ -44,6 +44,23 @@ type ServiceAddHTTPInput struct {
 	UrlRewrite     string `json:"url_rewrite" form:"url_rewrite" comment:"url重写功能" example:"" validate:"valid_url_rewrite"`              //url重写功能
 	HeaderTransfer string `json:"header_transfer" form:"header_transfer" comment:"header转换" example:"" validate:"valid_header_transfer"` //header转换
 
+	RequestTransform  string `json:"request_transform" form:"request_transform" comment:"请求转换" example:"" validate:"valid_transform"`   //请求转换
+	ResponseTransform string `json:"response_transform" form:"response_transform" comment:"响应转换" example:"" validate:"valid_transform"` //响应转换
+	TransformMaxBody  int    `json:"transform_max_body" form:"transform_max_body" comment:"参与转换的最大body" example:"" validate:"min=0"`    //参与转换的最大body
+
+	NeedCors             int    `json:"need_cors" form:"need_cors" comment:"启用跨域" example:"" validate:"max=1,min=0"`                                 //启用跨域
+	CorsAllowOrigins     string `json:"cors_allow_origins" form:"cors_allow_origins" comment:"允许的origin" example:"" validate:"valid_cors_origins"`   //允许的origin
+	CorsAllowMethods     string `json:"cors_allow_methods" form:"cors_allow_methods" comment:"允许的方法" example:"" validate:"valid_methods"`            //允许的方法
+	CorsAllowHeaders     string `json:"cors_allow_headers" form:"cors_allow_headers" comment:"允许的header" example:"" validate:""`                     //允许的header
+	CorsAllowCredentials int    `json:"cors_allow_credentials" form:"cors_allow_credentials" comment:"允许携带cookie" example:"" validate:"max=1,min=0"` //允许携带cookie
+	CorsMaxAge           int    `json:"cors_max_age" form:"cors_max_age" comment:"预检缓存时间, 单位s" example:"" validate:"min=0"`                          //预检缓存时间, 单位s
+
+	NeedCompress    int    `json:"need_compress" form:"need_compress" comment:"启用响应压缩" example:"" validate:"max=1,min=0"`           //启用响应压缩
+	CompressMinSize int    `json:"compress_min_size" form:"compress_min_size" comment:"最小压缩大小, 单位byte" example:"" validate:"min=0"` //最小压缩大小, 单位byte
+	CompressTypes   string `json:"compress_types" form:"compress_types" comment:"可压缩的content-type" example:"" validate:""`          //可压缩的content-type
+
+	Routes []HTTPRouteInput `json:"routes" form:"routes" comment:"路由列表" example:"" validate:"dive"` //路由列表
+
 	OpenAuth          int    `json:"open_auth" form:"open_auth" comment:"是否开启权限" example:"" validate:"max=1,min=0"`                 //关键词
 	BlackList         string `json:"black_list" form:"black_list" comment:"黑名单ip" example:"" validate:""`                           //黑名单ip
 	WhiteList         string `json:"white_list" form:"white_list" comment:"白名单ip" example:"" validate:""`                           //白名单ip
@@ -72,6 +89,23 @@ type ServiceUpdateHTTPInput struct {
 	UrlRewrite     string `json:"url_rewrite" form:"url_rewrite" comment:"url重写功能" example:"" validate:"valid_url_rewrite"`              //url重写功能
 	HeaderTransfer string `json:"header_transfer" form:"header_transfer" comment:"header转换" example:"" validate:"valid_header_transfer"` //header转换
 
+	RequestTransform  string `json:"request_transform" form:"request_transform" comment:"请求转换" example:"" validate:"valid_transform"`   //请求转换
+	ResponseTransform string `json:"response_transform" form:"response_transform" comment:"响应转换" example:"" validate:"valid_transform"` //响应转换
+	TransformMaxBody  int    `json:"transform_max_body" form:"transform_max_body" comment:"参与转换的最大body" example:"" validate:"min=0"`    //参与转换的最大body
+
+	NeedCors             int    `json:"need_cors" form:"need_cors" comment:"启用跨域" example:"" validate:"max=1,min=0"`                                 //启用跨域
+	CorsAllowOrigins     string `json:"cors_allow_origins" form:"cors_allow_origins" comment:"允许的origin" example:"" validate:"valid_cors_origins"`   //允许的origin
+	CorsAllowMethods     string `json:"cors_allow_methods" form:"cors_allow_methods" comment:"允许的方法" example:"" validate:"valid_methods"`            //允许的方法
+	CorsAllowHeaders     string `json:"cors_allow_headers" form:"cors_allow_headers" comment:"允许的header" example:"" validate:""`                     //允许的header
+	CorsAllowCredentials int    `json:"cors_allow_credentials" form:"cors_allow_credentials" comment:"允许携带cookie" example:"" validate:"max=1,min=0"` //允许携带cookie
+	CorsMaxAge           int    `json:"cors_max_age" form:"cors_max_age" comment:"预检缓存时间, 单位s" example:"" validate:"min=0"`                          //预检缓存时间, 单位s
+
+	NeedCompress    int    `json:"need_compress" form:"need_compress" comment:"启用响应压缩" example:"" validate:"max=1,min=0"`           //启用响应压缩
+	CompressMinSize int    `json:"compress_min_size" form:"compress_min_size" comment:"最小压缩大小, 单位byte" example:"" validate:"min=0"` //最小压缩大小, 单位byte
+	CompressTypes   string `json:"compress_types" form:"compress_types" comment:"可压缩的content-type" example:"" validate:""`          //可压缩的content-type
+
+	Routes []HTTPRouteInput `json:"routes" form:"routes" comment:"路由列表" example:"" validate:"dive"` //路由列表
+
 	OpenAuth          int    `json:"open_auth" form:"open_auth" comment:"是否开启权限" example:"" validate:"max=1,min=0"`                 //关键词
 	BlackList         string `json:"black_list" form:"black_list" comment:"黑名单ip" example:"" validate:""`                           //黑名单ip
 	WhiteList         string `json:"white_list" form:"white_list" comment:"白名单ip" example:"" validate:""`                           //白名单ip
@@ -87,17 +121,32 @@ type ServiceUpdateHTTPInput struct {
 	UpstreamMaxIdle        int    `json:"upstream_max_idle" form:"upstream_max_idle" comment:"最大空闲链接数" example:"" validate:"min=0"`                     //最大空闲链接数
 }
 
+// 服务内的路由，方法、header、query 都为空时只按路径匹配
+type HTTPRouteInput struct {
+	Path        string `json:"path" form:"path" comment:"路径模式" example:"/users/:id" validate:"required,valid_route_path"`
+	Methods     string `json:"methods" form:"methods" comment:"允许的方法" example:"GET,POST" validate:"valid_methods"`
+	HeaderMatch string `json:"header_match" form:"header_match" comment:"header匹配" example:"" validate:"valid_header_match"`
+	QueryMatch  string `json:"query_match" form:"query_match" comment:"query匹配" example:"" validate:"valid_query_match"`
+	Priority    int    `json:"priority" form:"priority" comment:"优先级" example:"0" validate:"min=0,max=1000"`
+}
+
 type ServiceStatsOutput struct {
-	Today     []int `json:"today" form:"today"`
-	Yesterday []int `json:"yesterday" form:"yesterday"`
+	Today              []int   `json:"today" form:"today"`
+	Yesterday          []int   `json:"yesterday" form:"yesterday"`
+	QPS                int64   `json:"qps" form:"qps"`                                   //当前qps
+	TodayRequestNum    int64   `json:"today_request_num" form:"today_request_num"`       //今日请求数
+	ErrorRate          float64 `json:"error_rate" form:"error_rate"`                     //今日5xx错误率
+	P50                int64   `json:"p50" form:"p50"`                                   //近一到两小时延迟p50, 单位ms
+	P95                int64   `json:"p95" form:"p95"`                                   //近一到两小时延迟p95, 单位ms
+	P99                int64   `json:"p99" form:"p99"`                                   //近一到两小时延迟p99, 单位ms
+	CompressSavedBytes int64   `json:"compress_saved_bytes" form:"compress_saved_bytes"` //今日压缩节省的字节数
 }
 
 type ServiceAddTcpInput struct {
-	ServiceName    string `json:"service_name" form:"service_name" comment:"服务名称" validate:"required,valid_service_name"`
-	ServiceDesc    string `json:"service_desc" form:"service_desc" comment:"服务描述" validate:"required"`
-	Port           int    `json:"port" form:"port" comment:"端口，需要设置8001-8999范围内" validate:"required,min=8001,max=8999"`
-	HeaderTransfer string `json:"header_transfer" form:"header_transfer" comment:"header头转换" validate:"
-"`
+	ServiceName       string `json:"service_name" form:"service_name" comment:"服务名称" validate:"required,valid_service_name"`
+	ServiceDesc       string `json:"service_desc" form:"service_desc" comment:"服务描述" validate:"required"`
+	Port              int    `json:"port" form:"port" comment:"端口，需要设置8001-8999范围内" validate:"required,min=8001,max=8999"`
+	HeaderTransfer    string `json:"header_transfer" form:"header_transfer" comment:"header头转换" validate:""`
 	OpenAuth          int    `json:"open_auth" form:"open_auth" comment:"是否开启权限验证" validate:""`
 	BlackList         string `json:"black_list" form:"black_list" comment:"黑名单IP，以逗号间隔，白名单优先级高于黑名单" validate:"valid_iplist"`
 	WhiteList         string `json:"white_list" form:"white_list" comment:"白名单IP，以逗号间隔，白名单优先级高于黑名单" validate:"valid_iplist"`
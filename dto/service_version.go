@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+)
+
+type ServiceVersionListInput struct {
+	ID         int64 `json:"id" form:"id" comment:"服务ID" example:"1" validate:"required"`
+	PageNumber int   `json:"page_number" form:"page_number" comment:"页码" example:"1" validate:"required,min=1,max=999"`
+	PageSize   int   `json:"page_size" form:"page_size" comment:"每页条数" example:"20" validate:"required,min=1,max=999"`
+}
+
+type ServiceVersionListOutput struct {
+	List    []ServiceVersionItemOutput `json:"list" form:"list" comment:"版本列表"`
+	Total   int64                      `json:"total" form:"total" comment:"版本总数"`
+	Current int64                      `json:"current" form:"current" comment:"当前版本"`
+}
+
+type ServiceVersionItemOutput struct {
+	Version   int64  `json:"version"`
+	Action    string `json:"action"`
+	AdminID   int    `json:"admin_id"`
+	AdminName string `json:"admin_name"`
+	CreatedAt string `json:"created_at"`
+}
+
+type ServiceVersionDiffInput struct {
+	ID   int64 `json:"id" form:"id" comment:"服务ID" example:"1" validate:"required"`
+	From int64 `json:"from" form:"from" comment:"旧版本" example:"1" validate:"required,min=1"`
+	To   int64 `json:"to" form:"to" comment:"新版本，为空时与当前版本比较" example:"2" validate:"min=0"`
+}
+
+type ServiceVersionDiffOutput struct {
+	ID   int64                         `json:"id"`
+	From int64                         `json:"from"`
+	To   int64                         `json:"to"`
+	Diff map[string]public.AuditChange `json:"diff"`
+}
+
+type ServiceRollbackInput struct {
+	ID      int64 `json:"id" form:"id" comment:"服务ID" example:"1" validate:"required"`
+	Version int64 `json:"version" form:"version" comment:"回滚到的版本" example:"1" validate:"required,min=1"`
+}
+
+func (params *ServiceVersionListInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
+
+func (params *ServiceVersionDiffInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
+
+func (params *ServiceRollbackInput) BindParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, params)
+}
@@ -3,6 +3,8 @@ module github.com/JunxiHe459/gateway
 go 1.14
 
 require (
+	github.com/andybalholm/brotli v1.0.4
+	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/e421083458/go_gateway v0.0.0-20200620084504-d602eb8bc883
 	github.com/e421083458/golang_common v1.0.3
 	github.com/e421083458/gorm v1.0.1
@@ -11,13 +13,19 @@ require (
 	github.com/gin-gonic/gin v1.4.0
 	github.com/go-playground/locales v0.12.1
 	github.com/go-playground/universal-translator v0.16.0
+	github.com/gorilla/securecookie v1.1.1
+	github.com/gorilla/sessions v1.1.3
 	github.com/jinzhu/now v1.1.2 // indirect
+	github.com/mattn/go-sqlite3 v1.14.6 // indirect
 	github.com/pkg/errors v0.8.1
+	github.com/spf13/viper v1.7.0
 	github.com/swaggo/files v0.0.0-20190704085106-630677cd5c14
 	github.com/swaggo/gin-swagger v1.2.0
 	github.com/swaggo/swag v1.6.5
+	golang.org/x/net v0.0.0-20190827160401-ba9fcec4b297
 	golang.org/x/time v0.0.0-20200416051211-89c76fbcd5d1
 	gopkg.in/go-playground/validator.v9 v9.29.0
+	gopkg.in/yaml.v2 v2.2.4
 )
 
 replace github.com/gin-contrib/sse v0.1.0 => github.com/e421083458/sse v0.1.1
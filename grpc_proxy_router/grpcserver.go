@@ -0,0 +1,114 @@
+package grpc_proxy_router
+
+import (
+	"context"
+	"fmt"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/http_proxy_middleware"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"log"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// grpcServerMap 按服务 id 记录正在监听的 server 及启动时使用的配置
+var (
+	grpcServerMap = map[int64]*grpcServerItem{}
+	grpcLocker    sync.Mutex
+)
+
+type grpcServerItem struct {
+	server        *http.Server
+	serviceDetail *dao.ServiceDetail
+}
+
+// GrpcServerRun 每个 grpc 服务监听自己的端口
+// grpc 基于 http2，这里按明文 http2 (h2c) 接收请求并透明转发，不解析 protobuf
+// 因此 header 转换、限流、故障注入等中间件与 http 代理共用
+func GrpcServerRun() {
+	grpcLocker.Lock()
+	defer grpcLocker.Unlock()
+	for _, serviceItem := range dao.ServiceManagerHandler.GetGrpcServiceList() {
+		grpcServerStart(serviceItem)
+	}
+}
+
+// GrpcServerReload 按当前服务列表调整监听：删除或配置变化的服务先关闭，再启动新增与变化的服务
+func GrpcServerReload() {
+	grpcLocker.Lock()
+	defer grpcLocker.Unlock()
+	pending := map[int64]*dao.ServiceDetail{}
+	for _, serviceItem := range dao.ServiceManagerHandler.GetGrpcServiceList() {
+		pending[serviceItem.Info.ID] = serviceItem
+	}
+	for serviceID, item := range grpcServerMap {
+		if serviceDetail, ok := pending[serviceID]; ok && reflect.DeepEqual(serviceDetail, item.serviceDetail) {
+			delete(pending, serviceID)
+			continue
+		}
+		grpcServerShutdown(item.server)
+		delete(grpcServerMap, serviceID)
+	}
+	for _, serviceItem := range pending {
+		grpcServerStart(serviceItem)
+	}
+}
+
+// grpcServerStart 调用方持有 grpcLocker
+func grpcServerStart(serviceDetail *dao.ServiceDetail) {
+	addr := fmt.Sprintf(":%d", serviceDetail.GRPCRule.Port)
+
+	router := gin.New()
+	router.Use(
+		middleware.RecoveryMiddleware(),
+		http_proxy_middleware.ServiceMiddleware(serviceDetail),
+		http_proxy_middleware.HTTPTraceMiddleware(),
+		http_proxy_middleware.HTTPAccessLogMiddleware(),
+		http_proxy_middleware.HTTPMetricsMiddleware(),
+		http_proxy_middleware.HTTPFlowCountMiddleware(),
+		http_proxy_middleware.HTTPRequestTailMiddleware(),
+		http_proxy_middleware.HTTPWhiteListMiddleware(),
+		http_proxy_middleware.HTTPBlackListMiddleware(),
+		http_proxy_middleware.HTTPFlowLimitMiddleware(),
+		http_proxy_middleware.HTTPRenterAuthMiddleware(),
+		http_proxy_middleware.HTTPRenterFlowCountMiddleware(),
+		http_proxy_middleware.HTTPFaultInjectionMiddleware(),
+		http_proxy_middleware.HTTPHeaderTransferMiddleware(),
+		http_proxy_middleware.HTTPReverseProxyMiddleware())
+
+	grpcServer := &http.Server{
+		Addr:    addr,
+		Handler: h2c.NewHandler(router, &http2.Server{}),
+	}
+	grpcServerMap[serviceDetail.Info.ID] = &grpcServerItem{server: grpcServer, serviceDetail: serviceDetail}
+
+	go func() {
+		log.Printf(" [INFO] grpc_proxy_run %v\n", addr)
+		if err := grpcServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf(" [ERROR] grpc_proxy_run %v err:%v\n", addr, err)
+		}
+	}()
+}
+
+func GrpcServerStop() {
+	grpcLocker.Lock()
+	defer grpcLocker.Unlock()
+	for _, item := range grpcServerMap {
+		grpcServerShutdown(item.server)
+	}
+	grpcServerMap = map[int64]*grpcServerItem{}
+}
+
+func grpcServerShutdown(grpcServer *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := grpcServer.Shutdown(ctx); err != nil {
+		log.Printf(" [ERROR] grpc_proxy_stop %v err:%v\n", grpcServer.Addr, err)
+	}
+	log.Printf(" [INFO] grpc_proxy_stop %v stopped\n", grpcServer.Addr)
+}
@@ -0,0 +1,51 @@
+package http_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// 结构化访问日志
+// 反向代理通过 c.Set("upstream", node) 与 c.Set("retries", n) 记录下游节点与重试次数
+func HTTPAccessLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+		startTime := time.Now()
+		c.Next()
+
+		entry := &public.AccessLogEntry{
+			Time:          startTime.Format(time.RFC3339Nano),
+			TraceID:       public.GetGinTraceContext(c).TraceId,
+			Service:       serviceDetail.Info.ServiceName,
+			ClientIP:      c.ClientIP(),
+			Method:        c.Request.Method,
+			Path:          c.Request.URL.Path,
+			Upstream:      c.GetString("upstream"),
+			Status:        c.Writer.Status(),
+			RequestBytes:  c.Request.ContentLength,
+			ResponseBytes: int64(c.Writer.Size()),
+			LatencyMs:     time.Since(startTime).Milliseconds(),
+			Retries:       c.GetInt("retries"),
+		}
+		if renterInterface, ok := c.Get("renter"); ok {
+			entry.Renter = renterInterface.(*dao.Renter).RenterID
+		}
+		if entry.RequestBytes < 0 {
+			entry.RequestBytes = 0
+		}
+		if entry.ResponseBytes < 0 {
+			entry.ResponseBytes = 0
+		}
+		public.AccessLoggerHandler.Log(entry)
+	}
+}
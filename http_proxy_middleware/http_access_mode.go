@@ -0,0 +1,29 @@
+package http_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// 按域名或路径前缀匹配服务，之后的中间件通过 c.Get("service") 读取
+func HTTPAccessModeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service, err := dao.ServiceManagerHandler.HTTPAccessMode(c)
+		if err != nil {
+			middleware.ResponseError(c, 1001, err)
+			c.Abort()
+			return
+		}
+		c.Set("service", service)
+		c.Next()
+	}
+}
+
+// 固定服务，grpc 服务按端口区分，每个端口对应一个服务
+func ServiceMiddleware(serviceDetail *dao.ServiceDetail) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("service", serviceDetail)
+		c.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package http_proxy_middleware
+
+import (
+	"fmt"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// ip 黑名单，白名单优先级更高，设置了白名单时不检查黑名单
+func HTTPBlackListMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+
+		whiteIpList := []string{}
+		if serviceDetail.AccessControl.WhiteList != "" {
+			whiteIpList = strings.Split(serviceDetail.AccessControl.WhiteList, ",")
+		}
+		blackIpList := []string{}
+		if serviceDetail.AccessControl.BlackList != "" {
+			blackIpList = strings.Split(serviceDetail.AccessControl.BlackList, ",")
+		}
+		if serviceDetail.AccessControl.OpenAuth == 1 && len(whiteIpList) == 0 && len(blackIpList) > 0 {
+			if public.InStringSlice(blackIpList, c.ClientIP()) {
+				middleware.ResponseError(c, 3001, errors.New(fmt.Sprintf("%s in black ip list", c.ClientIP())))
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,211 @@
+package http_proxy_middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// 响应压缩，根据 Accept-Encoding 选择 br 或 gzip
+func HTTPCompressMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+		httpRule := serviceDetail.HTTPRule
+		if httpRule.NeedCompress != 1 || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+		// 客户端不支持压缩时也要包装，保证每个响应都带 Vary: Accept-Encoding
+		writer := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       public.NegotiateEncoding(c.GetHeader("Accept-Encoding")),
+			bodyless:       c.Request.Method == http.MethodHead,
+			minSize:        httpRule.CompressMinSize,
+			types:          httpRule.CompressTypes,
+		}
+		if writer.minSize <= 0 {
+			writer.minSize = public.CompressMinSizeDefault
+		}
+		if writer.types == "" {
+			writer.types = public.CompressTypesDefault
+		}
+		c.Writer = writer
+		c.Next()
+		writer.Close()
+		c.Writer = writer.ResponseWriter
+
+		if saved := writer.rawBytes - writer.compressedBytes; writer.compressor != nil && saved > 0 {
+			counter, err := public.FlowCounterHandler.GetCounter(public.CompressSavedPrefix + serviceDetail.Info.ServiceName)
+			if err != nil {
+				return
+			}
+			counter.IncreaseBy(saved)
+		}
+	}
+}
+
+// compressWriter 先缓存不足 minSize 的数据，达到阈值后再决定是否压缩
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minSize  int
+	types    string
+	// HEAD 请求以及 204/304 响应没有 body，不压缩也不改 header
+	bodyless bool
+
+	buffer     bytes.Buffer
+	decided    bool
+	compressor io.WriteCloser
+
+	rawBytes        int64
+	compressedBytes int64
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	w.rawBytes += int64(len(data))
+	if w.decided {
+		return w.writeDecided(data)
+	}
+	w.buffer.Write(data)
+	if w.buffer.Len() < w.minSize {
+		return len(data), nil
+	}
+	w.decide(true)
+	if err := w.flushBuffer(); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if code == http.StatusNoContent || code == http.StatusNotModified || code < http.StatusOK {
+		w.bodyless = true
+	}
+	// gin 的 WriteHeader 只记录状态码，header 在第一次 Write 时才发出，之后仍可以决定是否压缩
+	addVary(w.Header(), "Accept-Encoding")
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush 在未达到阈值时继续缓存，反向代理对 chunked 响应每次写入都会 Flush
+// 只有明确的流式 content-type 才放弃压缩直接透传
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if !isStreamingType(w.Header().Get("Content-Type")) {
+			return
+		}
+		w.decide(false)
+		w.flushBuffer()
+	}
+	if flusher, ok := w.compressor.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Close() {
+	if !w.decided {
+		w.decide(false)
+		w.flushBuffer()
+	}
+	if w.compressor != nil {
+		w.compressor.Close()
+	}
+}
+
+func (w *compressWriter) decide(bigEnough bool) {
+	w.decided = true
+	header := w.Header()
+	addVary(header, "Accept-Encoding")
+	if !bigEnough || w.bodyless || w.encoding == "" || header.Get("Content-Encoding") != "" ||
+		!w.compressible(header.Get("Content-Type")) {
+		return
+	}
+	header.Set("Content-Encoding", w.encoding)
+	header.Del("Content-Length")
+	target := &countWriter{writer: w.ResponseWriter, count: &w.compressedBytes}
+	if w.encoding == public.EncodingBrotli {
+		w.compressor = brotli.NewWriter(target)
+	} else {
+		w.compressor = gzip.NewWriter(target)
+	}
+}
+
+func (w *compressWriter) flushBuffer() error {
+	data := w.buffer.Bytes()
+	w.buffer.Reset()
+	if len(data) == 0 {
+		return nil
+	}
+	_, err := w.writeDecided(data)
+	return err
+}
+
+func (w *compressWriter) writeDecided(data []byte) (int, error) {
+	if w.compressor != nil {
+		return w.compressor.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) compressible(contentType string) bool {
+	if contentType == "" || isStreamingType(contentType) {
+		return false
+	}
+	for _, item := range strings.Split(w.types, ",") {
+		if item != "" && strings.HasPrefix(contentType, strings.TrimSpace(item)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isStreamingType(contentType string) bool {
+	for _, item := range strings.Split(public.CompressStreamingTypes, ",") {
+		if strings.HasPrefix(contentType, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// addVary 上游已经带了同名 Vary 时不重复添加
+func addVary(header http.Header, value string) {
+	for _, line := range header["Vary"] {
+		for _, item := range strings.Split(line, ",") {
+			item = strings.TrimSpace(item)
+			if item == "*" || strings.EqualFold(item, value) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", value)
+}
+
+type countWriter struct {
+	writer io.Writer
+	count  *int64
+}
+
+func (w *countWriter) Write(data []byte) (int, error) {
+	n, err := w.writer.Write(data)
+	*w.count += int64(n)
+	return n, err
+}
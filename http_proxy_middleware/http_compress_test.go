@@ -0,0 +1,99 @@
+package http_proxy_middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newCompressTestGateway 返回经过压缩中间件和反向代理访问 upstream 的地址
+func newCompressTestGateway(t *testing.T, upstream http.HandlerFunc) string {
+	server := httptest.NewServer(upstream)
+	t.Cleanup(server.Close)
+	target, _ := url.Parse(server.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("service", &dao.ServiceDetail{
+			Info:     &dao.ServiceInfo{ServiceName: "compress_test"},
+			HTTPRule: &dao.HttpRule{NeedCompress: 1},
+		})
+	}, HTTPCompressMiddleware())
+	router.GET("/", func(c *gin.Context) {
+		proxy.ServeHTTP(c.Writer, c.Request)
+	})
+	gateway := httptest.NewServer(router)
+	t.Cleanup(gateway.Close)
+	return gateway.URL
+}
+
+func getWithGzip(t *testing.T, url string) (*http.Response, []byte) {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	// 关闭自动解压，直接检查网关返回的内容
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, body
+}
+
+func TestHTTPCompressChunkedUpstream(t *testing.T) {
+	chunk := strings.Repeat("x", 400)
+	gateway := newCompressTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// 没有 Content-Length，上游以 chunked 发送，反向代理每次写入后都会 Flush
+		for i := 0; i < 4; i++ {
+			w.Write([]byte(chunk))
+			w.(http.Flusher).Flush()
+		}
+	})
+
+	resp, compressed := getWithGzip(t, gateway)
+	if got := resp.Header.Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != strings.Repeat(chunk, 4) {
+		t.Fatalf("got %d bytes after gunzip", len(body))
+	}
+}
+
+func TestHTTPCompressEventStreamPassThrough(t *testing.T) {
+	gateway := newCompressTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: hello\n\n"))
+		w.(http.Flusher).Flush()
+	})
+
+	resp, body := getWithGzip(t, gateway)
+	if got := resp.Header.Get("Content-Encoding"); got != "" {
+		t.Fatalf("Content-Encoding = %q, want none", got)
+	}
+	if string(body) != "data: hello\n\n" {
+		t.Fatalf("got %q", body)
+	}
+}
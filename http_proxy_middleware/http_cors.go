@@ -0,0 +1,73 @@
+package http_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"net/http"
+	"strconv"
+)
+
+// 跨域处理，预检请求由网关直接应答，普通请求补充 CORS 响应头
+func HTTPCorsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		httpRule := serverInterface.(*dao.ServiceDetail).HTTPRule
+		origin := c.GetHeader("Origin")
+		if httpRule.NeedCors != 1 || origin == "" {
+			c.Next()
+			return
+		}
+
+		isPreflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		c.Writer.Header().Add("Vary", "Origin")
+		if !public.MatchOrigin(httpRule.CorsAllowOrigins, origin) {
+			if isPreflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		// 列表包含 * 时不允许携带 cookie，校验之前保存的旧配置同样按此处理
+		allowAny := public.CorsOriginsHasAny(httpRule.CorsAllowOrigins)
+		allowCredentials := httpRule.CorsAllowCredentials == 1 && !allowAny
+		allowOrigin := origin
+		if allowAny {
+			allowOrigin = "*"
+		}
+		c.Header("Access-Control-Allow-Origin", allowOrigin)
+		if allowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+		if !isPreflight {
+			c.Next()
+			return
+		}
+
+		allowMethods := httpRule.CorsAllowMethods
+		if allowMethods == "" {
+			allowMethods = "GET,POST,PUT,PATCH,DELETE,HEAD,OPTIONS"
+		}
+		c.Header("Access-Control-Allow-Methods", allowMethods)
+		allowHeaders := httpRule.CorsAllowHeaders
+		if allowHeaders == "" {
+			allowHeaders = c.GetHeader("Access-Control-Request-Headers")
+		}
+		if allowHeaders != "" {
+			c.Header("Access-Control-Allow-Headers", allowHeaders)
+		}
+		if httpRule.CorsMaxAge > 0 {
+			c.Header("Access-Control-Max-Age", strconv.Itoa(httpRule.CorsMaxAge))
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,80 @@
+package http_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 故障注入，按规则对请求做延迟、中断或重置连接
+func HTTPFaultInjectionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+
+		renterID := ""
+		if renterInterface, ok := c.Get("renter"); ok {
+			renterID = renterInterface.(*dao.Renter).RenterID
+		}
+		faultRule := dao.FaultRuleManagerHandler.GetFaultRule(serviceDetail.Info.ID, renterID, c.Request.Header)
+		if faultRule == nil {
+			c.Next()
+			return
+		}
+
+		switch faultRule.FaultType {
+		case public.FaultTypeDelay:
+			select {
+			case <-time.After(faultRule.Delay()):
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			}
+		case public.FaultTypeAbort:
+			if serviceDetail.Info.LoadType == public.LoadTypeGRPC {
+				abortWithGrpcStatus(c, faultRule.GrpcCode, "fault injection")
+				return
+			}
+			c.AbortWithStatus(faultRule.AbortCode)
+			return
+		case public.FaultTypeReset:
+			hijacker, ok := c.Writer.(http.Hijacker)
+			if !ok {
+				c.AbortWithStatus(http.StatusBadGateway)
+				return
+			}
+			conn, _, err := hijacker.Hijack()
+			if err != nil {
+				c.AbortWithStatus(http.StatusBadGateway)
+				return
+			}
+			// SO_LINGER=0 使 Close 直接发送 RST
+			if tcpConn, ok := conn.(*net.TCPConn); ok {
+				tcpConn.SetLinger(0)
+			}
+			conn.Close()
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// abortWithGrpcStatus grpc 的错误放在 trailers-only 响应里，http 状态码固定为 200
+func abortWithGrpcStatus(c *gin.Context, code int, message string) {
+	c.Header("Content-Type", "application/grpc")
+	c.Header("Grpc-Status", strconv.Itoa(code))
+	c.Header("Grpc-Message", message)
+	c.AbortWithStatus(http.StatusOK)
+}
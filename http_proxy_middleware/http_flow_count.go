@@ -0,0 +1,59 @@
+package http_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"net/http"
+	"time"
+)
+
+// 流量统计，记录全站与服务请求数、5xx 错误数与请求耗时，grpc 服务共用
+func HTTPFlowCountMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+		serviceName := serviceDetail.Info.ServiceName
+
+		if err := public.FlowCounterHandler.IncreaseServiceFlow(serviceName); err != nil {
+			middleware.ResponseError(c, 4001, err)
+			c.Abort()
+			return
+		}
+
+		startTime := time.Now()
+		c.Next()
+
+		latencyCounter, err := public.LatencyCounterHandler.GetCounter(public.FlowServicePrefix + serviceName)
+		if err == nil {
+			latencyCounter.Record(time.Since(startTime))
+		}
+		if isServerError(c) {
+			errorCounter, err := public.FlowCounterHandler.GetCounter(public.FlowServiceErrorPrefix + serviceName)
+			if err == nil {
+				errorCounter.Increase()
+			}
+		}
+	}
+}
+
+// isServerError http 返回 5xx，或 grpc 响应的 grpc-status 为服务端错误
+// grpc-status 通常在 trailer 中，反向代理转发后同样写入 c.Writer.Header()
+func isServerError(c *gin.Context) bool {
+	if c.Writer.Status() >= http.StatusInternalServerError {
+		return true
+	}
+	header := c.Writer.Header()
+	grpcStatus := header.Get("Grpc-Status")
+	if values := header[http.TrailerPrefix+"Grpc-Status"]; grpcStatus == "" && len(values) > 0 {
+		grpcStatus = values[0]
+	}
+	return public.IsGrpcServerError(grpcStatus)
+}
@@ -0,0 +1,34 @@
+package http_proxy_middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsServerErrorGrpcStatus(t *testing.T) {
+	cases := []struct {
+		status int
+		header string
+		value  string
+		want   bool
+	}{
+		{http.StatusBadGateway, "", "", true},
+		{http.StatusOK, "", "", false},
+		{http.StatusOK, "Grpc-Status", "0", false},
+		{http.StatusOK, "Grpc-Status", "3", false},
+		{http.StatusOK, "Grpc-Status", "14", true},
+		{http.StatusOK, http.TrailerPrefix + "Grpc-Status", "13", true},
+	}
+	for _, item := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		if item.header != "" {
+			c.Writer.Header()[item.header] = []string{item.value}
+		}
+		c.Writer.WriteHeader(item.status)
+		if got := isServerError(c); got != item.want {
+			t.Errorf("status %d %s=%s: got %v, want %v", item.status, item.header, item.value, got, item.want)
+		}
+	}
+}
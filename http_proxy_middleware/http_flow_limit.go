@@ -0,0 +1,63 @@
+package http_proxy_middleware
+
+import (
+	"fmt"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// 服务端与客户端 ip 限流
+func HTTPFlowLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+		serviceName := serviceDetail.Info.ServiceName
+		renterID := ""
+		if renterInterface, ok := c.Get("renter"); ok {
+			renterID = renterInterface.(*dao.Renter).RenterID
+		}
+
+		if serviceDetail.AccessControl.ServiceFlowLimit != 0 {
+			serviceLimiter, err := public.FlowLimiterHandler.GetLimiter(
+				public.FlowServicePrefix+serviceName,
+				float64(serviceDetail.AccessControl.ServiceFlowLimit))
+			if err != nil {
+				middleware.ResponseError(c, 5001, err)
+				c.Abort()
+				return
+			}
+			if !serviceLimiter.Allow() {
+				public.MetricRateLimitRejections.Inc(serviceName, public.MetricsRenterLabel(renterID), "service")
+				middleware.ResponseError(c, 5002, errors.New(fmt.Sprintf("service flow limit %v", serviceDetail.AccessControl.ServiceFlowLimit)))
+				c.Abort()
+				return
+			}
+		}
+
+		if serviceDetail.AccessControl.ClientIPFlowLimit > 0 {
+			clientLimiter, err := public.FlowLimiterHandler.GetLimiter(
+				public.FlowServicePrefix+serviceName+"_"+c.ClientIP(),
+				float64(serviceDetail.AccessControl.ClientIPFlowLimit))
+			if err != nil {
+				middleware.ResponseError(c, 5003, err)
+				c.Abort()
+				return
+			}
+			if !clientLimiter.Allow() {
+				public.MetricRateLimitRejections.Inc(serviceName, public.MetricsRenterLabel(renterID), "client_ip")
+				middleware.ResponseError(c, 5002, errors.New(fmt.Sprintf("%v flow limit %v", c.ClientIP(), serviceDetail.AccessControl.ClientIPFlowLimit)))
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package http_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// header 转换，格式: add/edit headname headvalue 或 del headname -，多条逗号间隔
+// grpc 服务使用 grpc_rule 中的配置，metadata 即 header
+func HTTPHeaderTransferMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+		headerTransfer := serviceDetail.HTTPRule.HeaderTransfer
+		if serviceDetail.Info.LoadType == public.LoadTypeGRPC {
+			headerTransfer = serviceDetail.GRPCRule.HeaderTransfer
+		}
+		for _, item := range strings.Split(headerTransfer, ",") {
+			items := strings.Split(item, " ")
+			if len(items) != 3 {
+				continue
+			}
+			if items[0] == "add" || items[0] == "edit" {
+				c.Request.Header.Set(items[1], items[2])
+			}
+			if items[0] == "del" {
+				c.Request.Header.Del(items[1])
+			}
+		}
+		c.Next()
+	}
+}
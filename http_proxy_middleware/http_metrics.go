@@ -0,0 +1,38 @@
+package http_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"strconv"
+	"time"
+)
+
+// prometheus 指标，记录请求数、延迟与在途请求数
+func HTTPMetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+		serviceName := serviceDetail.Info.ServiceName
+		loadType := public.MetricsLoadTypeLabel(serviceDetail.Info.LoadType)
+
+		public.MetricActiveConnections.Inc(serviceName, loadType)
+		defer public.MetricActiveConnections.Dec(serviceName, loadType)
+		startTime := time.Now()
+		c.Next()
+
+		renterID := ""
+		if renterInterface, ok := c.Get("renter"); ok {
+			renterID = renterInterface.(*dao.Renter).RenterID
+		}
+		public.MetricRequestTotal.Inc(serviceName, public.MetricsRenterLabel(renterID), loadType, strconv.Itoa(c.Writer.Status()))
+		public.MetricRequestDuration.Observe(time.Since(startTime).Seconds(), serviceName, loadType)
+	}
+}
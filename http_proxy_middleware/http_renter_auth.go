@@ -0,0 +1,73 @@
+package http_proxy_middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// 租户认证，按 X-Renter-Id 与 X-Renter-Secret 识别租户，通过后 c.Set("renter", renter)
+// 服务开启权限验证时必须认证通过，未开启时认证失败按匿名请求处理
+func HTTPRenterAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+		openAuth := serviceDetail.AccessControl.OpenAuth == 1
+
+		renterID := c.GetHeader(public.RenterIDHeader)
+		secret := c.GetHeader(public.RenterSecretHeader)
+		// 密钥不透传给上游，之后的中间件也看不到
+		c.Request.Header.Del(public.RenterSecretHeader)
+		if renterID == "" {
+			if openAuth {
+				middleware.ResponseError(c, 3001, errors.New("renter id required"))
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		renter, ok := dao.RenterManagerHandler.GetRenter(renterID)
+		if !ok || subtle.ConstantTimeCompare([]byte(renter.Secret), []byte(secret)) != 1 {
+			if openAuth {
+				middleware.ResponseError(c, 3002, errors.New("invalid renter id or secret"))
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		if !renterIPAllowed(renter.WhiteIPS, c.ClientIP()) {
+			middleware.ResponseError(c, 3003, errors.New(fmt.Sprintf("%s not in renter white ip list", c.ClientIP())))
+			c.Abort()
+			return
+		}
+		c.Set("renter", renter)
+		c.Next()
+	}
+}
+
+// renterIPAllowed 白名单为空时不限制，逗号间隔，支持前缀匹配
+func renterIPAllowed(whiteIPs, clientIP string) bool {
+	if strings.TrimSpace(whiteIPs) == "" {
+		return true
+	}
+	for _, item := range strings.Split(whiteIPs, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" && strings.HasPrefix(clientIP, item) {
+			return true
+		}
+	}
+	return false
+}
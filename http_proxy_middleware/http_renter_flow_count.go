@@ -0,0 +1,81 @@
+package http_proxy_middleware
+
+import (
+	"fmt"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// 租户流量统计与日/秒限额，放在租户认证之后
+// 同时按 租户+服务 计数，供用量汇总使用；租户的 5xx 错误数与请求耗时供看板 Top-N 使用
+func HTTPRenterFlowCountMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		renterInterface, ok := c.Get("renter")
+		if !ok {
+			c.Next()
+			return
+		}
+		renter := renterInterface.(*dao.Renter)
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+
+		renterCounter, err := public.FlowCounterHandler.GetCounter(public.FlowAppPrefix + renter.RenterID)
+		if err != nil {
+			middleware.ResponseError(c, 2002, err)
+			c.Abort()
+			return
+		}
+		// TotalCount 是每秒从 CounterStore 读回的当日总量，多个网关节点共享；超限的请求不计入用量
+		if renter.Qpd > 0 && renterCounter.TotalCount >= renter.Qpd {
+			middleware.ResponseError(c, 2003, errors.New(fmt.Sprintf("租户日请求量限流 limit:%v current:%v", renter.Qpd, renterCounter.TotalCount)))
+			c.Abort()
+			return
+		}
+		// 每秒请求量与服务限流一样按节点限制
+		if renter.Qps > 0 {
+			renterLimiter, err := public.FlowLimiterHandler.GetLimiter(public.FlowAppPrefix+renter.RenterID, float64(renter.Qps))
+			if err != nil {
+				middleware.ResponseError(c, 5001, err)
+				c.Abort()
+				return
+			}
+			if !renterLimiter.Allow() {
+				public.MetricRateLimitRejections.Inc(serviceDetail.Info.ServiceName, public.MetricsRenterLabel(renter.RenterID), "renter")
+				middleware.ResponseError(c, 5002, errors.New(fmt.Sprintf("租户每秒请求量限流 limit:%v", renter.Qps)))
+				c.Abort()
+				return
+			}
+		}
+		renterCounter.Increase()
+		usageCounter, err := public.FlowCounterHandler.GetCounter(public.RenterServiceCounterName(renter.RenterID, serviceDetail.Info.ServiceName))
+		if err != nil {
+			middleware.ResponseError(c, 2002, err)
+			c.Abort()
+			return
+		}
+		usageCounter.Increase()
+
+		startTime := time.Now()
+		c.Next()
+
+		latencyCounter, err := public.LatencyCounterHandler.GetCounter(public.FlowAppPrefix + renter.RenterID)
+		if err == nil {
+			latencyCounter.Record(time.Since(startTime))
+		}
+		if isServerError(c) {
+			errorCounter, err := public.FlowCounterHandler.GetCounter(public.FlowAppErrorPrefix + renter.RenterID)
+			if err == nil {
+				errorCounter.Increase()
+			}
+		}
+	}
+}
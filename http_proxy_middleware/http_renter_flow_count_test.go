@@ -0,0 +1,93 @@
+package http_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// nopCounterStore 让测试中注册的计数器不访问 redis
+type nopCounterStore struct{}
+
+func (s *nopCounterStore) Incr(trace *lib.TraceContext, incrs ...public.CounterIncr) error {
+	return nil
+}
+
+func (s *nopCounterStore) Get(trace *lib.TraceContext, key string) (int64, bool, error) {
+	return 0, false, nil
+}
+
+func (s *nopCounterStore) MGet(trace *lib.TraceContext, keys ...string) ([]int64, error) {
+	return make([]int64, len(keys)), nil
+}
+
+func (s *nopCounterStore) HGetAll(trace *lib.TraceContext, key string) (map[string]int64, error) {
+	return map[string]int64{}, nil
+}
+
+func (s *nopCounterStore) Scan(trace *lib.TraceContext, prefix string) (map[string]int64, error) {
+	return map[string]int64{}, nil
+}
+
+func newRenterFlowCountRouter(t *testing.T, renter *dao.Renter) *gin.Engine {
+	oldStore, oldLocation := public.CounterStoreHandler, lib.TimeLocation
+	t.Cleanup(func() {
+		public.CounterStoreHandler, lib.TimeLocation = oldStore, oldLocation
+	})
+	public.CounterStoreHandler = &nopCounterStore{}
+	lib.TimeLocation = time.Local
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("service", &dao.ServiceDetail{Info: &dao.ServiceInfo{ServiceName: "renter_limit_test"}})
+		c.Set("renter", renter)
+	}, HTTPRenterFlowCountMiddleware())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return router
+}
+
+func TestHTTPRenterFlowCountQpd(t *testing.T) {
+	renter := &dao.Renter{RenterID: "renter_qpd_test", Qpd: 10}
+	router := newRenterFlowCountRouter(t, renter)
+	renterCounter, _ := public.FlowCounterHandler.GetCounter(public.FlowAppPrefix + renter.RenterID)
+
+	renterCounter.TotalCount = 9
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("below qpd: status %d", recorder.Code)
+	}
+
+	renterCounter.TotalCount = 10
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if recorder.Code == http.StatusOK {
+		t.Fatal("request over qpd was not rejected")
+	}
+}
+
+func TestHTTPRenterFlowCountQps(t *testing.T) {
+	// 令牌桶容量是 qps 的 3 倍，连续请求超过容量后被拒绝
+	renter := &dao.Renter{RenterID: "renter_qps_test", Qps: 1}
+	router := newRenterFlowCountRouter(t, renter)
+
+	rejected := 0
+	for i := 0; i < 5; i++ {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		if recorder.Code != http.StatusOK {
+			rejected++
+		}
+	}
+	if rejected != 2 {
+		t.Fatalf("rejected %d of 5 requests, want 2", rejected)
+	}
+}
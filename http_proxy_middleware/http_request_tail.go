@@ -0,0 +1,56 @@
+package http_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// 请求实时查看，只有管理端正在 tail 该服务时才发布请求，header 按 deny-list 脱敏
+func HTTPRequestTailMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+		if !public.RequestTailHandler.Watching(serviceDetail.Info.ServiceName) {
+			c.Next()
+			return
+		}
+		startTime := time.Now()
+		c.Next()
+
+		event := &public.RequestTailEvent{
+			Time:           startTime.Format(time.RFC3339Nano),
+			TraceID:        public.GetGinTraceContext(c).TraceId,
+			Service:        serviceDetail.Info.ServiceName,
+			ClientIP:       c.ClientIP(),
+			Method:         c.Request.Method,
+			Path:           c.Request.URL.Path,
+			Query:          public.RedactorHandler.RedactValues(c.Request.URL.Query()).Encode(),
+			Upstream:       c.GetString("upstream"),
+			Status:         c.Writer.Status(),
+			LatencyMs:      time.Since(startTime).Milliseconds(),
+			RequestBytes:   c.Request.ContentLength,
+			ResponseBytes:  int64(c.Writer.Size()),
+			RequestHeader:  public.RedactorHandler.RedactHeader(c.Request.Header),
+			ResponseHeader: public.RedactorHandler.RedactHeader(c.Writer.Header()),
+		}
+		if renterInterface, ok := c.Get("renter"); ok {
+			event.Renter = renterInterface.(*dao.Renter).RenterID
+		}
+		if event.RequestBytes < 0 {
+			event.RequestBytes = 0
+		}
+		if event.ResponseBytes < 0 {
+			event.ResponseBytes = 0
+		}
+		public.RequestTailHandler.Publish(event)
+	}
+}
@@ -0,0 +1,80 @@
+package http_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/JunxiHe459/gateway/reverse_proxy"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"net/http"
+)
+
+// 反向代理，放在中间件链最后，按负载均衡选择下游节点转发
+func HTTPReverseProxyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+
+		// 熔断期间不转发，grpc 返回 UNAVAILABLE
+		// 没能转发到上游(取不到负载均衡或节点)的请求也记为失败
+		proxied := false
+		if public.CircuitBreakerHandler.Enabled() {
+			breaker := public.CircuitBreakerHandler.GetBreaker(serviceDetail.Info.ServiceName)
+			if !breaker.Allow() {
+				if serviceDetail.Info.LoadType == public.LoadTypeGRPC {
+					abortWithGrpcStatus(c, public.GrpcCodeUnavailable, "circuit breaker open")
+					return
+				}
+				c.String(http.StatusServiceUnavailable, "circuit breaker open")
+				c.Abort()
+				return
+			}
+			defer func() {
+				breaker.Record(proxied && !isServerError(c))
+			}()
+		}
+
+		lb, err := dao.LoadBalancerHandler.GetLoadBalancer(serviceDetail)
+		if err != nil {
+			middleware.ResponseError(c, 2002, err)
+			c.Abort()
+			return
+		}
+		trans, err := dao.TransportorHandler.GetTrans(serviceDetail)
+		if err != nil {
+			middleware.ResponseError(c, 2003, err)
+			c.Abort()
+			return
+		}
+		addr, err := lb.Get(c.ClientIP())
+		if err != nil || addr == "" {
+			middleware.ResponseError(c, 2004, errors.New("no available upstream"))
+			c.Abort()
+			return
+		}
+		target, err := reverse_proxy.ParseUpstream(addr)
+		if err != nil {
+			middleware.ResponseError(c, 2005, err)
+			c.Abort()
+			return
+		}
+		c.Set("upstream", target.Host)
+
+		var proxy = reverse_proxy.NewLoadBalanceReverseProxy(c, target, trans, nil)
+		if serviceDetail.Info.LoadType == public.LoadTypeGRPC {
+			// grpc 流式响应需要立即刷新
+			proxy.FlushInterval = -1
+		} else {
+			proxy.ModifyResponse = NewResponseTransformer(c, serviceDetail.HTTPRule)
+		}
+		proxy.ServeHTTP(c.Writer, c.Request)
+		proxied = true
+		c.Abort()
+	}
+}
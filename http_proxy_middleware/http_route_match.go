@@ -0,0 +1,42 @@
+package http_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// 服务内路由匹配，按优先级取第一条命中的路由，路径参数写入 c.Params
+// 服务没有配置路由时不做限制
+func HTTPRouteMatchMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+		if len(serviceDetail.HTTPRoutes) == 0 {
+			c.Next()
+			return
+		}
+
+		for index := range serviceDetail.HTTPRoutes {
+			route := &serviceDetail.HTTPRoutes[index]
+			params, ok := route.Match(c.Request)
+			if !ok {
+				continue
+			}
+			for key, value := range params {
+				c.Params = append(c.Params, gin.Param{Key: key, Value: value})
+			}
+			c.Set("route", route)
+			c.Next()
+			return
+		}
+		middleware.ResponseError(c, 2002, errors.New("route not found"))
+		c.Abort()
+	}
+}
@@ -0,0 +1,28 @@
+package http_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// 前缀匹配的服务去掉路径中的前缀再转发，例如 /test_http/abc => /abc
+func HTTPStripUriMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+		if serviceDetail.HTTPRule.RuleType == public.HTTPPrefixURL && serviceDetail.HTTPRule.NeedStripUri == 1 {
+			c.Request.URL.Path = strings.TrimPrefix(c.Request.URL.Path, serviceDetail.HTTPRule.Rule)
+			c.Request.URL.RawPath = ""
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package http_proxy_middleware
+
+import (
+	"context"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"net/http"
+)
+
+// 链路追踪，为代理请求创建 server span 并把 traceparent 透传给下游
+func HTTPTraceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+
+		var parent *lib.TraceContext
+		if traceId, spanId, ok := public.ParseTraceparent(c.GetHeader(public.TraceparentHeader)); ok {
+			parent = &lib.TraceContext{}
+			parent.TraceId = traceId
+			parent.SpanId = spanId
+		}
+		span := public.StartSpan(parent, c.Request.Method+" "+serviceDetail.Info.ServiceName, public.SpanKindServer)
+		span.SetAttribute("gateway.service", serviceDetail.Info.ServiceName)
+		span.SetAttribute("http.method", c.Request.Method)
+		span.SetAttribute("http.target", c.Request.RequestURI)
+		span.SetAttribute("net.peer.ip", c.ClientIP())
+
+		traceContext := span.TraceContext()
+		c.Set("trace", traceContext)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), "trace", traceContext))
+		c.Request.Header.Set(public.TraceparentHeader, span.Traceparent())
+		c.Next()
+
+		span.SetAttribute("http.status_code", c.Writer.Status())
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			span.SetError(errors.New(http.StatusText(c.Writer.Status())))
+		}
+		span.End()
+	}
+}
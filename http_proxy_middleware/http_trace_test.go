@@ -0,0 +1,58 @@
+package http_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPTraceMiddleware(t *testing.T) {
+	exporter := public.NewInMemorySpanExporter()
+	public.TracerHandler.SetExporter("gateway_test", exporter)
+	defer public.TracerHandler.SetExporter("gateway", nil)
+
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(public.TraceparentHeader)
+	}))
+	defer upstream.Close()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("service", &dao.ServiceDetail{Info: &dao.ServiceInfo{ServiceName: "demo"}})
+	}, HTTPTraceMiddleware())
+	router.GET("/demo", func(c *gin.Context) {
+		req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, upstream.URL, nil)
+		resp, err := (&public.TraceTransport{}).RoundTrip(req)
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadGateway)
+			return
+		}
+		resp.Body.Close()
+	})
+
+	inTraceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	inSpanID := "00f067aa0ba902b7"
+	req := httptest.NewRequest(http.MethodGet, "/demo", nil)
+	req.Header.Set(public.TraceparentHeader, "00-"+inTraceID+"-"+inSpanID+"-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	client, server := spans[0], spans[1]
+	if server.Kind != public.SpanKindServer || server.TraceID != inTraceID || server.ParentSpanID != inSpanID {
+		t.Errorf("server span does not continue inbound trace: %+v", server)
+	}
+	if client.Kind != public.SpanKindClient || client.TraceID != inTraceID || client.ParentSpanID != server.SpanID {
+		t.Errorf("upstream span is not a child of server span: %+v", client)
+	}
+	if traceparent != client.Traceparent() {
+		t.Errorf("upstream got traceparent %q, want %q", traceparent, client.Traceparent())
+	}
+}
@@ -0,0 +1,132 @@
+package http_proxy_middleware
+
+import (
+	"bytes"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 请求转换，修改 json body 字段并注入模板 header
+func HTTPRequestTransformMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+		if serviceDetail.HTTPRule.RequestTransform == "" {
+			c.Next()
+			return
+		}
+
+		rules, err := public.ParseTransformRules(serviceDetail.HTTPRule.RequestTransform)
+		if err != nil {
+			middleware.ResponseError(c, 2002, err)
+			c.Abort()
+			return
+		}
+		vars := transformVars(c)
+		for _, rule := range rules {
+			if rule.Op == public.TransformOpHeader {
+				c.Request.Header.Set(rule.Key, public.RenderTemplate(rule.Value, vars))
+			}
+		}
+
+		// 长度未知或过大的 body 直接透传，不读入内存
+		if !isJSON(c.Request.Header) || c.Request.ContentLength <= 0 ||
+			c.Request.ContentLength > transformMaxBody(serviceDetail.HTTPRule) {
+			c.Next()
+			return
+		}
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			middleware.ResponseError(c, 2003, err)
+			c.Abort()
+			return
+		}
+		newBody, err := public.TransformJSONBody(body, rules, vars)
+		if err != nil {
+			// 不是 json 对象时保持原样
+			newBody = body
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(newBody))
+		c.Request.ContentLength = int64(len(newBody))
+		c.Request.Header.Set("Content-Length", strconv.Itoa(len(newBody)))
+		c.Next()
+	}
+}
+
+// NewResponseTransformer 返回给 ReverseProxy.ModifyResponse 使用的响应转换函数
+func NewResponseTransformer(c *gin.Context, rule *dao.HttpRule) func(res *http.Response) error {
+	return func(res *http.Response) error {
+		if rule.ResponseTransform == "" {
+			return nil
+		}
+		rules, err := public.ParseTransformRules(rule.ResponseTransform)
+		if err != nil {
+			return err
+		}
+		vars := transformVars(c)
+		for _, item := range rules {
+			if item.Op == public.TransformOpHeader {
+				res.Header.Set(item.Key, public.RenderTemplate(item.Value, vars))
+			}
+		}
+
+		if !isJSON(res.Header) || res.Header.Get("Content-Encoding") != "" ||
+			res.ContentLength <= 0 || res.ContentLength > transformMaxBody(rule) {
+			return nil
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+		newBody, err := public.TransformJSONBody(body, rules, vars)
+		if err != nil {
+			newBody = body
+		}
+		res.Body = ioutil.NopCloser(bytes.NewBuffer(newBody))
+		res.ContentLength = int64(len(newBody))
+		res.Header.Set("Content-Length", strconv.Itoa(len(newBody)))
+		return nil
+	}
+}
+
+// 模板可用变量: client_ip renter_id trace_id service_name 以及 path.<参数名>
+func transformVars(c *gin.Context) map[string]string {
+	vars := map[string]string{
+		"client_ip": c.ClientIP(),
+		"trace_id":  public.GetGinTraceContext(c).TraceId,
+	}
+	if serverInterface, ok := c.Get("service"); ok {
+		vars["service_name"] = serverInterface.(*dao.ServiceDetail).Info.ServiceName
+	}
+	if renterInterface, ok := c.Get("renter"); ok {
+		vars["renter_id"] = renterInterface.(*dao.Renter).RenterID
+	}
+	for _, param := range c.Params {
+		vars["path."+param.Key] = param.Value
+	}
+	return vars
+}
+
+func transformMaxBody(rule *dao.HttpRule) int64 {
+	if rule.TransformMaxBody > 0 {
+		return int64(rule.TransformMaxBody)
+	}
+	return public.TransformMaxBodyDefault
+}
+
+func isJSON(header http.Header) bool {
+	return strings.Contains(header.Get("Content-Type"), "application/json")
+}
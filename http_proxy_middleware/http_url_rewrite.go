@@ -0,0 +1,36 @@
+package http_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"regexp"
+	"strings"
+)
+
+// url 重写，格式: 正则 替换内容，多条逗号间隔，按顺序执行
+func HTTPUrlRewriteMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+		for _, item := range strings.Split(serviceDetail.HTTPRule.UrlRewrite, ",") {
+			items := strings.Split(item, " ")
+			if len(items) != 2 {
+				continue
+			}
+			re, err := regexp.Compile(items[0])
+			if err != nil {
+				continue
+			}
+			c.Request.URL.Path = re.ReplaceAllString(c.Request.URL.Path, items[1])
+			c.Request.URL.RawPath = ""
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+package http_proxy_middleware
+
+import (
+	"fmt"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// ip 白名单，开启权限验证且设置了白名单时生效
+func HTTPWhiteListMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serverInterface, ok := c.Get("service")
+		if !ok {
+			middleware.ResponseError(c, 2001, errors.New("service not found"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+
+		iplist := []string{}
+		if serviceDetail.AccessControl.WhiteList != "" {
+			iplist = strings.Split(serviceDetail.AccessControl.WhiteList, ",")
+		}
+		if serviceDetail.AccessControl.OpenAuth == 1 && len(iplist) > 0 {
+			if !public.InStringSlice(iplist, c.ClientIP()) {
+				middleware.ResponseError(c, 3001, errors.New(fmt.Sprintf("%s not in white ip list", c.ClientIP())))
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
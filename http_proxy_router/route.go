@@ -0,0 +1,40 @@
+package http_proxy_router
+
+import (
+	"github.com/JunxiHe459/gateway/http_proxy_middleware"
+	"github.com/JunxiHe459/gateway/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// InitRouter 代理不注册路由，所有请求都经过中间件链，由 HTTPReverseProxyMiddleware 转发
+// 顺序: 匹配服务 -> 观测 -> 访问控制与限流 -> 租户 -> 故障注入 -> 请求改写 -> 转发
+func InitRouter(middlewares ...gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	// 路径原样转发给上游，不做尾部斜杠重定向
+	router.RedirectTrailingSlash = false
+	router.RedirectFixedPath = false
+	router.Use(middlewares...)
+	router.Use(
+		middleware.RecoveryMiddleware(),
+		http_proxy_middleware.HTTPAccessModeMiddleware(),
+		http_proxy_middleware.HTTPTraceMiddleware(),
+		http_proxy_middleware.HTTPAccessLogMiddleware(),
+		http_proxy_middleware.HTTPMetricsMiddleware(),
+		http_proxy_middleware.HTTPFlowCountMiddleware(),
+		http_proxy_middleware.HTTPRequestTailMiddleware(),
+		http_proxy_middleware.HTTPCorsMiddleware(),
+		http_proxy_middleware.HTTPWhiteListMiddleware(),
+		http_proxy_middleware.HTTPBlackListMiddleware(),
+		http_proxy_middleware.HTTPFlowLimitMiddleware(),
+		http_proxy_middleware.HTTPRenterAuthMiddleware(),
+		http_proxy_middleware.HTTPRenterFlowCountMiddleware(),
+		http_proxy_middleware.HTTPFaultInjectionMiddleware(),
+		http_proxy_middleware.HTTPRouteMatchMiddleware(),
+		http_proxy_middleware.HTTPHeaderTransferMiddleware(),
+		http_proxy_middleware.HTTPStripUriMiddleware(),
+		http_proxy_middleware.HTTPUrlRewriteMiddleware(),
+		http_proxy_middleware.HTTPRequestTransformMiddleware(),
+		http_proxy_middleware.HTTPCompressMiddleware(),
+		http_proxy_middleware.HTTPReverseProxyMiddleware())
+	return router
+}
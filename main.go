@@ -1,39 +1,131 @@
 package main
 
 import (
+	"github.com/JunxiHe459/gateway/dao"
 	"github.com/JunxiHe459/gateway/global"
+	"github.com/JunxiHe459/gateway/grpc_proxy_router"
+	"github.com/JunxiHe459/gateway/http_proxy_router"
+	"github.com/JunxiHe459/gateway/public"
 	"github.com/JunxiHe459/gateway/router"
+	"github.com/JunxiHe459/gateway/tcp_proxy_router"
 	"github.com/e421083458/golang_common/lib"
+	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 func init() {
 	initConf()
 	initDB()
+	if err := public.InitStateStore(); err != nil {
+		print("Init state store failed: ", err.Error())
+	}
+	public.InitTracer()
+	public.InitRedactor()
+	if err := public.InitAccessLogger(); err != nil {
+		print("Init access logger failed: ", err.Error())
+	}
 }
 
 func main() {
 	defer lib.Destroy()
+	if len(os.Args) > 1 {
+		code := runCommand(os.Args[1:])
+		lib.Destroy()
+		os.Exit(code)
+	}
+	dao.RenterUsageManagerHandler.Start()
+	dao.AlertManagerHandler.Start()
 	router.HttpServerRun()
+	proxyOn := lib.GetBoolConf("proxy.base.on")
+	if proxyOn {
+		proxyServerRun()
+	}
 
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGKILL, syscall.SIGQUIT, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
+	if proxyOn {
+		proxyServerStop()
+	}
 	router.HttpServerStop()
 }
 
+// proxyServerRun 加载服务、租户与故障规则后启动 http/https/tcp/grpc 代理
+func proxyServerRun() {
+	if err := dao.ServiceManagerHandler.LoadOnce(); err != nil {
+		print("Load services failed: ", err.Error())
+	}
+	dao.LoadBalancerHandler.LoadAll(dao.ServiceManagerHandler.GetServiceList())
+	if err := dao.RenterManagerHandler.LoadOnce(); err != nil {
+		print("Load renters failed: ", err.Error())
+	}
+	if err := dao.FaultRuleManagerHandler.Load(); err != nil {
+		print("Load fault rules failed: ", err.Error())
+	}
+	go proxyReload()
+	go public.WatchServiceChange(proxyServiceChange)
+
+	http_proxy_router.HttpServerRun()
+	if lib.GetBoolConf("proxy.https.on") {
+		http_proxy_router.HttpsServerRun()
+	}
+	tcp_proxy_router.TcpServerRun()
+	grpc_proxy_router.GrpcServerRun()
+}
+
+// proxyReload 定时刷新租户与故障规则，多实例部署时其他实例的修改也能生效
+func proxyReload() {
+	interval := lib.GetIntConf("proxy.base.reload_interval")
+	if interval <= 0 {
+		interval = public.ProxyReloadIntervalDefault
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := dao.RenterManagerHandler.Load(); err != nil {
+			log.Printf(" [ERROR] reload renters err:%v\n", err)
+		}
+		if err := dao.FaultRuleManagerHandler.Load(); err != nil {
+			log.Printf(" [ERROR] reload fault rules err:%v\n", err)
+		}
+	}
+}
+
+// proxyServiceChange 收到服务变更通知后重新加载服务，并按新的服务列表调整 tcp/grpc 监听
+func proxyServiceChange(event *public.ServiceChangeEvent) {
+	dao.ServiceChangeHandler(event)
+	tcp_proxy_router.TcpServerReload()
+	grpc_proxy_router.GrpcServerReload()
+}
+
+func proxyServerStop() {
+	grpc_proxy_router.GrpcServerStop()
+	tcp_proxy_router.TcpServerStop()
+	if lib.GetBoolConf("proxy.https.on") {
+		http_proxy_router.HttpsServerStop()
+	}
+	http_proxy_router.HttpServerStop()
+}
+
 func initConf() {
-	_ = lib.InitModule("./conf/dev/", []string{"base", "mysql", "redis"})
+	_ = lib.InitModule("./conf/dev/", []string{"base", "redis"})
 }
 
 func initDB() {
+	if err := dao.InitStorage(); err != nil {
+		print("Init storage failed: ", err.Error())
+		return
+	}
 	var err error
 	global.DB, err = lib.GetGormPool("default")
-	global.DB = global.DB.Debug()
 	if err != nil {
 		print("Get Global Variable DB failed")
+		return
 	}
+	global.DB = global.DB.Debug()
+	public.RegisterGormTrace(global.DB)
 }
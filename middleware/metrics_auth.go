@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/gin-gonic/gin"
+	"os"
+	"strings"
+)
+
+// MetricsAuthMiddleware /metrics 访问控制
+// 配置了 token 时要求 Authorization: Bearer <token>，否则只允许 base.http.allow_ip 中的地址
+func MetricsAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := MetricsToken()
+		if token == "" {
+			IPAuthMiddleware()(c)
+			return
+		}
+		auth := c.GetHeader("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+			ResponseError(c, InvalidRequestErrorCode, errors.New("invalid metrics token"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// MetricsToken 环境变量优先于 base.metrics.token
+func MetricsToken() string {
+	if token := os.Getenv(public.MetricsTokenEnv); token != "" {
+		return token
+	}
+	return lib.GetStringConf("base.metrics.token")
+}
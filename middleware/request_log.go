@@ -5,31 +5,47 @@ import (
 	"github.com/JunxiHe459/gateway/public"
 	"github.com/e421083458/golang_common/lib"
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 	"io/ioutil"
+	"net/http"
 	"time"
 )
 
 // 请求进入日志
 func RequestInLog(c *gin.Context) {
 	traceContext := lib.NewTrace()
-	if traceId := c.Request.Header.Get("com-header-rid"); traceId != "" {
+	if traceId, spanId, ok := public.ParseTraceparent(c.Request.Header.Get(public.TraceparentHeader)); ok {
 		traceContext.TraceId = traceId
-	}
-	if spanId := c.Request.Header.Get("com-header-spanid"); spanId != "" {
 		traceContext.SpanId = spanId
+	} else {
+		if traceId := c.Request.Header.Get("com-header-rid"); traceId != "" {
+			traceContext.TraceId = traceId
+		}
+		if spanId := c.Request.Header.Get("com-header-spanid"); spanId != "" {
+			traceContext.SpanId = spanId
+		}
 	}
+	span := public.StartSpan(traceContext, c.Request.Method+" "+c.Request.URL.Path, public.SpanKindServer)
+	span.SetAttribute("http.method", c.Request.Method)
+	span.SetAttribute("http.target", c.Request.RequestURI)
+	span.SetAttribute("net.peer.ip", c.ClientIP())
+	// 之后的日志、数据库与 redis 调用都挂在这个 span 下
+	traceContext = span.TraceContext()
 
 	c.Set("startExecTime", time.Now())
 	c.Set("trace", traceContext)
+	c.Set("span", span)
 
 	bodyBytes, _ := ioutil.ReadAll(c.Request.Body)
 	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes)) // Write body back
 
+	// 写日志前按 deny-list 脱敏，header 只记录 allow-list，密码、密钥与认证 header 不落盘
 	lib.Log.TagInfo(traceContext, "_com_request_in", map[string]interface{}{
 		"uri":    c.Request.RequestURI,
 		"method": c.Request.Method,
-		"args":   c.Request.PostForm,
-		"body":   string(bodyBytes),
+		"args":   public.RedactorHandler.RedactValues(c.Request.PostForm),
+		"header": public.RedactorHandler.RedactHeader(c.Request.Header),
+		"body":   public.RedactorHandler.RedactBody(string(bodyBytes)),
 		"from":   c.ClientIP(),
 	})
 }
@@ -42,12 +58,21 @@ func RequestOutLog(c *gin.Context) {
 	st, _ := c.Get("startExecTime")
 
 	startExecTime, _ := st.(time.Time)
+	if spanInterface, ok := c.Get("span"); ok {
+		span := spanInterface.(*public.Span)
+		span.SetAttribute("http.status_code", c.Writer.Status())
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			span.SetError(errors.New(http.StatusText(c.Writer.Status())))
+		}
+		span.End()
+	}
+	responseStr, _ := response.(string)
 	public.ComLogNotice(c, "_com_request_out", map[string]interface{}{
 		"uri":       c.Request.RequestURI,
 		"method":    c.Request.Method,
-		"args":      c.Request.PostForm,
+		"args":      public.RedactorHandler.RedactValues(c.Request.PostForm),
 		"from":      c.ClientIP(),
-		"response":  response,
+		"response":  public.RedactorHandler.RedactBody(responseStr),
 		"proc_time": endExecTime.Sub(startExecTime).Seconds(),
 	})
 }
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	dlog "github.com/e421083458/golang_common/log"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// captureWriter 收集写入日志的记录
+type captureWriter struct {
+	lines  []string
+	locker sync.Mutex
+}
+
+func (w *captureWriter) Init() error {
+	return nil
+}
+
+func (w *captureWriter) Write(r *dlog.Record) error {
+	w.locker.Lock()
+	defer w.locker.Unlock()
+	w.lines = append(w.lines, r.String())
+	return nil
+}
+
+func (w *captureWriter) text() string {
+	w.locker.Lock()
+	defer w.locker.Unlock()
+	return strings.Join(w.lines, "")
+}
+
+var logCapture = &captureWriter{}
+
+func init() {
+	dlog.Register(logCapture)
+}
+
+func TestRequestLogRedact(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestLog())
+	router.POST("/admin_login/login", func(c *gin.Context) {
+		c.Set("response", `{"errno":0,"data":{"token":"resp-token-value"}}`)
+		c.String(http.StatusOK, "ok")
+	})
+
+	body := `{"username":"admin","password":"body-password-value","profile":{"secret":"nested-secret-value"}}`
+	req := httptest.NewRequest(http.MethodPost, "/admin_login/login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer auth-header-value")
+	req.Header.Set("Cookie", "session=cookie-value")
+	req.Header.Set("X-Api-Key", "custom-key-value")
+	req.Header.Set("User-Agent", "request-log-test")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var text string
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		text = logCapture.text()
+		if strings.Contains(text, "_com_request_out") {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(text, "_com_request_in") || !strings.Contains(text, "_com_request_out") {
+		t.Fatalf("request logs not written: %s", text)
+	}
+	for _, secret := range []string{"body-password-value", "nested-secret-value", "auth-header-value",
+		"cookie-value", "custom-key-value", "resp-token-value"} {
+		if strings.Contains(text, secret) {
+			t.Errorf("%q reached the log writer", secret)
+		}
+	}
+	if !strings.Contains(text, "request-log-test") || !strings.Contains(text, "admin") {
+		t.Errorf("allowed fields missing from log: %s", text)
+	}
+}
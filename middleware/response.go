@@ -18,6 +18,7 @@ const (
 	InternalErrorCode
 
 	InvalidRequestErrorCode ResponseCode = 401
+	PermissionDeniedCode    ResponseCode = 403
 	CustomizeCode           ResponseCode = 1000
 
 	GROUPALL_SAVE_FLOWERROR ResponseCode = 2001
@@ -1,20 +1,75 @@
 package middleware
 
 import (
+	"encoding/json"
 	"errors"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/dto"
+	"github.com/JunxiHe459/gateway/global"
 	"github.com/JunxiHe459/gateway/public"
 	"github.com/gin-gonic/contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
 
+// SessionAuthMiddleware 每次请求都从数据库读取管理员，禁用、删除、角色变更与 session 吊销立即生效
+// 已经由 TokenAuthMiddleware 认证的请求不再校验 session
 func SessionAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if _, ok := c.Get("admin"); ok {
+			c.Next()
+			return
+		}
 		session := sessions.Default(c)
-		if adminInfo, ok := session.Get(public.AdminSessionInfoKey).(string); !ok || adminInfo == "" {
+		adminInfo, ok := session.Get(public.AdminSessionInfoKey).(string)
+		if !ok || adminInfo == "" {
 			ResponseError(c, InternalErrorCode, errors.New("User not login"))
 			c.Abort()
 			return
 		}
+		adminSessionInfo := &dto.AdminSessionInfo{}
+		if err := json.Unmarshal([]byte(adminInfo), adminSessionInfo); err != nil {
+			ResponseError(c, InternalErrorCode, errors.New("User not login"))
+			c.Abort()
+			return
+		}
+		admin, err := (&dao.Admin{}).FindByUserName(c, global.DB, adminSessionInfo.UsernName)
+		if err != nil || admin.Id != adminSessionInfo.ID || admin.IsDisable == 1 ||
+			admin.SessionVersion != adminSessionInfo.SessionVersion {
+			session.Delete(public.AdminSessionInfoKey)
+			_ = session.Save()
+			ResponseError(c, InternalErrorCode, errors.New("User not login"))
+			c.Abort()
+			return
+		}
+		c.Set("admin", admin)
 		c.Next()
 	}
 }
+
+// RequirePermission 放在 SessionAuthMiddleware 之后，按当前管理员的角色与 token 的 scopes 校验权限
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminInterface, ok := c.Get("admin")
+		if !ok {
+			ResponseError(c, InternalErrorCode, errors.New("User not login"))
+			c.Abort()
+			return
+		}
+		admin := adminInterface.(*dao.Admin)
+		if !admin.HasPermission(permission) || !tokenAllows(c, permission) {
+			ResponseError(c, PermissionDeniedCode, errors.New("Permission denied: "+permission))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasPermission 当前管理员是否有权限，用于同一接口按权限返回不同内容
+func HasPermission(c *gin.Context, permission string) bool {
+	adminInterface, ok := c.Get("admin")
+	if !ok {
+		return false
+	}
+	return adminInterface.(*dao.Admin).HasPermission(permission) && tokenAllows(c, permission)
+}
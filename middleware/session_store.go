@@ -0,0 +1,388 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/gob"
+	"fmt"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/garyburd/redigo/redis"
+	"github.com/gin-gonic/contrib/sessions"
+	"github.com/gorilla/securecookie"
+	gsessions "github.com/gorilla/sessions"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 管理端登录 session，cookie 中只保存签名后的 session id
+// redis 中的 key 前缀与序列化方式和 redistore 一致，升级后已登录的 session 仍然有效
+
+const (
+	sessionKeyPrefix = "session_"
+	sessionMaxAge    = 86400 * 30
+)
+
+type sessionBackend interface {
+	load(id string) (map[interface{}]interface{}, bool, error)
+	save(id string, values map[interface{}]interface{}, maxAge int) error
+	delete(id string) error
+}
+
+// SessionStore state driver 为 redis 时 session 保存在 redis，redis 不可用期间保存在本进程
+// 读取时先查本进程，有副本说明是降级期间写入的，redis 恢复后按剩余有效期写回 redis
+// redis 不可用期间删除的 session 在本进程留下删除标记，标记期间读取不到，redis 恢复后补删
+type SessionStore struct {
+	Codecs  []securecookie.Codec
+	options *gsessions.Options
+	remote  sessionBackend
+	local   *memorySessionBackend
+	retryAt int64
+}
+
+// NewSessionStore 按 base.state.driver 与 base.session 创建 session 存储
+func NewSessionStore() *SessionStore {
+	secret := SessionSecret()
+	if secret == "" {
+		// 未配置时使用随机密钥，重启后已登录的 session 失效，多节点之间也无法共用 session
+		secret = public.NewSalt() + public.NewSalt()
+		log.Printf(" [WARN] %s is not set, admin sessions use a random secret\n", public.SessionSecretEnv)
+	}
+	store := &SessionStore{
+		Codecs:  securecookie.CodecsFromPairs([]byte(secret)),
+		options: &gsessions.Options{Path: "/", MaxAge: sessionMaxAge},
+		local:   newMemorySessionBackend(),
+	}
+	if public.StateDriver() == public.StateDriverRedis {
+		addr := lib.GetStringConf("base.session.redis_addr")
+		if addr == "" {
+			addr = "localhost:6379"
+		}
+		store.remote = newRedisSessionBackend(addr, SessionRedisPassword())
+	}
+	return store
+}
+
+// SessionSecret 环境变量优先于 base.session.secret
+func SessionSecret() string {
+	if secret := os.Getenv(public.SessionSecretEnv); secret != "" {
+		return secret
+	}
+	return lib.GetStringConf("base.session.secret")
+}
+
+// SessionRedisPassword 环境变量优先于 base.session.redis_password
+func SessionRedisPassword() string {
+	if password := os.Getenv(public.SessionRedisPasswordEnv); password != "" {
+		return password
+	}
+	return lib.GetStringConf("base.session.redis_password")
+}
+
+func (s *SessionStore) Options(options sessions.Options) {
+	s.options = &gsessions.Options{
+		Path:     options.Path,
+		Domain:   options.Domain,
+		MaxAge:   options.MaxAge,
+		Secure:   options.Secure,
+		HttpOnly: options.HttpOnly,
+	}
+}
+
+func (s *SessionStore) Get(r *http.Request, name string) (*gsessions.Session, error) {
+	return gsessions.GetRegistry(r).Get(s, name)
+}
+
+func (s *SessionStore) New(r *http.Request, name string) (*gsessions.Session, error) {
+	session := gsessions.NewSession(s, name)
+	options := *s.options
+	session.Options = &options
+	session.IsNew = true
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	if err := securecookie.DecodeMulti(name, cookie.Value, &session.ID, s.Codecs...); err != nil {
+		return session, err
+	}
+	if values, ok := s.load(session.ID); ok {
+		session.Values = values
+		session.IsNew = false
+	}
+	return session, nil
+}
+
+// Save MaxAge 小于 0 时删除 session
+func (s *SessionStore) Save(r *http.Request, w http.ResponseWriter, session *gsessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		s.delete(session.ID)
+		http.SetCookie(w, gsessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+	if session.ID == "" {
+		session.ID = strings.TrimRight(base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32)), "=")
+	}
+	maxAge := session.Options.MaxAge
+	if maxAge == 0 {
+		maxAge = sessionMaxAge
+	}
+	if err := s.save(session.ID, session.Values, maxAge); err != nil {
+		return err
+	}
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, gsessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+func (s *SessionStore) load(id string) (map[interface{}]interface{}, bool) {
+	s.flushDeleted()
+	if s.local.deleted(id) {
+		return nil, false
+	}
+	if values, maxAge, ok := s.local.loadWithMaxAge(id); ok {
+		if s.remoteAvailable() {
+			if err := s.remote.save(id, values, maxAge); err == nil {
+				s.local.delete(id)
+			} else {
+				s.remoteFailed(err)
+			}
+		}
+		return values, true
+	}
+	if !s.remoteAvailable() {
+		return nil, false
+	}
+	values, ok, err := s.remote.load(id)
+	if err != nil {
+		s.remoteFailed(err)
+		return nil, false
+	}
+	return values, ok
+}
+
+func (s *SessionStore) save(id string, values map[interface{}]interface{}, maxAge int) error {
+	s.flushDeleted()
+	s.local.clearDeleted(id)
+	if s.remoteAvailable() {
+		err := s.remote.save(id, values, maxAge)
+		if err == nil {
+			s.local.delete(id)
+			return nil
+		}
+		s.remoteFailed(err)
+	}
+	return s.local.save(id, values, maxAge)
+}
+
+// delete redis 删除失败时留下删除标记，避免 redis 恢复后退出登录的 session 重新生效
+func (s *SessionStore) delete(id string) {
+	s.local.delete(id)
+	if s.remote == nil {
+		return
+	}
+	if s.remoteAvailable() {
+		err := s.remote.delete(id)
+		if err == nil {
+			return
+		}
+		s.remoteFailed(err)
+	}
+	maxAge := s.options.MaxAge
+	if maxAge <= 0 {
+		maxAge = sessionMaxAge
+	}
+	s.local.markDeleted(id, maxAge)
+}
+
+// flushDeleted redis 可用时补删有删除标记的 session
+func (s *SessionStore) flushDeleted() {
+	if !s.remoteAvailable() {
+		return
+	}
+	for _, id := range s.local.deletedIDs() {
+		if err := s.remote.delete(id); err != nil {
+			s.remoteFailed(err)
+			return
+		}
+		s.local.clearDeleted(id)
+	}
+}
+
+// remoteAvailable redis 失败后 health_interval 内不再访问，避免每个请求都等待连接超时
+func (s *SessionStore) remoteAvailable() bool {
+	return s.remote != nil && time.Now().UnixNano() >= atomic.LoadInt64(&s.retryAt)
+}
+
+func (s *SessionStore) remoteFailed(err error) {
+	fmt.Println("SessionStore redis err", err)
+	atomic.StoreInt64(&s.retryAt, time.Now().Add(public.StateHealthInterval()).UnixNano())
+}
+
+type redisSessionBackend struct {
+	pool *redis.Pool
+}
+
+func newRedisSessionBackend(addr, password string) *redisSessionBackend {
+	return &redisSessionBackend{pool: &redis.Pool{
+		MaxIdle:     10,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", addr,
+				redis.DialPassword(password),
+				redis.DialConnectTimeout(100*time.Millisecond),
+				redis.DialReadTimeout(500*time.Millisecond),
+				redis.DialWriteTimeout(500*time.Millisecond))
+		},
+	}}
+}
+
+func (b *redisSessionBackend) load(id string) (map[interface{}]interface{}, bool, error) {
+	conn := b.pool.Get()
+	defer conn.Close()
+	data, err := redis.Bytes(conn.Do("GET", sessionKeyPrefix+id))
+	if err == redis.ErrNil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	values := map[interface{}]interface{}{}
+	if err := gob.NewDecoder(bytes.NewBuffer(data)).Decode(&values); err != nil {
+		return nil, false, nil
+	}
+	return values, true, nil
+}
+
+func (b *redisSessionBackend) save(id string, values map[interface{}]interface{}, maxAge int) error {
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(values); err != nil {
+		return err
+	}
+	conn := b.pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("SETEX", sessionKeyPrefix+id, maxAge, buf.Bytes())
+	return err
+}
+
+func (b *redisSessionBackend) delete(id string) error {
+	conn := b.pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("DEL", sessionKeyPrefix+id)
+	return err
+}
+
+type memorySession struct {
+	Values   map[interface{}]interface{}
+	ExpireAt time.Time
+}
+
+// memorySessionBackend 保存 values 的浅拷贝，session 中只有字符串
+// tombstones 记录 redis 中还没删掉的 session 与标记的过期时间
+type memorySessionBackend struct {
+	sessions   map[string]*memorySession
+	tombstones map[string]time.Time
+	locker     sync.Mutex
+	sweptAt    time.Time
+}
+
+func newMemorySessionBackend() *memorySessionBackend {
+	return &memorySessionBackend{
+		sessions:   map[string]*memorySession{},
+		tombstones: map[string]time.Time{},
+		sweptAt:    time.Now(),
+	}
+}
+
+func (b *memorySessionBackend) load(id string) (map[interface{}]interface{}, bool, error) {
+	values, _, ok := b.loadWithMaxAge(id)
+	return values, ok, nil
+}
+
+// loadWithMaxAge 同时返回剩余有效期，单位s，写回 redis 时沿用
+func (b *memorySessionBackend) loadWithMaxAge(id string) (map[interface{}]interface{}, int, bool) {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	session, ok := b.sessions[id]
+	now := time.Now()
+	if !ok || !now.Before(session.ExpireAt) {
+		return nil, 0, false
+	}
+	maxAge := int((session.ExpireAt.Sub(now) + time.Second - 1) / time.Second)
+	return copySessionValues(session.Values), maxAge, true
+}
+
+func (b *memorySessionBackend) save(id string, values map[interface{}]interface{}, maxAge int) error {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	now := time.Now()
+	if now.Sub(b.sweptAt) >= time.Minute {
+		b.sweptAt = now
+		for key, session := range b.sessions {
+			if !now.Before(session.ExpireAt) {
+				delete(b.sessions, key)
+			}
+		}
+		for key, expireAt := range b.tombstones {
+			if !now.Before(expireAt) {
+				delete(b.tombstones, key)
+			}
+		}
+	}
+	b.sessions[id] = &memorySession{
+		Values:   copySessionValues(values),
+		ExpireAt: now.Add(time.Duration(maxAge) * time.Second),
+	}
+	return nil
+}
+
+func (b *memorySessionBackend) delete(id string) error {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	delete(b.sessions, id)
+	return nil
+}
+
+func (b *memorySessionBackend) markDeleted(id string, maxAge int) {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	b.tombstones[id] = time.Now().Add(time.Duration(maxAge) * time.Second)
+}
+
+func (b *memorySessionBackend) deleted(id string) bool {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	expireAt, ok := b.tombstones[id]
+	return ok && time.Now().Before(expireAt)
+}
+
+func (b *memorySessionBackend) deletedIDs() []string {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	ids := make([]string, 0, len(b.tombstones))
+	for id := range b.tombstones {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (b *memorySessionBackend) clearDeleted(id string) {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	delete(b.tombstones, id)
+}
+
+func copySessionValues(values map[interface{}]interface{}) map[interface{}]interface{} {
+	out := make(map[interface{}]interface{}, len(values))
+	for key, value := range values {
+		out[key] = value
+	}
+	return out
+}
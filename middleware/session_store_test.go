@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/e421083458/golang_common/lib"
+	gsessions "github.com/gorilla/sessions"
+	"github.com/spf13/viper"
+	"sync"
+	"testing"
+)
+
+// fakeSessionBackend 代替 redis，down 时所有操作失败
+type fakeSessionBackend struct {
+	sessions map[string]map[interface{}]interface{}
+	maxAges  map[string]int
+	down     bool
+	locker   sync.Mutex
+}
+
+func newFakeSessionBackend() *fakeSessionBackend {
+	return &fakeSessionBackend{sessions: map[string]map[interface{}]interface{}{}, maxAges: map[string]int{}}
+}
+
+var errFakeSessionBackend = errors.New("fake redis unavailable")
+
+func (b *fakeSessionBackend) load(id string) (map[interface{}]interface{}, bool, error) {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	if b.down {
+		return nil, false, errFakeSessionBackend
+	}
+	values, ok := b.sessions[id]
+	return values, ok, nil
+}
+
+func (b *fakeSessionBackend) save(id string, values map[interface{}]interface{}, maxAge int) error {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	if b.down {
+		return errFakeSessionBackend
+	}
+	b.sessions[id] = copySessionValues(values)
+	b.maxAges[id] = maxAge
+	return nil
+}
+
+func (b *fakeSessionBackend) delete(id string) error {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	if b.down {
+		return errFakeSessionBackend
+	}
+	delete(b.sessions, id)
+	return nil
+}
+
+func (b *fakeSessionBackend) setDown(down bool) {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	b.down = down
+}
+
+// newTestSessionStore redis 失败后不等待 health_interval，下一次调用立即重试
+func newTestSessionStore(t *testing.T) (*SessionStore, *fakeSessionBackend) {
+	oldConf := lib.ViperConfMap
+	lib.ViperConfMap = map[string]*viper.Viper{"base": viper.New()}
+	t.Cleanup(func() { lib.ViperConfMap = oldConf })
+	remote := newFakeSessionBackend()
+	store := &SessionStore{
+		options: &gsessions.Options{Path: "/", MaxAge: sessionMaxAge},
+		remote:  remote,
+		local:   newMemorySessionBackend(),
+	}
+	return store, remote
+}
+
+func TestSessionStoreDeleteDuringOutage(t *testing.T) {
+	store, remote := newTestSessionStore(t)
+	values := map[interface{}]interface{}{"admin": "1"}
+	if err := store.save("sid", values, 3600); err != nil {
+		t.Fatal(err)
+	}
+
+	remote.setDown(true)
+	store.delete("sid")
+	store.retryAt = 0
+	if _, ok := store.load("sid"); ok {
+		t.Fatal("deleted session loaded during outage")
+	}
+
+	// redis 恢复后旧的 session 仍在 redis 中，删除标记使它不会重新生效，并在 redis 中补删
+	remote.setDown(false)
+	store.retryAt = 0
+	if _, ok := store.load("sid"); ok {
+		t.Fatal("deleted session loaded after redis recovered")
+	}
+	if _, ok, _ := remote.load("sid"); ok {
+		t.Fatal("session was not deleted from redis")
+	}
+	if ids := store.local.deletedIDs(); len(ids) != 0 {
+		t.Fatalf("tombstones = %v, want none", ids)
+	}
+}
+
+func TestSessionStoreResyncKeepsMaxAge(t *testing.T) {
+	store, remote := newTestSessionStore(t)
+	remote.setDown(true)
+	values := map[interface{}]interface{}{"admin": "1"}
+	if err := store.save("sid", values, 120); err != nil {
+		t.Fatal(err)
+	}
+
+	remote.setDown(false)
+	store.retryAt = 0
+	if _, ok := store.load("sid"); !ok {
+		t.Fatal("session saved during outage was not loaded")
+	}
+	if maxAge := remote.maxAges["sid"]; maxAge <= 0 || maxAge > 120 {
+		t.Fatalf("resynced max age = %d, want the remaining 120s", maxAge)
+	}
+	if _, ok, _ := store.local.load("sid"); ok {
+		t.Fatal("local copy should be removed after resync")
+	}
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/global"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"time"
+)
+
+// TokenAuthMiddleware 放在 SessionAuthMiddleware 之前，带 Authorization: Bearer 时按 API token 或 JWT 认证
+// 不带时交给 SessionAuthMiddleware 校验 session
+func TokenAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authorization := c.GetHeader("Authorization")
+		if authorization == "" {
+			c.Next()
+			return
+		}
+		token := public.ParseBearerToken(authorization)
+		if token == "" {
+			ResponseError(c, InternalErrorCode, errors.New("Invalid token"))
+			c.Abort()
+			return
+		}
+		adminToken, claims, err := findAdminToken(c, token)
+		now := time.Now()
+		if err != nil || adminToken.Expired(now) {
+			ResponseError(c, InternalErrorCode, errors.New("Invalid token"))
+			c.Abort()
+			return
+		}
+		// 管理员被禁用或删除后 token 立即失效
+		admin, err := (&dao.Admin{}).Find(c, global.DB, &dao.Admin{Id: adminToken.AdminID})
+		if err != nil || admin.IsDelete == 1 || admin.IsDisable == 1 {
+			ResponseError(c, InternalErrorCode, errors.New("Invalid token"))
+			c.Abort()
+			return
+		}
+		_ = adminToken.Touch(c, global.DB, now)
+		c.Set("admin", admin)
+		if claims != nil {
+			// JWT 的权限不超过签发它的 API token 当前的 scopes
+			scoped := *adminToken
+			scoped.Scopes = strings.Join(adminToken.IntersectScopes(claims.Scopes), ",")
+			adminToken = &scoped
+			c.Set("admin_jwt", claims)
+		}
+		c.Set("admin_token", adminToken)
+		c.Next()
+	}
+}
+
+// findAdminToken JWT 按 jti 查找签发它的 API token，token 吊销后 JWT 同时失效
+func findAdminToken(c *gin.Context, token string) (*dao.AdminToken, *public.AdminJwtClaims, error) {
+	if !public.IsAdminJwt(token) {
+		adminToken, err := (&dao.AdminToken{}).FindByToken(c, global.DB, token)
+		return adminToken, nil, err
+	}
+	claims, err := public.ParseAdminJwt(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if claims.TokenID() <= 0 {
+		return nil, nil, errors.New("invalid jwt claims")
+	}
+	adminToken, err := (&dao.AdminToken{}).Find(c, global.DB, &dao.AdminToken{ID: claims.TokenID()})
+	if err != nil {
+		return nil, nil, err
+	}
+	if adminToken.IsDelete == 1 || adminToken.AdminID != claims.AdminID() {
+		return nil, nil, errors.New("token revoked")
+	}
+	return adminToken, claims, nil
+}
+
+// tokenAllows 使用 token 认证时，权限还要在 token 的 scopes 内
+func tokenAllows(c *gin.Context, permission string) bool {
+	tokenInterface, ok := c.Get("admin_token")
+	if !ok {
+		return true
+	}
+	return tokenInterface.(*dao.AdminToken).HasScope(permission)
+}
+
+// RequireAdminToken 只允许 API token 认证的请求，用于 API token 换取 JWT
+func RequireAdminToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, isJwt := c.Get("admin_jwt")
+		if _, ok := c.Get("admin_token"); !ok || isJwt {
+			ResponseError(c, InternalErrorCode, errors.New("API token required"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -13,184 +13,311 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 )
 
 //设置 Validator
 func ParamValidationMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		//参照：https://github.com/go-playground/validator/blob/v9/_examples/translations/main.go
-
-		//设置支持语言
-		english := en.New()
-		chinese := zh.New()
-
-		//设置国际化翻译器
-		uni := ut.New(chinese, chinese, english)
-		val := validator.New()
-
-		//根据参数取翻译器实例
-		locale := c.DefaultQuery("locale", "chinese")
-		locale = "chinese"
-
-		trans, _ := uni.GetTranslator(locale)
-
-		//翻译器注册到validator
-		switch locale {
-		case "english":
-			en_translations.RegisterDefaultTranslations(val, trans)
-			val.RegisterTagNameFunc(func(fld reflect.StructField) string {
-				return fld.Tag.Get("en_comment")
-			})
-			break
-
-		default:
-			_ = zh_translations.RegisterDefaultTranslations(val, trans)
-			val.RegisterTagNameFunc(func(fld reflect.StructField) string {
-				return fld.Tag.Get("comment")
-			})
-
-			// 自定义验证方法
-			// 验证用户名是否为 admin
-			_ = val.RegisterValidation("valid_username", func(fl validator.FieldLevel) bool {
-				return fl.Field().String() == "admin"
-			})
-
-			_ = val.RegisterValidation("valid_service_name", func(fl validator.FieldLevel) bool {
-				flag, err := regexp.Match(`^[a-zA-Z0-9_-]{6,128}$`, []byte(fl.Field().String()))
-				if err != nil {
-					println("regexp.Math error: ", err.Error())
+		val, trans := NewValidator()
+		c.Set(public.TranslatorKey, trans)
+		c.Set(public.ValidatorKey, val)
+		c.Next()
+	}
+}
+
+// NewValidator 创建注册了自定义规则与翻译的 validator，请求参数校验与配置文档导入共用
+func NewValidator() (*validator.Validate, ut.Translator) {
+	//参照：https://github.com/go-playground/validator/blob/v9/_examples/translations/main.go
+
+	//设置支持语言
+	english := en.New()
+	chinese := zh.New()
+
+	//设置国际化翻译器
+	uni := ut.New(chinese, chinese, english)
+	val := validator.New()
+
+	//取翻译器实例
+	locale := "chinese"
+
+	trans, _ := uni.GetTranslator(locale)
+
+	//翻译器注册到validator
+	switch locale {
+	case "english":
+		en_translations.RegisterDefaultTranslations(val, trans)
+		val.RegisterTagNameFunc(func(fld reflect.StructField) string {
+			return fld.Tag.Get("en_comment")
+		})
+		break
+
+	default:
+		_ = zh_translations.RegisterDefaultTranslations(val, trans)
+		val.RegisterTagNameFunc(func(fld reflect.StructField) string {
+			return fld.Tag.Get("comment")
+		})
+
+		// 自定义验证方法
+		// 验证用户名 3-32 位字母、数字、下划线、点或横线
+		_ = val.RegisterValidation("valid_username", func(fl validator.FieldLevel) bool {
+			flag, _ := regexp.Match(`^[a-zA-Z0-9_.-]{3,32}$`, []byte(fl.Field().String()))
+			return flag
+		})
+
+		_ = val.RegisterValidation("valid_service_name", func(fl validator.FieldLevel) bool {
+			flag, err := regexp.Match(`^[a-zA-Z0-9_-]{6,128}$`, []byte(fl.Field().String()))
+			if err != nil {
+				println("regexp.Math error: ", err.Error())
+				return false
+			}
+			return flag
+		})
+
+		// 验证 rule 接入方式 不能为空
+		_ = val.RegisterValidation("valid_rule", func(fl validator.FieldLevel) bool {
+			flag, err := regexp.Match(`^\S+$`, []byte(fl.Field().String()))
+			if err != nil {
+				println("regexp.Math error: ", err.Error())
+				return false
+			}
+			return flag
+		})
+
+		val.RegisterValidation("valid_url_rewrite", func(fl validator.FieldLevel) bool {
+			if fl.Field().String() == "" {
+				return true
+			}
+			for _, ms := range strings.Split(fl.Field().String(), ",") {
+				if len(strings.Split(ms, " ")) != 2 {
 					return false
 				}
-				return flag
-			})
-
-			// 验证 rule 接入方式 不能为空
-			_ = val.RegisterValidation("valid_rule", func(fl validator.FieldLevel) bool {
-				flag, err := regexp.Match(`^\S+$`, []byte(fl.Field().String()))
-				if err != nil {
-					println("regexp.Math error: ", err.Error())
+			}
+			return true
+		})
+
+		val.RegisterValidation("valid_header_transfer", func(fl validator.FieldLevel) bool {
+			if fl.Field().String() == "" {
+				return true
+			}
+			for _, ms := range strings.Split(fl.Field().String(), ",") {
+				if len(strings.Split(ms, " ")) != 3 {
 					return false
 				}
-				return flag
-			})
+			}
+			return true
+		})
 
-			val.RegisterValidation("valid_url_rewrite", func(fl validator.FieldLevel) bool {
-				if fl.Field().String() == "" {
-					return true
-				}
-				for _, ms := range strings.Split(fl.Field().String(), ",") {
-					if len(strings.Split(ms, " ")) != 2 {
-						return false
-					}
-				}
-				return true
-			})
+		val.RegisterValidation("valid_transform", func(fl validator.FieldLevel) bool {
+			_, err := public.ParseTransformRules(fl.Field().String())
+			return err == nil
+		})
 
-			val.RegisterValidation("valid_header_transfer", func(fl validator.FieldLevel) bool {
-				if fl.Field().String() == "" {
-					return true
-				}
-				for _, ms := range strings.Split(fl.Field().String(), ",") {
-					if len(strings.Split(ms, " ")) != 3 {
-						return false
-					}
-				}
+		val.RegisterValidation("valid_cors_origins", func(fl validator.FieldLevel) bool {
+			allowCredentials := false
+			if field := reflect.Indirect(fl.Parent()).FieldByName("CorsAllowCredentials"); field.IsValid() {
+				allowCredentials = field.Int() == 1
+			}
+			return public.ValidateCorsOrigins(fl.Field().String(), allowCredentials) == nil
+		})
+
+		val.RegisterValidation("valid_methods", func(fl validator.FieldLevel) bool {
+			if fl.Field().String() == "" {
 				return true
-			})
+			}
+			for _, item := range strings.Split(fl.Field().String(), ",") {
+				if matched, _ := regexp.Match(`^[A-Z]+$`, []byte(item)); !matched {
+					return false
+				}
+			}
+			return true
+		})
 
-			val.RegisterValidation("valid_ipportlist", func(fl validator.FieldLevel) bool {
-				for _, ms := range strings.Split(fl.Field().String(), ",") {
-					if matched, _ := regexp.Match(`^\S+\:\d+$`, []byte(ms)); !matched {
-						return false
-					}
+		val.RegisterValidation("valid_ipportlist", func(fl validator.FieldLevel) bool {
+			for _, ms := range strings.Split(fl.Field().String(), ",") {
+				if matched, _ := regexp.Match(`^\S+\:\d+$`, []byte(ms)); !matched {
+					return false
 				}
-				return true
-			})
+			}
+			return true
+		})
 
-			val.RegisterValidation("valid_iplist", func(fl validator.FieldLevel) bool {
-				if fl.Field().String() == "" {
-					return true
+		val.RegisterValidation("valid_iplist", func(fl validator.FieldLevel) bool {
+			if fl.Field().String() == "" {
+				return true
+			}
+			for _, item := range strings.Split(fl.Field().String(), ",") {
+				matched, _ := regexp.Match(`\S+`, []byte(item)) //ip_addr
+				if !matched {
+					return false
 				}
-				for _, item := range strings.Split(fl.Field().String(), ",") {
-					matched, _ := regexp.Match(`\S+`, []byte(item)) //ip_addr
-					if !matched {
-						return false
-					}
+			}
+			return true
+		})
+		val.RegisterValidation("valid_weightlist", func(fl validator.FieldLevel) bool {
+			fmt.Println(fl.Field().String())
+			for _, ms := range strings.Split(fl.Field().String(), ",") {
+				if matched, _ := regexp.Match(`^\d+$`, []byte(ms)); !matched {
+					return false
 				}
+			}
+			return true
+		})
+
+		val.RegisterValidation("valid_header_match", func(fl validator.FieldLevel) bool {
+			if fl.Field().String() == "" {
 				return true
-			})
-			val.RegisterValidation("valid_weightlist", func(fl validator.FieldLevel) bool {
-				fmt.Println(fl.Field().String())
-				for _, ms := range strings.Split(fl.Field().String(), ",") {
-					if matched, _ := regexp.Match(`^\d+$`, []byte(ms)); !matched {
-						return false
-					}
+			}
+			for _, ms := range strings.Split(fl.Field().String(), ",") {
+				if len(strings.Split(ms, " ")) != 2 {
+					return false
 				}
+			}
+			return true
+		})
+
+		val.RegisterValidation("valid_query_match", func(fl validator.FieldLevel) bool {
+			if fl.Field().String() == "" {
 				return true
-			})
-
-			//自定义验证器
-			//https://github.com/go-playground/validator/blob/v9/_examples/translations/main.go
-			// TODO： {0} 咋获取变量名字的？
-			_ = val.RegisterTranslation("valid_username", trans, func(ut ut.Translator) error {
-				return ut.Add("valid_username", "{0} 填写不正确哦", true)
-			}, func(ut ut.Translator, fe validator.FieldError) string {
-				t, _ := ut.T("valid_username", fe.Field())
-				return t
-			})
-
-			_ = val.RegisterTranslation("valid_servicename", trans, func(ut ut.Translator) error {
-				return ut.Add("valid_servicename", "{0} 不符合输入格式", true)
-			}, func(ut ut.Translator, fe validator.FieldError) string {
-				t, _ := ut.T("valid_servicename", fe.Field())
-				return t
-			})
-
-			_ = val.RegisterTranslation("valid_rule", trans, func(ut ut.Translator) error {
-				return ut.Add("valid_rule", "{0} 必须是非空字符", true)
-			}, func(ut ut.Translator, fe validator.FieldError) string {
-				t, _ := ut.T("valid_rule", fe.Field())
-				return t
-			})
-
-			_ = val.RegisterTranslation("valid_url_rewrite", trans, func(ut ut.Translator) error {
-				return ut.Add("valid_rule", "{0} 需要用逗号隔开", true)
-			}, func(ut ut.Translator, fe validator.FieldError) string {
-				t, _ := ut.T("valid_rule", fe.Field())
-				return t
-			})
-
-			_ = val.RegisterTranslation("valid_header_transfer", trans, func(ut ut.Translator) error {
-				return ut.Add("valid_rule", "{0} 需要用逗号隔开", true)
-			}, func(ut ut.Translator, fe validator.FieldError) string {
-				t, _ := ut.T("valid_rule", fe.Field())
-				return t
-			})
-
-			val.RegisterTranslation("valid_ipportlist", trans, func(ut ut.Translator) error {
-				return ut.Add("valid_ipportlist", "{0} 记得加 : 哦", true)
-			}, func(ut ut.Translator, fe validator.FieldError) string {
-				t, _ := ut.T("valid_ipportlist", fe.Field())
-				return t
-			})
-
-			val.RegisterTranslation("valid_iplist", trans, func(ut ut.Translator) error {
-				return ut.Add("valid_iplist", "{0} 例如：127.0.0.1 多条需换行", true)
-			}, func(ut ut.Translator, fe validator.FieldError) string {
-				t, _ := ut.T("valid_iplist", fe.Field())
-				return t
-			})
-
-			val.RegisterTranslation("valid_weightlist", trans, func(ut ut.Translator) error {
-				return ut.Add("valid_weightlist", "{0} 权重必须是数字，用逗号隔开", true)
-			}, func(ut ut.Translator, fe validator.FieldError) string {
-				t, _ := ut.T("valid_weightlist", fe.Field())
-				return t
-			})
-			break
-		}
-		c.Set(public.TranslatorKey, trans)
-		c.Set(public.ValidatorKey, val)
-		c.Next()
+			}
+			for _, ms := range strings.Split(fl.Field().String(), ",") {
+				if matched, _ := regexp.Match(`^[^=\s]+=\S*$`, []byte(ms)); !matched {
+					return false
+				}
+			}
+			return true
+		})
+
+		val.RegisterValidation("valid_route_path", func(fl validator.FieldLevel) bool {
+			flag, _ := regexp.Match(`^/([\w.~:-]+/)*([\w.~:-]+|\*\w+)?$`, []byte(fl.Field().String()))
+			return flag
+		})
+
+		val.RegisterValidation("valid_date", func(fl validator.FieldLevel) bool {
+			_, err := time.Parse(public.UsageDateFormat, fl.Field().String())
+			return err == nil
+		})
+
+		val.RegisterValidation("valid_status_filter", func(fl validator.FieldLevel) bool {
+			flag, _ := regexp.Match(`^[1-5]([0-9]{2}|xx)$`, []byte(fl.Field().String()))
+			return flag
+		})
+
+		//自定义验证器
+		//https://github.com/go-playground/validator/blob/v9/_examples/translations/main.go
+		// TODO： {0} 咋获取变量名字的？
+		_ = val.RegisterTranslation("valid_username", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_username", "{0} 为 3-32 位字母、数字、下划线、点或横线", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_username", fe.Field())
+			return t
+		})
+
+		_ = val.RegisterTranslation("valid_servicename", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_servicename", "{0} 不符合输入格式", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_servicename", fe.Field())
+			return t
+		})
+
+		_ = val.RegisterTranslation("valid_rule", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_rule", "{0} 必须是非空字符", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_rule", fe.Field())
+			return t
+		})
+
+		_ = val.RegisterTranslation("valid_url_rewrite", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_rule", "{0} 需要用逗号隔开", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_rule", fe.Field())
+			return t
+		})
+
+		_ = val.RegisterTranslation("valid_header_transfer", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_rule", "{0} 需要用逗号隔开", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_rule", fe.Field())
+			return t
+		})
+
+		val.RegisterTranslation("valid_transform", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_transform", "{0} 格式为 add field value、del field、rename field newname 或 header name value，用逗号隔开", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_transform", fe.Field())
+			return t
+		})
+
+		val.RegisterTranslation("valid_cors_origins", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_cors_origins", "{0} 例如：https://*.example.com 多条用逗号隔开，允许携带cookie时不能使用*", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_cors_origins", fe.Field())
+			return t
+		})
+
+		val.RegisterTranslation("valid_methods", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_methods", "{0} 例如：GET,POST 需要大写", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_methods", fe.Field())
+			return t
+		})
+
+		val.RegisterTranslation("valid_ipportlist", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_ipportlist", "{0} 记得加 : 哦", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_ipportlist", fe.Field())
+			return t
+		})
+
+		val.RegisterTranslation("valid_iplist", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_iplist", "{0} 例如：127.0.0.1 多条需换行", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_iplist", fe.Field())
+			return t
+		})
+
+		val.RegisterTranslation("valid_weightlist", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_weightlist", "{0} 权重必须是数字，用逗号隔开", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_weightlist", fe.Field())
+			return t
+		})
+
+		val.RegisterTranslation("valid_query_match", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_query_match", "{0} 格式为 key=value，多条用逗号隔开", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_query_match", fe.Field())
+			return t
+		})
+
+		val.RegisterTranslation("valid_route_path", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_route_path", "{0} 例如：/users/:id 或 /static/*filepath", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_route_path", fe.Field())
+			return t
+		})
+
+		val.RegisterTranslation("valid_date", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_date", "{0} 格式为 2006-01-02", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_date", fe.Field())
+			return t
+		})
+
+		val.RegisterTranslation("valid_status_filter", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_status_filter", "{0} 例如：502 或 5xx", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_status_filter", fe.Field())
+			return t
+		})
+
+		val.RegisterTranslation("valid_header_match", trans, func(ut ut.Translator) error {
+			return ut.Add("valid_header_match", "{0} 格式为 headname headvalue，多条用逗号隔开", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("valid_header_match", fe.Field())
+			return t
+		})
+		break
 	}
+	return val, trans
 }
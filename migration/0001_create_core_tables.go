@@ -0,0 +1,297 @@
+package migration
+
+// 管理员、服务与租户，对应 dao 中的 Admin、ServiceInfo、HttpRule、TcpRule、GrpcRule、AccessControl、LoadBalance、Renter
+// 使用 IF NOT EXISTS，之前手工导入过 SQL 的环境执行后只补上版本记录与基线之后新增的列
+
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "create_core_tables",
+		Up: map[string][]string{
+			"mysql": {
+				`CREATE TABLE IF NOT EXISTS gateway_admin (
+  id int(11) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  user_name varchar(255) NOT NULL DEFAULT '' COMMENT '管理员用户名',
+  salt varchar(50) NOT NULL DEFAULT '' COMMENT '盐',
+  password varchar(255) NOT NULL DEFAULT '' COMMENT '密码',
+  role varchar(32) NOT NULL DEFAULT '' COMMENT '角色 super_admin/service_operator/renter_manager/read_only',
+  is_disable tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否禁用',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '添加时间',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '更新时间',
+  is_delete tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否删除',
+  PRIMARY KEY (id),
+  KEY idx_user_name (user_name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='管理员表'`,
+				`CREATE TABLE IF NOT EXISTS gateway_service_info (
+  id bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  load_type tinyint(4) NOT NULL DEFAULT '0' COMMENT '负载类型 0=http 1=tcp 2=grpc',
+  service_name varchar(255) NOT NULL DEFAULT '' COMMENT '服务名称',
+  service_desc varchar(255) NOT NULL DEFAULT '' COMMENT '服务描述',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '添加时间',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '更新时间',
+  is_delete tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否已删除；0：否；1：是',
+  PRIMARY KEY (id),
+  KEY idx_service_name (service_name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='网关基本信息表'`,
+				`CREATE TABLE IF NOT EXISTS gateway_service_http_rule (
+  id bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  service_id bigint(20) NOT NULL DEFAULT '0' COMMENT '服务id',
+  rule_type tinyint(4) NOT NULL DEFAULT '0' COMMENT '匹配类型 0=url前缀url_prefix 1=域名domain',
+  rule varchar(255) NOT NULL DEFAULT '' COMMENT 'type=domain表示域名，type=url_prefix时表示url前缀',
+  need_https tinyint(4) NOT NULL DEFAULT '0' COMMENT '支持https 1=支持',
+  need_strip_uri tinyint(4) NOT NULL DEFAULT '0' COMMENT '启用strip_uri 1=启用',
+  need_websocket tinyint(4) NOT NULL DEFAULT '0' COMMENT '启用websocket 1=启用',
+  url_rewrite varchar(5000) NOT NULL DEFAULT '' COMMENT 'url重写功能，每行一个',
+  header_transfer varchar(5000) NOT NULL DEFAULT '' COMMENT 'header转换支持增加(add)、删除(del)、修改(edit) 格式: add headname headvalue',
+  request_transform varchar(5000) NOT NULL DEFAULT '' COMMENT '请求转换 格式: add field value',
+  response_transform varchar(5000) NOT NULL DEFAULT '' COMMENT '响应转换 格式同请求转换',
+  transform_max_body int(11) NOT NULL DEFAULT '0' COMMENT '参与转换的最大 body, 单位byte 0=默认1MB',
+  need_cors tinyint(4) NOT NULL DEFAULT '0' COMMENT '启用跨域 1=启用',
+  cors_allow_origins varchar(1000) NOT NULL DEFAULT '' COMMENT '允许的 origin, 逗号间隔',
+  cors_allow_methods varchar(255) NOT NULL DEFAULT '' COMMENT '允许的方法, 逗号间隔',
+  cors_allow_headers varchar(1000) NOT NULL DEFAULT '' COMMENT '允许的 header, 逗号间隔',
+  cors_allow_credentials tinyint(4) NOT NULL DEFAULT '0' COMMENT '允许携带 cookie 1=允许',
+  cors_max_age int(11) NOT NULL DEFAULT '0' COMMENT '预检结果缓存时间, 单位s',
+  need_compress tinyint(4) NOT NULL DEFAULT '0' COMMENT '启用响应压缩 1=启用',
+  compress_min_size int(11) NOT NULL DEFAULT '0' COMMENT '最小压缩大小, 单位byte 0=默认1024',
+  compress_types varchar(1000) NOT NULL DEFAULT '' COMMENT '可压缩的 content-type 前缀, 逗号间隔',
+  PRIMARY KEY (id),
+  KEY idx_service_id (service_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='网关路由匹配表'`,
+				`CREATE TABLE IF NOT EXISTS gateway_service_tcp_rule (
+  id bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  service_id bigint(20) NOT NULL DEFAULT '0' COMMENT '服务id',
+  port int(5) NOT NULL DEFAULT '0' COMMENT '端口号',
+  PRIMARY KEY (id),
+  KEY idx_service_id (service_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='网关tcp路由匹配表'`,
+				`CREATE TABLE IF NOT EXISTS gateway_service_grpc_rule (
+  id bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  service_id bigint(20) NOT NULL DEFAULT '0' COMMENT '服务id',
+  port int(5) NOT NULL DEFAULT '0' COMMENT '端口',
+  header_transfer varchar(5000) NOT NULL DEFAULT '' COMMENT 'header转换支持增加(add)、删除(del)、修改(edit) 格式: add headname headvalue',
+  PRIMARY KEY (id),
+  KEY idx_service_id (service_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='网关grpc路由匹配表'`,
+				`CREATE TABLE IF NOT EXISTS gateway_service_access_control (
+  id bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  service_id bigint(20) NOT NULL DEFAULT '0' COMMENT '服务id',
+  open_auth tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否开启权限 1=开启',
+  black_list varchar(1000) NOT NULL DEFAULT '' COMMENT '黑名单ip',
+  white_list varchar(1000) NOT NULL DEFAULT '' COMMENT '白名单ip',
+  white_host_name varchar(1000) NOT NULL DEFAULT '' COMMENT '白名单主机',
+  clientip_flow_limit int(11) NOT NULL DEFAULT '0' COMMENT '客户端ip限流',
+  service_flow_limit int(20) NOT NULL DEFAULT '0' COMMENT '服务端限流',
+  PRIMARY KEY (id),
+  KEY idx_service_id (service_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='网关权限控制表'`,
+				`CREATE TABLE IF NOT EXISTS gateway_service_load_balance (
+  id bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  service_id bigint(20) NOT NULL DEFAULT '0' COMMENT '服务id',
+  check_method tinyint(20) NOT NULL DEFAULT '0' COMMENT '检查方法 0=tcpchk,检测端口是否握手成功',
+  check_timeout int(10) NOT NULL DEFAULT '0' COMMENT 'check超时时间,单位s',
+  check_interval int(11) NOT NULL DEFAULT '0' COMMENT '检查间隔, 单位s',
+  round_type tinyint(4) NOT NULL DEFAULT '0' COMMENT '轮询方式 0=random 1=round-robin 2=weight_round-robin 3=ip_hash',
+  ip_list varchar(2000) NOT NULL DEFAULT '' COMMENT 'ip列表',
+  weight_list varchar(2000) NOT NULL DEFAULT '' COMMENT '权重列表',
+  forbid_list varchar(2000) NOT NULL DEFAULT '' COMMENT '禁用ip列表',
+  upstream_connect_timeout int(11) NOT NULL DEFAULT '0' COMMENT '建立连接超时, 单位s',
+  upstream_header_timeout int(11) NOT NULL DEFAULT '0' COMMENT '获取header超时, 单位s',
+  upstream_idle_timeout int(10) NOT NULL DEFAULT '0' COMMENT '链接最大空闲时间, 单位s',
+  upstream_max_idle int(11) NOT NULL DEFAULT '0' COMMENT '最大空闲链接数',
+  PRIMARY KEY (id),
+  KEY idx_service_id (service_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='网关负载表'`,
+				`CREATE TABLE IF NOT EXISTS gateway_renter (
+  id bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '自增id',
+  renter_id varchar(255) NOT NULL DEFAULT '' COMMENT '租户id',
+  name varchar(255) NOT NULL DEFAULT '' COMMENT '租户名称',
+  secret varchar(255) NOT NULL DEFAULT '' COMMENT '密钥',
+  white_ips varchar(1000) NOT NULL DEFAULT '' COMMENT 'ip白名单，支持前缀匹配',
+  qpd bigint(20) NOT NULL DEFAULT '0' COMMENT '日请求量限制',
+  qps bigint(20) NOT NULL DEFAULT '0' COMMENT '每秒请求量限制',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '添加时间',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '更新时间',
+  is_delete tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否已删除；0：否；1：是',
+  PRIMARY KEY (id),
+  KEY idx_renter_id (renter_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='网关租户表'`,
+			},
+			"sqlite3": {
+				`CREATE TABLE IF NOT EXISTS gateway_admin (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  user_name varchar(255) NOT NULL DEFAULT '',
+  salt varchar(50) NOT NULL DEFAULT '',
+  password varchar(255) NOT NULL DEFAULT '',
+  role varchar(32) NOT NULL DEFAULT '',
+  is_disable integer NOT NULL DEFAULT '0',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  is_delete integer NOT NULL DEFAULT '0'
+)`,
+				"CREATE INDEX IF NOT EXISTS gateway_admin_idx_user_name ON gateway_admin (user_name)",
+				`CREATE TABLE IF NOT EXISTS gateway_service_info (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  load_type integer NOT NULL DEFAULT '0',
+  service_name varchar(255) NOT NULL DEFAULT '',
+  service_desc varchar(255) NOT NULL DEFAULT '',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  is_delete integer NOT NULL DEFAULT '0'
+)`,
+				"CREATE INDEX IF NOT EXISTS gateway_service_info_idx_service_name ON gateway_service_info (service_name)",
+				`CREATE TABLE IF NOT EXISTS gateway_service_http_rule (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  service_id integer NOT NULL DEFAULT '0',
+  rule_type integer NOT NULL DEFAULT '0',
+  rule varchar(255) NOT NULL DEFAULT '',
+  need_https integer NOT NULL DEFAULT '0',
+  need_strip_uri integer NOT NULL DEFAULT '0',
+  need_websocket integer NOT NULL DEFAULT '0',
+  url_rewrite varchar(5000) NOT NULL DEFAULT '',
+  header_transfer varchar(5000) NOT NULL DEFAULT '',
+  request_transform varchar(5000) NOT NULL DEFAULT '',
+  response_transform varchar(5000) NOT NULL DEFAULT '',
+  transform_max_body integer NOT NULL DEFAULT '0',
+  need_cors integer NOT NULL DEFAULT '0',
+  cors_allow_origins varchar(1000) NOT NULL DEFAULT '',
+  cors_allow_methods varchar(255) NOT NULL DEFAULT '',
+  cors_allow_headers varchar(1000) NOT NULL DEFAULT '',
+  cors_allow_credentials integer NOT NULL DEFAULT '0',
+  cors_max_age integer NOT NULL DEFAULT '0',
+  need_compress integer NOT NULL DEFAULT '0',
+  compress_min_size integer NOT NULL DEFAULT '0',
+  compress_types varchar(1000) NOT NULL DEFAULT ''
+)`,
+				"CREATE INDEX IF NOT EXISTS gateway_service_http_rule_idx_service_id ON gateway_service_http_rule (service_id)",
+				`CREATE TABLE IF NOT EXISTS gateway_service_tcp_rule (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  service_id integer NOT NULL DEFAULT '0',
+  port integer NOT NULL DEFAULT '0'
+)`,
+				"CREATE INDEX IF NOT EXISTS gateway_service_tcp_rule_idx_service_id ON gateway_service_tcp_rule (service_id)",
+				`CREATE TABLE IF NOT EXISTS gateway_service_grpc_rule (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  service_id integer NOT NULL DEFAULT '0',
+  port integer NOT NULL DEFAULT '0',
+  header_transfer varchar(5000) NOT NULL DEFAULT ''
+)`,
+				"CREATE INDEX IF NOT EXISTS gateway_service_grpc_rule_idx_service_id ON gateway_service_grpc_rule (service_id)",
+				`CREATE TABLE IF NOT EXISTS gateway_service_access_control (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  service_id integer NOT NULL DEFAULT '0',
+  open_auth integer NOT NULL DEFAULT '0',
+  black_list varchar(1000) NOT NULL DEFAULT '',
+  white_list varchar(1000) NOT NULL DEFAULT '',
+  white_host_name varchar(1000) NOT NULL DEFAULT '',
+  clientip_flow_limit integer NOT NULL DEFAULT '0',
+  service_flow_limit integer NOT NULL DEFAULT '0'
+)`,
+				"CREATE INDEX IF NOT EXISTS gateway_service_access_control_idx_service_id ON gateway_service_access_control (service_id)",
+				`CREATE TABLE IF NOT EXISTS gateway_service_load_balance (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  service_id integer NOT NULL DEFAULT '0',
+  check_method integer NOT NULL DEFAULT '0',
+  check_timeout integer NOT NULL DEFAULT '0',
+  check_interval integer NOT NULL DEFAULT '0',
+  round_type integer NOT NULL DEFAULT '0',
+  ip_list varchar(2000) NOT NULL DEFAULT '',
+  weight_list varchar(2000) NOT NULL DEFAULT '',
+  forbid_list varchar(2000) NOT NULL DEFAULT '',
+  upstream_connect_timeout integer NOT NULL DEFAULT '0',
+  upstream_header_timeout integer NOT NULL DEFAULT '0',
+  upstream_idle_timeout integer NOT NULL DEFAULT '0',
+  upstream_max_idle integer NOT NULL DEFAULT '0'
+)`,
+				"CREATE INDEX IF NOT EXISTS gateway_service_load_balance_idx_service_id ON gateway_service_load_balance (service_id)",
+				`CREATE TABLE IF NOT EXISTS gateway_renter (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  renter_id varchar(255) NOT NULL DEFAULT '',
+  name varchar(255) NOT NULL DEFAULT '',
+  secret varchar(255) NOT NULL DEFAULT '',
+  white_ips varchar(1000) NOT NULL DEFAULT '',
+  qpd integer NOT NULL DEFAULT '0',
+  qps integer NOT NULL DEFAULT '0',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  is_delete integer NOT NULL DEFAULT '0'
+)`,
+				"CREATE INDEX IF NOT EXISTS gateway_renter_idx_renter_id ON gateway_renter (renter_id)",
+			},
+		},
+		// 基线表结构中 gateway_admin 与 gateway_service_http_rule 没有这些列，表已存在时补上
+		Columns: []Column{
+			{Table: "gateway_admin", Name: "role", Definition: map[string]string{
+				"mysql":   `varchar(32) NOT NULL DEFAULT '' COMMENT '角色 super_admin/service_operator/renter_manager/read_only'`,
+				"sqlite3": `varchar(32) NOT NULL DEFAULT ''`,
+			}},
+			{Table: "gateway_admin", Name: "is_disable", Definition: map[string]string{
+				"mysql":   `tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否禁用'`,
+				"sqlite3": `integer NOT NULL DEFAULT '0'`,
+			}},
+			{Table: "gateway_service_http_rule", Name: "request_transform", Definition: map[string]string{
+				"mysql":   `varchar(5000) NOT NULL DEFAULT '' COMMENT '请求转换 格式: add field value'`,
+				"sqlite3": `varchar(5000) NOT NULL DEFAULT ''`,
+			}},
+			{Table: "gateway_service_http_rule", Name: "response_transform", Definition: map[string]string{
+				"mysql":   `varchar(5000) NOT NULL DEFAULT '' COMMENT '响应转换 格式同请求转换'`,
+				"sqlite3": `varchar(5000) NOT NULL DEFAULT ''`,
+			}},
+			{Table: "gateway_service_http_rule", Name: "transform_max_body", Definition: map[string]string{
+				"mysql":   `int(11) NOT NULL DEFAULT '0' COMMENT '参与转换的最大 body, 单位byte 0=默认1MB'`,
+				"sqlite3": `integer NOT NULL DEFAULT '0'`,
+			}},
+			{Table: "gateway_service_http_rule", Name: "need_cors", Definition: map[string]string{
+				"mysql":   `tinyint(4) NOT NULL DEFAULT '0' COMMENT '启用跨域 1=启用'`,
+				"sqlite3": `integer NOT NULL DEFAULT '0'`,
+			}},
+			{Table: "gateway_service_http_rule", Name: "cors_allow_origins", Definition: map[string]string{
+				"mysql":   `varchar(1000) NOT NULL DEFAULT '' COMMENT '允许的 origin, 逗号间隔'`,
+				"sqlite3": `varchar(1000) NOT NULL DEFAULT ''`,
+			}},
+			{Table: "gateway_service_http_rule", Name: "cors_allow_methods", Definition: map[string]string{
+				"mysql":   `varchar(255) NOT NULL DEFAULT '' COMMENT '允许的方法, 逗号间隔'`,
+				"sqlite3": `varchar(255) NOT NULL DEFAULT ''`,
+			}},
+			{Table: "gateway_service_http_rule", Name: "cors_allow_headers", Definition: map[string]string{
+				"mysql":   `varchar(1000) NOT NULL DEFAULT '' COMMENT '允许的 header, 逗号间隔'`,
+				"sqlite3": `varchar(1000) NOT NULL DEFAULT ''`,
+			}},
+			{Table: "gateway_service_http_rule", Name: "cors_allow_credentials", Definition: map[string]string{
+				"mysql":   `tinyint(4) NOT NULL DEFAULT '0' COMMENT '允许携带 cookie 1=允许'`,
+				"sqlite3": `integer NOT NULL DEFAULT '0'`,
+			}},
+			{Table: "gateway_service_http_rule", Name: "cors_max_age", Definition: map[string]string{
+				"mysql":   `int(11) NOT NULL DEFAULT '0' COMMENT '预检结果缓存时间, 单位s'`,
+				"sqlite3": `integer NOT NULL DEFAULT '0'`,
+			}},
+			{Table: "gateway_service_http_rule", Name: "need_compress", Definition: map[string]string{
+				"mysql":   `tinyint(4) NOT NULL DEFAULT '0' COMMENT '启用响应压缩 1=启用'`,
+				"sqlite3": `integer NOT NULL DEFAULT '0'`,
+			}},
+			{Table: "gateway_service_http_rule", Name: "compress_min_size", Definition: map[string]string{
+				"mysql":   `int(11) NOT NULL DEFAULT '0' COMMENT '最小压缩大小, 单位byte 0=默认1024'`,
+				"sqlite3": `integer NOT NULL DEFAULT '0'`,
+			}},
+			{Table: "gateway_service_http_rule", Name: "compress_types", Definition: map[string]string{
+				"mysql":   `varchar(1000) NOT NULL DEFAULT '' COMMENT '可压缩的 content-type 前缀, 逗号间隔'`,
+				"sqlite3": `varchar(1000) NOT NULL DEFAULT ''`,
+			}},
+		},
+		Down: map[string][]string{
+			"mysql":   dropCoreTables,
+			"sqlite3": dropCoreTables,
+		},
+	})
+}
+
+var dropCoreTables = []string{
+	"DROP TABLE IF EXISTS gateway_renter",
+	"DROP TABLE IF EXISTS gateway_service_load_balance",
+	"DROP TABLE IF EXISTS gateway_service_access_control",
+	"DROP TABLE IF EXISTS gateway_service_grpc_rule",
+	"DROP TABLE IF EXISTS gateway_service_tcp_rule",
+	"DROP TABLE IF EXISTS gateway_service_http_rule",
+	"DROP TABLE IF EXISTS gateway_service_info",
+	"DROP TABLE IF EXISTS gateway_admin",
+}
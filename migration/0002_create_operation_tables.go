@@ -0,0 +1,199 @@
+package migration
+
+// 服务路由、故障注入、配置版本、租户用量、告警规则与审计日志
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "create_operation_tables",
+		Up: map[string][]string{
+			"mysql": {
+				`CREATE TABLE IF NOT EXISTS gateway_service_http_route (
+  id bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  service_id bigint(20) NOT NULL DEFAULT '0' COMMENT '服务id',
+  path varchar(255) NOT NULL DEFAULT '' COMMENT '路径模式 支持 /users/:id 与 /static/*filepath',
+  methods varchar(255) NOT NULL DEFAULT '' COMMENT '允许的方法, 逗号间隔, 为空表示全部',
+  header_match varchar(1000) NOT NULL DEFAULT '' COMMENT 'header匹配 格式: headname headvalue, 多条逗号间隔',
+  query_match varchar(1000) NOT NULL DEFAULT '' COMMENT 'query匹配 格式: key=value, 多条逗号间隔',
+  priority int(11) NOT NULL DEFAULT '0' COMMENT '优先级, 越大越先匹配',
+  is_delete tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否已删除；0：否；1：是',
+  PRIMARY KEY (id),
+  KEY idx_service_id (service_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='网关http路由表'`,
+				`CREATE TABLE IF NOT EXISTS gateway_service_fault_rule (
+  id bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  service_id bigint(20) NOT NULL DEFAULT '0' COMMENT '服务id',
+  fault_type tinyint(4) NOT NULL DEFAULT '0' COMMENT '故障类型 0=delay 1=abort 2=reset',
+  delay_ms int(11) NOT NULL DEFAULT '0' COMMENT '固定延迟, 单位ms',
+  delay_jitter_ms int(11) NOT NULL DEFAULT '0' COMMENT '随机延迟上限, 单位ms',
+  abort_code int(11) NOT NULL DEFAULT '0' COMMENT 'abort 时返回的 http 状态码',
+  grpc_code int(11) NOT NULL DEFAULT '0' COMMENT 'abort 时返回的 grpc code',
+  percentage int(11) NOT NULL DEFAULT '0' COMMENT '命中流量百分比 0-100',
+  renter_id varchar(255) NOT NULL DEFAULT '' COMMENT '只对该租户生效，为空表示全部租户',
+  header_match varchar(1000) NOT NULL DEFAULT '' COMMENT 'header匹配 格式: headname headvalue, 多条逗号间隔',
+  expire_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '过期时间',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '添加时间',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '更新时间',
+  is_delete tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否已删除；0：否；1：是',
+  PRIMARY KEY (id),
+  KEY idx_service_id (service_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='网关故障注入规则表'`,
+				`CREATE TABLE IF NOT EXISTS gateway_service_version (
+  id bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  service_id bigint(20) NOT NULL DEFAULT '0' COMMENT '服务id',
+  version bigint(20) NOT NULL DEFAULT '0' COMMENT '版本号，每个服务从 1 开始递增',
+  action varchar(32) NOT NULL DEFAULT '' COMMENT '产生版本的操作 baseline/add/update/rollback',
+  detail mediumtext NOT NULL COMMENT 'ServiceDetail json',
+  admin_id int(11) NOT NULL DEFAULT '0' COMMENT '管理员id',
+  admin_name varchar(255) NOT NULL DEFAULT '' COMMENT '管理员用户名',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '添加时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_service_version (service_id, version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='网关服务配置版本表'`,
+				`CREATE TABLE IF NOT EXISTS gateway_renter_usage_day (
+  id bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  renter_id varchar(255) NOT NULL DEFAULT '' COMMENT '租户id',
+  service_name varchar(255) NOT NULL DEFAULT '' COMMENT '服务名称',
+  day char(10) NOT NULL DEFAULT '' COMMENT '日期 格式: 2006-01-02',
+  request_count bigint(20) NOT NULL DEFAULT '0' COMMENT '请求量',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '添加时间',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '更新时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_renter_service_day (renter_id, service_name, day),
+  KEY idx_day (day)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='网关租户日用量表'`,
+				`CREATE TABLE IF NOT EXISTS gateway_alert_rule (
+  id bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  name varchar(255) NOT NULL DEFAULT '' COMMENT '规则名称',
+  rule_type tinyint(4) NOT NULL DEFAULT '0' COMMENT '规则类型 0=error_rate 1=renter_qpd 2=upstream_unhealthy 3=cert_expiry',
+  service_id bigint(20) NOT NULL DEFAULT '0' COMMENT '服务id，0 表示全部服务',
+  renter_id varchar(255) NOT NULL DEFAULT '' COMMENT '租户id，为空表示全部设置了日请求量限制的租户',
+  target varchar(1000) NOT NULL DEFAULT '' COMMENT '证书检查地址 host:port，多条逗号间隔',
+  threshold double NOT NULL DEFAULT '0' COMMENT '阈值 error_rate 与 renter_qpd 为百分比，cert_expiry 为天数',
+  for_seconds int(11) NOT NULL DEFAULT '0' COMMENT '条件持续多久后触发, 单位s',
+  webhook_url varchar(1000) NOT NULL DEFAULT '' COMMENT '通知地址，为空时使用 base.alert.webhook_url',
+  enabled tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否启用；0：否；1：是',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '添加时间',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '更新时间',
+  is_delete tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否已删除；0：否；1：是',
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='网关告警规则表'`,
+				`CREATE TABLE IF NOT EXISTS gateway_admin_audit_log (
+  id bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  admin_id int(11) NOT NULL DEFAULT '0' COMMENT '管理员id',
+  admin_name varchar(255) NOT NULL DEFAULT '' COMMENT '管理员用户名',
+  action varchar(32) NOT NULL DEFAULT '' COMMENT '操作 add/update/delete/change_password/rollback',
+  resource_type varchar(32) NOT NULL DEFAULT '' COMMENT '对象类型 service/renter/admin',
+  resource_id bigint(20) NOT NULL DEFAULT '0' COMMENT '对象id',
+  resource_name varchar(255) NOT NULL DEFAULT '' COMMENT '对象名称',
+  endpoint varchar(255) NOT NULL DEFAULT '' COMMENT '请求方法与路径',
+  client_ip varchar(64) NOT NULL DEFAULT '' COMMENT '客户端ip',
+  before_snapshot mediumtext NOT NULL COMMENT '修改前快照 json，已脱敏',
+  after_snapshot mediumtext NOT NULL COMMENT '修改后快照 json，已脱敏',
+  diff mediumtext NOT NULL COMMENT '变化字段 json',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '操作时间',
+  PRIMARY KEY (id),
+  KEY idx_resource (resource_type, resource_id),
+  KEY idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='管理员操作审计日志表'`,
+			},
+			"sqlite3": {
+				`CREATE TABLE IF NOT EXISTS gateway_service_http_route (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  service_id integer NOT NULL DEFAULT '0',
+  path varchar(255) NOT NULL DEFAULT '',
+  methods varchar(255) NOT NULL DEFAULT '',
+  header_match varchar(1000) NOT NULL DEFAULT '',
+  query_match varchar(1000) NOT NULL DEFAULT '',
+  priority integer NOT NULL DEFAULT '0',
+  is_delete integer NOT NULL DEFAULT '0'
+)`,
+				"CREATE INDEX IF NOT EXISTS gateway_service_http_route_idx_service_id ON gateway_service_http_route (service_id)",
+				`CREATE TABLE IF NOT EXISTS gateway_service_fault_rule (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  service_id integer NOT NULL DEFAULT '0',
+  fault_type integer NOT NULL DEFAULT '0',
+  delay_ms integer NOT NULL DEFAULT '0',
+  delay_jitter_ms integer NOT NULL DEFAULT '0',
+  abort_code integer NOT NULL DEFAULT '0',
+  grpc_code integer NOT NULL DEFAULT '0',
+  percentage integer NOT NULL DEFAULT '0',
+  renter_id varchar(255) NOT NULL DEFAULT '',
+  header_match varchar(1000) NOT NULL DEFAULT '',
+  expire_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  is_delete integer NOT NULL DEFAULT '0'
+)`,
+				"CREATE INDEX IF NOT EXISTS gateway_service_fault_rule_idx_service_id ON gateway_service_fault_rule (service_id)",
+				`CREATE TABLE IF NOT EXISTS gateway_service_version (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  service_id integer NOT NULL DEFAULT '0',
+  version integer NOT NULL DEFAULT '0',
+  action varchar(32) NOT NULL DEFAULT '',
+  detail text NOT NULL,
+  admin_id integer NOT NULL DEFAULT '0',
+  admin_name varchar(255) NOT NULL DEFAULT '',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00'
+)`,
+				"CREATE UNIQUE INDEX IF NOT EXISTS gateway_service_version_uniq_service_version ON gateway_service_version (service_id, version)",
+				`CREATE TABLE IF NOT EXISTS gateway_renter_usage_day (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  renter_id varchar(255) NOT NULL DEFAULT '',
+  service_name varchar(255) NOT NULL DEFAULT '',
+  day char(10) NOT NULL DEFAULT '',
+  request_count integer NOT NULL DEFAULT '0',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00'
+)`,
+				"CREATE UNIQUE INDEX IF NOT EXISTS gateway_renter_usage_day_uniq_renter_service_day ON gateway_renter_usage_day (renter_id, service_name, day)",
+				"CREATE INDEX IF NOT EXISTS gateway_renter_usage_day_idx_day ON gateway_renter_usage_day (day)",
+				`CREATE TABLE IF NOT EXISTS gateway_alert_rule (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  name varchar(255) NOT NULL DEFAULT '',
+  rule_type integer NOT NULL DEFAULT '0',
+  service_id integer NOT NULL DEFAULT '0',
+  renter_id varchar(255) NOT NULL DEFAULT '',
+  target varchar(1000) NOT NULL DEFAULT '',
+  threshold double NOT NULL DEFAULT '0',
+  for_seconds integer NOT NULL DEFAULT '0',
+  webhook_url varchar(1000) NOT NULL DEFAULT '',
+  enabled integer NOT NULL DEFAULT '0',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  is_delete integer NOT NULL DEFAULT '0'
+)`,
+				`CREATE TABLE IF NOT EXISTS gateway_admin_audit_log (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  admin_id integer NOT NULL DEFAULT '0',
+  admin_name varchar(255) NOT NULL DEFAULT '',
+  action varchar(32) NOT NULL DEFAULT '',
+  resource_type varchar(32) NOT NULL DEFAULT '',
+  resource_id integer NOT NULL DEFAULT '0',
+  resource_name varchar(255) NOT NULL DEFAULT '',
+  endpoint varchar(255) NOT NULL DEFAULT '',
+  client_ip varchar(64) NOT NULL DEFAULT '',
+  before_snapshot text NOT NULL,
+  after_snapshot text NOT NULL,
+  diff text NOT NULL,
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00'
+)`,
+				"CREATE INDEX IF NOT EXISTS gateway_admin_audit_log_idx_resource ON gateway_admin_audit_log (resource_type, resource_id)",
+				"CREATE INDEX IF NOT EXISTS gateway_admin_audit_log_idx_created_at ON gateway_admin_audit_log (created_at)",
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   dropOperationTables,
+			"sqlite3": dropOperationTables,
+		},
+	})
+}
+
+var dropOperationTables = []string{
+	"DROP TABLE IF EXISTS gateway_admin_audit_log",
+	"DROP TABLE IF EXISTS gateway_alert_rule",
+	"DROP TABLE IF EXISTS gateway_renter_usage_day",
+	"DROP TABLE IF EXISTS gateway_service_version",
+	"DROP TABLE IF EXISTS gateway_service_fault_rule",
+	"DROP TABLE IF EXISTS gateway_service_http_route",
+}
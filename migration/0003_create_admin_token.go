@@ -0,0 +1,51 @@
+package migration
+
+// 管理接口 API token
+
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "create_admin_token",
+		Up: map[string][]string{
+			"mysql": {
+				`CREATE TABLE IF NOT EXISTS gateway_admin_token (
+  id bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  admin_id bigint(20) NOT NULL DEFAULT '0' COMMENT '所属管理员id',
+  name varchar(255) NOT NULL DEFAULT '' COMMENT '名称',
+  token_prefix varchar(32) NOT NULL DEFAULT '' COMMENT 'token 前缀, 用于辨认',
+  token_hash char(64) NOT NULL DEFAULT '' COMMENT 'token 的 sha256',
+  scopes varchar(1000) NOT NULL DEFAULT '' COMMENT '权限, 逗号间隔',
+  expired_at datetime DEFAULT NULL COMMENT '过期时间, 为空表示不过期',
+  last_used_at datetime DEFAULT NULL COMMENT '最近使用时间',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '添加时间',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '更新时间',
+  is_delete tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否已吊销；0：否；1：是',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_token_hash (token_hash),
+  KEY idx_admin_id (admin_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='管理接口token表'`,
+			},
+			"sqlite3": {
+				`CREATE TABLE IF NOT EXISTS gateway_admin_token (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  admin_id integer NOT NULL DEFAULT '0',
+  name varchar(255) NOT NULL DEFAULT '',
+  token_prefix varchar(32) NOT NULL DEFAULT '',
+  token_hash char(64) NOT NULL DEFAULT '',
+  scopes varchar(1000) NOT NULL DEFAULT '',
+  expired_at datetime DEFAULT NULL,
+  last_used_at datetime DEFAULT NULL,
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  is_delete integer NOT NULL DEFAULT '0'
+)`,
+				"CREATE UNIQUE INDEX IF NOT EXISTS gateway_admin_token_uniq_token_hash ON gateway_admin_token (token_hash)",
+				"CREATE INDEX IF NOT EXISTS gateway_admin_token_idx_admin_id ON gateway_admin_token (admin_id)",
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   {"DROP TABLE IF EXISTS gateway_admin_token"},
+			"sqlite3": {"DROP TABLE IF EXISTS gateway_admin_token"},
+		},
+	})
+}
@@ -0,0 +1,43 @@
+package migration
+
+// 管理员两步验证
+
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "create_admin_totp",
+		Up: map[string][]string{
+			"mysql": {
+				`CREATE TABLE IF NOT EXISTS gateway_admin_totp (
+  id bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  admin_id bigint(20) NOT NULL DEFAULT '0' COMMENT '管理员id',
+  secret varchar(64) NOT NULL DEFAULT '' COMMENT 'base32 密钥',
+  is_enable tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否已启用；0：绑定中；1：已启用',
+  last_step bigint(20) NOT NULL DEFAULT '0' COMMENT '最近一次使用的时间步, 防止验证码重复使用',
+  recovery_codes varchar(1000) NOT NULL DEFAULT '' COMMENT '未使用的恢复码 sha256, 逗号间隔',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '添加时间',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '更新时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_admin_id (admin_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='管理员两步验证表'`,
+			},
+			"sqlite3": {
+				`CREATE TABLE IF NOT EXISTS gateway_admin_totp (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  admin_id integer NOT NULL DEFAULT '0',
+  secret varchar(64) NOT NULL DEFAULT '',
+  is_enable integer NOT NULL DEFAULT '0',
+  last_step integer NOT NULL DEFAULT '0',
+  recovery_codes varchar(1000) NOT NULL DEFAULT '',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00'
+)`,
+				"CREATE UNIQUE INDEX IF NOT EXISTS gateway_admin_totp_uniq_admin_id ON gateway_admin_totp (admin_id)",
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   {"DROP TABLE IF EXISTS gateway_admin_totp"},
+			"sqlite3": {"DROP TABLE IF EXISTS gateway_admin_totp"},
+		},
+	})
+}
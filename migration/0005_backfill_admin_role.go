@@ -0,0 +1,20 @@
+package migration
+
+// 加入角色之前创建的管理员拥有全部权限，升级后保留为超级管理员
+// 之后角色为空按只读处理
+
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "backfill_admin_role",
+		Up: map[string][]string{
+			"mysql":   {"UPDATE gateway_admin SET role='super_admin' WHERE role=''"},
+			"sqlite3": {"UPDATE gateway_admin SET role='super_admin' WHERE role=''"},
+		},
+		// 回退后角色仍然有效，不需要清空
+		Down: map[string][]string{
+			"mysql":   {},
+			"sqlite3": {},
+		},
+	})
+}
@@ -0,0 +1,37 @@
+package migration
+
+// 两步验证密钥加密后变长，失败次数与锁定时间按管理员记录
+// 管理员的 session_version 增加后，之前登录的 session 全部失效
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "harden_admin_totp",
+		Up: map[string][]string{
+			"mysql": {
+				"ALTER TABLE gateway_admin_totp MODIFY secret varchar(255) NOT NULL DEFAULT '' COMMENT '加密后的密钥'",
+			},
+			// sqlite 不限制 varchar 长度
+			"sqlite3": {},
+		},
+		Columns: []Column{
+			{Table: "gateway_admin_totp", Name: "failed_attempts", Definition: map[string]string{
+				"mysql":   `int(11) NOT NULL DEFAULT '0' COMMENT '连续失败次数'`,
+				"sqlite3": `integer NOT NULL DEFAULT '0'`,
+			}},
+			{Table: "gateway_admin_totp", Name: "locked_until", Definition: map[string]string{
+				"mysql":   `datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '锁定到期时间'`,
+				"sqlite3": `datetime NOT NULL DEFAULT '1971-01-01 00:00:00'`,
+			}},
+			{Table: "gateway_admin", Name: "session_version", Definition: map[string]string{
+				"mysql":   `int(11) NOT NULL DEFAULT '0' COMMENT 'session 版本, 增加后已登录的 session 失效'`,
+				"sqlite3": `integer NOT NULL DEFAULT '0'`,
+			}},
+		},
+		// 回退后多出的列不影响旧版本，加密的密钥需要重新绑定
+		Down: map[string][]string{
+			"mysql":   {},
+			"sqlite3": {},
+		},
+	})
+}
@@ -0,0 +1,225 @@
+package migration
+
+import (
+	"fmt"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/gorm"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"sort"
+	"time"
+)
+
+// 数据库结构迁移，SQL 编译进二进制，版本号只增不改
+// 已执行的版本记录在 gateway_schema_migration，up 按版本号升序执行未执行的迁移，down 按降序回退
+// mysql 的 DDL 会隐式提交，每个迁移执行完所有语句后才写入版本记录，中途失败需要按报错手工处理
+
+// Migration Up 与 Down 按 gorm dialect 名称区分，目前支持 mysql 与 sqlite3
+// Columns 在 Up 的语句之后检查，表中缺少的列用 ALTER TABLE ... ADD COLUMN 补上，Down 不单独删除
+type Migration struct {
+	Version int
+	Name    string
+	Up      map[string][]string
+	Down    map[string][]string
+	Columns []Column
+}
+
+// Column Definition 为 ADD COLUMN 中列名之后的部分，按 gorm dialect 名称区分
+type Column struct {
+	Table      string
+	Name       string
+	Definition map[string]string
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type schemaMigration struct {
+	Version   int       `gorm:"column:version;primary_key"`
+	Name      string    `gorm:"column:name"`
+	AppliedAt time.Time `gorm:"column:applied_at"`
+}
+
+func (t *schemaMigration) TableName() string {
+	return "gateway_schema_migration"
+}
+
+var createSchemaMigration = map[string]string{
+	"mysql": `CREATE TABLE IF NOT EXISTS gateway_schema_migration (
+  version int(11) NOT NULL COMMENT '迁移版本',
+  name varchar(255) NOT NULL DEFAULT '' COMMENT '迁移名称',
+  applied_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '执行时间',
+  PRIMARY KEY (version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='数据库结构迁移记录'`,
+	"sqlite3": `CREATE TABLE IF NOT EXISTS gateway_schema_migration (
+  version integer NOT NULL PRIMARY KEY,
+  name varchar(255) NOT NULL DEFAULT '',
+  applied_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00'
+)`,
+}
+
+var migrations []Migration
+
+// register 在各迁移文件的 init 中调用
+func register(m Migration) {
+	migrations = append(migrations, m)
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+}
+
+// Latest 返回最新的迁移版本
+func Latest() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// List 返回每个迁移的执行状态
+func List(c *gin.Context, db *gorm.DB) ([]Status, error) {
+	applied, err := appliedMigrations(c, db)
+	if err != nil {
+		return nil, err
+	}
+	list := []Status{}
+	for _, m := range migrations {
+		item := Status{Version: m.Version, Name: m.Name}
+		if record, ok := applied[m.Version]; ok {
+			item.Applied = true
+			item.AppliedAt = record.AppliedAt
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+// Up 执行版本号不大于 target 的未执行迁移，target 为 0 时执行到最新
+func Up(c *gin.Context, db *gorm.DB, target int) ([]Migration, error) {
+	applied, err := appliedMigrations(c, db)
+	if err != nil {
+		return nil, err
+	}
+	done := []Migration{}
+	for _, m := range migrations {
+		if target > 0 && m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := execAll(c, db, m.Up); err != nil {
+			return done, errors.Wrapf(err, "migration %d %s up", m.Version, m.Name)
+		}
+		if err := addMissingColumns(c, db, m.Columns); err != nil {
+			return done, errors.Wrapf(err, "migration %d %s up", m.Version, m.Name)
+		}
+		record := &schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}
+		if err := db.SetCtx(public.GetGinTraceContext(c)).Create(record).Error; err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Down 按版本号从大到小回退 steps 个已执行的迁移
+func Down(c *gin.Context, db *gorm.DB, steps int) ([]Migration, error) {
+	applied, err := appliedMigrations(c, db)
+	if err != nil {
+		return nil, err
+	}
+	done := []Migration{}
+	for index := len(migrations) - 1; index >= 0 && len(done) < steps; index-- {
+		m := migrations[index]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if err := execAll(c, db, m.Down); err != nil {
+			return done, errors.Wrapf(err, "migration %d %s down", m.Version, m.Name)
+		}
+		if err := db.SetCtx(public.GetGinTraceContext(c)).Where("version=?", m.Version).Delete(&schemaMigration{}).Error; err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Bootstrap 没有可用管理员时创建一个超级管理员，返回随机生成的密码；已有管理员时返回空字符串
+func Bootstrap(c *gin.Context, db *gorm.DB, userName string) (string, error) {
+	list, err := (&dao.Admin{}).List(c, db)
+	if err != nil {
+		return "", err
+	}
+	if len(list) > 0 {
+		return "", nil
+	}
+	password := public.NewSalt()
+	salt := public.NewSalt()
+	admin := &dao.Admin{
+		UserName: userName,
+		Salt:     salt,
+		Password: public.SaltPassword(salt, password),
+		Role:     public.RoleSuperAdmin,
+	}
+	if err := admin.Save(c, db); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+func appliedMigrations(c *gin.Context, db *gorm.DB) (map[int]schemaMigration, error) {
+	statement, ok := createSchemaMigration[db.Dialect().GetName()]
+	if !ok {
+		return nil, errors.Errorf("migrations do not support %s", db.Dialect().GetName())
+	}
+	if err := db.SetCtx(public.GetGinTraceContext(c)).Exec(statement).Error; err != nil {
+		return nil, err
+	}
+	var list []schemaMigration
+	err := db.SetCtx(public.GetGinTraceContext(c)).Order("version asc").Find(&list).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	applied := map[int]schemaMigration{}
+	for _, item := range list {
+		applied[item.Version] = item
+	}
+	return applied, nil
+}
+
+// addMissingColumns 跳过表中已有的列，新建的表已经包含全部列，只有基线表结构会执行 ALTER TABLE
+func addMissingColumns(c *gin.Context, db *gorm.DB, columns []Column) error {
+	dialect := db.Dialect()
+	for _, column := range columns {
+		if dialect.HasColumn(column.Table, column.Name) {
+			continue
+		}
+		definition, ok := column.Definition[dialect.GetName()]
+		if !ok {
+			return errors.Errorf("no definition of %s.%s for %s", column.Table, column.Name, dialect.GetName())
+		}
+		statement := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", column.Table, column.Name, definition)
+		if err := db.SetCtx(public.GetGinTraceContext(c)).Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func execAll(c *gin.Context, db *gorm.DB, dialectStatements map[string][]string) error {
+	statements, ok := dialectStatements[db.Dialect().GetName()]
+	if !ok {
+		return errors.Errorf("no statements for %s", db.Dialect().GetName())
+	}
+	for _, statement := range statements {
+		if err := db.SetCtx(public.GetGinTraceContext(c)).Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
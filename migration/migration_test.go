@@ -0,0 +1,94 @@
+package migration_test
+
+import (
+	"github.com/JunxiHe459/gateway/migration"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/e421083458/gorm"
+	_ "github.com/e421083458/gorm/dialects/sqlite"
+	"path/filepath"
+	"testing"
+)
+
+// baselineSchema 引入迁移之前手工导入的表结构，没有角色、禁用、转换、跨域与压缩相关的列
+var baselineSchema = []string{
+	`CREATE TABLE gateway_admin (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  user_name varchar(255) NOT NULL DEFAULT '',
+  salt varchar(50) NOT NULL DEFAULT '',
+  password varchar(255) NOT NULL DEFAULT '',
+  created_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  updated_at datetime NOT NULL DEFAULT '1971-01-01 00:00:00',
+  is_delete integer NOT NULL DEFAULT '0'
+)`,
+	`CREATE TABLE gateway_service_http_rule (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  service_id integer NOT NULL DEFAULT '0',
+  rule_type integer NOT NULL DEFAULT '0',
+  rule varchar(255) NOT NULL DEFAULT '',
+  need_https integer NOT NULL DEFAULT '0',
+  need_strip_uri integer NOT NULL DEFAULT '0',
+  need_websocket integer NOT NULL DEFAULT '0',
+  url_rewrite varchar(5000) NOT NULL DEFAULT '',
+  header_transfer varchar(5000) NOT NULL DEFAULT ''
+)`,
+	`INSERT INTO gateway_admin (user_name, salt, password) VALUES ('admin', 'salt', 'password')`,
+}
+
+func openTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "gateway.db")+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SingularTable(true)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestUpBaselineSchema(t *testing.T) {
+	db := openTestDB(t)
+	for _, statement := range baselineSchema {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := public.NewBackgroundContext()
+	if _, err := migration.Up(c, db, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	for table, columns := range map[string][]string{
+		"gateway_admin":             {"role", "is_disable"},
+		"gateway_service_http_rule": {"request_transform", "need_cors", "cors_max_age", "compress_types"},
+	} {
+		for _, column := range columns {
+			if !db.Dialect().HasColumn(table, column) {
+				t.Errorf("%s.%s was not added", table, column)
+			}
+		}
+	}
+	var role string
+	if err := db.Table("gateway_admin").Where("user_name=?", "admin").Select("role").Row().Scan(&role); err != nil {
+		t.Fatal(err)
+	}
+	if role != public.RoleSuperAdmin {
+		t.Fatalf("role = %q, want %q", role, public.RoleSuperAdmin)
+	}
+	if err := db.Exec("INSERT INTO gateway_service_http_rule (service_id, rule, need_cors, cors_allow_origins) VALUES (1, '/demo', 1, '*')").Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpFreshSchemaIsIdempotent(t *testing.T) {
+	db := openTestDB(t)
+	c := public.NewBackgroundContext()
+	done, err := migration.Up(c, db, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != migration.Latest() {
+		t.Fatalf("applied %d migrations, want %d", len(done), migration.Latest())
+	}
+	if done, err = migration.Up(c, db, 0); err != nil || len(done) != 0 {
+		t.Fatalf("second Up applied %d migrations, err %v", len(done), err)
+	}
+}
@@ -0,0 +1,198 @@
+package public
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/e421083458/golang_common/lib"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 数据面访问日志，请求线程只负责入队，序列化与写入在后台协程完成
+
+type AccessLogEntry struct {
+	Time          string `json:"time"`
+	TraceID       string `json:"trace_id"`
+	Service       string `json:"service"`
+	Renter        string `json:"renter"`
+	ClientIP      string `json:"client_ip"`
+	Method        string `json:"method"`
+	Path          string `json:"path"`
+	Upstream      string `json:"upstream"`
+	Status        int    `json:"status"`
+	RequestBytes  int64  `json:"request_bytes"`
+	ResponseBytes int64  `json:"response_bytes"`
+	LatencyMs     int64  `json:"latency_ms"`
+	Retries       int    `json:"retries"`
+}
+
+type AccessLogSink interface {
+	Write(line []byte) error
+}
+
+var AccessLoggerHandler *AccessLogger
+
+func init() {
+	AccessLoggerHandler = NewAccessLogger()
+}
+
+type AccessLogger struct {
+	On           bool
+	SampleRate   float64
+	RedactFields []string
+	Sinks        []AccessLogSink
+	queue        chan *AccessLogEntry
+	once         sync.Once
+}
+
+func NewAccessLogger() *AccessLogger {
+	return &AccessLogger{
+		SampleRate: 1,
+	}
+}
+
+// InitAccessLogger 读取 base.access_log 配置
+func InitAccessLogger() error {
+	if !lib.GetBoolConf("base.access_log.on") {
+		return nil
+	}
+	sinks := []AccessLogSink{}
+	if lib.GetBoolConf("base.access_log.file.on") {
+		sink, err := NewFileAccessLogSink(lib.GetStringConf("base.access_log.file.path"),
+			int64(lib.GetIntConf("base.access_log.file.max_size"))<<20,
+			lib.GetIntConf("base.access_log.file.max_backups"))
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+	if lib.GetBoolConf("base.access_log.stdout.on") {
+		sinks = append(sinks, &WriterAccessLogSink{Writer: os.Stdout})
+	}
+	if lib.GetBoolConf("base.access_log.syslog.on") {
+		sink, err := NewSyslogAccessLogSink(lib.GetStringConf("base.access_log.syslog.addr"),
+			lib.GetStringConf("base.access_log.syslog.tag"))
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+	sampleRate := 1.0
+	if lib.GetConf("base.access_log.sample_rate") != nil {
+		sampleRate = lib.GetFloat64Conf("base.access_log.sample_rate")
+	}
+	AccessLoggerHandler.Start(sinks, sampleRate,
+		lib.GetStringSliceConf("base.access_log.redact_fields"),
+		lib.GetIntConf("base.access_log.queue_size"))
+	return nil
+}
+
+func (l *AccessLogger) Start(sinks []AccessLogSink, sampleRate float64, redactFields []string, queueSize int) {
+	l.once.Do(func() {
+		if queueSize <= 0 {
+			queueSize = 4096
+		}
+		l.Sinks = sinks
+		l.SampleRate = sampleRate
+		l.RedactFields = redactFields
+		l.queue = make(chan *AccessLogEntry, queueSize)
+		l.On = true
+		go l.run()
+	})
+}
+
+// Log 按采样率入队，5xx 总是记录；队列满时丢弃，不阻塞请求
+func (l *AccessLogger) Log(entry *AccessLogEntry) {
+	if !l.On {
+		return
+	}
+	if entry.Status < 500 && l.SampleRate < 1 && rand.Float64() >= l.SampleRate {
+		return
+	}
+	select {
+	case l.queue <- entry:
+	default:
+		MetricAccessLogDropped.Inc()
+	}
+}
+
+func (l *AccessLogger) run() {
+	for entry := range l.queue {
+		line, err := l.format(entry)
+		if err != nil {
+			continue
+		}
+		for _, sink := range l.Sinks {
+			if err := sink.Write(line); err != nil {
+				fmt.Println("AccessLogSink write err", err)
+			}
+		}
+	}
+}
+
+func (l *AccessLogger) format(entry *AccessLogEntry) ([]byte, error) {
+	if len(l.RedactFields) == 0 {
+		return json.Marshal(entry)
+	}
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return json.Marshal(redactAccessLogFields(fields, l.RedactFields))
+}
+
+// redactAccessLogFields 按字段名脱敏任意层级的同名字段，"a.b" 形式只匹配对应路径
+func redactAccessLogFields(value interface{}, redactFields []string) interface{} {
+	for _, field := range redactFields {
+		path := strings.Split(field, ".")
+		if len(path) == 1 {
+			value = redactAccessLogKey(value, field)
+			continue
+		}
+		redactAccessLogPath(value, path)
+	}
+	return value
+}
+
+func redactAccessLogKey(value interface{}, key string) interface{} {
+	switch item := value.(type) {
+	case map[string]interface{}:
+		for name, child := range item {
+			if name == key {
+				item[name] = RedactedValue
+				continue
+			}
+			item[name] = redactAccessLogKey(child, key)
+		}
+	case []interface{}:
+		for index, child := range item {
+			item[index] = redactAccessLogKey(child, key)
+		}
+	}
+	return value
+}
+
+func redactAccessLogPath(value interface{}, path []string) {
+	switch item := value.(type) {
+	case map[string]interface{}:
+		child, ok := item[path[0]]
+		if !ok {
+			return
+		}
+		if len(path) == 1 {
+			item[path[0]] = RedactedValue
+			return
+		}
+		redactAccessLogPath(child, path[1:])
+	case []interface{}:
+		for _, child := range item {
+			redactAccessLogPath(child, path)
+		}
+	}
+}
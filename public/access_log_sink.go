@@ -0,0 +1,123 @@
+package public
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// WriterAccessLogSink 写入任意 io.Writer，用于 stdout
+type WriterAccessLogSink struct {
+	Writer io.Writer
+	locker sync.Mutex
+}
+
+func (s *WriterAccessLogSink) Write(line []byte) error {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	_, err := s.Writer.Write(append(line, '\n'))
+	return err
+}
+
+// FileAccessLogSink 按大小切割，保留 MaxBackups 个历史文件 path.1 ... path.N
+type FileAccessLogSink struct {
+	Path       string
+	MaxSize    int64
+	MaxBackups int
+	file       *os.File
+	size       int64
+	locker     sync.Mutex
+}
+
+func NewFileAccessLogSink(path string, maxSize int64, maxBackups int) (*FileAccessLogSink, error) {
+	if path == "" {
+		path = "./logs/gateway.access.log"
+	}
+	if maxSize <= 0 {
+		maxSize = 100 << 20
+	}
+	if maxBackups <= 0 {
+		maxBackups = 7
+	}
+	sink := &FileAccessLogSink{Path: path, MaxSize: maxSize, MaxBackups: maxBackups}
+	if err := sink.open(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+func (s *FileAccessLogSink) Write(line []byte) error {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	if s.size+int64(len(line))+1 > s.MaxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(append(line, '\n'))
+	s.size += int64(n)
+	return err
+}
+
+func (s *FileAccessLogSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+func (s *FileAccessLogSink) rotate() error {
+	s.file.Close()
+	for index := s.MaxBackups - 1; index >= 1; index-- {
+		os.Rename(fmt.Sprintf("%s.%d", s.Path, index), fmt.Sprintf("%s.%d", s.Path, index+1))
+	}
+	if err := os.Rename(s.Path, s.Path+".1"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return s.open()
+}
+
+// SyslogAccessLogSink 以 RFC 3164 格式通过 udp 发送，facility local0 severity info
+type SyslogAccessLogSink struct {
+	Tag      string
+	hostname string
+	conn     net.Conn
+	locker   sync.Mutex
+}
+
+func NewSyslogAccessLogSink(addr, tag string) (*SyslogAccessLogSink, error) {
+	if addr == "" {
+		addr = "127.0.0.1:514"
+	}
+	if tag == "" {
+		tag = "gateway"
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	return &SyslogAccessLogSink{Tag: tag, hostname: hostname, conn: conn}, nil
+}
+
+func (s *SyslogAccessLogSink) Write(line []byte) error {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	_, err := fmt.Fprintf(s.conn, "<134>%s %s %s: %s", time.Now().Format(time.Stamp), s.hostname, s.Tag, line)
+	return err
+}
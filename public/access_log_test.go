@@ -0,0 +1,44 @@
+package public
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRedactAccessLogFieldsNested(t *testing.T) {
+	var value interface{}
+	raw := `{"client_ip":"10.0.0.1","status":200,
+		"request":{"headers":{"token":"t1","accept":"*/*"},"client_ip":"10.0.0.2"},
+		"upstreams":[{"addr":"127.0.0.1:9001","auth":{"token":"t2"}}],
+		"meta":{"user":{"name":"alice"}}}`
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(redactAccessLogFields(value, []string{"token", "client_ip", "meta.user.name"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := string(out)
+	for _, secret := range []string{"t1", "t2", "10.0.0.1", "10.0.0.2", "alice"} {
+		if strings.Contains(line, secret) {
+			t.Errorf("%q not redacted: %s", secret, line)
+		}
+	}
+	for _, keep := range []string{"*/*", "127.0.0.1:9001", `"status":200`} {
+		if !strings.Contains(line, keep) {
+			t.Errorf("%q should be kept: %s", keep, line)
+		}
+	}
+}
+
+func TestAccessLogFormatRedact(t *testing.T) {
+	logger := &AccessLogger{RedactFields: []string{"client_ip"}}
+	line, err := logger.format(&AccessLogEntry{ClientIP: "10.0.0.1", Service: "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(line), "10.0.0.1") || !strings.Contains(string(line), `"service":"demo"`) {
+		t.Errorf("unexpected line: %s", line)
+	}
+}
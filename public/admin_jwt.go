@@ -0,0 +1,94 @@
+package public
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/e421083458/golang_common/lib"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 管理接口 JWT，由 API token 换取，HS256 签名，jti 为 API token 的 id
+// 校验时仍然查询 API token，token 吊销或过期后换取的 JWT 同时失效
+
+type AdminJwtClaims struct {
+	Scopes []string `json:"scopes"`
+	jwt.StandardClaims
+}
+
+var (
+	jwtSecret       []byte
+	jwtSecretLocker sync.Mutex
+)
+
+// JwtSecret 环境变量优先于 base.admin_token.jwt_secret，都为空时使用进程内的随机密钥
+func JwtSecret() []byte {
+	jwtSecretLocker.Lock()
+	defer jwtSecretLocker.Unlock()
+	if jwtSecret != nil {
+		return jwtSecret
+	}
+	secret := os.Getenv(JwtSecretEnv)
+	if secret == "" {
+		secret = lib.GetStringConf("base.admin_token.jwt_secret")
+	}
+	if secret == "" {
+		// 重启后已签发的 JWT 失效，多节点之间也不能互认
+		secret = NewSalt() + NewSalt()
+		log.Printf(" [WARN] %s is not set, admin JWTs use a random secret\n", JwtSecretEnv)
+	}
+	jwtSecret = []byte(secret)
+	return jwtSecret
+}
+
+// IsAdminJwt API token 以 AdminTokenPrefix 开头，JWT 由三段组成
+func IsAdminJwt(token string) bool {
+	return !strings.HasPrefix(token, AdminTokenPrefix) && strings.Count(token, ".") == 2
+}
+
+func NewAdminJwt(tokenID int64, adminID int, scopes []string, expiredAt time.Time) (string, error) {
+	claims := &AdminJwtClaims{
+		Scopes: scopes,
+		StandardClaims: jwt.StandardClaims{
+			Id:        strconv.FormatInt(tokenID, 10),
+			Subject:   strconv.Itoa(adminID),
+			Issuer:    JwtIssuer,
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: expiredAt.Unix(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(JwtSecret())
+}
+
+// ParseAdminJwt 只接受 HS256，校验签名、签发方与有效期
+func ParseAdminJwt(token string) (*AdminJwtClaims, error) {
+	claims := &AdminJwtClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return JwtSecret(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims.Issuer != JwtIssuer || claims.ExpiresAt == 0 {
+		return nil, errors.New("invalid jwt claims")
+	}
+	return claims, nil
+}
+
+func (c *AdminJwtClaims) TokenID() int64 {
+	id, _ := strconv.ParseInt(c.Id, 10, 64)
+	return id
+}
+
+func (c *AdminJwtClaims) AdminID() int {
+	id, _ := strconv.Atoi(c.Subject)
+	return id
+}
@@ -0,0 +1,61 @@
+package public
+
+import (
+	"github.com/dgrijalva/jwt-go"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminJwt(t *testing.T) {
+	jwtSecretLocker.Lock()
+	oldSecret := jwtSecret
+	jwtSecret = []byte("test-jwt-secret")
+	jwtSecretLocker.Unlock()
+	defer func() { jwtSecret = oldSecret }()
+
+	token, err := NewAdminJwt(7, 3, []string{PermServiceRead}, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsAdminJwt(token) || IsAdminJwt(NewAdminToken()) {
+		t.Fatal("jwt and api token formats are not told apart")
+	}
+	claims, err := ParseAdminJwt(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.TokenID() != 7 || claims.AdminID() != 3 || len(claims.Scopes) != 1 || claims.Scopes[0] != PermServiceRead {
+		t.Fatalf("claims = %+v", claims)
+	}
+
+	parts := strings.Split(token, ".")
+	if _, err := ParseAdminJwt(parts[0] + "." + parts[1] + ".invalid"); err == nil {
+		t.Error("tampered signature accepted")
+	}
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if _, err := ParseAdminJwt(unsigned); err == nil {
+		t.Error("unsigned jwt accepted")
+	}
+	expired, _ := NewAdminJwt(7, 3, nil, time.Now().Add(-time.Minute))
+	if _, err := ParseAdminJwt(expired); err == nil {
+		t.Error("expired jwt accepted")
+	}
+	other, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("other-secret"))
+	if _, err := ParseAdminJwt(other); err == nil {
+		t.Error("jwt signed with another secret accepted")
+	}
+}
+
+func TestRedactorMatchesWholeFieldNames(t *testing.T) {
+	redactor := NewRedactor(RedactFieldsDefault, RedactHeadersDefault)
+	out := redactor.RedactBody(`{"token":"gw_secret","token_prefix":"gw_1234","Password":"p","data":{"secret_uri":"otpauth://x"},"code":"135790"}`)
+	for _, value := range []string{"gw_secret", `"p"`, "otpauth", "135790"} {
+		if strings.Contains(out, value) {
+			t.Errorf("%s not redacted: %s", value, out)
+		}
+	}
+	if !strings.Contains(out, "gw_1234") {
+		t.Errorf("token_prefix redacted: %s", out)
+	}
+}
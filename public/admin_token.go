@@ -0,0 +1,31 @@
+package public
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// 管理接口 API token，只在创建时返回一次明文，数据库中保存 sha256
+
+// NewAdminToken 返回 AdminTokenPrefix 开头的随机 token
+func NewAdminToken() string {
+	b := make([]byte, 20)
+	_, _ = rand.Read(b)
+	return AdminTokenPrefix + hex.EncodeToString(b)
+}
+
+func HashAdminToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseBearerToken 从 Authorization header 中取出 token，不是 Bearer 时返回空
+func ParseBearerToken(authorization string) string {
+	parts := strings.SplitN(strings.TrimSpace(authorization), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
@@ -0,0 +1,58 @@
+package public
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// AlertNotification 告警 webhook 的 JSON 内容，同一告警的 firing 与 resolved 使用相同的 fingerprint
+type AlertNotification struct {
+	Status      string  `json:"status"`
+	Fingerprint string  `json:"fingerprint"`
+	RuleID      int64   `json:"rule_id"`
+	RuleName    string  `json:"rule_name"`
+	RuleType    string  `json:"rule_type"`
+	Subject     string  `json:"subject"`
+	Value       float64 `json:"value"`
+	Threshold   float64 `json:"threshold"`
+	Message     string  `json:"message"`
+	StartsAt    string  `json:"starts_at"`
+	ResolvedAt  string  `json:"resolved_at,omitempty"`
+}
+
+var alertWebhookClient = &http.Client{Timeout: 5 * time.Second}
+
+// SendAlertWebhook POST 通知到 url，非 2xx 或网络错误时重试，最多 AlertWebhookRetry 次
+func SendAlertWebhook(url string, notification *AlertNotification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		err = postAlertWebhook(url, body)
+		if err == nil || attempt >= AlertWebhookRetry {
+			return err
+		}
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+}
+
+func postAlertWebhook(url string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := alertWebhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded %d", url, resp.StatusCode)
+	}
+	return nil
+}
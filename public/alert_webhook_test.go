@@ -0,0 +1,41 @@
+package public
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSendAlertWebhookRetry(t *testing.T) {
+	var attempts int32
+	received := make(chan AlertNotification, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("content type %q", r.Header.Get("Content-Type"))
+		}
+		notification := AlertNotification{}
+		if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
+			t.Error(err)
+		}
+		received <- notification
+	}))
+	defer receiver.Close()
+
+	err := SendAlertWebhook(receiver.URL, &AlertNotification{Status: AlertStatusFiring, Fingerprint: "1:demo", RuleID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+	notification := <-received
+	if notification.Status != AlertStatusFiring || notification.Fingerprint != "1:demo" {
+		t.Errorf("unexpected notification %+v", notification)
+	}
+}
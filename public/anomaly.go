@@ -0,0 +1,43 @@
+package public
+
+import (
+	"math"
+)
+
+// 异常检测：当前值与基线(前几天同一小时)比较，score 为偏离均值的标准差倍数
+// 标准差过小时使用下限，避免平稳流量下的误报
+
+// TrafficAnomaly 请求量上涨或下跌都算异常，标准差下限为均值的 10% 且不小于 1
+func TrafficAnomaly(value float64, baseline []float64, threshold float64) (mean float64, score float64, anomalous bool) {
+	if len(baseline) == 0 {
+		return 0, 0, false
+	}
+	mean, stddev := meanStddev(baseline)
+	stddev = math.Max(stddev, math.Max(mean*0.1, 1))
+	score = (value - mean) / stddev
+	return mean, score, math.Abs(score) >= threshold
+}
+
+// ErrorRateAnomaly 只关注错误率上涨，标准差下限为 1 个百分点
+func ErrorRateAnomaly(value float64, baseline []float64, threshold float64) (mean float64, score float64, anomalous bool) {
+	if len(baseline) == 0 {
+		return 0, 0, false
+	}
+	mean, stddev := meanStddev(baseline)
+	stddev = math.Max(stddev, 0.01)
+	score = (value - mean) / stddev
+	return mean, score, score >= threshold
+}
+
+func meanStddev(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(len(values))
+	variance := 0.0
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
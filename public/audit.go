@@ -0,0 +1,143 @@
+package public
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// 审计日志的快照与差异，快照展开为 "http_rule.rule" 这样的路径，方便比较与查询
+// 写入前按日志 deny-list 脱敏，密码与密钥只记录是否变化
+
+// 每次保存都会变化的时间字段不计入差异
+var auditIgnoreFields = []string{"updated_at", "update_at", "UpdatedAt"}
+
+// 不写入快照的字段，密码的盐脱敏后也没有意义
+var auditExcludeFields = []string{"salt"}
+
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditSnapshot 在修改前调用，返回对象当前值的拷贝，nil 表示对象不存在
+func AuditSnapshot(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil
+	}
+	out := map[string]interface{}{}
+	flattenAudit("", value, out)
+	return out
+}
+
+// AuditRecord 返回脱敏后的前后快照与变化字段，均为 json
+func AuditRecord(before, after map[string]interface{}) (beforeJSON, afterJSON, diffJSON string) {
+	diff := RedactAuditDiff(AuditDiff(before, after))
+	RedactorHandler.Locker.RLock()
+	defer RedactorHandler.Locker.RUnlock()
+	return auditJSON(RedactorHandler.redactFlat(before)), auditJSON(RedactorHandler.redactFlat(after)), auditJSON(diff)
+}
+
+// RedactAuditDiff 敏感字段只保留是否变化
+func RedactAuditDiff(diff map[string]AuditChange) map[string]AuditChange {
+	RedactorHandler.Locker.RLock()
+	defer RedactorHandler.Locker.RUnlock()
+	for key, change := range diff {
+		if RedactorHandler.deniedField(key) {
+			if change.Before != nil {
+				change.Before = RedactedValue
+			}
+			if change.After != nil {
+				change.After = RedactedValue
+			}
+			diff[key] = change
+		}
+	}
+	return diff
+}
+
+// AuditDiff 返回两个快照之间变化的字段，不做脱敏
+func AuditDiff(before, after map[string]interface{}) map[string]AuditChange {
+	diff := map[string]AuditChange{}
+	for key, value := range before {
+		if auditIgnored(key) {
+			continue
+		}
+		if afterValue, ok := after[key]; !ok || !auditEqual(value, afterValue) {
+			diff[key] = AuditChange{Before: value, After: after[key]}
+		}
+	}
+	for key, value := range after {
+		if _, ok := before[key]; !ok && !auditIgnored(key) {
+			diff[key] = AuditChange{After: value}
+		}
+	}
+	return diff
+}
+
+func (r *Redactor) redactFlat(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	out := map[string]interface{}{}
+	for key, value := range values {
+		if r.deniedField(key) {
+			out[key] = RedactedValue
+			continue
+		}
+		out[key] = value
+	}
+	return out
+}
+
+func flattenAudit(prefix string, value interface{}, out map[string]interface{}) {
+	switch item := value.(type) {
+	case map[string]interface{}:
+		for key, child := range item {
+			if InStringSlice(auditExcludeFields, key) {
+				continue
+			}
+			flattenAudit(joinAuditKey(prefix, key), child, out)
+		}
+	case []interface{}:
+		for index, child := range item {
+			flattenAudit(joinAuditKey(prefix, strconv.Itoa(index)), child, out)
+		}
+	default:
+		out[prefix] = value
+	}
+}
+
+func auditIgnored(key string) bool {
+	if index := strings.LastIndex(key, "."); index >= 0 {
+		key = key[index+1:]
+	}
+	return InStringSlice(auditIgnoreFields, key)
+}
+
+func joinAuditKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func auditEqual(a, b interface{}) bool {
+	return auditJSON(a) == auditJSON(b)
+}
+
+func auditJSON(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
@@ -0,0 +1,31 @@
+package public
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAuditRecordExcludesSalt(t *testing.T) {
+	type admin struct {
+		UserName string `json:"user_name"`
+		Salt     string `json:"salt"`
+		Password string `json:"password"`
+		Role     string `json:"role"`
+	}
+	before := AuditSnapshot(&admin{UserName: "ops", Salt: "salt-before", Password: "hash-before", Role: RoleReadOnly})
+	after := AuditSnapshot(&admin{UserName: "ops", Salt: "salt-after", Password: "hash-after", Role: RoleSuperAdmin})
+	if _, ok := after["salt"]; ok {
+		t.Fatal("salt in snapshot")
+	}
+	beforeJSON, afterJSON, diffJSON := AuditRecord(before, after)
+	for _, text := range []string{beforeJSON, afterJSON, diffJSON} {
+		for _, secret := range []string{"salt-before", "salt-after", "hash-before", "hash-after"} {
+			if strings.Contains(text, secret) {
+				t.Errorf("%q in audit record %s", secret, text)
+			}
+		}
+	}
+	if !strings.Contains(diffJSON, `"role"`) || !strings.Contains(diffJSON, `"password"`) {
+		t.Errorf("role and password change missing from diff %s", diffJSON)
+	}
+}
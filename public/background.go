@@ -0,0 +1,11 @@
+package public
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// NewBackgroundContext 返回给后台任务调用 dao 方法使用的 gin.Context
+// dao 方法只从中读取 trace，不需要请求与 ResponseWriter
+func NewBackgroundContext() *gin.Context {
+	return &gin.Context{}
+}
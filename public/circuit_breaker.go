@@ -0,0 +1,144 @@
+package public
+
+import (
+	"github.com/e421083458/golang_common/lib"
+	"sync"
+	"time"
+)
+
+// CircuitBreaker 按服务统计上游连续失败次数
+// closed: 正常放行; open: 直接拒绝; half-open: 熔断到期后只放行一个探测请求，成功后恢复，失败后重新熔断
+type CircuitBreaker struct {
+	ServiceName      string
+	FailureThreshold int
+	OpenDuration     time.Duration
+
+	state    int
+	failures int
+	openedAt time.Time
+	probing  bool
+	locker   sync.Mutex
+}
+
+func NewCircuitBreaker(serviceName string, failureThreshold int, openDuration time.Duration) *CircuitBreaker {
+	breaker := &CircuitBreaker{
+		ServiceName:      serviceName,
+		FailureThreshold: failureThreshold,
+		OpenDuration:     openDuration,
+		state:            CircuitStateClosed,
+	}
+	MetricCircuitBreakerState.Set(CircuitStateClosed, serviceName)
+	return breaker
+}
+
+// Allow 返回 false 时请求不应转发到上游
+func (b *CircuitBreaker) Allow() bool {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	switch b.state {
+	case CircuitStateOpen:
+		if time.Since(b.openedAt) < b.OpenDuration {
+			return false
+		}
+		b.setState(CircuitStateHalfOpen)
+		b.probing = true
+		return true
+	case CircuitStateHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// Record 记录一次被放行请求的结果
+func (b *CircuitBreaker) Record(success bool) {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	if success {
+		b.failures = 0
+		b.probing = false
+		if b.state != CircuitStateClosed {
+			b.setState(CircuitStateClosed)
+		}
+		return
+	}
+	b.failures++
+	if b.state == CircuitStateHalfOpen || b.failures >= b.FailureThreshold {
+		b.probing = false
+		b.openedAt = time.Now()
+		b.setState(CircuitStateOpen)
+	}
+}
+
+func (b *CircuitBreaker) State() int {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	return b.state
+}
+
+func (b *CircuitBreaker) setState(state int) {
+	b.state = state
+	MetricCircuitBreakerState.Set(float64(state), b.ServiceName)
+}
+
+var CircuitBreakerHandler *CircuitBreakerManager
+
+func init() {
+	CircuitBreakerHandler = NewCircuitBreakerManager()
+}
+
+type CircuitBreakerManager struct {
+	BreakerMap map[string]*CircuitBreaker
+	Locker     sync.RWMutex
+}
+
+func NewCircuitBreakerManager() *CircuitBreakerManager {
+	return &CircuitBreakerManager{
+		BreakerMap: map[string]*CircuitBreaker{},
+		Locker:     sync.RWMutex{},
+	}
+}
+
+// Enabled 由 proxy.breaker.on 控制
+func (m *CircuitBreakerManager) Enabled() bool {
+	return lib.GetBoolConf("proxy.breaker.on")
+}
+
+func (m *CircuitBreakerManager) GetBreaker(serviceName string) *CircuitBreaker {
+	m.Locker.RLock()
+	breaker, ok := m.BreakerMap[serviceName]
+	m.Locker.RUnlock()
+	if ok {
+		return breaker
+	}
+
+	m.Locker.Lock()
+	defer m.Locker.Unlock()
+	if breaker, ok := m.BreakerMap[serviceName]; ok {
+		return breaker
+	}
+	threshold := lib.GetIntConf("proxy.breaker.failure_threshold")
+	if threshold <= 0 {
+		threshold = CircuitFailureThresholdDefault
+	}
+	openSeconds := lib.GetIntConf("proxy.breaker.open_seconds")
+	if openSeconds <= 0 {
+		openSeconds = CircuitOpenSecondsDefault
+	}
+	breaker = NewCircuitBreaker(serviceName, threshold, time.Duration(openSeconds)*time.Second)
+	m.BreakerMap[serviceName] = breaker
+	return breaker
+}
+
+// Remove 服务删除或改名后清理熔断状态与指标
+func (m *CircuitBreakerManager) Remove(serviceName string) {
+	m.Locker.Lock()
+	defer m.Locker.Unlock()
+	if _, ok := m.BreakerMap[serviceName]; ok {
+		delete(m.BreakerMap, serviceName)
+		MetricCircuitBreakerState.Delete(serviceName)
+	}
+}
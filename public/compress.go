@@ -0,0 +1,29 @@
+package public
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NegotiateEncoding 根据 Accept-Encoding 选择压缩算法，优先 br，其次 gzip，都不接受时返回空
+func NegotiateEncoding(acceptEncoding string) string {
+	accepted := map[string]bool{}
+	for _, item := range strings.Split(acceptEncoding, ",") {
+		parts := strings.Split(strings.TrimSpace(item), ";")
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		quality := 1.0
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				quality, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+		accepted[name] = quality > 0
+	}
+	for _, encoding := range []string{EncodingBrotli, EncodingGzip} {
+		if ok, exists := accepted[encoding]; ok || (!exists && accepted["*"]) {
+			return encoding
+		}
+	}
+	return ""
+}
@@ -0,0 +1,26 @@
+package public
+
+import (
+	"github.com/e421083458/golang_common/lib"
+	"github.com/spf13/viper"
+	"strings"
+	"testing"
+)
+
+// useTestConf 用 values 临时替换配置，key 格式与 lib.GetStringConf 相同，如 "base.state.driver"
+func useTestConf(t *testing.T, values map[string]interface{}) {
+	oldConf := lib.ViperConfMap
+	lib.ViperConfMap = map[string]*viper.Viper{}
+	for key, value := range values {
+		keys := strings.SplitN(key, ".", 2)
+		v, ok := lib.ViperConfMap[keys[0]]
+		if !ok {
+			v = viper.New()
+			lib.ViperConfMap[keys[0]] = v
+		}
+		v.Set(keys[1], value)
+	}
+	t.Cleanup(func() {
+		lib.ViperConfMap = oldConf
+	})
+}
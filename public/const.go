@@ -4,6 +4,8 @@ const (
 	ValidatorKey        = "ValidatorKey"
 	TranslatorKey       = "TranslatorKey"
 	AdminSessionInfoKey = "AdminSessionInfoKey"
+	// 密码校验通过、两步验证未完成的登录
+	AdminPendingSessionKey = "AdminPendingSessionKey"
 
 	LoadTypeHTTP = 0
 	LoadTypeTCP  = 1
@@ -15,12 +17,153 @@ const (
 	RedisFlowDayKey  = "flow_day_count"
 	RedisFlowHourKey = "flow_hour_count"
 
+	RedisLatencyHourKey = "latency_hour_count"
+
+	StateDriverRedis           = "redis"
+	StateDriverMemory          = "memory"
+	StateHealthIntervalDefault = 1
+
+	// 小时数据保留 8 天，供异常检测对比前 7 天同一小时
+	RedisFlowDayKeyExpire  = 86400 * 2
+	RedisFlowHourKeyExpire = 86400 * 8
+
 	FlowTotal         = "flow_total"
 	FlowServicePrefix = "flow_service_"
 	FlowAppPrefix     = "flow_app_"
 
-	JwtSignKey = "my_sign_key"
-	JwtExpires = 60 * 60
+	// 错误计数使用独立的前缀，避免与名为 error_xxx 的服务或租户的请求计数共用同一个 key
+	FlowServiceErrorPrefix  = "flow_error_service_"
+	FlowAppErrorPrefix      = "flow_error_app_"
+	FlowRenterServicePrefix = "flow_renter_service_"
+
+	RenterUsageRetentionMonthsDefault = 12
+	RenterUsageRollupIntervalDefault  = 300
+	UsageDateFormat                   = "2006-01-02"
+
+	CompressSavedPrefix = "flow_compress_saved_"
+
+	DashDimensionService = "service"
+	DashDimensionRenter  = "renter"
+	DashMetricQPS        = "qps"
+	DashMetricErrorRate  = "error_rate"
+	DashMetricLatency    = "latency"
+
+	AnomalyMetricTraffic       = "traffic"
+	AnomalyMetricErrorRate     = "error_rate"
+	AnomalyBaselineDaysDefault = 7
+	AnomalyThresholdDefault    = 3
+	AnomalyMinRequests         = 100
+
+	// 租户调用代理时携带的身份，密钥在网关校验后删除，不透传给上游
+	RenterIDHeader     = "X-Renter-Id"
+	RenterSecretHeader = "X-Renter-Secret"
+
+	// 代理定时从数据库刷新租户与故障规则的间隔, 单位s
+	ProxyReloadIntervalDefault = 10
+
+	FaultTypeDelay = 0
+	FaultTypeAbort = 1
+	FaultTypeReset = 2
+
+	TransformOpAdd          = "add"
+	TransformOpDel          = "del"
+	TransformOpRename       = "rename"
+	TransformOpHeader       = "header"
+	TransformMaxBodyDefault = 1 << 20
+
+	EncodingGzip           = "gzip"
+	EncodingBrotli         = "br"
+	CompressMinSizeDefault = 1024
+	CompressTypesDefault   = "text/,application/json,application/javascript,application/xml"
+	// 流式响应每次 Flush 都要立即发给客户端，不压缩
+	CompressStreamingTypes = "text/event-stream,application/x-ndjson,application/grpc"
+
+	AlertTypeErrorRate  = 0
+	AlertTypeRenterQpd  = 1
+	AlertTypeUpstream   = 2
+	AlertTypeCertExpiry = 3
+
+	AlertStatusPending  = "pending"
+	AlertStatusFiring   = "firing"
+	AlertStatusResolved = "resolved"
+
+	AlertEvaluateIntervalDefault = 30
+	AlertRateWindowDefault       = 60
+	AlertRepeatIntervalDefault   = 3600
+	AlertErrorRateMinRequests    = 20
+	AlertWebhookRetry            = 3
+	AlertUpstreamDialConcurrency = 32
+
+	RequestTailChannelPrefix   = "request_tail_"
+	RequestTailWatchPrefix     = "request_tail_watch_"
+	RequestTailWatchTTL        = 10
+	RequestTailDurationDefault = 60
+	RequestTailDurationMax     = 600
+
+	AuditActionAdd            = "add"
+	AuditActionUpdate         = "update"
+	AuditActionDelete         = "delete"
+	AuditActionChangePassword = "change_password"
+	AuditActionRollback       = "rollback"
+	AuditActionEnableTOTP     = "enable_totp"
+	AuditActionDisableTOTP    = "disable_totp"
+
+	AuditResourceService    = "service"
+	AuditResourceRenter     = "renter"
+	AuditResourceAdmin      = "admin"
+	AuditResourceAdminToken = "admin_token"
+	AuditEndpointCLI        = "cli"
+
+	AdminTokenPrefix = "gw_"
+	// 最近使用时间最多每分钟写一次
+	AdminTokenTouchInterval = 60
+
+	TOTPPeriod            = 30
+	TOTPSkew              = 1
+	TOTPRecoveryCodeCount = 10
+	TOTPIssuerDefault     = "gateway"
+	// 两步验证的状态，见 dto.AdminLoginOutput
+	TOTPStateVerify = "verify"
+	TOTPStateSetup  = "setup"
+
+	AdminLoginPendingTimeoutDefault = 300
+	// 每个管理员两步验证连续失败达到上限后锁定，锁定期间正确的验证码也不接受，单位s
+	TOTPMaxFailures    = 5
+	TOTPLockoutDefault = 900
+	// 两步验证密钥加密保存，加密 key 由环境变量或 base.admin.totp_key 设置
+	TOTPKeyEnv          = "GATEWAY_TOTP_KEY"
+	TOTPEncryptedPrefix = "v1:"
+
+	ServiceVersionBaseline = "baseline"
+	ServiceChangeChannel   = "service_config_changed"
+
+	GatewayConfigVersion    = 1
+	GatewayConfigFormatYAML = "yaml"
+	GatewayConfigFormatJSON = "json"
+
+	// API token 换取的 JWT 最长有效期，单位s，签名密钥可以由环境变量设置
+	JwtExpires   = 60 * 60
+	JwtIssuer    = "gateway"
+	JwtSecretEnv = "GATEWAY_JWT_SECRET"
+
+	MetricsRenterLabelLimit = 100
+	MetricsRenterNone       = "none"
+	MetricsRenterOther      = "other"
+	// /metrics 的 bearer token 可以由环境变量覆盖，避免写入配置文件
+	MetricsTokenEnv = "GATEWAY_METRICS_TOKEN"
+	// 管理端 session 的签名密钥与 redis 密码同样可以由环境变量设置
+	SessionSecretEnv        = "GATEWAY_SESSION_SECRET"
+	SessionRedisPasswordEnv = "GATEWAY_SESSION_REDIS_PASSWORD"
+
+	CircuitStateClosed   = 0
+	CircuitStateHalfOpen = 1
+	CircuitStateOpen     = 2
+	// 连续失败次数达到阈值后熔断，熔断期间直接返回 503，到期后放行一个探测请求
+	CircuitFailureThresholdDefault = 5
+	CircuitOpenSecondsDefault      = 10
+	GrpcCodeUnavailable            = 14
+
+	RedactedValue = "***"
 )
 
 var (
@@ -29,4 +172,16 @@ var (
 		LoadTypeTCP:  "TCP",
 		LoadTypeGRPC: "GRPC",
 	}
+
+	FaultTypeMap = map[int]string{
+		FaultTypeDelay: "delay",
+		FaultTypeAbort: "abort",
+		FaultTypeReset: "reset",
+	}
+	AlertTypeMap = map[int]string{
+		AlertTypeErrorRate:  "error_rate",
+		AlertTypeRenterQpd:  "renter_qpd",
+		AlertTypeUpstream:   "upstream_unhealthy",
+		AlertTypeCertExpiry: "cert_expiry",
+	}
 )
@@ -0,0 +1,71 @@
+package public
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// 通配符只能出现在 host 的最左侧，并且后面紧跟 "."，例如 https://*.example.com
+var corsOriginRegexp = regexp.MustCompile(`^https?://(\*\.)?[^\s/*]+$`)
+
+// ValidateCorsOrigins 校验允许的 origin 列表
+// 允许携带 cookie 时不能使用 *，否则任意站点都能带着用户的 cookie 访问
+func ValidateCorsOrigins(allowOrigins string, allowCredentials bool) error {
+	if allowOrigins == "" {
+		return nil
+	}
+	for _, item := range strings.Split(allowOrigins, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" {
+			if allowCredentials {
+				return errors.New("cors origin * can not be used with credentials")
+			}
+			continue
+		}
+		if !corsOriginRegexp.MatchString(item) {
+			return errors.New("invalid cors origin: " + item)
+		}
+	}
+	return nil
+}
+
+// CorsOriginsHasAny 列表中是否包含 *
+func CorsOriginsHasAny(allowOrigins string) bool {
+	for _, item := range strings.Split(allowOrigins, ",") {
+		if strings.TrimSpace(item) == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchOrigin 判断 origin 是否在允许列表中
+// 列表项支持 * 以及 https://*.example.com 这种子域名通配，通配只匹配完整的子域名
+func MatchOrigin(allowOrigins, origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, item := range strings.Split(allowOrigins, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || strings.EqualFold(item, origin) {
+			return true
+		}
+		index := strings.Index(item, "*")
+		if index < 0 {
+			continue
+		}
+		prefix, suffix := strings.ToLower(item[:index]), strings.ToLower(item[index+1:])
+		lowerOrigin := strings.ToLower(origin)
+		if !strings.HasPrefix(suffix, ".") || len(lowerOrigin) <= len(prefix)+len(suffix) ||
+			!strings.HasPrefix(lowerOrigin, prefix) || !strings.HasSuffix(lowerOrigin, suffix) {
+			continue
+		}
+		// 通配部分只能是子域名，不能跨越 scheme、端口或路径
+		label := lowerOrigin[len(prefix) : len(lowerOrigin)-len(suffix)]
+		if !strings.ContainsAny(label, "/:@") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package public
+
+import (
+	"testing"
+)
+
+func TestMatchOriginWildcardLabelBoundary(t *testing.T) {
+	cases := []struct {
+		allow  string
+		origin string
+		want   bool
+	}{
+		{"https://*.example.com", "https://a.example.com", true},
+		{"https://*.example.com", "https://a.b.example.com", true},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "https://evilexample.com", false},
+		{"https://*.example.com", "http://a.example.com", false},
+		{"https://*example.com", "https://evilexample.com", false},
+		{"https://*.example.com", "https://evil.com:1@a.example.com", false},
+		{"https://a.example.com", "https://A.example.com", true},
+	}
+	for _, item := range cases {
+		if got := MatchOrigin(item.allow, item.origin); got != item.want {
+			t.Errorf("MatchOrigin(%q, %q) = %v, want %v", item.allow, item.origin, got, item.want)
+		}
+	}
+}
+
+func TestValidateCorsOrigins(t *testing.T) {
+	if err := ValidateCorsOrigins("*", true); err == nil {
+		t.Error("* with credentials should be rejected")
+	}
+	if err := ValidateCorsOrigins("https://a.com,*", false); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateCorsOrigins("https://*example.com", false); err == nil {
+		t.Error("wildcard without label boundary should be rejected")
+	}
+	if err := ValidateCorsOrigins("https://*.example.com", true); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,153 @@
+package public
+
+import (
+	"fmt"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/garyburd/redigo/redis"
+	"github.com/pkg/errors"
+	"strconv"
+	"time"
+)
+
+// 流量计数与租户限额状态的存储，由 base.state.driver 选择
+// redis: 多个网关节点共享计数，redis 不可用时降级为本地计数，恢复后把本地增量回补到 redis
+// memory: 只在进程内计数，适合单机部署与测试，重启后清零
+
+// CounterIncr 一次写入中的一个计数器增量，Field 不为空时累加到 hash 的字段
+type CounterIncr struct {
+	Key    string
+	Field  string
+	Value  int64
+	Expire int
+}
+
+type CounterStore interface {
+	// Incr 批量累加并续期，Value 为 0 时也会创建计数器
+	Incr(trace *lib.TraceContext, incrs ...CounterIncr) error
+	// Get ok 为 false 表示计数器不存在
+	Get(trace *lib.TraceContext, key string) (int64, bool, error)
+	// MGet 不存在的计数器返回 0
+	MGet(trace *lib.TraceContext, keys ...string) ([]int64, error)
+	HGetAll(trace *lib.TraceContext, key string) (map[string]int64, error)
+	// Scan 返回所有以 prefix 开头的计数器，key 为完整的计数器名
+	Scan(trace *lib.TraceContext, prefix string) (map[string]int64, error)
+}
+
+var CounterStoreHandler CounterStore = &redisCounterStore{}
+
+// StateDriver 返回配置的状态存储
+func StateDriver() string {
+	if driver := lib.GetStringConf("base.state.driver"); driver != "" {
+		return driver
+	}
+	return StateDriverRedis
+}
+
+// StateHealthInterval redis 不可用期间的探测间隔
+func StateHealthInterval() time.Duration {
+	if interval := lib.GetIntConf("base.state.health_interval"); interval > 0 {
+		return time.Duration(interval) * time.Second
+	}
+	return StateHealthIntervalDefault * time.Second
+}
+
+// InitStateStore 在读取配置后调用，计数器第一次写入前完成
+func InitStateStore() error {
+	switch driver := StateDriver(); driver {
+	case StateDriverRedis:
+		CounterStoreHandler = newFallbackCounterStore(&redisCounterStore{}, StateHealthInterval())
+	case StateDriverMemory:
+		CounterStoreHandler = newMemoryCounterStore()
+	default:
+		return errors.Errorf("unknown state driver %s", driver)
+	}
+	return nil
+}
+
+type redisCounterStore struct{}
+
+func (s *redisCounterStore) Incr(trace *lib.TraceContext, incrs ...CounterIncr) error {
+	return RedisConfPipline(trace, func(c redis.Conn) {
+		for _, incr := range incrs {
+			if incr.Field != "" {
+				c.Send("HINCRBY", incr.Key, incr.Field, incr.Value)
+			} else {
+				c.Send("INCRBY", incr.Key, incr.Value)
+			}
+			if incr.Expire > 0 {
+				c.Send("EXPIRE", incr.Key, incr.Expire)
+			}
+		}
+	})
+}
+
+func (s *redisCounterStore) Get(trace *lib.TraceContext, key string) (int64, bool, error) {
+	count, err := redis.Int64(RedisConfDo(trace, "GET", key))
+	if err == redis.ErrNil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return count, true, nil
+}
+
+func (s *redisCounterStore) MGet(trace *lib.TraceContext, keys ...string) ([]int64, error) {
+	if len(keys) == 0 {
+		return []int64{}, nil
+	}
+	args := []interface{}{}
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	values, err := redis.Values(RedisConfDo(trace, "MGET", args...))
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]int64, len(keys))
+	for index, value := range values {
+		if value == nil || index >= len(counts) {
+			continue
+		}
+		counts[index], _ = redis.Int64(value, nil)
+	}
+	return counts, nil
+}
+
+func (s *redisCounterStore) HGetAll(trace *lib.TraceContext, key string) (map[string]int64, error) {
+	values, err := redis.StringMap(RedisConfDo(trace, "HGETALL", key))
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]int64{}
+	for field, value := range values {
+		out[field], _ = strconv.ParseInt(value, 10, 64)
+	}
+	return out, nil
+}
+
+func (s *redisCounterStore) Scan(trace *lib.TraceContext, prefix string) (map[string]int64, error) {
+	out := map[string]int64{}
+	cursor := "0"
+	for {
+		reply, err := redis.Values(RedisConfDo(trace, "SCAN", cursor, "MATCH", prefix+"*", "COUNT", 1000))
+		if err != nil {
+			return nil, err
+		}
+		if len(reply) != 2 {
+			return nil, fmt.Errorf("unexpected SCAN reply")
+		}
+		cursor, _ = redis.String(reply[0], nil)
+		keys, _ := redis.Strings(reply[1], nil)
+		for _, key := range keys {
+			count, err := redis.Int64(RedisConfDo(trace, "GET", key))
+			if err != nil && err != redis.ErrNil {
+				return nil, err
+			}
+			out[key] = count
+		}
+		if cursor == "0" {
+			return out, nil
+		}
+	}
+}
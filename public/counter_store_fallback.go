@@ -0,0 +1,171 @@
+package public
+
+import (
+	"errors"
+	"fmt"
+	"github.com/e421083458/golang_common/lib"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var errCounterStoreDegraded = errors.New("redis unavailable, counters are buffered locally")
+
+// fallbackCounterStore redis 写入失败后切到本地计数，增量暂存在 pending
+// 降级期间读取返回最近一次从 redis 读到的值加上本地增量，租户日限额仍然按累计值判断
+// 后台按 interval 探测 redis，恢复后把 pending 回补到 redis，全部确认后再切回
+type fallbackCounterStore struct {
+	remote   CounterStore
+	pending  *memoryCounterStore
+	known    *memoryCounterStore
+	degraded int32
+	interval time.Duration
+	// 写 pending 持读锁，回补时持写锁取出 pending，保证切回后没有遗留的增量
+	locker sync.RWMutex
+}
+
+func newFallbackCounterStore(remote CounterStore, interval time.Duration) *fallbackCounterStore {
+	s := &fallbackCounterStore{
+		remote:   remote,
+		pending:  newMemoryCounterStore(),
+		known:    newMemoryCounterStore(),
+		interval: interval,
+	}
+	go s.run()
+	return s
+}
+
+// Degraded 是否正在使用本地计数
+func (s *fallbackCounterStore) Degraded() bool {
+	return atomic.LoadInt32(&s.degraded) == 1
+}
+
+func (s *fallbackCounterStore) Incr(trace *lib.TraceContext, incrs ...CounterIncr) error {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	if !s.Degraded() {
+		err := s.remote.Incr(trace, incrs...)
+		if err == nil {
+			return nil
+		}
+		MetricFlowCountFlushErrors.Inc()
+		s.markDegraded(err)
+	}
+	return s.pending.Incr(trace, incrs...)
+}
+
+func (s *fallbackCounterStore) Get(trace *lib.TraceContext, key string) (int64, bool, error) {
+	if !s.Degraded() {
+		count, ok, err := s.remote.Get(trace, key)
+		if err == nil {
+			if ok {
+				s.known.set(key, count, RedisFlowDayKeyExpire)
+			}
+			return count, ok, nil
+		}
+		s.markDegraded(err)
+	}
+	known, knownOK, _ := s.known.Get(trace, key)
+	pending, pendingOK, _ := s.pending.Get(trace, key)
+	return known + pending, knownOK || pendingOK, nil
+}
+
+func (s *fallbackCounterStore) MGet(trace *lib.TraceContext, keys ...string) ([]int64, error) {
+	if !s.Degraded() {
+		counts, err := s.remote.MGet(trace, keys...)
+		if err == nil {
+			return counts, nil
+		}
+		s.markDegraded(err)
+	}
+	counts := make([]int64, len(keys))
+	for index, key := range keys {
+		counts[index], _, _ = s.Get(trace, key)
+	}
+	return counts, nil
+}
+
+// HGetAll 降级期间只有本节点的增量
+func (s *fallbackCounterStore) HGetAll(trace *lib.TraceContext, key string) (map[string]int64, error) {
+	if !s.Degraded() {
+		values, err := s.remote.HGetAll(trace, key)
+		if err == nil {
+			return values, nil
+		}
+		s.markDegraded(err)
+	}
+	return s.pending.HGetAll(trace, key)
+}
+
+// Scan 用于汇总落库，降级期间返回错误，等回补后再汇总，避免用不完整的数据覆盖
+func (s *fallbackCounterStore) Scan(trace *lib.TraceContext, prefix string) (map[string]int64, error) {
+	if s.Degraded() {
+		return nil, errCounterStoreDegraded
+	}
+	values, err := s.remote.Scan(trace, prefix)
+	if err != nil {
+		s.markDegraded(err)
+	}
+	return values, err
+}
+
+func (s *fallbackCounterStore) markDegraded(err error) {
+	if atomic.CompareAndSwapInt32(&s.degraded, 0, 1) {
+		fmt.Println("CounterStore redis unavailable, counting locally:", err)
+		MetricStateStoreDegraded.Set(1)
+	}
+}
+
+func (s *fallbackCounterStore) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if s.Degraded() {
+			s.tryResync()
+		}
+	}
+}
+
+// tryResync 出现 panic 时只结束这一次回补，下一个周期继续探测
+func (s *fallbackCounterStore) tryResync() {
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Println("CounterStore resync panic:", err)
+		}
+	}()
+	if err := s.resync(); err != nil {
+		return
+	}
+	fmt.Println("CounterStore redis recovered")
+}
+
+// resync 先用空写入探测 redis，再逐个回补 pending 中的增量，只扣除 redis 确认写入的部分
+// 第一轮不加锁，期间新的增量仍写入 pending；第二轮持写锁回补剩余的部分后切回 redis，任何一步失败都保持降级
+func (s *fallbackCounterStore) resync() error {
+	if err := s.remote.Incr(nil); err != nil {
+		return err
+	}
+	if err := s.flushPending(); err != nil {
+		return err
+	}
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	if err := s.flushPending(); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&s.degraded, 0)
+	MetricStateStoreDegraded.Set(0)
+	return nil
+}
+
+func (s *fallbackCounterStore) flushPending() error {
+	for _, incr := range s.pending.snapshot() {
+		if err := s.remote.Incr(nil, incr); err != nil {
+			return err
+		}
+		s.pending.acknowledge(incr)
+		// 降级期间读取的是 known 加 pending，已回补的部分转到 known，读到的累计值不变
+		s.known.Incr(nil, incr)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package public
+
+import (
+	"errors"
+	"github.com/e421083458/golang_common/lib"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errFakeRemote = errors.New("fake redis unavailable")
+
+// fakeRemoteStore 代替 redis，down 时读写都失败，allowIncr 大于等于 0 时只允许这么多次写入成功
+type fakeRemoteStore struct {
+	*memoryCounterStore
+	locker    sync.Mutex
+	down      bool
+	panics    bool
+	allowIncr int
+}
+
+func newFakeRemoteStore() *fakeRemoteStore {
+	return &fakeRemoteStore{memoryCounterStore: newMemoryCounterStore(), allowIncr: -1}
+}
+
+func (s *fakeRemoteStore) set(down bool, allowIncr int) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	s.down = down
+	s.allowIncr = allowIncr
+}
+
+func (s *fakeRemoteStore) Incr(trace *lib.TraceContext, incrs ...CounterIncr) error {
+	s.locker.Lock()
+	if s.panics {
+		s.locker.Unlock()
+		panic("fake redis panic")
+	}
+	if s.down || s.allowIncr == 0 {
+		s.locker.Unlock()
+		return errFakeRemote
+	}
+	if s.allowIncr > 0 && len(incrs) > 0 {
+		s.allowIncr--
+	}
+	s.locker.Unlock()
+	return s.memoryCounterStore.Incr(trace, incrs...)
+}
+
+func (s *fakeRemoteStore) Get(trace *lib.TraceContext, key string) (int64, bool, error) {
+	s.locker.Lock()
+	down := s.down
+	s.locker.Unlock()
+	if down {
+		return 0, false, errFakeRemote
+	}
+	return s.memoryCounterStore.Get(trace, key)
+}
+
+func newTestFallbackStore(remote CounterStore) *fallbackCounterStore {
+	// 测试中直接调用 resync，后台探测间隔设得足够长
+	return newFallbackCounterStore(remote, time.Hour)
+}
+
+func TestFallbackCounterStoreDegradeAndResync(t *testing.T) {
+	remote := newFakeRemoteStore()
+	store := newTestFallbackStore(remote)
+
+	if err := store.Incr(nil, CounterIncr{Key: "a", Value: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if count, _, _ := store.Get(nil, "a"); count != 1 {
+		t.Fatalf("a = %d, want 1", count)
+	}
+
+	remote.set(true, -1)
+	store.Incr(nil, CounterIncr{Key: "a", Value: 2}, CounterIncr{Key: "b", Value: 5}, CounterIncr{Key: "c", Value: 7})
+	if !store.Degraded() {
+		t.Fatal("store should be degraded")
+	}
+	if count, _, _ := store.Get(nil, "a"); count != 3 {
+		t.Fatalf("degraded a = %d, want known 1 + pending 2", count)
+	}
+
+	// 恢复后只确认一个计数器就再次失败：已确认的从 pending 扣除，其余保留，仍然降级
+	remote.set(false, 1)
+	if err := store.resync(); err == nil {
+		t.Fatal("resync should fail")
+	}
+	if !store.Degraded() {
+		t.Fatal("store should stay degraded after a failed resync")
+	}
+	if pending := store.pending.snapshot(); len(pending) != 2 {
+		t.Fatalf("pending = %v, want 2 unacknowledged counters", pending)
+	}
+	if count, _, _ := store.Get(nil, "a"); count != 3 {
+		t.Fatalf("a = %d during partial resync, want 3", count)
+	}
+
+	remote.set(false, -1)
+	if err := store.resync(); err != nil {
+		t.Fatal(err)
+	}
+	if store.Degraded() {
+		t.Fatal("store should switch back to redis")
+	}
+	if pending := store.pending.snapshot(); len(pending) != 0 {
+		t.Fatalf("pending = %v, want empty", pending)
+	}
+	for key, want := range map[string]int64{"a": 3, "b": 5, "c": 7} {
+		if count, _, _ := remote.memoryCounterStore.Get(nil, key); count != want {
+			t.Errorf("redis %s = %d, want %d", key, count, want)
+		}
+	}
+}
+
+func TestFallbackCounterStoreResyncKeepsNewIncrements(t *testing.T) {
+	remote := newFakeRemoteStore()
+	store := newTestFallbackStore(remote)
+	remote.set(true, -1)
+	store.Incr(nil, CounterIncr{Key: "a", Value: 2})
+
+	// snapshot 之后写入的增量不会被确认扣除
+	incrs := store.pending.snapshot()
+	store.Incr(nil, CounterIncr{Key: "a", Value: 3})
+	for _, incr := range incrs {
+		store.pending.acknowledge(incr)
+	}
+	if count, _, _ := store.pending.Get(nil, "a"); count != 3 {
+		t.Fatalf("pending a = %d, want 3", count)
+	}
+}
+
+func TestFallbackCounterStoreResyncPanic(t *testing.T) {
+	remote := newFakeRemoteStore()
+	store := newTestFallbackStore(remote)
+	remote.set(true, -1)
+	store.Incr(nil, CounterIncr{Key: "a", Value: 1})
+
+	remote.set(false, -1)
+	remote.panics = true
+	store.tryResync()
+	if !store.Degraded() {
+		t.Fatal("store should stay degraded after a panic")
+	}
+	remote.panics = false
+	store.tryResync()
+	if store.Degraded() {
+		t.Fatal("store should recover on the next attempt")
+	}
+	if count, _, _ := remote.memoryCounterStore.Get(nil, "a"); count != 1 {
+		t.Fatalf("redis a = %d, want 1", count)
+	}
+}
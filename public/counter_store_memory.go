@@ -0,0 +1,187 @@
+package public
+
+import (
+	"github.com/e421083458/golang_common/lib"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryCounter struct {
+	Value    int64
+	Fields   map[string]int64
+	ExpireAt time.Time
+}
+
+func (c *memoryCounter) expired(now time.Time) bool {
+	return !c.ExpireAt.IsZero() && !now.Before(c.ExpireAt)
+}
+
+// memoryCounterStore 进程内计数，过期的计数器在访问时忽略，每分钟最多清理一次
+type memoryCounterStore struct {
+	counters map[string]*memoryCounter
+	locker   sync.Mutex
+	sweptAt  time.Time
+}
+
+func newMemoryCounterStore() *memoryCounterStore {
+	return &memoryCounterStore{
+		counters: map[string]*memoryCounter{},
+		sweptAt:  time.Now(),
+	}
+}
+
+func (s *memoryCounterStore) Incr(trace *lib.TraceContext, incrs ...CounterIncr) error {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	now := time.Now()
+	s.sweep(now)
+	for _, incr := range incrs {
+		counter := s.lookup(incr.Key, now)
+		if counter == nil {
+			counter = &memoryCounter{}
+			s.counters[incr.Key] = counter
+		}
+		if incr.Field != "" {
+			if counter.Fields == nil {
+				counter.Fields = map[string]int64{}
+			}
+			counter.Fields[incr.Field] += incr.Value
+		} else {
+			counter.Value += incr.Value
+		}
+		if incr.Expire > 0 {
+			counter.ExpireAt = now.Add(time.Duration(incr.Expire) * time.Second)
+		}
+	}
+	return nil
+}
+
+func (s *memoryCounterStore) Get(trace *lib.TraceContext, key string) (int64, bool, error) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	counter := s.lookup(key, time.Now())
+	if counter == nil {
+		return 0, false, nil
+	}
+	return counter.Value, true, nil
+}
+
+func (s *memoryCounterStore) MGet(trace *lib.TraceContext, keys ...string) ([]int64, error) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	now := time.Now()
+	counts := make([]int64, len(keys))
+	for index, key := range keys {
+		if counter := s.lookup(key, now); counter != nil {
+			counts[index] = counter.Value
+		}
+	}
+	return counts, nil
+}
+
+func (s *memoryCounterStore) HGetAll(trace *lib.TraceContext, key string) (map[string]int64, error) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	out := map[string]int64{}
+	if counter := s.lookup(key, time.Now()); counter != nil {
+		for field, value := range counter.Fields {
+			out[field] = value
+		}
+	}
+	return out, nil
+}
+
+func (s *memoryCounterStore) Scan(trace *lib.TraceContext, prefix string) (map[string]int64, error) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	now := time.Now()
+	out := map[string]int64{}
+	for key, counter := range s.counters {
+		if strings.HasPrefix(key, prefix) && !counter.expired(now) {
+			out[key] = counter.Value
+		}
+	}
+	return out, nil
+}
+
+// set 覆盖计数器的值
+func (s *memoryCounterStore) set(key string, value int64, expire int) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	now := time.Now()
+	s.sweep(now)
+	counter := &memoryCounter{Value: value}
+	if expire > 0 {
+		counter.ExpireAt = now.Add(time.Duration(expire) * time.Second)
+	}
+	s.counters[key] = counter
+}
+
+// snapshot 按剩余有效期把所有计数器转为增量，不修改计数器
+func (s *memoryCounterStore) snapshot() []CounterIncr {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	now := time.Now()
+	incrs := []CounterIncr{}
+	for key, counter := range s.counters {
+		if counter.expired(now) {
+			continue
+		}
+		expire := 0
+		if !counter.ExpireAt.IsZero() {
+			expire = int(counter.ExpireAt.Sub(now)/time.Second) + 1
+		}
+		if counter.Fields == nil {
+			incrs = append(incrs, CounterIncr{Key: key, Value: counter.Value, Expire: expire})
+		}
+		for field, value := range counter.Fields {
+			incrs = append(incrs, CounterIncr{Key: key, Field: field, Value: value, Expire: expire})
+		}
+	}
+	return incrs
+}
+
+// acknowledge 减去已经写入 redis 的增量，减到 0 的字段与计数器删除，snapshot 之后新增的部分保留
+func (s *memoryCounterStore) acknowledge(incr CounterIncr) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	counter, ok := s.counters[incr.Key]
+	if !ok {
+		return
+	}
+	if incr.Field != "" {
+		counter.Fields[incr.Field] -= incr.Value
+		if counter.Fields[incr.Field] == 0 {
+			delete(counter.Fields, incr.Field)
+		}
+		if len(counter.Fields) == 0 {
+			delete(s.counters, incr.Key)
+		}
+		return
+	}
+	counter.Value -= incr.Value
+	if counter.Value == 0 && counter.Fields == nil {
+		delete(s.counters, incr.Key)
+	}
+}
+
+func (s *memoryCounterStore) lookup(key string, now time.Time) *memoryCounter {
+	counter, ok := s.counters[key]
+	if !ok || counter.expired(now) {
+		return nil
+	}
+	return counter
+}
+
+func (s *memoryCounterStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < time.Minute {
+		return
+	}
+	s.sweptAt = now
+	for key, counter := range s.counters {
+		if counter.expired(now) {
+			delete(s.counters, key)
+		}
+	}
+}
@@ -26,16 +26,54 @@ func init() {
 }
 
 func (counter *FlowCounter) GetCounter(serverName string) (*RedisFlowCountService, error) {
-	for _, item := range counter.RedisFlowCountSlice {
-		if item.AppID == serverName {
-			return item, nil
-		}
+	counter.Locker.RLock()
+	item, ok := counter.RedisFlowCountMap[serverName]
+	counter.Locker.RUnlock()
+	if ok {
+		return item, nil
 	}
 
-	newCounter := NewRedisFlowCountService(serverName, 1*time.Second)
-	counter.RedisFlowCountSlice = append(counter.RedisFlowCountSlice, newCounter)
 	counter.Locker.Lock()
 	defer counter.Locker.Unlock()
+	if item, ok := counter.RedisFlowCountMap[serverName]; ok {
+		return item, nil
+	}
+	newCounter := NewRedisFlowCountService(serverName, 1*time.Second)
+	counter.RedisFlowCountSlice = append(counter.RedisFlowCountSlice, newCounter)
 	counter.RedisFlowCountMap[serverName] = newCounter
 	return newCounter, nil
 }
+
+// Lookup 只读查询计数器，不注册也不启动上报协程，管理接口与后台任务读取统计数据时使用
+func (counter *FlowCounter) Lookup(serverName string) *RedisFlowCountService {
+	counter.Locker.RLock()
+	defer counter.Locker.RUnlock()
+	if item, ok := counter.RedisFlowCountMap[serverName]; ok {
+		return item
+	}
+	return &RedisFlowCountService{AppID: serverName}
+}
+
+// IncreaseServiceFlow 每个代理请求调用一次，同时累加全站与服务的计数
+// http、tcp、grpc 代理共用
+func (counter *FlowCounter) IncreaseServiceFlow(serviceName string) error {
+	totalCounter, err := counter.GetCounter(FlowTotal)
+	if err != nil {
+		return err
+	}
+	totalCounter.Increase()
+	serviceCounter, err := counter.GetCounter(FlowServicePrefix + serviceName)
+	if err != nil {
+		return err
+	}
+	serviceCounter.Increase()
+	return nil
+}
+
+// grpc 中相当于 http 5xx 的状态码: Unknown DeadlineExceeded Unimplemented Internal Unavailable DataLoss
+var grpcServerErrorCodes = map[string]bool{"2": true, "4": true, "12": true, "13": true, "14": true, "15": true}
+
+// IsGrpcServerError 判断 grpc-status 是否为服务端错误，客户端错误(参数、鉴权等)不计入错误数
+func IsGrpcServerError(grpcStatus string) bool {
+	return grpcServerErrorCodes[grpcStatus]
+}
@@ -31,20 +31,24 @@ func init() {
 }
 
 func (counter *FlowLimiter) GetLimiter(serverName string, qps float64) (*rate.Limiter, error) {
-	for _, item := range counter.FlowLmiterSlice {
-		if item.ServiceName == serverName {
-			return item.Limter, nil
-		}
+	counter.Locker.RLock()
+	item, ok := counter.FlowLmiterMap[serverName]
+	counter.Locker.RUnlock()
+	if ok {
+		return item.Limter, nil
 	}
 
+	counter.Locker.Lock()
+	defer counter.Locker.Unlock()
+	if item, ok := counter.FlowLmiterMap[serverName]; ok {
+		return item.Limter, nil
+	}
 	newLimiter := rate.NewLimiter(rate.Limit(qps), int(qps*3))
-	item := &FlowLimiterItem{
+	item = &FlowLimiterItem{
 		ServiceName: serverName,
 		Limter:      newLimiter,
 	}
 	counter.FlowLmiterSlice = append(counter.FlowLmiterSlice, item)
-	counter.Locker.Lock()
-	defer counter.Locker.Unlock()
 	counter.FlowLmiterMap[serverName] = item
 	return newLimiter, nil
 }
@@ -0,0 +1,64 @@
+package public
+
+import (
+	"sync"
+)
+
+// 网关代理相关指标，/metrics 输出
+var (
+	MetricRequestTotal = MetricsRegistryHandler.Register(NewCounterVec(
+		"gateway_requests_total", "Total proxied requests.",
+		"service", "renter", "load_type", "status"))
+	MetricRequestDuration = MetricsRegistryHandler.Register(NewHistogramVec(
+		"gateway_request_duration_seconds", "Proxied request latency in seconds.",
+		[]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		"service", "load_type"))
+	MetricActiveConnections = MetricsRegistryHandler.Register(NewGaugeVec(
+		"gateway_active_connections", "In-flight proxied requests or tcp connections.",
+		"service", "load_type"))
+	MetricUpstreamHealth = MetricsRegistryHandler.Register(NewGaugeVec(
+		"gateway_upstream_health", "Upstream health from load balance checks, 1 healthy 0 unhealthy.",
+		"service", "load_type", "upstream"))
+	MetricRateLimitRejections = MetricsRegistryHandler.Register(NewCounterVec(
+		"gateway_rate_limit_rejections_total", "Requests rejected by flow limits.",
+		"service", "renter", "limit"))
+	MetricCircuitBreakerState = MetricsRegistryHandler.Register(NewGaugeVec(
+		"gateway_circuit_breaker_state", "Circuit breaker state, 0 closed 1 half-open 2 open.",
+		"service"))
+	MetricFlowCountFlushErrors = MetricsRegistryHandler.Register(NewCounterVec(
+		"gateway_flow_count_flush_errors_total", "Failed flushes of flow counters to redis."))
+	MetricStateStoreDegraded = MetricsRegistryHandler.Register(NewGaugeVec(
+		"gateway_state_store_degraded", "1 when flow counters fall back to local counting because redis is unavailable."))
+	MetricAccessLogDropped = MetricsRegistryHandler.Register(NewCounterVec(
+		"gateway_access_log_dropped_total", "Access log entries dropped because the queue was full."))
+)
+
+var metricsRenterLabels = struct {
+	renters map[string]bool
+	locker  sync.Mutex
+}{renters: map[string]bool{}}
+
+// MetricsRenterLabel 限制 renter 标签的基数，超过 MetricsRenterLabelLimit 个租户后统一记为 other
+func MetricsRenterLabel(renterID string) string {
+	if renterID == "" {
+		return MetricsRenterNone
+	}
+	metricsRenterLabels.locker.Lock()
+	defer metricsRenterLabels.locker.Unlock()
+	if metricsRenterLabels.renters[renterID] {
+		return renterID
+	}
+	if len(metricsRenterLabels.renters) >= MetricsRenterLabelLimit {
+		return MetricsRenterOther
+	}
+	metricsRenterLabels.renters[renterID] = true
+	return renterID
+}
+
+// MetricsLoadTypeLabel 将 load_type 转为可读的标签值
+func MetricsLoadTypeLabel(loadType int) string {
+	if name, ok := LoadTypeMap[loadType]; ok {
+		return name
+	}
+	return "unknown"
+}
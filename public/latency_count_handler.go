@@ -0,0 +1,55 @@
+package public
+
+import (
+	"sync"
+	"time"
+)
+
+var LatencyCounterHandler *LatencyCounter
+
+type LatencyCounter struct {
+	RedisLatencyCountMap   map[string]*RedisLatencyCountService
+	RedisLatencyCountSlice []*RedisLatencyCountService
+	Locker                 sync.RWMutex
+}
+
+func NewLatencyCounter() *LatencyCounter {
+	return &LatencyCounter{
+		RedisLatencyCountMap:   map[string]*RedisLatencyCountService{},
+		RedisLatencyCountSlice: []*RedisLatencyCountService{},
+		Locker:                 sync.RWMutex{},
+	}
+}
+
+func init() {
+	LatencyCounterHandler = NewLatencyCounter()
+}
+
+func (counter *LatencyCounter) GetCounter(serverName string) (*RedisLatencyCountService, error) {
+	counter.Locker.RLock()
+	item, ok := counter.RedisLatencyCountMap[serverName]
+	counter.Locker.RUnlock()
+	if ok {
+		return item, nil
+	}
+
+	counter.Locker.Lock()
+	defer counter.Locker.Unlock()
+	if item, ok := counter.RedisLatencyCountMap[serverName]; ok {
+		return item, nil
+	}
+	newCounter := NewRedisLatencyCountService(serverName, 1*time.Second)
+	counter.RedisLatencyCountSlice = append(counter.RedisLatencyCountSlice, newCounter)
+	counter.RedisLatencyCountMap[serverName] = newCounter
+	return newCounter, nil
+}
+
+// Lookup 只读查询计数器，不注册也不启动上报协程
+func (counter *LatencyCounter) Lookup(serverName string) *RedisLatencyCountService {
+	counter.Locker.RLock()
+	defer counter.Locker.RUnlock()
+	if item, ok := counter.RedisLatencyCountMap[serverName]; ok {
+		return item
+	}
+	return &RedisLatencyCountService{AppID: serverName}
+}
@@ -0,0 +1,226 @@
+package public
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 轻量的 prometheus 指标实现，输出 text 格式 0.0.4
+
+const (
+	MetricTypeCounter   = "counter"
+	MetricTypeGauge     = "gauge"
+	MetricTypeHistogram = "histogram"
+)
+
+// 指标在包级变量中注册，不能等到 init 再创建
+var MetricsRegistryHandler = NewMetricsRegistry()
+
+type MetricsRegistry struct {
+	MetricSlice []*MetricVec
+	Locker      sync.RWMutex
+}
+
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{
+		MetricSlice: []*MetricVec{},
+		Locker:      sync.RWMutex{},
+	}
+}
+
+func (r *MetricsRegistry) Register(vec *MetricVec) *MetricVec {
+	r.Locker.Lock()
+	defer r.Locker.Unlock()
+	r.MetricSlice = append(r.MetricSlice, vec)
+	return vec
+}
+
+// Write 按注册顺序输出所有指标
+func (r *MetricsRegistry) Write(w io.Writer) error {
+	r.Locker.RLock()
+	defer r.Locker.RUnlock()
+	buf := bufio.NewWriter(w)
+	for _, vec := range r.MetricSlice {
+		vec.writeTo(buf)
+	}
+	return buf.Flush()
+}
+
+type MetricVec struct {
+	Name       string
+	Help       string
+	Type       string
+	LabelNames []string
+	Buckets    []float64
+
+	series map[string]*metricSeries
+	locker sync.Mutex
+}
+
+type metricSeries struct {
+	labelValues  []string
+	value        float64
+	bucketCounts []uint64
+	count        uint64
+}
+
+func NewCounterVec(name, help string, labelNames ...string) *MetricVec {
+	return newMetricVec(name, help, MetricTypeCounter, nil, labelNames)
+}
+
+func NewGaugeVec(name, help string, labelNames ...string) *MetricVec {
+	return newMetricVec(name, help, MetricTypeGauge, nil, labelNames)
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *MetricVec {
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+	return newMetricVec(name, help, MetricTypeHistogram, sorted, labelNames)
+}
+
+func newMetricVec(name, help, metricType string, buckets []float64, labelNames []string) *MetricVec {
+	return &MetricVec{
+		Name:       name,
+		Help:       help,
+		Type:       metricType,
+		LabelNames: labelNames,
+		Buckets:    buckets,
+		series:     map[string]*metricSeries{},
+	}
+}
+
+// Inc counter 或 gauge 加一
+func (v *MetricVec) Inc(labelValues ...string) {
+	v.Add(1, labelValues...)
+}
+
+// Dec gauge 减一
+func (v *MetricVec) Dec(labelValues ...string) {
+	v.Add(-1, labelValues...)
+}
+
+func (v *MetricVec) Add(delta float64, labelValues ...string) {
+	v.locker.Lock()
+	defer v.locker.Unlock()
+	v.getSeries(labelValues).value += delta
+}
+
+// Set 只用于 gauge
+func (v *MetricVec) Set(value float64, labelValues ...string) {
+	v.locker.Lock()
+	defer v.locker.Unlock()
+	v.getSeries(labelValues).value = value
+}
+
+// Observe 只用于 histogram
+func (v *MetricVec) Observe(value float64, labelValues ...string) {
+	v.locker.Lock()
+	defer v.locker.Unlock()
+	series := v.getSeries(labelValues)
+	for index, bound := range v.Buckets {
+		if value <= bound {
+			series.bucketCounts[index]++
+		}
+	}
+	series.value += value
+	series.count++
+}
+
+// Reset 清空所有序列，用于按快照重建的 gauge
+func (v *MetricVec) Reset() {
+	v.locker.Lock()
+	defer v.locker.Unlock()
+	v.series = map[string]*metricSeries{}
+}
+
+// Delete 删除一个序列，例如服务删除后不再输出它的 gauge
+func (v *MetricVec) Delete(labelValues ...string) {
+	v.locker.Lock()
+	defer v.locker.Unlock()
+	delete(v.series, strings.Join(labelValues, "\xff"))
+}
+
+func (v *MetricVec) getSeries(labelValues []string) *metricSeries {
+	key := strings.Join(labelValues, "\xff")
+	series, ok := v.series[key]
+	if !ok {
+		series = &metricSeries{
+			labelValues:  append([]string{}, labelValues...),
+			bucketCounts: make([]uint64, len(v.Buckets)),
+		}
+		v.series[key] = series
+	}
+	return series
+}
+
+func (v *MetricVec) writeTo(w *bufio.Writer) {
+	v.locker.Lock()
+	defer v.locker.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n", v.Name, strings.Replace(v.Help, "\n", " ", -1))
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.Name, v.Type)
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		series := v.series[key]
+		labels := v.formatLabels(series.labelValues)
+		if v.Type != MetricTypeHistogram {
+			fmt.Fprintf(w, "%s%s %s\n", v.Name, wrapLabels(labels), formatMetricValue(series.value))
+			continue
+		}
+		for index, bound := range v.Buckets {
+			le := fmt.Sprintf(`le="%s"`, formatMetricValue(bound))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.Name, wrapLabels(joinLabels(labels, le)), series.bucketCounts[index])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.Name, wrapLabels(joinLabels(labels, `le="+Inf"`)), series.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.Name, wrapLabels(labels), formatMetricValue(series.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.Name, wrapLabels(labels), series.count)
+	}
+}
+
+func (v *MetricVec) formatLabels(labelValues []string) string {
+	items := []string{}
+	for index, name := range v.LabelNames {
+		value := ""
+		if index < len(labelValues) {
+			value = labelValues[index]
+		}
+		items = append(items, fmt.Sprintf(`%s="%s"`, name, escapeLabelValue(value)))
+	}
+	return strings.Join(items, ",")
+}
+
+func joinLabels(labels, extra string) string {
+	if labels == "" {
+		return extra
+	}
+	return labels + "," + extra
+}
+
+func wrapLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func escapeLabelValue(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+	return strings.Replace(value, "\n", `\n`, -1)
+}
+
+func formatMetricValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,114 @@
+package public
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsRegistryExposition(t *testing.T) {
+	registry := NewMetricsRegistry()
+	requests := registry.Register(NewCounterVec("test_requests_total", "Test requests.", "service", "status"))
+	latency := registry.Register(NewHistogramVec("test_duration_seconds", "Test latency.", []float64{1, 0.1}, "service"))
+	connections := registry.Register(NewGaugeVec("test_connections", "Test gauge."))
+
+	requests.Inc("b", "200")
+	requests.Add(2, "a", "500")
+	requests.Inc(`q"x\y`+"\n", "200")
+	latency.Observe(0.05, "a")
+	latency.Observe(0.5, "a")
+	latency.Observe(3, "a")
+	connections.Set(3)
+	connections.Dec()
+
+	buf := &bytes.Buffer{}
+	if err := registry.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		`# HELP test_requests_total Test requests.`,
+		`# TYPE test_requests_total counter`,
+		`test_requests_total{service="a",status="500"} 2`,
+		`test_requests_total{service="b",status="200"} 1`,
+		`test_requests_total{service="q\"x\\y\n",status="200"} 1`,
+		`# HELP test_duration_seconds Test latency.`,
+		`# TYPE test_duration_seconds histogram`,
+		`test_duration_seconds_bucket{service="a",le="0.1"} 1`,
+		`test_duration_seconds_bucket{service="a",le="1"} 2`,
+		`test_duration_seconds_bucket{service="a",le="+Inf"} 3`,
+		`test_duration_seconds_sum{service="a"} 3.55`,
+		`test_duration_seconds_count{service="a"} 3`,
+		`# HELP test_connections Test gauge.`,
+		`# TYPE test_connections gauge`,
+		`test_connections 2`,
+		``,
+	}, "\n")
+	if buf.String() != want {
+		t.Fatalf("unexpected exposition:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	requests.Delete("a", "500")
+	requests.Reset()
+	buf.Reset()
+	registry.Write(buf)
+	if strings.Contains(buf.String(), "test_requests_total{") {
+		t.Fatalf("series should be removed:\n%s", buf.String())
+	}
+}
+
+func TestMetricsRenterLabelLimit(t *testing.T) {
+	if MetricsRenterLabel("") != MetricsRenterNone {
+		t.Fatal("empty renter should map to none")
+	}
+	for index := 0; index < MetricsRenterLabelLimit+10; index++ {
+		MetricsRenterLabel("test_renter_" + strconv.Itoa(index))
+	}
+	if MetricsRenterLabel("test_renter_new") != MetricsRenterOther {
+		t.Fatal("renters above the limit should map to other")
+	}
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	breaker := NewCircuitBreaker("test_breaker", 2, 30*time.Millisecond)
+	gauge := func() string {
+		buf := &bytes.Buffer{}
+		registry := NewMetricsRegistry()
+		registry.Register(MetricCircuitBreakerState)
+		registry.Write(buf)
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if strings.Contains(line, `service="test_breaker"`) {
+				return line[strings.LastIndex(line, " ")+1:]
+			}
+		}
+		return ""
+	}
+	if !breaker.Allow() || gauge() != "0" {
+		t.Fatal("new breaker should be closed")
+	}
+	breaker.Record(false)
+	breaker.Record(false)
+	if breaker.Allow() || breaker.State() != CircuitStateOpen || gauge() != "2" {
+		t.Fatal("breaker should open after reaching the failure threshold")
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if !breaker.Allow() || breaker.State() != CircuitStateHalfOpen || gauge() != "1" {
+		t.Fatal("breaker should let one probe through after the open duration")
+	}
+	if breaker.Allow() {
+		t.Fatal("only one probe is allowed while half-open")
+	}
+	breaker.Record(false)
+	if breaker.State() != CircuitStateOpen {
+		t.Fatal("a failed probe should reopen the breaker")
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	breaker.Allow()
+	breaker.Record(true)
+	if !breaker.Allow() || breaker.State() != CircuitStateClosed || gauge() != "0" {
+		t.Fatal("a successful probe should close the breaker")
+	}
+}
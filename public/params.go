@@ -22,7 +22,12 @@ func DefaultGetValidParams(c *gin.Context, params interface{}) error {
 	if err != nil {
 		return err
 	}
-	err = valid.Struct(params)
+	return ValidateParams(valid, trans, params)
+}
+
+// ValidateParams 按 validate tag 检查 params，错误信息翻译后用逗号拼接
+func ValidateParams(valid *validator.Validate, trans ut.Translator, params interface{}) error {
+	err := valid.Struct(params)
 	if err != nil {
 		errs := err.(validator.ValidationErrors)
 		sliceErrs := []string{}
@@ -0,0 +1,65 @@
+package public
+
+import (
+	"sync"
+)
+
+// memoryPubSub 进程内的发布订阅，state driver 为 memory 或 redis 不可用时代替 redis 频道
+// 管理端与代理在同一个进程中运行时可以互相通知
+var memoryPubSub = newMemoryBroker()
+
+type memorySubscription struct {
+	Channel  string
+	Messages chan []byte
+}
+
+type memoryBroker struct {
+	subscribers map[string]map[*memorySubscription]bool
+	locker      sync.RWMutex
+}
+
+func newMemoryBroker() *memoryBroker {
+	return &memoryBroker{
+		subscribers: map[string]map[*memorySubscription]bool{},
+	}
+}
+
+// Publish 订阅者的缓冲区满时丢弃消息，不阻塞发布方
+func (b *memoryBroker) Publish(channel string, payload []byte) {
+	b.locker.RLock()
+	defer b.locker.RUnlock()
+	for sub := range b.subscribers[channel] {
+		select {
+		case sub.Messages <- payload:
+		default:
+		}
+	}
+}
+
+func (b *memoryBroker) Subscribe(channel string, size int) *memorySubscription {
+	sub := &memorySubscription{
+		Channel:  channel,
+		Messages: make(chan []byte, size),
+	}
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	if b.subscribers[channel] == nil {
+		b.subscribers[channel] = map[*memorySubscription]bool{}
+	}
+	b.subscribers[channel][sub] = true
+	return sub
+}
+
+// Unsubscribe 之后关闭 Messages
+func (b *memoryBroker) Unsubscribe(sub *memorySubscription) {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	if _, ok := b.subscribers[sub.Channel][sub]; !ok {
+		return
+	}
+	delete(b.subscribers[sub.Channel], sub)
+	if len(b.subscribers[sub.Channel]) == 0 {
+		delete(b.subscribers, sub.Channel)
+	}
+	close(sub.Messages)
+}
@@ -0,0 +1,54 @@
+package public
+
+// 管理员角色与权限，路由注册时通过 middleware.RequirePermission 声明需要的权限
+
+const (
+	RoleSuperAdmin      = "super_admin"
+	RoleServiceOperator = "service_operator"
+	RoleRenterManager   = "renter_manager"
+	RoleReadOnly        = "read_only"
+
+	PermServiceRead   = "service:read"
+	PermServiceWrite  = "service:write"
+	PermRenterRead    = "renter:read"
+	PermRenterWrite   = "renter:write"
+	PermDashboardRead = "dashboard:read"
+	PermAlertRead     = "alert:read"
+	PermAlertWrite    = "alert:write"
+	PermAdminManage   = "admin:manage"
+	PermAuditRead     = "audit:read"
+)
+
+var RolePermissionMap = map[string][]string{
+	RoleSuperAdmin: {
+		PermServiceRead, PermServiceWrite, PermRenterRead, PermRenterWrite,
+		PermDashboardRead, PermAlertRead, PermAlertWrite, PermAdminManage,
+		PermAuditRead,
+	},
+	RoleServiceOperator: {
+		PermServiceRead, PermServiceWrite, PermRenterRead,
+		PermDashboardRead, PermAlertRead, PermAlertWrite,
+	},
+	RoleRenterManager: {
+		PermServiceRead, PermRenterRead, PermRenterWrite,
+		PermDashboardRead, PermAlertRead,
+	},
+	RoleReadOnly: {
+		PermServiceRead, PermRenterRead, PermDashboardRead, PermAlertRead,
+	},
+}
+
+// RoleHasPermission 未知角色没有任何权限
+func RoleHasPermission(role, permission string) bool {
+	return InStringSlice(RolePermissionMap[role], permission)
+}
+
+// ValidPermission 是否为某个角色拥有的权限，用于校验 token 的 scopes
+func ValidPermission(permission string) bool {
+	for _, permissions := range RolePermissionMap {
+		if InStringSlice(permissions, permission) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package public
+
+import (
+	"encoding/json"
+	"github.com/e421083458/golang_common/lib"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// 日志脱敏，字段名或 header 名命中 deny-list 的值替换为 RedactedValue
+// 字段名不区分大小写完全匹配，token_prefix 等只是包含敏感词的字段不脱敏
+
+var (
+	RedactFieldsDefault  = []string{"password", "secret", "secret_uri", "token", "recovery_codes", "code"}
+	RedactHeadersDefault = []string{"Authorization", "Cookie", "Set-Cookie"}
+	// 只有 allow-list 中的 header 写入日志，其余 header 直接丢弃
+	LogAllowHeadersDefault = []string{"Accept", "Accept-Encoding", "Accept-Language", "Content-Length", "Content-Type",
+		"Origin", "Referer", "User-Agent", "Traceparent", "X-Forwarded-For", "X-Real-Ip", "X-Request-Id"}
+	// 管理接口返回的 API token、两步验证密钥、恢复码与提交的验证码，配置了 fields 也总是脱敏
+	RedactFieldsRequired = []string{"secret", "secret_uri", "token", "recovery_codes", "code"}
+)
+
+var RedactorHandler *Redactor
+
+func init() {
+	RedactorHandler = NewRedactor(RedactFieldsDefault, RedactHeadersDefault)
+}
+
+type Redactor struct {
+	Fields       []string
+	Headers      []string
+	AllowHeaders []string
+	Locker       sync.RWMutex
+}
+
+func NewRedactor(fields, headers []string) *Redactor {
+	redactor := &Redactor{}
+	redactor.SetDenyList(fields, headers)
+	redactor.SetAllowHeaders(LogAllowHeadersDefault)
+	return redactor
+}
+
+// InitRedactor 读取 base.log.redact 配置，未配置时保留默认值
+func InitRedactor() {
+	fields := lib.GetStringSliceConf("base.log.redact.fields")
+	headers := lib.GetStringSliceConf("base.log.redact.headers")
+	if len(fields) == 0 {
+		fields = RedactFieldsDefault
+	}
+	for _, field := range RedactFieldsRequired {
+		if !InStringSlice(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	if len(headers) == 0 {
+		headers = RedactHeadersDefault
+	}
+	RedactorHandler.SetDenyList(fields, headers)
+	if allowHeaders := lib.GetStringSliceConf("base.log.redact.allow_headers"); len(allowHeaders) > 0 {
+		RedactorHandler.SetAllowHeaders(allowHeaders)
+	}
+}
+
+func (r *Redactor) SetDenyList(fields, headers []string) {
+	r.Locker.Lock()
+	defer r.Locker.Unlock()
+	r.Fields = []string{}
+	for _, field := range fields {
+		r.Fields = append(r.Fields, strings.ToLower(field))
+	}
+	r.Headers = []string{}
+	for _, header := range headers {
+		r.Headers = append(r.Headers, http.CanonicalHeaderKey(header))
+	}
+}
+
+func (r *Redactor) SetAllowHeaders(headers []string) {
+	r.Locker.Lock()
+	defer r.Locker.Unlock()
+	r.AllowHeaders = []string{}
+	for _, header := range headers {
+		r.AllowHeaders = append(r.AllowHeaders, http.CanonicalHeaderKey(header))
+	}
+}
+
+func (r *Redactor) deniedField(key string) bool {
+	return InStringSlice(r.Fields, strings.ToLower(key))
+}
+
+// RedactBody 支持 json 与 form 格式，其他格式中出现敏感字段名时整体替换
+func (r *Redactor) RedactBody(body string) string {
+	r.Locker.RLock()
+	defer r.Locker.RUnlock()
+	if strings.TrimSpace(body) == "" {
+		return body
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(body), &value); err == nil {
+		out, err := json.Marshal(r.redactJSON(value))
+		if err != nil {
+			return RedactedValue
+		}
+		return string(out)
+	}
+	if values, err := url.ParseQuery(body); err == nil && strings.Contains(body, "=") && !strings.ContainsAny(body, " \n{") {
+		return r.redactValues(values).Encode()
+	}
+	lowerBody := strings.ToLower(body)
+	for _, field := range r.Fields {
+		if strings.Contains(lowerBody, field) {
+			return RedactedValue
+		}
+	}
+	return body
+}
+
+func (r *Redactor) RedactValues(values url.Values) url.Values {
+	r.Locker.RLock()
+	defer r.Locker.RUnlock()
+	return r.redactValues(values)
+}
+
+// RedactHeader 只保留 allow-list 中的 header，同时命中 deny-list 的仍然脱敏
+func (r *Redactor) RedactHeader(header http.Header) map[string]string {
+	r.Locker.RLock()
+	defer r.Locker.RUnlock()
+	out := map[string]string{}
+	for key, values := range header {
+		if !InStringSlice(r.AllowHeaders, http.CanonicalHeaderKey(key)) {
+			continue
+		}
+		if InStringSlice(r.Headers, http.CanonicalHeaderKey(key)) {
+			out[key] = RedactedValue
+			continue
+		}
+		out[key] = strings.Join(values, ",")
+	}
+	return out
+}
+
+func (r *Redactor) redactValues(values url.Values) url.Values {
+	out := url.Values{}
+	for key, items := range values {
+		if r.deniedField(key) {
+			out[key] = []string{RedactedValue}
+			continue
+		}
+		out[key] = items
+	}
+	return out
+}
+
+func (r *Redactor) redactJSON(value interface{}) interface{} {
+	switch item := value.(type) {
+	case map[string]interface{}:
+		for key, child := range item {
+			if r.deniedField(key) {
+				item[key] = RedactedValue
+				continue
+			}
+			item[key] = r.redactJSON(child)
+		}
+		return item
+	case []interface{}:
+		for index, child := range item {
+			item[index] = r.redactJSON(child)
+		}
+		return item
+	}
+	return value
+}
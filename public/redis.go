@@ -1,28 +1,53 @@
 package public
 
 import (
-	"github.com/e421083458/go_gateway/golang_common/lib"
+	"github.com/e421083458/golang_common/lib"
 	"github.com/garyburd/redigo/redis"
 )
 
-func RedisConfPipline(pip ...func(c redis.Conn)) error {
+// trace 属于请求 span 时记录 redis span，后台定时任务传 nil
+func RedisConfPipline(trace *lib.TraceContext, pip ...func(c redis.Conn)) error {
+	span := StartChildSpan(trace, "redis.pipeline", SpanKindClient)
+	if span != nil {
+		span.SetAttribute("db.system", "redis")
+		defer span.End()
+	}
 	c, err := lib.RedisConnFactory("default")
 	if err != nil {
+		if span != nil {
+			span.SetError(err)
+		}
 		return err
 	}
 	defer c.Close()
 	for _, f := range pip {
 		f(c)
 	}
-	c.Flush()
-	return nil
+	err = c.Flush()
+	if span != nil {
+		span.SetError(err)
+	}
+	return err
 }
 
-func RedisConfDo(commandName string, args ...interface{}) (interface{}, error) {
+func RedisConfDo(trace *lib.TraceContext, commandName string, args ...interface{}) (interface{}, error) {
+	span := StartChildSpan(trace, "redis."+commandName, SpanKindClient)
+	if span != nil {
+		span.SetAttribute("db.system", "redis")
+		span.SetAttribute("db.operation", commandName)
+		defer span.End()
+	}
 	c, err := lib.RedisConnFactory("default")
 	if err != nil {
+		if span != nil {
+			span.SetError(err)
+		}
 		return nil, err
 	}
 	defer c.Close()
-	return c.Do(commandName, args...)
+	reply, err := c.Do(commandName, args...)
+	if span != nil && err != redis.ErrNil {
+		span.SetError(err)
+	}
+	return reply, err
 }
@@ -2,8 +2,8 @@ package public
 
 import (
 	"fmt"
-	"github.com/e421083458/go_gateway/golang_common/lib"
-	"github.com/garyburd/redigo/redis"
+	"github.com/e421083458/golang_common/lib"
+	"strings"
 	"sync/atomic"
 	"time"
 )
@@ -39,17 +39,16 @@ func NewRedisFlowCountService(appID string, interval time.Duration) *RedisFlowCo
 			currentTime := time.Now()
 			dayKey := reqCounter.GetDayKey(currentTime)
 			hourKey := reqCounter.GetHourKey(currentTime)
-			if err := RedisConfPipline(func(c redis.Conn) {
-				c.Send("INCRBY", dayKey, tickerCount)
-				c.Send("EXPIRE", dayKey, 86400*2)
-				c.Send("INCRBY", hourKey, tickerCount)
-				c.Send("EXPIRE", hourKey, 86400*2)
-			}); err != nil {
-				fmt.Println("RedisConfPipline err", err)
+			if err := CounterStoreHandler.Incr(nil,
+				CounterIncr{Key: dayKey, Value: tickerCount, Expire: RedisFlowDayKeyExpire},
+				CounterIncr{Key: hourKey, Value: tickerCount, Expire: RedisFlowHourKeyExpire},
+			); err != nil {
+				fmt.Println("CounterStore Incr err", err)
+				MetricFlowCountFlushErrors.Inc()
 				continue
 			}
 
-			totalCount, err := reqCounter.GetDayData(currentTime)
+			totalCount, err := reqCounter.GetDayData(nil, currentTime)
 			if err != nil {
 				fmt.Println("reqCounter.GetDayData err", err)
 				continue
@@ -80,12 +79,55 @@ func (o *RedisFlowCountService) GetHourKey(t time.Time) string {
 	return fmt.Sprintf("%s_%s_%s", RedisFlowHourKey, hourStr, o.AppID)
 }
 
-func (o *RedisFlowCountService) GetHourData(t time.Time) (int64, error) {
-	return redis.Int64(RedisConfDo("GET", o.GetHourKey(t)))
+func (o *RedisFlowCountService) GetHourData(trace *lib.TraceContext, t time.Time) (int64, error) {
+	count, _, err := CounterStoreHandler.Get(trace, o.GetHourKey(t))
+	return count, err
 }
 
-func (o *RedisFlowCountService) GetDayData(t time.Time) (int64, error) {
-	return redis.Int64(RedisConfDo("GET", o.GetDayKey(t)))
+// LookupHourData 与 GetHourData 相同，但区分没有数据(ok=false)与请求量为 0
+func (o *RedisFlowCountService) LookupHourData(trace *lib.TraceContext, t time.Time) (int64, bool, error) {
+	return CounterStoreHandler.Get(trace, o.GetHourKey(t))
+}
+
+// GetWindowData 返回 t 所在小时及之前共 hours 个小时的请求量之和
+func (o *RedisFlowCountService) GetWindowData(trace *lib.TraceContext, t time.Time, hours int) (int64, error) {
+	keys := []string{}
+	for index := 0; index < hours; index++ {
+		keys = append(keys, o.GetHourKey(t.Add(-time.Duration(index)*time.Hour)))
+	}
+	counts, err := CounterStoreHandler.MGet(trace, keys...)
+	if err != nil {
+		return 0, err
+	}
+	total := int64(0)
+	for _, count := range counts {
+		total += count
+	}
+	return total, nil
+}
+
+func (o *RedisFlowCountService) GetDayData(trace *lib.TraceContext, t time.Time) (int64, error) {
+	count, _, err := CounterStoreHandler.Get(trace, o.GetDayKey(t))
+	return count, err
+}
+
+// RenterServiceCounterName 租户+服务维度的计数器名，服务名不含 ':'，按最后一个 ':' 拆分
+func RenterServiceCounterName(renterID, serviceName string) string {
+	return FlowRenterServicePrefix + renterID + ":" + serviceName
+}
+
+// ScanDayData 返回某天所有以 appIDPrefix 开头的计数器，key 为去掉前缀后的 appID 后缀
+func ScanDayData(trace *lib.TraceContext, t time.Time, appIDPrefix string) (map[string]int64, error) {
+	dayKeyPrefix := fmt.Sprintf("%s_%s_", RedisFlowDayKey, t.In(lib.TimeLocation).Format("20060102"))
+	counts, err := CounterStoreHandler.Scan(trace, dayKeyPrefix+appIDPrefix)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]int64{}
+	for key, count := range counts {
+		out[strings.TrimPrefix(key, dayKeyPrefix+appIDPrefix)] = count
+	}
+	return out, nil
 }
 
 //原子增加
@@ -99,3 +141,8 @@ func (o *RedisFlowCountService) Increase() {
 		atomic.AddInt64(&o.TickerCount, 1)
 	}()
 }
+
+//原子增加 n
+func (o *RedisFlowCountService) IncreaseBy(n int64) {
+	atomic.AddInt64(&o.TickerCount, n)
+}
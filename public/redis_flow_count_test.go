@@ -0,0 +1,103 @@
+package public
+
+import (
+	"github.com/e421083458/golang_common/lib"
+	"testing"
+	"time"
+)
+
+// 用进程内计数代替 redis，测试结束后恢复
+func useTestCounterStore(t *testing.T) {
+	oldStore, oldLocation := CounterStoreHandler, lib.TimeLocation
+	CounterStoreHandler = newMemoryCounterStore()
+	lib.TimeLocation = time.UTC
+	t.Cleanup(func() {
+		CounterStoreHandler, lib.TimeLocation = oldStore, oldLocation
+	})
+}
+
+func waitFor(t *testing.T, timeout time.Duration, check func() bool) {
+	deadline := time.Now().Add(timeout)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRedisFlowCountServiceFlushesDayAndHour(t *testing.T) {
+	useTestCounterStore(t)
+	counter := NewRedisFlowCountService("test_flush", 20*time.Millisecond)
+	counter.IncreaseBy(7)
+
+	now := time.Now()
+	waitFor(t, 2*time.Second, func() bool {
+		count, _ := counter.GetDayData(nil, now)
+		return count == 7
+	})
+	if count, _ := counter.GetHourData(nil, now); count != 7 {
+		t.Fatalf("hour count = %d, want 7", count)
+	}
+	if _, ok, _ := counter.LookupHourData(nil, now.Add(-time.Hour)); ok {
+		t.Fatal("previous hour should have no data")
+	}
+}
+
+func TestRedisFlowCountServiceQPS(t *testing.T) {
+	useTestCounterStore(t)
+	counter := NewRedisFlowCountService("test_qps", 20*time.Millisecond)
+	// 第一次 flush 只记录起始时间
+	time.Sleep(50 * time.Millisecond)
+	counter.IncreaseBy(100)
+	waitFor(t, 3*time.Second, func() bool {
+		return counter.QPS > 0
+	})
+	if counter.QPS > 100 {
+		t.Fatalf("qps = %d, want at most 100", counter.QPS)
+	}
+}
+
+func TestIncreaseServiceFlowFeedsTotalAndService(t *testing.T) {
+	useTestCounterStore(t)
+	flowCounter := NewFlowCounter()
+	for index := 0; index < 3; index++ {
+		if err := flowCounter.IncreaseServiceFlow("test_svc"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	total, _ := flowCounter.GetCounter(FlowTotal)
+	service, _ := flowCounter.GetCounter(FlowServicePrefix + "test_svc")
+	now := time.Now()
+	waitFor(t, 3*time.Second, func() bool {
+		totalCount, _ := total.GetDayData(nil, now)
+		serviceCount, _ := service.GetDayData(nil, now)
+		return totalCount == 3 && serviceCount == 3
+	})
+}
+
+func TestFlowCounterLookupDoesNotRegister(t *testing.T) {
+	useTestCounterStore(t)
+	now := time.Now()
+	writer := &RedisFlowCountService{AppID: FlowServicePrefix + "lookup_only"}
+	if err := CounterStoreHandler.Incr(nil, CounterIncr{Key: writer.GetHourKey(now), Value: 5, Expire: RedisFlowHourKeyExpire}); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewFlowCounter()
+	count, err := handler.Lookup(FlowServicePrefix+"lookup_only").GetWindowData(nil, now, 1)
+	if err != nil || count != 5 {
+		t.Fatalf("lookup count = %d, %v; want 5", count, err)
+	}
+	if len(handler.RedisFlowCountMap) != 0 {
+		t.Error("Lookup registered a counter")
+	}
+
+	registered, _ := handler.GetCounter(FlowServicePrefix + "lookup_only")
+	if handler.Lookup(FlowServicePrefix+"lookup_only") != registered {
+		t.Error("Lookup should return the registered counter")
+	}
+	if NewLatencyCounter().Lookup(FlowServicePrefix+"lookup_only").AppID == "" {
+		t.Error("latency lookup without app id")
+	}
+}
@@ -0,0 +1,136 @@
+package public
+
+import (
+	"fmt"
+	"github.com/e421083458/golang_common/lib"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// 延迟分桶上界, 单位ms, 最后一个桶表示超过 10s
+var LatencyBuckets = []int64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000}
+
+type RedisLatencyCountService struct {
+	AppID        string
+	Interval     time.Duration
+	BucketCounts []int64
+}
+
+func NewRedisLatencyCountService(appID string, interval time.Duration) *RedisLatencyCountService {
+	latencyCounter := &RedisLatencyCountService{
+		AppID:        appID,
+		Interval:     interval,
+		BucketCounts: make([]int64, len(LatencyBuckets)+1),
+	}
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				fmt.Println(err)
+			}
+		}()
+		ticker := time.NewTicker(interval)
+		for {
+			<-ticker.C
+			hourKey := latencyCounter.GetHourKey(time.Now())
+			incrs := []CounterIncr{}
+			for index := range latencyCounter.BucketCounts {
+				bucketCount := atomic.SwapInt64(&latencyCounter.BucketCounts[index], 0)
+				if bucketCount == 0 {
+					continue
+				}
+				incrs = append(incrs, CounterIncr{Key: hourKey, Field: strconv.Itoa(index), Value: bucketCount, Expire: RedisFlowHourKeyExpire})
+			}
+			if len(incrs) == 0 {
+				continue
+			}
+			if err := CounterStoreHandler.Incr(nil, incrs...); err != nil {
+				fmt.Println("CounterStore Incr err", err)
+				MetricFlowCountFlushErrors.Inc()
+				continue
+			}
+		}
+	}()
+	return latencyCounter
+}
+
+func (o *RedisLatencyCountService) GetHourKey(t time.Time) string {
+	hourStr := t.In(lib.TimeLocation).Format("2006010215")
+	return fmt.Sprintf("%s_%s_%s", RedisLatencyHourKey, hourStr, o.AppID)
+}
+
+// Record 记录一次请求耗时
+func (o *RedisLatencyCountService) Record(latency time.Duration) {
+	ms := latency.Milliseconds()
+	index := len(LatencyBuckets)
+	for bucketIndex, bucket := range LatencyBuckets {
+		if ms <= bucket {
+			index = bucketIndex
+			break
+		}
+	}
+	atomic.AddInt64(&o.BucketCounts[index], 1)
+}
+
+func (o *RedisLatencyCountService) GetHourData(trace *lib.TraceContext, t time.Time) ([]int64, error) {
+	values, err := CounterStoreHandler.HGetAll(trace, o.GetHourKey(t))
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]int64, len(LatencyBuckets)+1)
+	for key, value := range values {
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(counts) {
+			continue
+		}
+		counts[index] = value
+	}
+	return counts, nil
+}
+
+// GetPercentiles 根据当前小时与上一小时的分桶数据计算分位数
+// 返回值是命中桶的上界, 单位ms, 没有数据时返回 0
+func (o *RedisLatencyCountService) GetPercentiles(trace *lib.TraceContext, t time.Time, percentiles ...float64) ([]int64, error) {
+	return o.GetWindowPercentiles(trace, t, 2, percentiles...)
+}
+
+// GetWindowPercentiles 合并 t 所在小时及之前共 hours 个小时的分桶数据计算分位数
+func (o *RedisLatencyCountService) GetWindowPercentiles(trace *lib.TraceContext, t time.Time, hours int, percentiles ...float64) ([]int64, error) {
+	counts := make([]int64, len(LatencyBuckets)+1)
+	total := int64(0)
+	for hour := 0; hour < hours; hour++ {
+		hourData, err := o.GetHourData(trace, t.Add(-time.Duration(hour)*time.Hour))
+		if err != nil {
+			return nil, err
+		}
+		for index := range counts {
+			counts[index] += hourData[index]
+			total += hourData[index]
+		}
+	}
+
+	result := make([]int64, len(percentiles))
+	if total == 0 {
+		return result, nil
+	}
+	for pIndex, percentile := range percentiles {
+		target := int64(float64(total)*percentile + 0.5)
+		if target < 1 {
+			target = 1
+		}
+		accumulated := int64(0)
+		for index, count := range counts {
+			accumulated += count
+			if accumulated < target {
+				continue
+			}
+			if index < len(LatencyBuckets) {
+				result[pIndex] = LatencyBuckets[index]
+			} else {
+				result[pIndex] = LatencyBuckets[len(LatencyBuckets)-1]
+			}
+			break
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,248 @@
+package public
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/garyburd/redigo/redis"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 请求实时查看(tail)
+// 管理端在 redis 中设置 watch key 并订阅服务的频道，数据面只在 watch key 存在时发布请求，平时没有额外开销
+// state driver 为 memory 或 redis 不可用时使用进程内的 watch 与 memoryPubSub，只能看到本进程代理的请求
+
+type RequestTailEvent struct {
+	Time           string            `json:"time"`
+	TraceID        string            `json:"trace_id"`
+	Service        string            `json:"service"`
+	Renter         string            `json:"renter"`
+	ClientIP       string            `json:"client_ip"`
+	Method         string            `json:"method"`
+	Path           string            `json:"path"`
+	Query          string            `json:"query"`
+	Upstream       string            `json:"upstream"`
+	Status         int               `json:"status"`
+	LatencyMs      int64             `json:"latency_ms"`
+	RequestBytes   int64             `json:"request_bytes"`
+	ResponseBytes  int64             `json:"response_bytes"`
+	RequestHeader  map[string]string `json:"request_header"`
+	ResponseHeader map[string]string `json:"response_header"`
+}
+
+// RequestTailFilter 为空的条件不过滤；Status 支持 502 或 5xx，Path 按前缀匹配
+type RequestTailFilter struct {
+	RenterID string
+	Status   string
+	Path     string
+	ClientIP string
+}
+
+func (f *RequestTailFilter) Match(event *RequestTailEvent) bool {
+	if f.RenterID != "" && f.RenterID != event.Renter {
+		return false
+	}
+	if f.ClientIP != "" && f.ClientIP != event.ClientIP {
+		return false
+	}
+	if f.Path != "" && !strings.HasPrefix(event.Path, f.Path) {
+		return false
+	}
+	if f.Status != "" {
+		status := fmt.Sprintf("%d", event.Status)
+		if strings.HasSuffix(f.Status, "xx") {
+			return strings.HasPrefix(status, f.Status[:1])
+		}
+		return status == f.Status
+	}
+	return true
+}
+
+func RequestTailChannel(serviceName string) string {
+	return RequestTailChannelPrefix + serviceName
+}
+
+func RequestTailWatchKey(serviceName string) string {
+	return RequestTailWatchPrefix + serviceName
+}
+
+var RequestTailHandler *RequestTail
+
+func init() {
+	RequestTailHandler = NewRequestTail()
+}
+
+type requestTailWatch struct {
+	On        bool
+	CheckedAt time.Time
+}
+
+type RequestTail struct {
+	WatchMap map[string]*requestTailWatch
+	Locker   sync.RWMutex
+	queue    chan *RequestTailEvent
+	once     sync.Once
+	// localWatch 进程内的 watch 过期时间
+	localWatch map[string]time.Time
+}
+
+func NewRequestTail() *RequestTail {
+	return &RequestTail{
+		WatchMap:   map[string]*requestTailWatch{},
+		queue:      make(chan *RequestTailEvent, 1024),
+		localWatch: map[string]time.Time{},
+	}
+}
+
+// Watching 判断服务是否有人在 tail，redis 查询结果缓存 1 秒
+func (t *RequestTail) Watching(serviceName string) bool {
+	t.Locker.RLock()
+	watch, ok := t.WatchMap[serviceName]
+	t.Locker.RUnlock()
+	if ok && time.Since(watch.CheckedAt) < time.Second {
+		return watch.On
+	}
+	t.Locker.RLock()
+	on := time.Now().Before(t.localWatch[serviceName])
+	t.Locker.RUnlock()
+	if !on && StateDriver() == StateDriverRedis {
+		exists, err := redis.Bool(RedisConfDo(nil, "EXISTS", RequestTailWatchKey(serviceName)))
+		on = err == nil && exists
+	}
+	t.Locker.Lock()
+	defer t.Locker.Unlock()
+	t.WatchMap[serviceName] = &requestTailWatch{On: on, CheckedAt: time.Now()}
+	return on
+}
+
+// Publish 入队后由后台协程发布，队列满时丢弃，不阻塞请求
+func (t *RequestTail) Publish(event *RequestTailEvent) {
+	t.once.Do(func() {
+		go t.run()
+	})
+	select {
+	case t.queue <- event:
+	default:
+	}
+}
+
+func (t *RequestTail) run() {
+	for event := range t.queue {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		// redis 发布失败时改为进程内投递，同一进程的订阅者不会重复收到
+		if StateDriver() == StateDriverRedis {
+			_, err := RedisConfDo(nil, "PUBLISH", RequestTailChannel(event.Service), payload)
+			if err == nil {
+				continue
+			}
+			fmt.Println("RequestTail PUBLISH err", err)
+		}
+		memoryPubSub.Publish(RequestTailChannel(event.Service), payload)
+	}
+}
+
+// Watch 设置或续期 watch key，管理端在 tail 期间定时调用
+// 进程内总是记录，redis 不可用时本进程的代理仍然可以被 tail
+func (t *RequestTail) Watch(serviceName string) error {
+	t.Locker.Lock()
+	delete(t.WatchMap, serviceName)
+	t.localWatch[serviceName] = time.Now().Add(RequestTailWatchTTL * time.Second)
+	for name, expireAt := range t.localWatch {
+		if time.Now().After(expireAt) {
+			delete(t.localWatch, name)
+		}
+	}
+	t.Locker.Unlock()
+	if StateDriver() != StateDriverRedis {
+		return nil
+	}
+	if _, err := RedisConfDo(nil, "SET", RequestTailWatchKey(serviceName), 1, "EX", RequestTailWatchTTL); err != nil {
+		fmt.Println("RequestTail SET watch err", err)
+	}
+	return nil
+}
+
+// RequestTailSubscription 同时订阅进程内频道与 redis 频道，redis 订阅独占一个连接，用完必须 Close
+type RequestTailSubscription struct {
+	Events chan *RequestTailEvent
+	local  *memorySubscription
+	conn   *redis.PubSubConn
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+func (t *RequestTail) Subscribe(serviceName string) (*RequestTailSubscription, error) {
+	sub := &RequestTailSubscription{
+		Events: make(chan *RequestTailEvent, 256),
+		local:  memoryPubSub.Subscribe(RequestTailChannel(serviceName), 256),
+	}
+	if StateDriver() == StateDriverRedis {
+		if err := sub.subscribeRedis(serviceName); err != nil {
+			fmt.Println("RequestTail SUBSCRIBE err", err)
+		}
+	}
+	sub.wg.Add(1)
+	go func() {
+		defer sub.wg.Done()
+		for payload := range sub.local.Messages {
+			sub.deliver(payload)
+		}
+	}()
+	go func() {
+		sub.wg.Wait()
+		close(sub.Events)
+	}()
+	return sub, nil
+}
+
+func (s *RequestTailSubscription) subscribeRedis(serviceName string) error {
+	conn, err := lib.RedisConnFactory("default")
+	if err != nil {
+		return err
+	}
+	psc := &redis.PubSubConn{Conn: conn}
+	if err := psc.Subscribe(RequestTailChannel(serviceName)); err != nil {
+		conn.Close()
+		return err
+	}
+	s.conn = psc
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			// 订阅连接没有读超时，Close 后返回错误退出
+			switch reply := psc.ReceiveWithTimeout(0).(type) {
+			case redis.Message:
+				s.deliver(reply.Data)
+			case error:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *RequestTailSubscription) deliver(payload []byte) {
+	event := &RequestTailEvent{}
+	if err := json.Unmarshal(payload, event); err != nil {
+		return
+	}
+	select {
+	case s.Events <- event:
+	default:
+	}
+}
+
+func (s *RequestTailSubscription) Close() {
+	s.once.Do(func() {
+		memoryPubSub.Unsubscribe(s.local)
+		if s.conn != nil {
+			s.conn.Close()
+		}
+	})
+}
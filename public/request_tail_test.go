@@ -0,0 +1,45 @@
+package public
+
+import (
+	"testing"
+	"time"
+)
+
+// state driver 为 memory 时 watch 与请求通过进程内频道转发
+func TestRequestTailMemoryDriver(t *testing.T) {
+	useTestConf(t, map[string]interface{}{"base.state.driver": StateDriverMemory})
+	tail := NewRequestTail()
+	if tail.Watching("tail_demo") {
+		t.Fatal("watching before Watch")
+	}
+	if err := tail.Watch("tail_demo"); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := tail.Subscribe("tail_demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tail.Watching("tail_demo") {
+		t.Fatal("not watching after Watch")
+	}
+
+	tail.Publish(&RequestTailEvent{Service: "tail_demo", Path: "/demo", Status: 502})
+	select {
+	case event := <-sub.Events:
+		if event.Path != "/demo" || event.Status != 502 {
+			t.Errorf("unexpected event %+v", event)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("event not delivered")
+	}
+
+	sub.Close()
+	select {
+	case _, ok := <-sub.Events:
+		if ok {
+			t.Error("event after Close")
+		}
+	case <-time.After(time.Second):
+		t.Error("Events not closed after Close")
+	}
+}
@@ -0,0 +1,79 @@
+package public
+
+import (
+	"strings"
+)
+
+// MatchPath 按段匹配路径，:name 匹配任意单段，末尾的 *name 匹配剩余所有段
+// 匹配成功时返回路径参数
+func MatchPath(pattern, path string) (map[string]string, bool) {
+	patternSegs := splitPath(pattern)
+	pathSegs := splitPath(path)
+	params := map[string]string{}
+	for index, seg := range patternSegs {
+		if strings.HasPrefix(seg, "*") {
+			params[seg[1:]] = strings.Join(pathSegs[index:], "/")
+			return params, true
+		}
+		if index >= len(pathSegs) {
+			return nil, false
+		}
+		if strings.HasPrefix(seg, ":") {
+			params[seg[1:]] = pathSegs[index]
+			continue
+		}
+		if seg != pathSegs[index] {
+			return nil, false
+		}
+	}
+	if len(patternSegs) != len(pathSegs) {
+		return nil, false
+	}
+	return params, true
+}
+
+// PathOverlap 判断两个路径模式是否存在同时匹配的请求路径
+func PathOverlap(a, b string) bool {
+	aSegs := splitPath(a)
+	bSegs := splitPath(b)
+	for index := 0; index < len(aSegs) && index < len(bSegs); index++ {
+		if strings.HasPrefix(aSegs[index], "*") || strings.HasPrefix(bSegs[index], "*") {
+			return true
+		}
+		if strings.HasPrefix(aSegs[index], ":") || strings.HasPrefix(bSegs[index], ":") {
+			continue
+		}
+		if aSegs[index] != bSegs[index] {
+			return false
+		}
+	}
+	if len(aSegs) == len(bSegs) {
+		return true
+	}
+	// 长度不同时只有较长的一方在下一段是 * 才会重叠
+	if len(aSegs) > len(bSegs) {
+		return strings.HasPrefix(aSegs[len(bSegs)], "*")
+	}
+	return strings.HasPrefix(bSegs[len(aSegs)], "*")
+}
+
+// MethodOverlap 判断两个方法列表是否有交集，空列表表示匹配所有方法
+func MethodOverlap(a, b string) bool {
+	if a == "" || b == "" {
+		return true
+	}
+	for _, method := range strings.Split(a, ",") {
+		if InStringSlice(strings.Split(b, ","), method) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return []string{}
+	}
+	return strings.Split(path, "/")
+}
@@ -0,0 +1,93 @@
+package public
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/garyburd/redigo/redis"
+	"time"
+)
+
+// 服务配置变更通知
+// 管理端在事务提交后发布，代理进程订阅 ServiceChangeChannel 后重新加载对应服务，不用等待下一次全量加载
+// state driver 为 memory 或 redis 不可用时通过 memoryPubSub 通知同一进程中的代理
+
+type ServiceChangeEvent struct {
+	ServiceID   int64  `json:"service_id"`
+	ServiceName string `json:"service_name"`
+	// OldServiceName 改名时的旧名称，代理需要同时清理旧名称下的缓存
+	OldServiceName string `json:"old_service_name,omitempty"`
+	Action         string `json:"action"`
+	Version        int64  `json:"version"`
+}
+
+// ServiceNames 事件涉及的服务名称，改名时包含新旧两个
+func (e *ServiceChangeEvent) ServiceNames() []string {
+	if e.OldServiceName == "" || e.OldServiceName == e.ServiceName {
+		return []string{e.ServiceName}
+	}
+	return []string{e.ServiceName, e.OldServiceName}
+}
+
+// PublishServiceChange 发布失败只打印日志，配置已经落库，代理下一次全量加载时仍会生效
+func PublishServiceChange(event *ServiceChangeEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	if StateDriver() == StateDriverRedis {
+		_, err := RedisConfDo(nil, "PUBLISH", ServiceChangeChannel, payload)
+		if err == nil {
+			return
+		}
+		fmt.Println("ServiceChange PUBLISH err", err)
+	}
+	memoryPubSub.Publish(ServiceChangeChannel, payload)
+}
+
+// WatchServiceChange 阻塞订阅变更通知，供代理进程启动时在协程中调用
+// 进程内的通知一直订阅，redis 连接断开后 1s 重连
+func WatchServiceChange(handler func(event *ServiceChangeEvent)) {
+	local := memoryPubSub.Subscribe(ServiceChangeChannel, 256)
+	if StateDriver() == StateDriverRedis {
+		go func() {
+			for {
+				if err := watchServiceChange(handler); err != nil {
+					fmt.Println("ServiceChange SUBSCRIBE err", err)
+				}
+				time.Sleep(time.Second)
+			}
+		}()
+	}
+	for payload := range local.Messages {
+		handleServiceChange(payload, handler)
+	}
+}
+
+func watchServiceChange(handler func(event *ServiceChangeEvent)) error {
+	conn, err := lib.RedisConnFactory("default")
+	if err != nil {
+		return err
+	}
+	psc := redis.PubSubConn{Conn: conn}
+	defer psc.Close()
+	if err := psc.Subscribe(ServiceChangeChannel); err != nil {
+		return err
+	}
+	for {
+		switch reply := psc.ReceiveWithTimeout(0).(type) {
+		case redis.Message:
+			handleServiceChange(reply.Data, handler)
+		case error:
+			return reply
+		}
+	}
+}
+
+func handleServiceChange(payload []byte, handler func(event *ServiceChangeEvent)) {
+	event := &ServiceChangeEvent{}
+	if err := json.Unmarshal(payload, event); err != nil {
+		return
+	}
+	handler(event)
+}
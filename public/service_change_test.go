@@ -0,0 +1,48 @@
+package public
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestServiceChangeMemoryDriver(t *testing.T) {
+	useTestConf(t, map[string]interface{}{"base.state.driver": StateDriverMemory})
+
+	received := make(chan *ServiceChangeEvent, 8)
+	go WatchServiceChange(func(event *ServiceChangeEvent) {
+		received <- event
+	})
+
+	// 订阅在协程中建立，收到之前重复发布
+	event := &ServiceChangeEvent{ServiceID: 1, ServiceName: "demo_new", OldServiceName: "demo", Action: AuditActionUpdate, Version: 3}
+	deadline := time.After(2 * time.Second)
+	for {
+		PublishServiceChange(event)
+		select {
+		case got := <-received:
+			if !reflect.DeepEqual(got, event) {
+				t.Fatalf("event = %+v, want %+v", got, event)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("service change was not delivered")
+		}
+	}
+}
+
+func TestServiceChangeEventServiceNames(t *testing.T) {
+	event := &ServiceChangeEvent{ServiceName: "demo"}
+	if got := event.ServiceNames(); !reflect.DeepEqual(got, []string{"demo"}) {
+		t.Fatalf("ServiceNames() = %v", got)
+	}
+	event.OldServiceName = "demo"
+	if got := event.ServiceNames(); !reflect.DeepEqual(got, []string{"demo"}) {
+		t.Fatalf("ServiceNames() = %v", got)
+	}
+	event.OldServiceName = "demo_old"
+	if got := event.ServiceNames(); !reflect.DeepEqual(got, []string{"demo", "demo_old"}) {
+		t.Fatalf("ServiceNames() = %v", got)
+	}
+}
@@ -0,0 +1,185 @@
+package public
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/e421083458/golang_common/lib"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// 管理员登录两步验证，TOTP 按 RFC 6238，HMAC-SHA1、30 秒、6 位，与常见的验证器 App 兼容
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret 返回 base32 编码的 160 位密钥
+func NewTOTPSecret() string {
+	b := make([]byte, 20)
+	_, _ = rand.Read(b)
+	return totpEncoding.EncodeToString(b)
+}
+
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / TOTPPeriod
+}
+
+// TOTPCode 返回密钥在时间步 step 的验证码
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000), nil
+}
+
+// MatchTOTP 允许前后 TOTPSkew 个时间步的时钟误差，返回匹配的时间步
+// 调用方需要拒绝不大于上次使用的时间步，避免同一个验证码重复使用
+func MatchTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != 6 {
+		return 0, false
+	}
+	current := TOTPStep(now)
+	for step := current - TOTPSkew; step <= current+TOTPSkew; step++ {
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPProvisioningURI 返回 otpauth:// 地址，前端生成二维码供验证器 App 扫描
+func TOTPProvisioningURI(account, secret string) string {
+	issuer := TOTPIssuer()
+	query := url.Values{
+		"secret":    {secret},
+		"issuer":    {issuer},
+		"algorithm": {"SHA1"},
+		"digits":    {"6"},
+		"period":    {fmt.Sprint(TOTPPeriod)},
+	}
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// NewRecoveryCodes 返回一次性恢复码，形如 1a2b-3c4d
+func NewRecoveryCodes() []string {
+	codes := []string{}
+	for i := 0; i < TOTPRecoveryCodeCount; i++ {
+		b := make([]byte, 4)
+		_, _ = rand.Read(b)
+		code := hex.EncodeToString(b)
+		codes = append(codes, code[:4]+"-"+code[4:])
+	}
+	return codes
+}
+
+// HashRecoveryCode 忽略大小写、空格与连字符
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func TOTPIssuer() string {
+	if issuer := lib.GetStringConf("base.admin.totp_issuer"); issuer != "" {
+		return issuer
+	}
+	return TOTPIssuerDefault
+}
+
+// TOTPRequired 角色是否必须开启两步验证，见 base.admin.totp_enforce_roles
+func TOTPRequired(role string) bool {
+	return InStringSlice(lib.GetStringSliceConf("base.admin.totp_enforce_roles"), role)
+}
+
+// AdminLoginPendingTimeout 输入密码后完成两步验证的时限
+func AdminLoginPendingTimeout() time.Duration {
+	if timeout := lib.GetIntConf("base.admin.login_pending_timeout"); timeout > 0 {
+		return time.Duration(timeout) * time.Second
+	}
+	return AdminLoginPendingTimeoutDefault * time.Second
+}
+
+// TOTPLockout 连续失败达到 TOTPMaxFailures 后的锁定时长
+func TOTPLockout() time.Duration {
+	if lockout := lib.GetIntConf("base.admin.totp_lockout"); lockout > 0 {
+		return time.Duration(lockout) * time.Second
+	}
+	return TOTPLockoutDefault * time.Second
+}
+
+// totpCipher 加密 key 取 sha256 作为 AES-256 密钥，未配置时不能绑定两步验证
+func totpCipher() (cipher.AEAD, error) {
+	key := os.Getenv(TOTPKeyEnv)
+	if key == "" {
+		key = lib.GetStringConf("base.admin.totp_key")
+	}
+	if key == "" {
+		return nil, errors.New("未配置两步验证密钥的加密 key，请设置 " + TOTPKeyEnv + " 或 base.admin.totp_key")
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptTOTPSecret AES-GCM 加密，返回 TOTPEncryptedPrefix 开头的 base64
+func EncryptTOTPSecret(secret string) (string, error) {
+	aead, err := totpCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(secret), nil)
+	return TOTPEncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptTOTPSecret 不带 TOTPEncryptedPrefix 的是加密之前保存的明文，原样返回
+func DecryptTOTPSecret(value string) (string, error) {
+	if !strings.HasPrefix(value, TOTPEncryptedPrefix) {
+		return value, nil
+	}
+	aead, err := totpCipher()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, TOTPEncryptedPrefix))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("两步验证密钥格式错误")
+	}
+	secret, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("两步验证密钥解密失败，请检查 " + TOTPKeyEnv)
+	}
+	return string(secret), nil
+}
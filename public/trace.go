@@ -0,0 +1,242 @@
+package public
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/e421083458/golang_common/lib"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 与 OpenTelemetry 兼容的链路追踪
+// trace id 与 span id 沿用 lib.TraceContext 的 TraceId/SpanId，日志与 span 可以直接关联
+
+const (
+	SpanKindInternal = 1
+	SpanKindServer   = 2
+	SpanKindClient   = 3
+
+	SpanStatusOK    = 1
+	SpanStatusError = 2
+
+	TraceparentHeader = "traceparent"
+)
+
+var TracerHandler *Tracer
+
+func init() {
+	TracerHandler = NewTracer()
+}
+
+type SpanExporter interface {
+	Export(span *Span)
+}
+
+type Tracer struct {
+	ServiceName string
+	Exporter    SpanExporter
+	Locker      sync.RWMutex
+}
+
+func NewTracer() *Tracer {
+	return &Tracer{
+		ServiceName: "gateway",
+		Locker:      sync.RWMutex{},
+	}
+}
+
+// InitTracer 根据 base.trace 配置开启 otlp 导出
+func InitTracer() {
+	if !lib.GetBoolConf("base.trace.on") {
+		return
+	}
+	serviceName := lib.GetStringConf("base.trace.service_name")
+	if serviceName == "" {
+		serviceName = "gateway"
+	}
+	interval := time.Duration(lib.GetIntConf("base.trace.flush_interval")) * time.Second
+	exporter := NewOTLPSpanExporter(lib.GetStringConf("base.trace.otlp_endpoint"), serviceName,
+		interval, lib.GetIntConf("base.trace.batch_size"))
+	TracerHandler.SetExporter(serviceName, exporter)
+}
+
+// SetExporter 为空时只生成 id 用于透传，不导出 span
+func (t *Tracer) SetExporter(serviceName string, exporter SpanExporter) {
+	t.Locker.Lock()
+	defer t.Locker.Unlock()
+	t.ServiceName = serviceName
+	t.Exporter = exporter
+}
+
+func (t *Tracer) export(span *Span) {
+	t.Locker.RLock()
+	exporter := t.Exporter
+	t.Locker.RUnlock()
+	if exporter != nil {
+		exporter.Export(span)
+	}
+}
+
+type Span struct {
+	TraceID       string
+	SpanID        string
+	ParentSpanID  string
+	Name          string
+	Kind          int
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    map[string]string
+	StatusCode    int
+	StatusMessage string
+	traceContext  *lib.TraceContext
+	locker        sync.Mutex
+}
+
+// activeTraces 记录由未结束 span 生成的 lib.TraceContext
+// 后台任务的 trace 来自 lib.NewTrace，不在其中，不会产生孤立的 span
+var activeTraces sync.Map
+
+// StartSpan 以 trace 中的 SpanId 为父节点创建 span，trace 为空时开启新的链路
+func StartSpan(trace *lib.TraceContext, name string, kind int) *Span {
+	span := &Span{
+		SpanID:     newHexID(8),
+		Name:       name,
+		Kind:       kind,
+		StartTime:  time.Now(),
+		Attributes: map[string]string{},
+	}
+	if trace != nil && validTraceID(trace.TraceId) {
+		span.TraceID = trace.TraceId
+		if validSpanID(trace.SpanId) {
+			span.ParentSpanID = trace.SpanId
+		}
+	} else {
+		span.TraceID = newHexID(16)
+	}
+	return span
+}
+
+// StartChildSpan 只在 trace 属于未结束的 span 时创建子 span，否则返回 nil
+func StartChildSpan(trace *lib.TraceContext, name string, kind int) *Span {
+	if trace == nil {
+		return nil
+	}
+	if _, ok := activeTraces.Load(trace); !ok {
+		return nil
+	}
+	return StartSpan(trace, name, kind)
+}
+
+// TraceContext 返回以当前 span 为父节点的 lib.TraceContext，供日志、gorm 与下游调用使用
+func (s *Span) TraceContext() *lib.TraceContext {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	if s.traceContext == nil {
+		s.traceContext = &lib.TraceContext{}
+		s.traceContext.TraceId = s.TraceID
+		s.traceContext.SpanId = s.SpanID
+		if s.EndTime.IsZero() {
+			activeTraces.Store(s.traceContext, s)
+		}
+	}
+	return s.traceContext
+}
+
+// Traceparent 按 W3C trace context 格式输出
+func (s *Span) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", s.TraceID, s.SpanID)
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	s.Attributes[key] = fmt.Sprint(value)
+}
+
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	s.StatusCode = SpanStatusError
+	s.StatusMessage = err.Error()
+}
+
+func (s *Span) End() {
+	s.locker.Lock()
+	if !s.EndTime.IsZero() {
+		s.locker.Unlock()
+		return
+	}
+	s.EndTime = time.Now()
+	if s.traceContext != nil {
+		activeTraces.Delete(s.traceContext)
+	}
+	s.locker.Unlock()
+	TracerHandler.export(s)
+}
+
+// ParseTraceparent 解析 W3C traceparent header
+func ParseTraceparent(header string) (traceID string, spanID string, ok bool) {
+	items := strings.Split(strings.TrimSpace(header), "-")
+	if len(items) < 4 || len(items[0]) != 2 || items[0] == "ff" {
+		return "", "", false
+	}
+	if !validTraceID(items[1]) || !validSpanID(items[2]) {
+		return "", "", false
+	}
+	return strings.ToLower(items[1]), strings.ToLower(items[2]), true
+}
+
+func newHexID(size int) string {
+	b := make([]byte, size)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func validTraceID(id string) bool {
+	return validHexID(id, 32)
+}
+
+func validSpanID(id string) bool {
+	return validHexID(id, 16)
+}
+
+func validHexID(id string, length int) bool {
+	if len(id) != length || strings.Trim(id, "0") == "" {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// InMemorySpanExporter 保存在内存中，测试时用来断言 span
+type InMemorySpanExporter struct {
+	spans  []*Span
+	locker sync.Mutex
+}
+
+func NewInMemorySpanExporter() *InMemorySpanExporter {
+	return &InMemorySpanExporter{}
+}
+
+func (e *InMemorySpanExporter) Export(span *Span) {
+	e.locker.Lock()
+	defer e.locker.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+func (e *InMemorySpanExporter) GetSpans() []*Span {
+	e.locker.Lock()
+	defer e.locker.Unlock()
+	return append([]*Span{}, e.spans...)
+}
+
+func (e *InMemorySpanExporter) Reset() {
+	e.locker.Lock()
+	defer e.locker.Unlock()
+	e.spans = nil
+}
@@ -0,0 +1,67 @@
+package public
+
+import (
+	"github.com/e421083458/golang_common/lib"
+	"github.com/e421083458/gorm"
+)
+
+const gormSpanKey = "trace:span"
+
+// RegisterGormTrace 为 SetCtx 传入请求 span 的 lib.TraceContext 的查询创建 span
+func RegisterGormTrace(db *gorm.DB) {
+	callback := db.Callback()
+	callback.Create().Before("gorm:create").Register("trace:before_create", gormTraceBefore("gorm.create"))
+	callback.Create().After("gorm:create").Register("trace:after_create", gormTraceAfter)
+	callback.Query().Before("gorm:query").Register("trace:before_query", gormTraceBefore("gorm.query"))
+	callback.Query().After("gorm:query").Register("trace:after_query", gormTraceAfter)
+	callback.Update().Before("gorm:update").Register("trace:before_update", gormTraceBefore("gorm.update"))
+	callback.Update().After("gorm:update").Register("trace:after_update", gormTraceAfter)
+	callback.Delete().Before("gorm:delete").Register("trace:before_delete", gormTraceBefore("gorm.delete"))
+	callback.Delete().After("gorm:delete").Register("trace:after_delete", gormTraceAfter)
+	callback.RowQuery().Before("gorm:row_query").Register("trace:before_row_query", gormTraceBefore("gorm.row_query"))
+	callback.RowQuery().After("gorm:row_query").Register("trace:after_row_query", gormTraceAfter)
+}
+
+func gormTraceBefore(name string) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		ctx, ok := scope.DB().GetCtx()
+		if !ok {
+			return
+		}
+		trace, ok := ctx.(*lib.TraceContext)
+		if !ok {
+			return
+		}
+		span := StartChildSpan(trace, name, SpanKindClient)
+		if span == nil {
+			return
+		}
+		span.SetAttribute("db.system", gormDBSystem(scope))
+		span.SetAttribute("db.sql.table", scope.TableName())
+		scope.Set(gormSpanKey, span)
+	}
+}
+
+func gormTraceAfter(scope *gorm.Scope) {
+	value, ok := scope.Get(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(*Span)
+	if !ok {
+		return
+	}
+	span.SetAttribute("db.statement", scope.SQL)
+	if err := scope.DB().Error; err != nil && err != gorm.ErrRecordNotFound {
+		span.SetError(err)
+	}
+	span.End()
+}
+
+// gormDBSystem 返回 OpenTelemetry 约定的 db.system
+func gormDBSystem(scope *gorm.Scope) string {
+	if scope.Dialect().GetName() == "sqlite3" {
+		return "sqlite"
+	}
+	return scope.Dialect().GetName()
+}
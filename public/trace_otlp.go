@@ -0,0 +1,149 @@
+package public
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OTLPSpanExporter 按批次以 OTLP/HTTP JSON 格式上报 span
+type OTLPSpanExporter struct {
+	Endpoint    string
+	ServiceName string
+	Interval    time.Duration
+	BatchSize   int
+	Client      *http.Client
+	queue       chan *Span
+}
+
+func NewOTLPSpanExporter(endpoint, serviceName string, interval time.Duration, batchSize int) *OTLPSpanExporter {
+	if endpoint == "" {
+		endpoint = "http://127.0.0.1:4318/v1/traces"
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	if batchSize <= 0 {
+		batchSize = 512
+	}
+	exporter := &OTLPSpanExporter{
+		Endpoint:    endpoint,
+		ServiceName: serviceName,
+		Interval:    interval,
+		BatchSize:   batchSize,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan *Span, batchSize*4),
+	}
+	go exporter.run()
+	return exporter
+}
+
+// Export 队列满时直接丢弃，不阻塞请求
+func (e *OTLPSpanExporter) Export(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+	}
+}
+
+func (e *OTLPSpanExporter) run() {
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+	ticker := time.NewTicker(e.Interval)
+	batch := []*Span{}
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) < e.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		if err := e.send(batch); err != nil {
+			fmt.Println("OTLPSpanExporter send err", err)
+		}
+		batch = []*Span{}
+	}
+}
+
+func (e *OTLPSpanExporter) send(batch []*Span) error {
+	spans := []map[string]interface{}{}
+	for _, span := range batch {
+		spans = append(spans, otlpSpan(span))
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": []interface{}{otlpAttribute("service.name", e.ServiceName)},
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "github.com/JunxiHe459/gateway"},
+						"spans": spans,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := e.Client.Post(e.Endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("otlp endpoint returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func otlpSpan(span *Span) map[string]interface{} {
+	span.locker.Lock()
+	defer span.locker.Unlock()
+	keys := []string{}
+	for key := range span.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	attributes := []interface{}{}
+	for _, key := range keys {
+		attributes = append(attributes, otlpAttribute(key, span.Attributes[key]))
+	}
+	item := map[string]interface{}{
+		"traceId":           span.TraceID,
+		"spanId":            span.SpanID,
+		"name":              span.Name,
+		"kind":              span.Kind,
+		"startTimeUnixNano": strconv.FormatInt(span.StartTime.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+		"attributes":        attributes,
+	}
+	if span.ParentSpanID != "" {
+		item["parentSpanId"] = span.ParentSpanID
+	}
+	if span.StatusCode != 0 {
+		item["status"] = map[string]interface{}{"code": span.StatusCode, "message": span.StatusMessage}
+	}
+	return item
+}
+
+func otlpAttribute(key, value string) map[string]interface{} {
+	return map[string]interface{}{
+		"key":   key,
+		"value": map[string]interface{}{"stringValue": value},
+	}
+}
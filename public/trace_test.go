@@ -0,0 +1,90 @@
+package public
+
+import (
+	"context"
+	"github.com/e421083458/golang_common/lib"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func useTestExporter(t *testing.T) *InMemorySpanExporter {
+	exporter := NewInMemorySpanExporter()
+	TracerHandler.SetExporter("gateway_test", exporter)
+	t.Cleanup(func() {
+		TracerHandler.SetExporter("gateway", nil)
+	})
+	return exporter
+}
+
+func TestTraceTransportChildSpan(t *testing.T) {
+	exporter := useTestExporter(t)
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(TraceparentHeader)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer upstream.Close()
+
+	parent := StartSpan(nil, "GET demo", SpanKindServer)
+	ctx := context.WithValue(context.Background(), "trace", parent.TraceContext())
+	req, _ := http.NewRequest(http.MethodGet, upstream.URL, nil)
+	resp, err := (&TraceTransport{}).RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	child := spans[0]
+	if child.TraceID != parent.TraceID || child.ParentSpanID != parent.SpanID || child.Kind != SpanKindClient {
+		t.Errorf("child span not linked to parent: %+v", child)
+	}
+	if child.StatusCode != SpanStatusError || child.Attributes["http.status_code"] != "502" {
+		t.Errorf("5xx not recorded on span: %+v", child)
+	}
+	if want := child.Traceparent(); traceparent != want {
+		t.Errorf("upstream got traceparent %q, want %q", traceparent, want)
+	}
+	if req.Header.Get(TraceparentHeader) != "" {
+		t.Error("original request header modified")
+	}
+}
+
+func TestTraceTransportWithoutSpan(t *testing.T) {
+	exporter := useTestExporter(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+
+	// 后台任务的 trace 不属于任何 span
+	ctx := context.WithValue(context.Background(), "trace", lib.NewTrace())
+	req, _ := http.NewRequest(http.MethodGet, upstream.URL, nil)
+	resp, err := (&TraceTransport{}).RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("got %d orphan spans", len(spans))
+	}
+}
+
+func TestStartChildSpanAfterEnd(t *testing.T) {
+	useTestExporter(t)
+	parent := StartSpan(nil, "job", SpanKindInternal)
+	trace := parent.TraceContext()
+	if StartChildSpan(trace, "gorm.query", SpanKindClient) == nil {
+		t.Fatal("child span not created for active parent")
+	}
+	parent.End()
+	if StartChildSpan(trace, "gorm.query", SpanKindClient) != nil {
+		t.Error("child span created after parent ended")
+	}
+	if StartChildSpan(lib.NewTrace(), "gorm.query", SpanKindClient) != nil {
+		t.Error("child span created for trace without span")
+	}
+}
@@ -0,0 +1,49 @@
+package public
+
+import (
+	"fmt"
+	"github.com/e421083458/golang_common/lib"
+	"net/http"
+)
+
+// TraceTransport 为每次上游请求（包括重试）创建 client span 并写入 traceparent
+// 父节点取自 request context 中的 "trace"，没有请求 span 时直接透传
+type TraceTransport struct {
+	Base http.RoundTripper
+}
+
+func (t *TraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	trace, _ := req.Context().Value("trace").(*lib.TraceContext)
+	span := StartChildSpan(trace, "upstream "+req.Method, SpanKindClient)
+	if span == nil {
+		return base.RoundTrip(req)
+	}
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.String())
+	span.SetAttribute("net.peer.name", req.URL.Host)
+	defer span.End()
+
+	outReq := req.Clone(req.Context())
+	outReq.Header.Set(TraceparentHeader, span.Traceparent())
+	resp, err := base.RoundTrip(outReq)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetError(fmt.Errorf("upstream returned %d", resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// CloseIdleConnections 透传给底层连接池，服务删除时释放空闲连接
+func (t *TraceTransport) CloseIdleConnections() {
+	if closer, ok := t.Base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,184 @@
+package public
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 转换规则 多条用逗号隔开，字段支持 a.b.c 形式的嵌套路径
+// add field value      设置 json 字段，value 支持 {client_ip} 这类变量
+// del field            删除 json 字段
+// rename field newname 重命名 json 字段
+// header name value    设置 header，value 支持变量
+// value 取第二个空格之后的全部内容，可以包含空格，不能包含逗号
+// add 的 value 是合法 json 时按 json 写入，变量只替换其中的字符串值
+type TransformRule struct {
+	Op        string
+	Key       string
+	Value     string
+	IsJSON    bool
+	JSONValue interface{}
+}
+
+func ParseTransformRules(s string) ([]TransformRule, error) {
+	rules := []TransformRule{}
+	if s == "" {
+		return rules, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		items := strings.SplitN(item, " ", 3)
+		switch {
+		case items[0] == TransformOpDel && len(items) == 2:
+			rules = append(rules, TransformRule{Op: items[0], Key: items[1]})
+		case items[0] == TransformOpRename && len(items) == 3 && !strings.Contains(items[2], " "):
+			rules = append(rules, TransformRule{Op: items[0], Key: items[1], Value: items[2]})
+		case items[0] == TransformOpAdd && len(items) == 3:
+			rule := TransformRule{Op: items[0], Key: items[1], Value: items[2]}
+			// 模板在解析时就确定结构，变量内容不能再改变 json 结构
+			rule.IsJSON = decodeJSONUseNumber([]byte(items[2]), &rule.JSONValue) == nil
+			rules = append(rules, rule)
+		case items[0] == TransformOpHeader && len(items) == 3:
+			rules = append(rules, TransformRule{Op: items[0], Key: items[1], Value: items[2]})
+		default:
+			return nil, fmt.Errorf("invalid transform rule: %s", item)
+		}
+	}
+	return rules, nil
+}
+
+// RenderTemplate 把 {name} 替换为 vars 中对应的值，未知变量替换为空
+func RenderTemplate(tpl string, vars map[string]string) string {
+	if !strings.Contains(tpl, "{") {
+		return tpl
+	}
+	var out strings.Builder
+	for {
+		start := strings.Index(tpl, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(tpl[start:], "}")
+		if end < 0 {
+			break
+		}
+		out.WriteString(tpl[:start])
+		out.WriteString(vars[tpl[start+1:start+end]])
+		tpl = tpl[start+end+1:]
+	}
+	out.WriteString(tpl)
+	return out.String()
+}
+
+// TransformJSONBody 对 json 对象依次执行 add/del/rename 规则，header 规则会被忽略
+// 数字按 json.Number 保留原文，超过 2^53 的整数不会丢失精度
+func TransformJSONBody(body []byte, rules []TransformRule, vars map[string]string) ([]byte, error) {
+	doc := map[string]interface{}{}
+	if err := decodeJSONUseNumber(body, &doc); err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		switch rule.Op {
+		case TransformOpAdd:
+			var value interface{} = RenderTemplate(rule.Value, vars)
+			if rule.IsJSON {
+				value = renderJSONValue(rule.JSONValue, vars)
+			}
+			if err := setJSONField(doc, rule.Key, value); err != nil {
+				return nil, err
+			}
+		case TransformOpDel:
+			deleteJSONField(doc, rule.Key)
+		case TransformOpRename:
+			value, ok := getJSONField(doc, rule.Key)
+			if !ok {
+				continue
+			}
+			deleteJSONField(doc, rule.Key)
+			if err := setJSONField(doc, rule.Value, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return json.Marshal(doc)
+}
+
+// renderJSONValue 复制 json 值，只对字符串叶子做变量替换，转义由 json.Marshal 完成
+func renderJSONValue(value interface{}, vars map[string]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return RenderTemplate(v, vars)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = renderJSONValue(item, vars)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for index, item := range v {
+			out[index] = renderJSONValue(item, vars)
+		}
+		return out
+	}
+	return value
+}
+
+func decodeJSONUseNumber(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	// 只允许一个 json 值，"1 2" 这类内容按字符串处理
+	if decoder.More() {
+		return errors.New("unexpected data after json value")
+	}
+	return nil
+}
+
+func getJSONField(doc map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	current := doc
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	value, ok := current[keys[len(keys)-1]]
+	return value, ok
+}
+
+func setJSONField(doc map[string]interface{}, path string, value interface{}) error {
+	keys := strings.Split(path, ".")
+	current := doc
+	for _, key := range keys[:len(keys)-1] {
+		if _, ok := current[key]; !ok {
+			current[key] = map[string]interface{}{}
+		}
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			return errors.New("transform field is not an object: " + key)
+		}
+		current = next
+	}
+	current[keys[len(keys)-1]] = value
+	return nil
+}
+
+func deleteJSONField(doc map[string]interface{}, path string) {
+	keys := strings.Split(path, ".")
+	current := doc
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		current = next
+	}
+	delete(current, keys[len(keys)-1])
+}
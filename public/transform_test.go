@@ -0,0 +1,56 @@
+package public
+
+import (
+	"testing"
+)
+
+func TestTransformJSONBodyKeepsLargeIntegers(t *testing.T) {
+	rules, err := ParseTransformRules("add extra 9007199254740993,rename id user_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := TransformJSONBody([]byte(`{"id":9007199254740993,"price":1.10}`), rules, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"extra":9007199254740993,"price":1.10,"user_id":9007199254740993}`
+	if string(out) != want {
+		t.Fatalf("got %s, want %s", out, want)
+	}
+}
+
+func TestParseTransformRulesValueWithSpaces(t *testing.T) {
+	rules, err := ParseTransformRules("header X-Note hello {client_ip} world,add msg a b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules[0].Value != "hello {client_ip} world" || rules[1].Value != "a b" {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+	out, err := TransformJSONBody([]byte(`{}`), rules, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"msg":"a b"}` {
+		t.Fatalf("got %s", out)
+	}
+	if _, err := ParseTransformRules("rename a b c"); err == nil {
+		t.Fatal("rename target with spaces should be rejected")
+	}
+}
+
+func TestTransformJSONBodyVariablesStayStrings(t *testing.T) {
+	rules, err := ParseTransformRules(`add meta {"ip":"{client_ip}"},add note {client_ip}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]string{"client_ip": `x","admin":true,"y":"`}
+	out, err := TransformJSONBody([]byte(`{}`), rules, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"meta":{"ip":"x\",\"admin\":true,\"y\":\""},"note":"x\",\"admin\":true,\"y\":\""}`
+	if string(out) != want {
+		t.Fatalf("got %s, want %s", out, want)
+	}
+}
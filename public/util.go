@@ -2,7 +2,9 @@ package public
 
 import (
 	"crypto/md5"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -20,6 +22,13 @@ func SaltPassword(salt, password string) string {
 
 }
 
+// NewSalt 生成 16 字节随机盐
+func NewSalt() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 //MD5 md5加密
 func MD5(s string) string {
 	h := md5.New()
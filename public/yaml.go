@@ -0,0 +1,104 @@
+package public
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+)
+
+// 配置文档只定义 json tag，yaml 与 json 之间转换，yaml 输出保持 json 字段顺序
+
+// JSONToYAML 按 json 中字段出现的顺序输出 yaml
+func JSONToYAML(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	value, err := decodeOrderedJSON(decoder)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(value)
+}
+
+// YAMLToJSON json 是 yaml 的子集，两种格式的文档都可以直接传入
+func YAMLToJSON(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	value, err := convertYAMLValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+func decodeOrderedJSON(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch item := token.(type) {
+	case json.Delim:
+		if item == '{' {
+			out := yaml.MapSlice{}
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeOrderedJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, yaml.MapItem{Key: key, Value: value})
+			}
+			_, err := decoder.Token()
+			return out, err
+		}
+		out := []interface{}{}
+		for decoder.More() {
+			value, err := decodeOrderedJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, value)
+		}
+		_, err := decoder.Token()
+		return out, err
+	case json.Number:
+		if number, err := item.Int64(); err == nil {
+			return number, nil
+		}
+		return item.Float64()
+	default:
+		return item, nil
+	}
+}
+
+func convertYAMLValue(value interface{}) (interface{}, error) {
+	switch item := value.(type) {
+	case map[interface{}]interface{}:
+		out := map[string]interface{}{}
+		for key, child := range item {
+			childValue, err := convertYAMLValue(child)
+			if err != nil {
+				return nil, err
+			}
+			out[fmt.Sprint(key)] = childValue
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(item))
+		for index, child := range item {
+			childValue, err := convertYAMLValue(child)
+			if err != nil {
+				return nil, err
+			}
+			out[index] = childValue
+		}
+		return out, nil
+	default:
+		return item, nil
+	}
+}
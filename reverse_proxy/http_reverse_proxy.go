@@ -0,0 +1,66 @@
+package reverse_proxy
+
+import (
+	"fmt"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// NewLoadBalanceReverseProxy 把请求转发到 target，target 由调用方从负载均衡中取得
+// modifyResponse 可以为空，上游请求失败时返回 502，计入 5xx 统计
+func NewLoadBalanceReverseProxy(c *gin.Context, target *url.URL, trans http.RoundTripper, modifyResponse func(*http.Response) error) *httputil.ReverseProxy {
+	director := func(req *http.Request) {
+		targetQuery := target.RawQuery
+		req.URL.Scheme = target.Scheme
+		req.URL.Host = target.Host
+		req.URL.Path = singleJoiningSlash(target.Path, req.URL.Path)
+		req.URL.RawPath = ""
+		req.Host = target.Host
+		if targetQuery == "" || req.URL.RawQuery == "" {
+			req.URL.RawQuery = targetQuery + req.URL.RawQuery
+		} else {
+			req.URL.RawQuery = targetQuery + "&" + req.URL.RawQuery
+		}
+		if _, ok := req.Header["User-Agent"]; !ok {
+			req.Header.Set("User-Agent", "")
+		}
+	}
+
+	errFunc := func(w http.ResponseWriter, r *http.Request, err error) {
+		public.ComLogWarning(c, "_com_proxy_failure", map[string]interface{}{
+			"upstream": target.Host,
+			"error":    err.Error(),
+		})
+		http.Error(w, fmt.Sprintf("upstream %s unavailable", target.Host), http.StatusBadGateway)
+	}
+	return &httputil.ReverseProxy{
+		Director:       director,
+		Transport:      trans,
+		ModifyResponse: modifyResponse,
+		ErrorHandler:   errFunc,
+	}
+}
+
+// ParseUpstream 负载均衡返回的地址不带 scheme 时按 http 处理
+func ParseUpstream(addr string) (*url.URL, error) {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return url.Parse(addr)
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
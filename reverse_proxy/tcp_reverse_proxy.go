@@ -0,0 +1,81 @@
+package reverse_proxy
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"time"
+)
+
+func NewTcpLoadBalanceReverseProxy(addr string) *TcpReverseProxy {
+	return &TcpReverseProxy{
+		Addr:            addr,
+		KeepAlivePeriod: time.Second,
+		DialTimeout:     time.Second,
+	}
+}
+
+// TcpReverseProxy 与上游建立连接后双向复制数据，任意一端关闭即结束
+type TcpReverseProxy struct {
+	Addr            string
+	KeepAlivePeriod time.Duration
+	DialTimeout     time.Duration
+	DialContext     func(ctx context.Context, network, address string) (net.Conn, error)
+	OnDialError     func(src net.Conn, dstDialErr error)
+}
+
+func (dp *TcpReverseProxy) dialTimeout() time.Duration {
+	if dp.DialTimeout > 0 {
+		return dp.DialTimeout
+	}
+	return 10 * time.Second
+}
+
+func (dp *TcpReverseProxy) dialContext() func(ctx context.Context, network, address string) (net.Conn, error) {
+	if dp.DialContext != nil {
+		return dp.DialContext
+	}
+	return (&net.Dialer{
+		Timeout:   dp.dialTimeout(),
+		KeepAlive: dp.keepAlivePeriod(),
+	}).DialContext
+}
+
+func (dp *TcpReverseProxy) keepAlivePeriod() time.Duration {
+	if dp.KeepAlivePeriod != 0 {
+		return dp.KeepAlivePeriod
+	}
+	return time.Minute
+}
+
+func (dp *TcpReverseProxy) ServeTCP(ctx context.Context, src net.Conn) {
+	dialCtx, cancel := context.WithTimeout(ctx, dp.dialTimeout())
+	dst, err := dp.dialContext()(dialCtx, "tcp", dp.Addr)
+	cancel()
+	if err != nil {
+		dp.onDialError()(src, err)
+		return
+	}
+	defer dst.Close()
+
+	errc := make(chan error, 2)
+	go dp.proxyCopy(errc, src, dst)
+	go dp.proxyCopy(errc, dst, src)
+	<-errc
+}
+
+func (dp *TcpReverseProxy) onDialError() func(src net.Conn, dstDialErr error) {
+	if dp.OnDialError != nil {
+		return dp.OnDialError
+	}
+	return func(src net.Conn, dstDialErr error) {
+		log.Printf("tcpproxy: for incoming conn %v, error dialing %q: %v", src.RemoteAddr().String(), dp.Addr, dstDialErr)
+		src.Close()
+	}
+}
+
+func (dp *TcpReverseProxy) proxyCopy(errc chan<- error, dst, src net.Conn) {
+	_, err := io.Copy(dst, src)
+	errc <- err
+}
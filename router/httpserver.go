@@ -23,6 +23,7 @@ func HttpServerRun() {
 		WriteTimeout:   time.Duration(lib.GetIntConf("base.http.write_timeout")) * time.Second,
 		MaxHeaderBytes: 1 << uint(lib.GetIntConf("base.http.max_header_bytes")),
 	}
+	MetricsServerRun()
 	go func() {
 		log.Printf(" [INFO] HttpServerRun:%s\n", lib.GetStringConf("base.http.addr"))
 		if err := HttpSrvHandler.ListenAndServe(); err != nil {
@@ -32,6 +33,7 @@ func HttpServerRun() {
 }
 
 func HttpServerStop() {
+	MetricsServerStop()
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if err := HttpSrvHandler.Shutdown(ctx); err != nil {
@@ -0,0 +1,48 @@
+package router
+
+import (
+	"context"
+	"github.com/JunxiHe459/gateway/controller"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"time"
+)
+
+var MetricsSrvHandler *http.Server
+
+// MetricsServerRun 配置了 base.metrics.addr 时在单独的地址提供 /metrics，管理端口不再暴露
+func MetricsServerRun() {
+	addr := lib.GetStringConf("base.metrics.addr")
+	if addr == "" {
+		return
+	}
+	r := gin.New()
+	r.Use(gin.Recovery())
+	controller.MetricsRegister(r)
+	MetricsSrvHandler = &http.Server{
+		Addr:         addr,
+		Handler:      r,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Printf(" [INFO] MetricsServerRun:%s\n", addr)
+		if err := MetricsSrvHandler.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf(" [ERROR] MetricsServerRun:%s err:%v\n", addr, err)
+		}
+	}()
+}
+
+func MetricsServerStop() {
+	if MetricsSrvHandler == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := MetricsSrvHandler.Shutdown(ctx); err != nil {
+		log.Printf(" [ERROR] MetricsServerStop err:%v\n", err)
+	}
+	log.Printf(" [INFO] MetricsServerStop stopped\n")
+}
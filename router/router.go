@@ -9,7 +9,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/swaggo/files"
 	"github.com/swaggo/gin-swagger"
-	"log"
 )
 
 // @title Swagger Example API
@@ -78,16 +77,18 @@ func InitRouter(middlewares ...gin.HandlerFunc) *gin.Engine {
 		})
 	})
 
+	// prometheus 指标，配置了 base.metrics.addr 时由 MetricsServerRun 单独监听
+	if lib.GetStringConf("base.metrics.addr") == "" {
+		controller.MetricsRegister(router)
+	}
+
 	// AdminGroup 路由分组
 	adminGroup := router.Group("/admin")
-	redis, err := sessions.NewRedisStore(10, "tcp", "localhost:6379", "Karlhe459!", []byte("secret"))
-	if err != nil {
-		print("NewRedisStore Error: ", err.Error())
-		log.Fatalf("NewRedisStore Error: %v", err.Error())
-	}
+	// session 存储由 base.state 与 base.session 配置，redis 不可用时降级为本地保存
+	sessionStore := middleware.NewSessionStore()
 	// 为 adminGroup 启用中间件
 	adminGroup.Use(
-		sessions.Sessions("AdminSession", redis),
+		sessions.Sessions("AdminSession", sessionStore),
 		middleware.RecoveryMiddleware(),
 		middleware.RequestLog(),
 		middleware.ParamValidationMiddleware(),
@@ -100,13 +101,9 @@ func InitRouter(middlewares ...gin.HandlerFunc) *gin.Engine {
 
 	// 为 adminInfoGroup 注册 Admin Info	 到 /admin/info 这个路径
 	adminInfoGroup := router.Group("/admin/info")
-	if err != nil {
-		print("NewRedisStore Error: ", err.Error())
-		log.Fatalf("NewRedisStore Error: %v", err.Error())
-	}
 	// 为 adminGroup 启用中间件
 	adminInfoGroup.Use(
-		sessions.Sessions("AdminSession", redis),
+		sessions.Sessions("AdminSession", sessionStore),
 		middleware.RecoveryMiddleware(),
 		middleware.RequestLog(),
 		// 用来校验 session 的一个中间件
@@ -114,27 +111,40 @@ func InitRouter(middlewares ...gin.HandlerFunc) *gin.Engine {
 		middleware.ParamValidationMiddleware(),
 	)
 	controller.RegiterAdminInfo(adminInfoGroup)
+	controller.AdminTOTPRegister(adminInfoGroup)
+
+	// 管理员账号管理，只有超级管理员可以访问
+	adminUserGroup := router.Group("/admin_user")
+	adminUserGroup.Use(
+		sessions.Sessions("AdminSession", sessionStore),
+		middleware.RecoveryMiddleware(),
+		middleware.RequestLog(),
+		middleware.TokenAuthMiddleware(),
+		middleware.SessionAuthMiddleware(),
+		middleware.ParamValidationMiddleware(),
+	)
+	controller.AdminUserRegister(adminUserGroup)
 
 	// Service
 	serviceGroup := router.Group("/service")
-	if err != nil {
-		print("NewRedisStore Error: ", err.Error())
-		log.Fatalf("NewRedisStore Error: %v", err.Error())
-	}
 	serviceGroup.Use(
-		sessions.Sessions("AdminSession", redis),
+		sessions.Sessions("AdminSession", sessionStore),
 		middleware.RecoveryMiddleware(),
 		middleware.RequestLog(),
+		middleware.TokenAuthMiddleware(),
 		middleware.SessionAuthMiddleware(),
 		middleware.ParamValidationMiddleware(),
 	)
 	controller.RegisterService(serviceGroup)
+	controller.FaultRuleRegister(serviceGroup)
+	controller.RequestTailRegister(serviceGroup)
 
 	renterGroup := router.Group("/renter")
 	renterGroup.Use(
-		sessions.Sessions("AdminSession", redis),
+		sessions.Sessions("AdminSession", sessionStore),
 		middleware.RecoveryMiddleware(),
 		middleware.RequestLog(),
+		middleware.TokenAuthMiddleware(),
 		middleware.SessionAuthMiddleware(),
 		middleware.ParamValidationMiddleware(),
 	)
@@ -142,14 +152,69 @@ func InitRouter(middlewares ...gin.HandlerFunc) *gin.Engine {
 
 	dashboardGroup := router.Group("/dashboard")
 	dashboardGroup.Use(
-		sessions.Sessions("AdminSession", redis),
+		sessions.Sessions("AdminSession", sessionStore),
 		middleware.RecoveryMiddleware(),
 		middleware.RequestLog(),
+		middleware.TokenAuthMiddleware(),
 		middleware.SessionAuthMiddleware(),
 		middleware.ParamValidationMiddleware(),
 	)
 	controller.DashboardRegister(dashboardGroup)
 
+	alertGroup := router.Group("/alert")
+	alertGroup.Use(
+		sessions.Sessions("AdminSession", sessionStore),
+		middleware.RecoveryMiddleware(),
+		middleware.RequestLog(),
+		middleware.TokenAuthMiddleware(),
+		middleware.SessionAuthMiddleware(),
+		middleware.ParamValidationMiddleware(),
+	)
+	controller.AlertRuleRegister(alertGroup)
+
+	auditGroup := router.Group("/audit")
+	auditGroup.Use(
+		sessions.Sessions("AdminSession", sessionStore),
+		middleware.RecoveryMiddleware(),
+		middleware.RequestLog(),
+		middleware.TokenAuthMiddleware(),
+		middleware.SessionAuthMiddleware(),
+		middleware.ParamValidationMiddleware(),
+	)
+	controller.AuditRegister(auditGroup)
+
+	configGroup := router.Group("/config")
+	configGroup.Use(
+		sessions.Sessions("AdminSession", sessionStore),
+		middleware.RecoveryMiddleware(),
+		middleware.RequestLog(),
+		middleware.TokenAuthMiddleware(),
+		middleware.SessionAuthMiddleware(),
+		middleware.ParamValidationMiddleware(),
+	)
+	controller.GatewayConfigRegister(configGroup)
+
+	// API token 只能通过登录 session 管理，token 不能再创建 token
+	adminTokenGroup := router.Group("/admin_token")
+	adminTokenGroup.Use(
+		sessions.Sessions("AdminSession", sessionStore),
+		middleware.RecoveryMiddleware(),
+		middleware.RequestLog(),
+		middleware.SessionAuthMiddleware(),
+		middleware.ParamValidationMiddleware(),
+	)
+	controller.AdminTokenRegister(adminTokenGroup)
+
+	// API token 换取 JWT，只接受 API token 认证
+	adminJwtGroup := router.Group("/admin_token")
+	adminJwtGroup.Use(
+		middleware.RecoveryMiddleware(),
+		middleware.RequestLog(),
+		middleware.TokenAuthMiddleware(),
+		middleware.ParamValidationMiddleware(),
+	)
+	controller.AdminJwtRegister(adminJwtGroup)
+
 	return router
 
 }
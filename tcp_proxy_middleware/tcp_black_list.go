@@ -0,0 +1,39 @@
+package tcp_proxy_middleware
+
+import (
+	"fmt"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/public"
+	"strings"
+)
+
+// ip 黑名单，白名单优先级更高，设置了白名单时不检查黑名单
+func TCPBlackListMiddleware() func(c *TcpSliceRouterContext) {
+	return func(c *TcpSliceRouterContext) {
+		serverInterface := c.Get("service")
+		if serverInterface == nil {
+			c.conn.Write([]byte("get service empty"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+
+		whiteIpList := []string{}
+		if serviceDetail.AccessControl.WhiteList != "" {
+			whiteIpList = strings.Split(serviceDetail.AccessControl.WhiteList, ",")
+		}
+		blackIpList := []string{}
+		if serviceDetail.AccessControl.BlackList != "" {
+			blackIpList = strings.Split(serviceDetail.AccessControl.BlackList, ",")
+		}
+		clientIP := c.ClientIP()
+		if serviceDetail.AccessControl.OpenAuth == 1 && len(whiteIpList) == 0 && len(blackIpList) > 0 {
+			if public.InStringSlice(blackIpList, clientIP) {
+				c.conn.Write([]byte(fmt.Sprintf("%s in black ip list", clientIP)))
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package tcp_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/public"
+	"net"
+	"time"
+)
+
+// 故障注入，tcp 支持建立上游连接前延迟、关闭与重置客户端连接
+func TCPFaultInjectionMiddleware() func(c *TcpSliceRouterContext) {
+	return func(c *TcpSliceRouterContext) {
+		serverInterface := c.Get("service")
+		if serverInterface == nil {
+			c.conn.Write([]byte("get service empty"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+
+		faultRule := dao.FaultRuleManagerHandler.GetFaultRule(serviceDetail.Info.ID, "", nil)
+		if faultRule == nil {
+			c.Next()
+			return
+		}
+		switch faultRule.FaultType {
+		case public.FaultTypeDelay:
+			select {
+			case <-time.After(faultRule.Delay()):
+			case <-c.Ctx.Done():
+				c.Abort()
+				return
+			}
+		case public.FaultTypeAbort:
+			// tcp 没有状态码，正常关闭连接
+			c.conn.Close()
+			c.Abort()
+			return
+		case public.FaultTypeReset:
+			// SO_LINGER=0 使 Close 直接发送 RST
+			if tcpConn, ok := c.conn.(*net.TCPConn); ok {
+				tcpConn.SetLinger(0)
+			}
+			c.conn.Close()
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
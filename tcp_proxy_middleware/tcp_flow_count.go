@@ -0,0 +1,26 @@
+package tcp_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/public"
+)
+
+// 流量统计，每个 tcp 连接计一次全站与服务请求
+func TCPFlowCountMiddleware() func(c *TcpSliceRouterContext) {
+	return func(c *TcpSliceRouterContext) {
+		serverInterface := c.Get("service")
+		if serverInterface == nil {
+			c.conn.Write([]byte("get service empty"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+
+		if err := public.FlowCounterHandler.IncreaseServiceFlow(serviceDetail.Info.ServiceName); err != nil {
+			c.conn.Write([]byte(err.Error()))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package tcp_proxy_middleware
+
+import (
+	"fmt"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/public"
+)
+
+// 服务端与客户端 ip 限流，tcp 以新建连接计数
+func TCPFlowLimitMiddleware() func(c *TcpSliceRouterContext) {
+	return func(c *TcpSliceRouterContext) {
+		serverInterface := c.Get("service")
+		if serverInterface == nil {
+			c.conn.Write([]byte("get service empty"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+		serviceName := serviceDetail.Info.ServiceName
+
+		if serviceDetail.AccessControl.ServiceFlowLimit != 0 {
+			serviceLimiter, err := public.FlowLimiterHandler.GetLimiter(
+				public.FlowServicePrefix+serviceName,
+				float64(serviceDetail.AccessControl.ServiceFlowLimit))
+			if err != nil {
+				c.conn.Write([]byte(err.Error()))
+				c.Abort()
+				return
+			}
+			if !serviceLimiter.Allow() {
+				public.MetricRateLimitRejections.Inc(serviceName, public.MetricsRenterNone, "service")
+				c.conn.Write([]byte(fmt.Sprintf("service flow limit %v", serviceDetail.AccessControl.ServiceFlowLimit)))
+				c.Abort()
+				return
+			}
+		}
+
+		clientIP := c.ClientIP()
+		if serviceDetail.AccessControl.ClientIPFlowLimit > 0 {
+			clientLimiter, err := public.FlowLimiterHandler.GetLimiter(
+				public.FlowServicePrefix+serviceName+"_"+clientIP,
+				float64(serviceDetail.AccessControl.ClientIPFlowLimit))
+			if err != nil {
+				c.conn.Write([]byte(err.Error()))
+				c.Abort()
+				return
+			}
+			if !clientLimiter.Allow() {
+				public.MetricRateLimitRejections.Inc(serviceName, public.MetricsRenterNone, "client_ip")
+				c.conn.Write([]byte(fmt.Sprintf("%v flow limit %v", clientIP, serviceDetail.AccessControl.ClientIPFlowLimit)))
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package tcp_proxy_middleware
+
+import (
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/public"
+	"time"
+)
+
+// prometheus 指标，tcp 以连接为单位统计
+func TCPMetricsMiddleware() func(c *TcpSliceRouterContext) {
+	return func(c *TcpSliceRouterContext) {
+		serverInterface := c.Get("service")
+		if serverInterface == nil {
+			c.conn.Write([]byte("get service empty"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+		serviceName := serviceDetail.Info.ServiceName
+		loadType := public.MetricsLoadTypeLabel(serviceDetail.Info.LoadType)
+
+		public.MetricActiveConnections.Inc(serviceName, loadType)
+		defer public.MetricActiveConnections.Dec(serviceName, loadType)
+		startTime := time.Now()
+		c.Next()
+
+		public.MetricRequestTotal.Inc(serviceName, public.MetricsRenterNone, loadType, "closed")
+		public.MetricRequestDuration.Observe(time.Since(startTime).Seconds(), serviceName, loadType)
+	}
+}
@@ -0,0 +1,35 @@
+package tcp_proxy_middleware
+
+import (
+	"fmt"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/public"
+	"strings"
+)
+
+// ip 白名单，开启权限验证且设置了白名单时生效
+func TCPWhiteListMiddleware() func(c *TcpSliceRouterContext) {
+	return func(c *TcpSliceRouterContext) {
+		serverInterface := c.Get("service")
+		if serverInterface == nil {
+			c.conn.Write([]byte("get service empty"))
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*dao.ServiceDetail)
+
+		iplist := []string{}
+		if serviceDetail.AccessControl.WhiteList != "" {
+			iplist = strings.Split(serviceDetail.AccessControl.WhiteList, ",")
+		}
+		clientIP := c.ClientIP()
+		if serviceDetail.AccessControl.OpenAuth == 1 && len(iplist) > 0 {
+			if !public.InStringSlice(iplist, clientIP) {
+				c.conn.Write([]byte(fmt.Sprintf("%s not in white ip list", clientIP)))
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,17 @@
+package tcp_proxy_router
+
+import (
+	"context"
+	"log"
+	"net"
+)
+
+// tcpErrorHandler 取不到下游节点时记录原因并关闭连接
+type tcpErrorHandler struct {
+	err error
+}
+
+func (h *tcpErrorHandler) ServeTCP(ctx context.Context, conn net.Conn) {
+	log.Printf(" [ERROR] tcp_proxy %v err:%v\n", conn.RemoteAddr(), h.err)
+	conn.Close()
+}
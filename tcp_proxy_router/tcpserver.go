@@ -0,0 +1,132 @@
+package tcp_proxy_router
+
+import (
+	"context"
+	"fmt"
+	"github.com/JunxiHe459/gateway/dao"
+	"github.com/JunxiHe459/gateway/public"
+	"github.com/JunxiHe459/gateway/reverse_proxy"
+	"github.com/JunxiHe459/gateway/tcp_proxy_middleware"
+	"github.com/JunxiHe459/gateway/tcp_server"
+	"log"
+	"net"
+	"reflect"
+	"sync"
+)
+
+// tcpServerMap 按服务 id 记录正在监听的 server 及启动时使用的配置
+var (
+	tcpServerMap = map[int64]*tcpServerItem{}
+	tcpLocker    sync.Mutex
+)
+
+type tcpServerItem struct {
+	server        *tcp_server.TcpServer
+	serviceDetail *dao.ServiceDetail
+}
+
+// TcpServerRun 每个 tcp 服务监听自己的端口
+func TcpServerRun() {
+	tcpLocker.Lock()
+	defer tcpLocker.Unlock()
+	for _, serviceItem := range dao.ServiceManagerHandler.GetTcpServiceList() {
+		tcpServerStart(serviceItem)
+	}
+}
+
+// TcpServerReload 按当前服务列表调整监听：删除或配置变化的服务先关闭，再启动新增与变化的服务
+func TcpServerReload() {
+	tcpLocker.Lock()
+	defer tcpLocker.Unlock()
+	pending := map[int64]*dao.ServiceDetail{}
+	for _, serviceItem := range dao.ServiceManagerHandler.GetTcpServiceList() {
+		pending[serviceItem.Info.ID] = serviceItem
+	}
+	for serviceID, item := range tcpServerMap {
+		if serviceDetail, ok := pending[serviceID]; ok && reflect.DeepEqual(serviceDetail, item.serviceDetail) {
+			delete(pending, serviceID)
+			continue
+		}
+		item.server.Close()
+		delete(tcpServerMap, serviceID)
+		log.Printf(" [INFO] tcp_proxy_stop %v stopped\n", item.server.Addr)
+	}
+	for _, serviceItem := range pending {
+		tcpServerStart(serviceItem)
+	}
+}
+
+// tcpServerStart 调用方持有 tcpLocker
+func tcpServerStart(serviceDetail *dao.ServiceDetail) {
+	addr := fmt.Sprintf(":%d", serviceDetail.TCPRule.Port)
+
+	//构建路由及设置中间件
+	router := tcp_proxy_middleware.NewTcpSliceRouter()
+	router.Group("/").Use(
+		tcp_proxy_middleware.TCPFlowCountMiddleware(),
+		tcp_proxy_middleware.TCPMetricsMiddleware(),
+		tcp_proxy_middleware.TCPWhiteListMiddleware(),
+		tcp_proxy_middleware.TCPBlackListMiddleware(),
+		tcp_proxy_middleware.TCPFlowLimitMiddleware(),
+		tcp_proxy_middleware.TCPFaultInjectionMiddleware(),
+	)
+
+	//构建回调handler，每个连接按负载均衡选择下游节点
+	routerHandler := tcp_proxy_middleware.NewTcpSliceRouterHandler(
+		func(c *tcp_proxy_middleware.TcpSliceRouterContext) tcp_proxy_middleware.TCPHandler {
+			return newTcpUpstreamHandler(c)
+		}, router)
+
+	baseCtx := context.WithValue(context.Background(), "service", serviceDetail)
+	tcpServer := &tcp_server.TcpServer{
+		Addr:    addr,
+		Handler: routerHandler,
+		BaseCtx: baseCtx,
+	}
+	tcpServerMap[serviceDetail.Info.ID] = &tcpServerItem{server: tcpServer, serviceDetail: serviceDetail}
+
+	go func() {
+		log.Printf(" [INFO] tcp_proxy_run %v\n", addr)
+		// 单个端口监听失败不影响其他服务
+		if err := tcpServer.ListenAndServe(); err != nil && err != tcp_server.ErrServerClosed {
+			log.Printf(" [ERROR] tcp_proxy_run %v err:%v\n", addr, err)
+		}
+	}()
+}
+
+func newTcpUpstreamHandler(c *tcp_proxy_middleware.TcpSliceRouterContext) tcp_proxy_middleware.TCPHandler {
+	serviceDetail := c.Get("service").(*dao.ServiceDetail)
+	lb, err := dao.LoadBalancerHandler.GetLoadBalancer(serviceDetail)
+	if err != nil {
+		return &tcpErrorHandler{err: err}
+	}
+	addr, err := lb.Get(c.ClientIP())
+	if err != nil || addr == "" {
+		return &tcpErrorHandler{err: fmt.Errorf("no available upstream")}
+	}
+	proxy := reverse_proxy.NewTcpLoadBalanceReverseProxy(addr)
+	if public.CircuitBreakerHandler.Enabled() {
+		// tcp 以建立上游连接是否成功作为结果
+		breaker := public.CircuitBreakerHandler.GetBreaker(serviceDetail.Info.ServiceName)
+		if !breaker.Allow() {
+			return &tcpErrorHandler{err: fmt.Errorf("circuit breaker open")}
+		}
+		dialer := &net.Dialer{Timeout: proxy.DialTimeout, KeepAlive: proxy.KeepAlivePeriod}
+		proxy.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, address)
+			breaker.Record(err == nil)
+			return conn, err
+		}
+	}
+	return proxy
+}
+
+func TcpServerStop() {
+	tcpLocker.Lock()
+	defer tcpLocker.Unlock()
+	for _, item := range tcpServerMap {
+		item.server.Close()
+		log.Printf(" [INFO] tcp_proxy_stop %v stopped\n", item.server.Addr)
+	}
+	tcpServerMap = map[int64]*tcpServerItem{}
+}
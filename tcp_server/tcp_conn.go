@@ -0,0 +1,44 @@
+package tcp_server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+)
+
+type contextKey struct {
+	name string
+}
+
+func (k *contextKey) String() string {
+	return "tcp_proxy context value " + k.name
+}
+
+type conn struct {
+	server     *TcpServer
+	rwc        net.Conn
+	remoteAddr string
+}
+
+func (c *conn) close() {
+	c.rwc.Close()
+}
+
+func (c *conn) serve(ctx context.Context) {
+	defer func() {
+		if err := recover(); err != nil && err != ErrAbortHandler {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			fmt.Printf("tcp: panic serving %v: %v\n%s", c.remoteAddr, err, buf)
+		}
+		c.close()
+	}()
+	c.remoteAddr = c.rwc.RemoteAddr().String()
+	ctx = context.WithValue(ctx, LocalAddrContextKey, c.rwc.LocalAddr())
+	if c.server.Handler == nil {
+		panic("handler empty")
+	}
+	c.server.Handler.ServeTCP(ctx, c.rwc)
+}
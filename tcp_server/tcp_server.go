@@ -0,0 +1,152 @@
+package tcp_server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrServerClosed     = errors.New("tcp: Server closed")
+	ErrAbortHandler     = errors.New("tcp: abort TCPHandler")
+	ServerContextKey    = &contextKey{"tcp-server"}
+	LocalAddrContextKey = &contextKey{"local-addr"}
+)
+
+type onceCloseListener struct {
+	net.Listener
+	once     sync.Once
+	closeErr error
+}
+
+func (oc *onceCloseListener) Close() error {
+	oc.once.Do(oc.close)
+	return oc.closeErr
+}
+
+func (oc *onceCloseListener) close() {
+	oc.closeErr = oc.Listener.Close()
+}
+
+// TCPHandler 处理一个 tcp 连接，返回后连接被关闭
+type TCPHandler interface {
+	ServeTCP(ctx context.Context, conn net.Conn)
+}
+
+type TcpServer struct {
+	Addr    string
+	Handler TCPHandler
+	BaseCtx context.Context
+
+	WriteTimeout     time.Duration
+	ReadTimeout      time.Duration
+	KeepAliveTimeout time.Duration
+
+	mu         sync.Mutex
+	inShutdown int32
+	doneChan   chan struct{}
+	l          *onceCloseListener
+}
+
+func (srv *TcpServer) shuttingDown() bool {
+	return atomic.LoadInt32(&srv.inShutdown) != 0
+}
+
+func (srv *TcpServer) ListenAndServe() error {
+	if srv.shuttingDown() {
+		return ErrServerClosed
+	}
+	addr := srv.Addr
+	if addr == "" {
+		return errors.New("need addr")
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(ln)
+}
+
+// Close 停止监听，已经建立的连接由 handler 自行结束
+func (srv *TcpServer) Close() error {
+	atomic.StoreInt32(&srv.inShutdown, 1)
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.doneChan == nil {
+		srv.doneChan = make(chan struct{})
+	}
+	select {
+	case <-srv.doneChan:
+	default:
+		close(srv.doneChan)
+	}
+	if srv.l != nil {
+		return srv.l.Close()
+	}
+	return nil
+}
+
+func (srv *TcpServer) Serve(l net.Listener) error {
+	srv.mu.Lock()
+	srv.l = &onceCloseListener{Listener: l}
+	srv.mu.Unlock()
+	defer srv.l.Close()
+	if srv.shuttingDown() {
+		return ErrServerClosed
+	}
+	if srv.BaseCtx == nil {
+		srv.BaseCtx = context.Background()
+	}
+	ctx := context.WithValue(srv.BaseCtx, ServerContextKey, srv)
+	for {
+		rw, err := l.Accept()
+		if err != nil {
+			select {
+			case <-srv.getDoneChan():
+				return ErrServerClosed
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				fmt.Printf("tcp: accept fail, err: %v\n", err)
+				time.Sleep(5 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		c := srv.newConn(rw)
+		go c.serve(ctx)
+	}
+}
+
+func (srv *TcpServer) newConn(rwc net.Conn) *conn {
+	c := &conn{
+		server: srv,
+		rwc:    rwc,
+	}
+	if d := c.server.ReadTimeout; d != 0 {
+		c.rwc.SetReadDeadline(time.Now().Add(d))
+	}
+	if d := c.server.WriteTimeout; d != 0 {
+		c.rwc.SetWriteDeadline(time.Now().Add(d))
+	}
+	if d := c.server.KeepAliveTimeout; d != 0 {
+		if tcpConn, ok := c.rwc.(*net.TCPConn); ok {
+			tcpConn.SetKeepAlive(true)
+			tcpConn.SetKeepAlivePeriod(d)
+		}
+	}
+	return c
+}
+
+func (srv *TcpServer) getDoneChan() <-chan struct{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.doneChan == nil {
+		srv.doneChan = make(chan struct{})
+	}
+	return srv.doneChan
+}